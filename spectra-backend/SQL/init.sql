@@ -1,99 +1,150 @@
+// 事件表默认保留 90 天，可通过 PUT /api/admin/retention 调整
+
 // 错误日志表
 CREATE TABLE error_logs
 (
-    timestamp   DateTime,
+    timestamp   DateTime64(3),   -- 毫秒精度，保证同一秒内的事件顺序
     project_id  String,
     session_id  String,
     trace_id    String,
     user_id     String,
     url         String,
     referrer    String,
+    sdk_version LowCardinality(String) DEFAULT '',   -- 上报 SDK 的版本，旧版 SDK 为空
+    platform    LowCardinality(String) DEFAULT '',   -- 上报 SDK 的运行平台（web / ios / android ...）
     type        String,
     name        String,
     message     String,
-    extra       JSON
+    severity    LowCardinality(String) DEFAULT 'error',   -- fatal / error / warning / info
+    stack_hash  String,          -- extra.stack 的 SHA-256，完整堆栈见 stack_traces 表
+    extra       JSON,
+    INDEX idx_type type TYPE set(100) GRANULARITY 4,            -- 跳数索引，按错误类型过滤时跳过不相关的数据块
+    INDEX idx_name name TYPE bloom_filter(0.01) GRANULARITY 4   -- 错误名称取值不固定，使用布隆过滤器
 )
 ENGINE = MergeTree
 PARTITION BY toYYYYMMDD(timestamp)
-ORDER BY (project_id, timestamp);
+ORDER BY (project_id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+
+// 错误堆栈表，按哈希去重保存完整堆栈，错误日志通过 stack_hash 引用
+// timestamp 为最近一次写入时间，仍在出现的堆栈会被定期重新写入，不会先于引用它的错误日志过期
+CREATE TABLE stack_traces
+(
+    hash        String,
+    stack       String,
+    timestamp   DateTime64(3)
+)
+ENGINE = ReplacingMergeTree(timestamp)
+ORDER BY hash
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
 
 // 性能指标表
 CREATE TABLE performance_metrics
 (
-    timestamp   DateTime,
+    timestamp   DateTime64(3),   -- 毫秒精度，保证同一秒内的事件顺序
     project_id  String,
     session_id  String,
     trace_id    String,
     user_id     String,
     url         String,
     referrer    String,
+    sdk_version LowCardinality(String) DEFAULT '',   -- 上报 SDK 的版本，旧版 SDK 为空
+    platform    LowCardinality(String) DEFAULT '',   -- 上报 SDK 的运行平台（web / ios / android ...）
     type        String,       -- performance
     name        String,       -- FCP / LCP / CLS / TTFB...
     value       Float64,      -- 指标数值（ms / 分数）
-    extra       JSON
+    extra       JSON,
+    INDEX idx_name name TYPE set(100) GRANULARITY 4             -- 跳数索引，按指标名称过滤时跳过不相关的数据块
 )
 ENGINE = MergeTree
 PARTITION BY toYYYYMMDD(timestamp)
-ORDER BY (project_id, timestamp);
+ORDER BY (project_id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
 
 // 用户行为表
 CREATE TABLE user_actions
 (
-    timestamp   DateTime,
+    timestamp   DateTime64(3),   -- 毫秒精度，保证同一秒内的事件顺序
     project_id  String,
     session_id  String,
     trace_id    String,
     user_id     String,
     url         String,
     referrer    String,
+    sdk_version LowCardinality(String) DEFAULT '',   -- 上报 SDK 的版本，旧版 SDK 为空
+    platform    LowCardinality(String) DEFAULT '',   -- 上报 SDK 的运行平台（web / ios / android ...）
     type        String,      -- user
     name        String,      -- click / route / api_timing
     message     String,      -- 元素标识 / 路由信息
     method      String,      -- GET / POST（仅 api_timing）
     status      UInt16,      -- HTTP 状态码（仅 api_timing）
     value       Float64,     -- 接口耗时 / API duration
-    extra       JSON
+    extra       JSON,
+    INDEX idx_name name TYPE set(100) GRANULARITY 4             -- 跳数索引，按行为类型过滤时跳过不相关的数据块
 )
 ENGINE = MergeTree
 PARTITION BY toYYYYMMDD(timestamp)
-ORDER BY (project_id, timestamp);
+ORDER BY (project_id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
 
 // 自定义事件表
 CREATE TABLE custom_events
 (
-    timestamp   DateTime,
+    timestamp   DateTime64(3),   -- 毫秒精度，保证同一秒内的事件顺序
     project_id  String,
     session_id  String,
     trace_id    String,
     user_id     String,
     url         String,
     referrer    String,
+    sdk_version LowCardinality(String) DEFAULT '',   -- 上报 SDK 的版本，旧版 SDK 为空
+    platform    LowCardinality(String) DEFAULT '',   -- 上报 SDK 的运行平台（web / ios / android ...）
     type        String,      -- custom
     name        String,      -- 自定义事件名
     message     String,      -- 固定为 custom_event
-    extra       JSON         -- 自定义业务字段
+    extra       JSON,        -- 自定义业务字段
+    INDEX idx_name name TYPE bloom_filter(0.01) GRANULARITY 4   -- 跳数索引，事件名由业务自定义，使用布隆过滤器
 )
 ENGINE = MergeTree
 PARTITION BY toYYYYMMDD(timestamp)
-ORDER BY (project_id, timestamp);
+ORDER BY (project_id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
 
 // 页面停留时长表
 CREATE TABLE page_stay
 (
-    timestamp   DateTime,
+    timestamp   DateTime64(3),   -- 毫秒精度，保证同一秒内的事件顺序
     project_id  String,
     session_id  String,
     trace_id    String,
     user_id     String,
     url         String,
     referrer    String,
+    sdk_version LowCardinality(String) DEFAULT '',   -- 上报 SDK 的版本，旧版 SDK 为空
+    platform    LowCardinality(String) DEFAULT '',   -- 上报 SDK 的运行平台（web / ios / android ...）
     type        String,      -- page_stay
     name        String,      -- page_stay_time
     value       Float64,     -- 页面停留时长(ms)
-    extra       JSON
+    extra       JSON,
+    version     UInt64       -- 写入时间(ms)，同一会话同一页面保留 version 最大的一行
 )
-ENGINE = MergeTree
-PARTITION BY toYYYYMMDD(timestamp)
-ORDER BY (project_id, timestamp);
+-- 同一会话同一页面的多次上报只保留最新一次（Last-Write-Wins），查询需使用 FINAL
+-- session_id 为空的行无法关联到会话，排序键追加内容哈希，只合并完全相同的重复上报
+-- 按月分区，减少跨天停留被分到不同分区而无法合并的情况
+ENGINE = ReplacingMergeTree(version)
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (project_id, session_id, url, if(session_id = '', cityHash64(timestamp, trace_id, user_id, value), 0))
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
 
 
+
+// 深度健康检查探针表，仅供 GET /health/deep 读写，TTL 自动清理
+CREATE TABLE health_canary
+(
+    timestamp   DateTime64(3),
+    id          String,
+    extra       JSON
+)
+ENGINE = MergeTree
+ORDER BY (id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 1 DAY;
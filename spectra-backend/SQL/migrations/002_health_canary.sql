@@ -0,0 +1,11 @@
+-- 新增深度健康检查探针表，GET /health/deep 每次写入并读回一行
+-- 探针行通过 TTL 在一天后自动清理，无需手动删除
+CREATE TABLE IF NOT EXISTS health_canary
+(
+    timestamp   DateTime64(3),
+    id          String,
+    extra       JSON
+)
+ENGINE = MergeTree
+ORDER BY (id, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 1 DAY;
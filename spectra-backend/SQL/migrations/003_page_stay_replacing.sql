@@ -0,0 +1,32 @@
+-- page_stay 改为 ReplacingMergeTree，同一会话同一页面的多次上报只保留最新一次（Last-Write-Wins）
+-- 排序键变化无法直接 ALTER，先建新表并复制数据，再用 EXCHANGE TABLES 原子替换。需要 Atomic 数据库引擎（默认）。
+-- 历史数据的 version 取事件时间，已有的重复上报会在后台合并或查询 FINAL 时去重。
+-- 执行期间新写入的数据可能落在旧表中，建议在停写窗口执行。
+
+CREATE TABLE page_stay_lww
+(
+    timestamp   DateTime64(3),
+    project_id  String,
+    session_id  String,
+    trace_id    String,
+    user_id     String,
+    url         String,
+    referrer    String,
+    type        String,
+    name        String,
+    value       Float64,
+    extra       JSON,
+    version     UInt64
+)
+ENGINE = ReplacingMergeTree(version)
+PARTITION BY toYYYYMM(timestamp)
+ORDER BY (project_id, session_id, url, if(session_id = '', cityHash64(timestamp, trace_id, user_id, value), 0));
+
+INSERT INTO page_stay_lww
+SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, extra,
+       toUInt64(toUnixTimestamp64Milli(timestamp)) AS version
+FROM page_stay;
+
+EXCHANGE TABLES page_stay AND page_stay_lww;
+
+DROP TABLE page_stay_lww;
@@ -0,0 +1,9 @@
+-- 为所有事件表设置数据保留 TTL，超过保留天数的数据由 ClickHouse 在后台合并时删除
+-- 默认保留 90 天，按部署需要修改 INTERVAL 后执行；之后也可以通过 PUT /api/admin/retention 调整。
+-- MODIFY TTL 默认会对已有数据执行 MATERIALIZE TTL mutation，数据量大时耗时较长。
+
+ALTER TABLE error_logs MODIFY TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+ALTER TABLE performance_metrics MODIFY TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+ALTER TABLE user_actions MODIFY TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+ALTER TABLE custom_events MODIFY TTL toDateTime(timestamp) + INTERVAL 90 DAY;
+ALTER TABLE page_stay MODIFY TTL toDateTime(timestamp) + INTERVAL 90 DAY;
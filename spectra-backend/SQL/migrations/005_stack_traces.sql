@@ -0,0 +1,14 @@
+-- 错误堆栈去重：extra.stack 移到 stack_traces 表按哈希保存一次，错误日志只保留 stack_hash
+-- 历史数据的堆栈仍保留在 extra 中，stack_hash 为空。TTL 与 004_retention_ttl.sql 保持一致。
+
+ALTER TABLE error_logs ADD COLUMN IF NOT EXISTS stack_hash String DEFAULT '' AFTER message;
+
+CREATE TABLE IF NOT EXISTS stack_traces
+(
+    hash        String,
+    stack       String,
+    timestamp   DateTime64(3)
+)
+ENGINE = ReplacingMergeTree(timestamp)
+ORDER BY hash
+TTL toDateTime(timestamp) + INTERVAL 90 DAY;
@@ -0,0 +1,4 @@
+-- 错误日志新增严重程度：fatal / error / warning / info
+-- 历史数据和未上报严重程度的事件均为 error。
+
+ALTER TABLE error_logs ADD COLUMN IF NOT EXISTS severity LowCardinality(String) DEFAULT 'error' AFTER message;
@@ -0,0 +1,18 @@
+-- 为事件表的 type / name 列添加跳数索引（data skipping index），按事件类型或名称过滤时跳过不含目标值的数据块
+-- 排序键 ORDER BY (project_id, timestamp) 已在建表时设置，按项目和时间范围查询直接走主键，project_id 不需要额外的跳数索引；
+-- 排序键无法通过 ALTER 修改，如果服务启动日志提示排序键不符，需要参照 003_page_stay_replacing.sql 建新表后 EXCHANGE TABLES。
+-- name 取值较少的表使用 set 索引，自定义事件和错误名称取值不固定，使用 bloom_filter 索引。
+-- ADD INDEX 只对新写入的数据生效，MATERIALIZE INDEX 为已有数据构建索引，属于 mutation，数据量大时耗时较长，建议在低峰期执行。
+-- 服务启动时会检查这些索引，db.schema_check 为 create 时自动执行下面的 ADD INDEX（不包括 MATERIALIZE）。
+
+ALTER TABLE error_logs ADD INDEX IF NOT EXISTS idx_type type TYPE set(100) GRANULARITY 4;
+ALTER TABLE error_logs ADD INDEX IF NOT EXISTS idx_name name TYPE bloom_filter(0.01) GRANULARITY 4;
+ALTER TABLE performance_metrics ADD INDEX IF NOT EXISTS idx_name name TYPE set(100) GRANULARITY 4;
+ALTER TABLE user_actions ADD INDEX IF NOT EXISTS idx_name name TYPE set(100) GRANULARITY 4;
+ALTER TABLE custom_events ADD INDEX IF NOT EXISTS idx_name name TYPE bloom_filter(0.01) GRANULARITY 4;
+
+ALTER TABLE error_logs MATERIALIZE INDEX idx_type;
+ALTER TABLE error_logs MATERIALIZE INDEX idx_name;
+ALTER TABLE performance_metrics MATERIALIZE INDEX idx_name;
+ALTER TABLE user_actions MATERIALIZE INDEX idx_name;
+ALTER TABLE custom_events MATERIALIZE INDEX idx_name;
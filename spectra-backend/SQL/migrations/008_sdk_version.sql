@@ -0,0 +1,13 @@
+-- 所有事件表新增上报 SDK 的版本和平台，用于按 SDK 版本排查问题
+-- 旧版 SDK 不上报这两个字段，历史数据和未上报的事件均为空字符串；使用空字符串而不是 Nullable，查询和排序不需要额外处理 NULL。
+
+ALTER TABLE error_logs ADD COLUMN IF NOT EXISTS sdk_version LowCardinality(String) DEFAULT '' AFTER referrer;
+ALTER TABLE error_logs ADD COLUMN IF NOT EXISTS platform LowCardinality(String) DEFAULT '' AFTER sdk_version;
+ALTER TABLE performance_metrics ADD COLUMN IF NOT EXISTS sdk_version LowCardinality(String) DEFAULT '' AFTER referrer;
+ALTER TABLE performance_metrics ADD COLUMN IF NOT EXISTS platform LowCardinality(String) DEFAULT '' AFTER sdk_version;
+ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS sdk_version LowCardinality(String) DEFAULT '' AFTER referrer;
+ALTER TABLE user_actions ADD COLUMN IF NOT EXISTS platform LowCardinality(String) DEFAULT '' AFTER sdk_version;
+ALTER TABLE custom_events ADD COLUMN IF NOT EXISTS sdk_version LowCardinality(String) DEFAULT '' AFTER referrer;
+ALTER TABLE custom_events ADD COLUMN IF NOT EXISTS platform LowCardinality(String) DEFAULT '' AFTER sdk_version;
+ALTER TABLE page_stay ADD COLUMN IF NOT EXISTS sdk_version LowCardinality(String) DEFAULT '' AFTER referrer;
+ALTER TABLE page_stay ADD COLUMN IF NOT EXISTS platform LowCardinality(String) DEFAULT '' AFTER sdk_version;
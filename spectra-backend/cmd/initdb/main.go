@@ -0,0 +1,73 @@
+// initdb 执行建表或迁移脚本，默认执行 SQL/init.sql
+//
+//	go run ./cmd/initdb
+//	go run ./cmd/initdb -file SQL/migrations/008_sdk_version.sql
+//
+// 连接信息取自配置文件的 db 配置，语句超时、文件大小上限和失败后是否停止见 db.init
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"spectra-backend/config"
+	"spectra-backend/middleware"
+	"spectra-backend/repository"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	file := flag.String("file", "SQL/init.sql", "SQL script to execute")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	logger := middleware.InitLogger()
+	err = run(cfg, logger, *file)
+	if syncErr := middleware.SyncLogger(logger, time.Duration(cfg.Log.SyncTimeout)*time.Millisecond); syncErr != nil {
+		log.Printf("Failed to flush logs: %v", syncErr)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// run 读取脚本并逐条执行，失败原因已记录到日志
+func run(cfg *config.Config, logger *zap.Logger, file string) error {
+	// 先读取并拆分脚本，文件超出大小上限时不连接数据库
+	statements, err := loadStatements(file, int64(cfg.DB.Init.MaxFileBytes))
+	if err != nil {
+		logger.Error("Failed to load SQL script", zap.Error(err))
+		return err
+	}
+
+	repo, err := repository.NewClickHouseRepository(cfg, logger)
+	if err != nil {
+		logger.Error("Failed to connect to ClickHouse", zap.Error(err))
+		return err
+	}
+	defer repo.Close()
+
+	// 收到退出信号时取消正在执行的语句
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	opts := runOptions{
+		statementTimeout: time.Duration(cfg.DB.Init.StatementTimeout) * time.Second,
+		stopOnError:      cfg.DB.Init.StopOnError,
+	}
+	if err := runStatements(ctx, repo.DB, statements, opts, logger); err != nil {
+		logger.Error("Database initialization failed", zap.String("file", file), zap.Error(err))
+		return err
+	}
+	logger.Info("Database initialization completed", zap.String("file", file), zap.Int("statements", len(statements)))
+	return nil
+}
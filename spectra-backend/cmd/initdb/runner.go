@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// statementPreviewLen 日志和错误信息中语句预览的最大字符数
+const statementPreviewLen = 100
+
+// runOptions 脚本执行选项，取自 config.DBInitConfig
+type runOptions struct {
+	statementTimeout time.Duration // 单条语句的最长执行时间，0 表示不限制
+	stopOnError      bool
+}
+
+// statementError 执行失败的语句，错误信息包含语句序号和语句开头部分，便于定位脚本中的位置
+type statementError struct {
+	index     int // 语句序号，从1开始
+	statement string
+	err       error
+}
+
+func (e *statementError) Error() string {
+	return fmt.Sprintf("statement %d failed: %v: %s", e.index, e.err, preview(e.statement))
+}
+
+func (e *statementError) Unwrap() error {
+	return e.err
+}
+
+// loadStatements 读取 SQL 文件并拆分为语句，文件超过 maxBytes 时返回错误
+func loadStatements(path string, maxBytes int64) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQL file: %w", err)
+	}
+	defer file.Close()
+
+	statements, err := readStatements(file, maxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SQL file %s: %w", path, err)
+	}
+	return statements, nil
+}
+
+// readStatements 最多读取 maxBytes 字节并拆分为语句，内容超过 maxBytes 时返回错误，不执行截断后的脚本
+func readStatements(r io.Reader, maxBytes int64) ([]string, error) {
+	if maxBytes <= 0 {
+		return nil, fmt.Errorf("max file size must be positive, got %d", maxBytes)
+	}
+	// 只多读一个字节用于判断是否超限
+	content, err := io.ReadAll(io.LimitReader(r, maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > maxBytes {
+		return nil, fmt.Errorf("file exceeds %d bytes", maxBytes)
+	}
+	return splitStatements(string(content)), nil
+}
+
+// splitStatements 按行尾的分号拆分语句，跳过空行和 // 或 -- 开头的注释行
+// 文件末尾缺少分号的语句同样返回，不会被静默丢弃
+func splitStatements(content string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.Contains(line, ";") {
+			if statement := strings.TrimSpace(current.String()); statement != "" {
+				statements = append(statements, statement)
+			}
+			current.Reset()
+		}
+	}
+	if statement := strings.TrimSpace(current.String()); statement != "" {
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// runStatements 依次执行语句，每条语句单独设置超时
+// stopOnError 为 true 时返回第一条失败语句的 *statementError，之后的语句不再执行；
+// 否则记录失败并继续，全部执行完后返回所有失败语句的错误
+func runStatements(ctx context.Context, db *sql.DB, statements []string, opts runOptions, logger *zap.Logger) error {
+	var failures []error
+	for i, statement := range statements {
+		logger.Info("Executing statement", zap.Int("index", i+1), zap.String("statement", preview(statement)))
+		if err := execStatement(ctx, db, statement, opts.statementTimeout); err != nil {
+			stmtErr := &statementError{index: i + 1, statement: statement, err: err}
+			if opts.stopOnError {
+				return stmtErr
+			}
+			logger.Error("Statement failed, continuing with the next one", zap.Error(stmtErr))
+			failures = append(failures, stmtErr)
+			continue
+		}
+		logger.Info("Statement executed", zap.Int("index", i+1))
+	}
+	return errors.Join(failures...)
+}
+
+// execStatement 执行单条语句，timeout 大于0时超时后取消
+func execStatement(ctx context.Context, db *sql.DB, statement string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	_, err := db.ExecContext(ctx, statement)
+	return err
+}
+
+// preview 返回语句的开头部分，换行替换为空格
+func preview(statement string) string {
+	statement = strings.Join(strings.Fields(statement), " ")
+	if runes := []rune(statement); len(runes) > statementPreviewLen {
+		return string(runes[:statementPreviewLen]) + "..."
+	}
+	return statement
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fakeDB 不连接 ClickHouse 的 database/sql 驱动，记录执行的语句
+// 包含 failMarker 的语句返回 errFakeDDL，包含 hangMarker 的语句一直阻塞到 ctx 结束
+type fakeDB struct {
+	mu       sync.Mutex
+	executed []string
+}
+
+const (
+	failMarker = "FAIL"
+	hangMarker = "HANG"
+)
+
+// errFakeDDL fakeDB 中失败语句返回的错误
+var errFakeDDL = errors.New("fake DDL error")
+
+// openFakeDB 创建使用 fakeDB 的连接
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return db, fake
+}
+
+// statements 返回已执行的语句
+func (f *fakeDB) statements() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.executed...)
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+
+func (f *fakeDB) Driver() driver.Driver { return nil }
+
+// fakeConn fakeDB 的连接，只支持 ExecContext
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB does not support prepared statements")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakeDB does not support transactions")
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.db.mu.Lock()
+	c.db.executed = append(c.db.executed, query)
+	c.db.mu.Unlock()
+
+	switch {
+	case strings.Contains(query, failMarker):
+		return nil, errFakeDDL
+	case strings.Contains(query, hangMarker):
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func TestRunStatementsStopsOnFailure(t *testing.T) {
+	db, fake := openFakeDB(t)
+	statements := []string{
+		"CREATE TABLE a (id UInt64) ENGINE = Memory;",
+		"CREATE TABLE b (id FAIL) ENGINE = Memory;",
+		"CREATE TABLE c (id UInt64) ENGINE = Memory;",
+	}
+
+	err := runStatements(context.Background(), db, statements, runOptions{stopOnError: true}, zap.NewNop())
+
+	var stmtErr *statementError
+	if !errors.As(err, &stmtErr) {
+		t.Fatalf("runStatements() error = %v, want *statementError", err)
+	}
+	if stmtErr.index != 2 || !errors.Is(err, errFakeDDL) {
+		t.Errorf("error = %v, want statement 2 wrapping the DDL error", err)
+	}
+	// 错误信息中标明失败的语句
+	if msg := err.Error(); !strings.Contains(msg, "statement 2") || !strings.Contains(msg, "CREATE TABLE b") {
+		t.Errorf("error message %q does not name the failing statement", msg)
+	}
+	if got := fake.statements(); len(got) != 2 {
+		t.Errorf("executed %d statements, want 2 (stopped after the failure): %v", len(got), got)
+	}
+}
+
+func TestRunStatementsContinuesOnFailure(t *testing.T) {
+	db, fake := openFakeDB(t)
+	statements := []string{
+		"CREATE TABLE a FAIL;",
+		"CREATE TABLE b (id UInt64) ENGINE = Memory;",
+		"CREATE TABLE c FAIL;",
+	}
+
+	err := runStatements(context.Background(), db, statements, runOptions{stopOnError: false}, zap.NewNop())
+	if err == nil {
+		t.Fatal("runStatements() error = nil, want the failed statements")
+	}
+	if msg := err.Error(); !strings.Contains(msg, "statement 1") || !strings.Contains(msg, "statement 3") {
+		t.Errorf("error message %q does not name both failed statements", msg)
+	}
+	if got := fake.statements(); len(got) != 3 {
+		t.Errorf("executed %d statements, want all 3: %v", len(got), got)
+	}
+}
+
+func TestRunStatementsTimeout(t *testing.T) {
+	db, fake := openFakeDB(t)
+	statements := []string{"OPTIMIZE TABLE a HANG;", "CREATE TABLE b (id UInt64) ENGINE = Memory;"}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runStatements(context.Background(), db, statements,
+			runOptions{statementTimeout: 20 * time.Millisecond, stopOnError: true}, zap.NewNop())
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("runStatements() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("runStatements() did not return after the statement timeout")
+	}
+	if got := fake.statements(); len(got) != 1 {
+		t.Errorf("executed %d statements, want 1: %v", len(got), got)
+	}
+}
+
+func TestReadStatementsSizeCap(t *testing.T) {
+	content := "CREATE TABLE a (id UInt64) ENGINE = Memory;\n"
+	size := int64(len(content))
+
+	if statements, err := readStatements(strings.NewReader(content), size); err != nil || len(statements) != 1 {
+		t.Errorf("readStatements() at the cap = %v, %v, want one statement", statements, err)
+	}
+	if _, err := readStatements(strings.NewReader(content), size-1); err == nil {
+		t.Error("readStatements() over the cap returned no error")
+	}
+	if _, err := readStatements(strings.NewReader(content), 0); err == nil {
+		t.Error("readStatements() with a zero cap returned no error")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	content := `// 错误日志表
+CREATE TABLE a
+(
+    id UInt64   -- 主键
+)
+ENGINE = Memory;
+
+-- 迁移说明
+ALTER TABLE a ADD COLUMN name String;
+ALTER TABLE a DROP COLUMN name`
+
+	want := []string{
+		"CREATE TABLE a\n(\nid UInt64   -- 主键\n)\nENGINE = Memory;",
+		"ALTER TABLE a ADD COLUMN name String;",
+		// 末尾缺少分号的语句同样执行
+		"ALTER TABLE a DROP COLUMN name",
+	}
+	got := splitStatements(content)
+	if len(got) != len(want) {
+		t.Fatalf("splitStatements() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("statement %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
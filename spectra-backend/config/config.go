@@ -1,15 +1,33 @@
 package config
 
 import (
+	"fmt"
+	"regexp"
+
 	"github.com/spf13/viper"
 )
 
 // Config 应用程序配置结构
 type Config struct {
-	App    AppConfig    `mapstructure:"app"`
-	Server ServerConfig `mapstructure:"server"`
-	Log    LogConfig    `mapstructure:"log"`
-	DB     DBConfig     `mapstructure:"db"`
+	App       AppConfig       `mapstructure:"app"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Log       LogConfig       `mapstructure:"log"`
+	DB        DBConfig        `mapstructure:"db"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Ingest    IngestConfig    `mapstructure:"ingest"`
+	Dashboard DashboardConfig `mapstructure:"dashboard"`
+	Analytics AnalyticsConfig `mapstructure:"analytics"`
+	CORS      CORSConfig      `mapstructure:"cors"`
+	ProjectID ProjectIDConfig `mapstructure:"project_id"`
+	EventBus  EventBusConfig  `mapstructure:"event_bus"`
+	// WebVitals Web Vitals 评分卡使用的指标及阈值，key 为性能指标名称，不区分大小写
+	WebVitals map[string]WebVitalThreshold `mapstructure:"web_vitals"`
+}
+
+// EventBusConfig 事件总线配置，开启批量发布后事件攒批分发给实时消费者，减少高负载下逐个分发的开销
+type EventBusConfig struct {
+	BatchWindow  int `mapstructure:"batch_window"`   // 批量发布的时间窗口（毫秒），事件最多延迟该时间，0 表示逐个分发
+	BatchMaxSize int `mapstructure:"batch_max_size"` // 单个批次最多包含的事件数，达到后立即分发
 }
 
 // AppConfig 应用基本配置
@@ -25,21 +43,239 @@ type ServerConfig struct {
 	Host         string `mapstructure:"host"`
 	ReadTimeout  int    `mapstructure:"read_timeout"`
 	WriteTimeout int    `mapstructure:"write_timeout"`
+	// AllowedProjects 允许上报数据的项目ID列表，为空时不限制
+	AllowedProjects []string `mapstructure:"allowed_projects"`
+	// ResponseEnvelope 列表接口默认返回 {"data":[...],"meta":{...}} 包装格式，关闭时返回裸数组
+	// 客户端可通过 Accept 头显式选择格式，见 middleware.ResponseFormat
+	ResponseEnvelope bool `mapstructure:"response_envelope"`
+	// JSONCasing 响应 JSON 键名风格：snake（默认）或 camel，客户端可通过 Accept 头的 casing 参数覆盖
+	JSONCasing string `mapstructure:"json_casing"`
+	// TrustedProxies 受信任的反向代理 IP 或 CIDR，只有来自这些地址的请求才使用 X-Forwarded-For 等头解析客户端 IP
+	// 为空时不信任任何代理，客户端 IP 取连接的远端地址
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// APIVersion 当前 API 版本号（如 v1），路由注册在 /api/<版本号> 下，/api 作为其别名
+	APIVersion string `mapstructure:"api_version"`
+	// RequestTimeout 查询（GET）请求的最长处理时间（秒），超时后取消 ClickHouse 查询并返回 504，0 表示不限制
+	// 应小于 WriteTimeout，否则响应来不及写出连接就已关闭
+	RequestTimeout int `mapstructure:"request_timeout"`
+	// GroupTimeouts 按路由组设置的请求超时，优先于 RequestTimeout
+	GroupTimeouts GroupTimeoutConfig `mapstructure:"group_timeouts"`
+	// DefaultOrder 列表查询未指定 order 参数时的排序方向：desc（默认，最新的在前）或 asc
+	DefaultOrder string `mapstructure:"default_order"`
+	// MaxInFlight 全局同时处理的请求数上限，超出时直接返回 503，健康检查和指标接口不受限制；0 表示不限制
+	MaxInFlight int `mapstructure:"max_in_flight"`
+}
+
+// GroupTimeoutConfig 路由组请求超时配置（毫秒），超时后取消 ClickHouse 操作并返回 504，0 表示使用全局的 request_timeout
+// 上报路由保持较短的超时，聚合查询允许更长，互不影响；均应小于 WriteTimeout
+type GroupTimeoutConfig struct {
+	// Ingest 单条事件上报和压缩批量上报路由的超时；流式上报的时长取决于客户端发送速度，导入属于管理接口，均不受此限制
+	Ingest int `mapstructure:"ingest"`
+	// Analytics 聚合查询路由（受 analytics.max_concurrent 限制的接口）的超时，包含排队等待时间
+	Analytics int `mapstructure:"analytics"`
+}
+
+// apiVersionPattern API 版本号格式，v 加数字
+var apiVersionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+// IsAPIVersion 判断字符串是否为合法的 API 版本号，如 v1、v2
+func IsAPIVersion(version string) bool {
+	return apiVersionPattern.MatchString(version)
 }
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level    string `mapstructure:"level"` // debug, info, warn, error
-	Path     string `mapstructure:"path"`
-	MaxSize  int    `mapstructure:"max_size"` // MB
-	MaxAge   int    `mapstructure:"max_age"`  // days
-	Compress bool   `mapstructure:"compress"`
+	Level      string `mapstructure:"level"` // debug, info, warn, error
+	Path       string `mapstructure:"path"`
+	MaxSize    int    `mapstructure:"max_size"`    // MB
+	MaxAge     int    `mapstructure:"max_age"`     // days
+	MaxBackups int    `mapstructure:"max_backups"` // 保留的旧日志文件数，0 表示不限制
+	Compress   bool   `mapstructure:"compress"`
+	Output     string `mapstructure:"output"` // file, stdout, both；为空时开发环境为 both，其他环境为 file
+	// AccessLogHeaders 访问日志是否记录请求头
+	AccessLogHeaders bool `mapstructure:"access_log_headers"`
+	// RedactHeaders 访问日志中需要脱敏的请求头名称，不区分大小写
+	RedactHeaders []string `mapstructure:"redact_headers"`
+	// BodyLog 上报接口请求体和响应体的调试日志，仅在日志级别为 debug 时生效
+	BodyLog BodyLogConfig `mapstructure:"body_log"`
+	// SyncTimeout 服务退出前刷新日志的最长等待时间（毫秒），0 表示一直等待
+	SyncTimeout int `mapstructure:"sync_timeout"`
+}
+
+// BodyLogConfig 请求体和响应体调试日志配置，用于排查 SDK 接入问题，不要在生产环境开启
+type BodyLogConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	MaxBytes     int      `mapstructure:"max_bytes"`     // 请求体和响应体各自最多记录的字节数，超出部分截断
+	RedactFields []string `mapstructure:"redact_fields"` // 值需要脱敏的 JSON 字段名，不区分大小写
+}
+
+// AuthConfig 鉴权配置
+type AuthConfig struct {
+	AdminToken string `mapstructure:"admin_token"` // 管理接口令牌，为空时禁用管理接口
+	// AllowProjectPurge 为 true 时允许通过管理接口删除整个项目的数据，默认关闭
+	AllowProjectPurge bool `mapstructure:"allow_project_purge"`
+}
+
+// IngestConfig 数据上报配置
+type IngestConfig struct {
+	MaxExtraBytes int `mapstructure:"max_extra_bytes"` // Extra 字段最大字节数，0 表示不限制
+	// MetricAliases 性能指标名称别名，key 为别名（不区分大小写），value 为保存时使用的标准名称
+	MetricAliases map[string]string `mapstructure:"metric_aliases"`
+	Scrub         ScrubConfig       `mapstructure:"scrub"`
+	// MaxBatchItems 批量上报单个请求最多包含的事件数
+	MaxBatchItems int `mapstructure:"max_batch_items"`
+	// MaxBatchBytes 批量上报单个请求解码后的最大字节数
+	MaxBatchBytes int `mapstructure:"max_batch_bytes"`
+	// IDScheme 缺少 trace_id / session_id 时服务端生成 ID 的方案：uuid、ulid 或 none（不生成）
+	IDScheme  string          `mapstructure:"id_scheme"`
+	ClockSkew ClockSkewConfig `mapstructure:"clock_skew"`
+	// EventAge 实时上报中事件时间与服务端接收时间相差过大时拒绝事件
+	EventAge EventAgeConfig `mapstructure:"event_age"`
+	// ProjectLimit 限制近期接收数据的不同项目数，防止缺陷或攻击产生大量随机项目ID
+	ProjectLimit ProjectLimitConfig `mapstructure:"project_limit"`
+	// StrictFields 为 true 时单条上报中出现未定义的顶层字段返回 422，为 false 时忽略这些字段
+	StrictFields bool `mapstructure:"strict_fields"`
+	// MaxURLLength URL 和 Referrer 的最大字节数，超过时去掉查询参数和片段后截断，0 表示不限制
+	MaxURLLength int `mapstructure:"max_url_length"`
+	// NoContent 为 true 时上报成功返回 204 且不带响应体，请求携带 verbose=true 时仍返回完整响应
+	NoContent bool `mapstructure:"no_content"`
+	// SDKHeaders 请求体未上报 sdk_version / platform 时读取的请求头，为空时不读取
+	SDKHeaders SDKHeadersConfig `mapstructure:"sdk_headers"`
+	// Batching 单条上报的批量合并，开启后按表合并为多行插入，减少 ClickHouse 小 part
+	Batching BatchingConfig `mapstructure:"batching"`
+	// UserIDHash 保存前对 user_id 做加盐哈希，不保存原始用户标识
+	UserIDHash UserIDHashConfig `mapstructure:"user_id_hash"`
+	// UserAgent 根据请求 User-Agent 在 extra 中补充 browser / os / device_type
+	UserAgent UserAgentConfig `mapstructure:"user_agent"`
+}
+
+// UserAgentConfig User-Agent 解析配置，客户端已在 extra 中上报的同名字段不会被覆盖
+type UserAgentConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// BotPatterns User-Agent 中出现即视为爬虫或脚本的片段，不区分大小写，覆盖时需自行包含默认项
+	BotPatterns []string `mapstructure:"bot_patterns"`
+}
+
+// UserIDHashConfig user_id 哈希配置，哈希是单向的，开启后按原始 user_id 查询需先用相同的 salt 哈希
+type UserIDHashConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Salt    string `mapstructure:"salt"` // 哈希使用的 salt，开启时必填；修改后同一用户的哈希值随之改变
+}
+
+// BatchingConfig 单条上报批量合并配置，上报请求等待所在批次写入完成后返回，延迟最多增加 FlushInterval
+type BatchingConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	FlushInterval int  `mapstructure:"flush_interval"` // 刷新间隔（毫秒）
+	MaxSize       int  `mapstructure:"max_size"`       // 待写入事件数达到该值时立即刷新
+}
+
+// SDKHeadersConfig 携带 SDK 版本和平台的请求头名称
+type SDKHeadersConfig struct {
+	Version  string `mapstructure:"version"`
+	Platform string `mapstructure:"platform"`
+}
+
+// ProjectIDConfig project_id 格式配置，上报和查询接口共用
+type ProjectIDConfig struct {
+	// Pattern project_id 必须匹配的正则表达式，为空时不校验
+	Pattern string `mapstructure:"pattern"`
+	// Lowercase 为 true 时 project_id 统一转为小写后再校验和保存，避免大小写不同的重复项目
+	Lowercase bool `mapstructure:"lowercase"`
+}
+
+// ClockSkewConfig 客户端时钟偏差检测配置
+// 客户端时间与服务端接收时间相差超过阈值时，在 extra 中记录偏差，并可选替换为服务端时间
+type ClockSkewConfig struct {
+	Threshold int  `mapstructure:"threshold"` // 阈值（秒），0 表示不检测
+	Correct   bool `mapstructure:"correct"`   // 超过阈值时使用服务端接收时间
+}
+
+// EventAgeConfig 事件时间范围校验配置，超出范围的事件通常来自 SDK 缺陷或重放，返回 422
+type EventAgeConfig struct {
+	MaxAge    int `mapstructure:"max_age"`    // 事件时间早于接收时间的最大秒数，0 表示不限制
+	MaxFuture int `mapstructure:"max_future"` // 事件时间晚于接收时间的最大秒数，0 表示不限制
+}
+
+// ProjectLimitConfig 不同项目数限制配置，只统计当前实例内存中的记录，超出时新项目的上报返回 429
+type ProjectLimitConfig struct {
+	MaxProjects int `mapstructure:"max_projects"` // 最多接收的不同项目数，0 表示不限制
+	Window      int `mapstructure:"window"`       // 项目超过该秒数未上报后不再占用名额
+}
+
+// ScrubConfig 敏感信息脱敏配置
+// 启用后保存前对 message、url、referrer 以及 extra 中的字符串值进行脱敏
+type ScrubConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`  // 启用内置规则（邮箱、Bearer 令牌、URL 令牌参数、银行卡号）
+	Patterns []string `mapstructure:"patterns"` // 追加的自定义正则，匹配内容替换为 [REDACTED]
+}
+
+// DashboardConfig 看板摘要配置
+type DashboardConfig struct {
+	RefreshInterval int               `mapstructure:"refresh_interval"` // 后台刷新间隔（秒），0 表示关闭预计算
+	RefreshHint     RefreshHintConfig `mapstructure:"refresh_hint"`
+}
+
+// RefreshHintConfig 列表响应中的轮询间隔建议，按最近 5 分钟的写入速率计算
+type RefreshHintConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	Min     int  `mapstructure:"min"` // 建议间隔下限（秒），流量再大也不低于该值
+	Max     int  `mapstructure:"max"` // 建议间隔上限（秒），没有写入时返回该值
+}
+
+// AnalyticsConfig 聚合查询接口配置
+type AnalyticsConfig struct {
+	// MaxConcurrent 同时执行的聚合查询请求数上限，0 表示不限制；不影响上报和列表查询
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	// QueueTimeout 超出上限的请求排队等待的最长时间（毫秒），超时返回 503，0 表示不排队直接返回 503
+	QueueTimeout int `mapstructure:"queue_timeout"`
+}
+
+// CORSConfig 跨域配置，公开上报接口和查询/管理接口使用不同策略
+type CORSConfig struct {
+	API    CORSPolicy `mapstructure:"api"`    // 查询和管理接口，仅供内部看板调用
+	Ingest CORSPolicy `mapstructure:"ingest"` // 数据上报接口，会被任意客户域名调用
+}
+
+// CORSPolicy 单个路由组的跨域策略
+type CORSPolicy struct {
+	AllowOrigins     []string `mapstructure:"allow_origins"` // 包含 "*" 时允许所有来源，此时不能开启 allow_credentials
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"` // 预检结果缓存时间（秒）
+}
+
+// validate 校验跨域策略，浏览器会拒绝同时携带 "*" 和凭证的响应，上报接口因此全部失败
+func (p CORSPolicy) validate(name string) error {
+	if !p.AllowCredentials {
+		return nil
+	}
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			return fmt.Errorf("cors.%s: allow_credentials cannot be enabled when allow_origins contains \"*\"", name)
+		}
+	}
+	return nil
+}
+
+// WebVitalThreshold 单个 Web Vitals 指标的评级阈值
+// 值 <= Good 为良好，<= Poor 为需改进，否则为差
+type WebVitalThreshold struct {
+	Good float64 `mapstructure:"good"`
+	Poor float64 `mapstructure:"poor"`
+}
+
+// validate 校验阈值，良好阈值不能为负且必须小于差阈值
+func (t WebVitalThreshold) validate(name string) error {
+	if t.Good < 0 || t.Poor <= t.Good {
+		return fmt.Errorf("web_vitals.%s: good must be non-negative and less than poor", name)
+	}
+	return nil
 }
 
 // LoadConfig 加载配置文件
+// 按扩展名识别格式，支持 config.yaml / config.yml / config.json / config.toml，同一目录下存在多个时按 viper 的扩展名顺序取第一个
+// 无论使用哪种格式，默认值和环境变量覆盖的规则都相同
 func LoadConfig() (*Config, error) {
 	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
 	viper.AddConfigPath("./config/")
 	viper.AddConfigPath("./")
 	viper.AutomaticEnv()
@@ -64,6 +300,31 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	// 校验 API 版本号
+	if !IsAPIVersion(config.Server.APIVersion) {
+		return nil, fmt.Errorf("server.api_version: %q must be v followed by a number, such as v1", config.Server.APIVersion)
+	}
+
+	// 校验列表默认排序方向
+	if config.Server.DefaultOrder != "asc" && config.Server.DefaultOrder != "desc" {
+		return nil, fmt.Errorf("server.default_order: %q must be asc or desc", config.Server.DefaultOrder)
+	}
+
+	// 校验跨域策略
+	if err := config.CORS.API.validate("api"); err != nil {
+		return nil, err
+	}
+	if err := config.CORS.Ingest.validate("ingest"); err != nil {
+		return nil, err
+	}
+
+	// 校验 Web Vitals 阈值
+	for name, threshold := range config.WebVitals {
+		if err := threshold.validate(name); err != nil {
+			return nil, err
+		}
+	}
+
 	return &config, nil
 }
 
@@ -76,6 +337,30 @@ type DBConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	Debug    bool   `mapstructure:"debug"`
+	// QuerySettings 读查询附带的ClickHouse设置，如 max_execution_time（秒）、max_memory_usage（字节）
+	QuerySettings map[string]string `mapstructure:"query_settings"`
+	// InsertWorkers 批量写入工作池大小，各表的批量写入并发执行
+	InsertWorkers int `mapstructure:"insert_workers"`
+	// AsyncInsert 单条事件写入使用服务端 async_insert，由 ClickHouse 缓冲合并后落盘
+	AsyncInsert bool `mapstructure:"async_insert"`
+	// AsyncInsertWait 为 true 时等待缓冲落盘后才返回（wait_for_async_insert=1），为 false 时写入缓冲即返回，进程或服务端崩溃可能丢失数据
+	AsyncInsertWait bool `mapstructure:"async_insert_wait"`
+	// WarmupConns 启动时预先建立并 ping 的连接数，不超过连接池的最大空闲连接数，0 表示不预热
+	WarmupConns int `mapstructure:"warmup_conns"`
+	// SkipMalformedRows 列表查询遇到无法解析的行时跳过并记录，为 false 时整个查询返回错误
+	SkipMalformedRows bool `mapstructure:"skip_malformed_rows"`
+	// SchemaCheck 启动时检查事件表的排序键和跳数索引：warn 只记录警告，create 自动添加缺失的跳数索引，off 不检查
+	SchemaCheck string `mapstructure:"schema_check"`
+	// Init 建表和迁移脚本执行工具（cmd/initdb）的配置
+	Init DBInitConfig `mapstructure:"init"`
+}
+
+// DBInitConfig 建表和迁移脚本执行工具配置
+type DBInitConfig struct {
+	StatementTimeout int `mapstructure:"statement_timeout"` // 单条语句的最长执行时间（秒），超时后取消该语句，0 表示不限制
+	MaxFileBytes     int `mapstructure:"max_file_bytes"`    // SQL 文件的最大字节数，超出时不执行任何语句
+	// StopOnError 为 true 时第一条语句失败即停止，避免库结构只迁移了一半；为 false 时记录错误并继续执行后续语句
+	StopOnError bool `mapstructure:"stop_on_error"`
 }
 
 // setDefaultConfig 设置默认配置
@@ -90,13 +375,31 @@ func setDefaultConfig() {
 	viper.SetDefault("server.host", "0.0.0.0")
 	viper.SetDefault("server.read_timeout", 15)
 	viper.SetDefault("server.write_timeout", 15)
+	viper.SetDefault("server.allowed_projects", []string{})
+	viper.SetDefault("server.response_envelope", false)
+	viper.SetDefault("server.json_casing", "snake")
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.api_version", "v1")
+	viper.SetDefault("server.max_in_flight", 0)
+	viper.SetDefault("server.default_order", "desc")
+	viper.SetDefault("server.request_timeout", 0)
+	viper.SetDefault("server.group_timeouts.ingest", 0)
+	viper.SetDefault("server.group_timeouts.analytics", 0)
 
 	// Log 默认配置
 	viper.SetDefault("log.level", "info")
 	viper.SetDefault("log.path", "./logs/app.log")
 	viper.SetDefault("log.max_size", 500)
 	viper.SetDefault("log.max_age", 30)
+	viper.SetDefault("log.max_backups", 10)
 	viper.SetDefault("log.compress", true)
+	viper.SetDefault("log.output", "")
+	viper.SetDefault("log.access_log_headers", false)
+	viper.SetDefault("log.sync_timeout", 3000)
+	viper.SetDefault("log.redact_headers", []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-API-Key"})
+	viper.SetDefault("log.body_log.enabled", false)
+	viper.SetDefault("log.body_log.max_bytes", 4096)
+	viper.SetDefault("log.body_log.redact_fields", []string{"password", "token", "access_token", "secret", "api_key", "authorization", "cookie"})
 
 	// DB 默认配置
 	viper.SetDefault("db.driver", "clickhouse")
@@ -106,4 +409,86 @@ func setDefaultConfig() {
 	viper.SetDefault("db.username", "default")
 	viper.SetDefault("db.password", "QhH_vObgVEGw6")
 	viper.SetDefault("db.debug", false)
+	viper.SetDefault("db.query_settings", map[string]string{
+		"max_execution_time": "30",
+	})
+	viper.SetDefault("db.insert_workers", 4)
+	viper.SetDefault("db.async_insert", false)
+	viper.SetDefault("db.async_insert_wait", true)
+	viper.SetDefault("db.warmup_conns", 5)
+	viper.SetDefault("db.skip_malformed_rows", true)
+	viper.SetDefault("db.schema_check", "warn")
+	viper.SetDefault("db.init.statement_timeout", 60)
+	viper.SetDefault("db.init.max_file_bytes", 1<<20)
+	viper.SetDefault("db.init.stop_on_error", true)
+
+	// Ingest 默认配置
+	viper.SetDefault("ingest.max_extra_bytes", 16*1024)
+	viper.SetDefault("ingest.metric_aliases", map[string]string{})
+	viper.SetDefault("ingest.scrub.enabled", true)
+	viper.SetDefault("ingest.scrub.patterns", []string{})
+	viper.SetDefault("ingest.max_batch_items", 1000)
+	viper.SetDefault("ingest.max_batch_bytes", 1<<20)
+	viper.SetDefault("ingest.id_scheme", "uuid")
+	viper.SetDefault("ingest.clock_skew.threshold", 300)
+	viper.SetDefault("ingest.clock_skew.correct", false)
+	viper.SetDefault("ingest.event_age.max_age", 7*24*60*60)
+	viper.SetDefault("ingest.event_age.max_future", 600)
+	viper.SetDefault("ingest.project_limit.max_projects", 0)
+	viper.SetDefault("ingest.project_limit.window", 86400)
+	viper.SetDefault("ingest.strict_fields", false)
+	viper.SetDefault("ingest.no_content", false)
+	viper.SetDefault("ingest.max_url_length", 2048)
+	viper.SetDefault("ingest.sdk_headers.version", "X-SDK-Version")
+	viper.SetDefault("ingest.sdk_headers.platform", "X-SDK-Platform")
+	viper.SetDefault("ingest.batching.enabled", false)
+	viper.SetDefault("ingest.batching.flush_interval", 1000)
+	viper.SetDefault("ingest.batching.max_size", 1000)
+	viper.SetDefault("ingest.user_id_hash.enabled", false)
+	viper.SetDefault("ingest.user_id_hash.salt", "")
+	viper.SetDefault("ingest.user_agent.enabled", false)
+	viper.SetDefault("ingest.user_agent.bot_patterns", []string{
+		"bot", "crawler", "spider", "slurp", "headless", "lighthouse",
+		"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "postmanruntime",
+	})
+
+	// project_id 格式默认配置
+	viper.SetDefault("project_id.pattern", `^[a-zA-Z0-9_-]{1,64}$`)
+	viper.SetDefault("project_id.lowercase", false)
+
+	// CORS 默认配置
+	viper.SetDefault("cors.api.allow_origins", []string{"http://localhost:5173", "http://localhost:5174", "http://localhost:3000"})
+	viper.SetDefault("cors.api.allow_credentials", true)
+	viper.SetDefault("cors.api.max_age", 12*60*60)
+	viper.SetDefault("cors.ingest.allow_origins", []string{"*"})
+	viper.SetDefault("cors.ingest.allow_credentials", false)
+	viper.SetDefault("cors.ingest.max_age", 24*60*60)
+
+	// Dashboard 默认配置
+	viper.SetDefault("dashboard.refresh_interval", 300)
+	viper.SetDefault("dashboard.refresh_hint.enabled", true)
+	viper.SetDefault("dashboard.refresh_hint.min", 5)
+	viper.SetDefault("dashboard.refresh_hint.max", 300)
+
+	// Analytics 默认配置
+	viper.SetDefault("analytics.max_concurrent", 8)
+	viper.SetDefault("analytics.queue_timeout", 2000)
+
+	// Web Vitals 默认阈值，取自 web.dev 的官方建议（LCP/FID/INP 单位毫秒，CLS 无单位）
+	viper.SetDefault("web_vitals.lcp.good", 2500)
+	viper.SetDefault("web_vitals.lcp.poor", 4000)
+	viper.SetDefault("web_vitals.fid.good", 100)
+	viper.SetDefault("web_vitals.fid.poor", 300)
+	viper.SetDefault("web_vitals.inp.good", 200)
+	viper.SetDefault("web_vitals.inp.poor", 500)
+	viper.SetDefault("web_vitals.cls.good", 0.1)
+	viper.SetDefault("web_vitals.cls.poor", 0.25)
+
+	// EventBus 默认配置
+	viper.SetDefault("event_bus.batch_window", 0)
+	viper.SetDefault("event_bus.batch_max_size", 500)
+
+	// Auth 默认配置
+	viper.SetDefault("auth.admin_token", "")
+	viper.SetDefault("auth.allow_project_purge", false)
 }
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// loadConfigFile 在临时目录中写入 config.<ext> 后调用 LoadConfig
+// LoadConfig 使用全局 viper 并按相对路径查找配置文件，因此每次加载前重置 viper 并切换工作目录
+func loadConfigFile(t *testing.T, ext, content string) (*Config, error) {
+	t.Helper()
+	dir := t.TempDir()
+	if content != "" {
+		if err := os.WriteFile(filepath.Join(dir, "config."+ext), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	t.Cleanup(func() {
+		os.Chdir(wd)
+		viper.Reset()
+	})
+
+	viper.Reset()
+	return LoadConfig()
+}
+
+const yamlConfig = `
+server:
+  port: 9090
+  allowed_projects: [web, ios]
+  group_timeouts:
+    analytics: 30000
+log:
+  level: debug
+web_vitals:
+  lcp:
+    good: 2500
+    poor: 4000
+`
+
+const jsonConfig = `{
+  "server": {
+    "port": 9090,
+    "allowed_projects": ["web", "ios"],
+    "group_timeouts": {"analytics": 30000}
+  },
+  "log": {"level": "debug"},
+  "web_vitals": {"lcp": {"good": 2500, "poor": 4000}}
+}`
+
+func TestLoadConfigJSONMatchesYAML(t *testing.T) {
+	fromYAML, err := loadConfigFile(t, "yaml", yamlConfig)
+	if err != nil {
+		t.Fatalf("LoadConfig(yaml) error = %v", err)
+	}
+	fromJSON, err := loadConfigFile(t, "json", jsonConfig)
+	if err != nil {
+		t.Fatalf("LoadConfig(json) error = %v", err)
+	}
+
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("JSON and YAML configs differ:\nyaml %+v\njson %+v", fromYAML, fromJSON)
+	}
+
+	// 文件中的值覆盖默认值，未出现的键仍使用默认值
+	if fromJSON.Server.Port != 9090 || fromJSON.Log.Level != "debug" || fromJSON.Server.GroupTimeouts.Analytics != 30000 {
+		t.Errorf("file values not applied: %+v", fromJSON.Server)
+	}
+	if !reflect.DeepEqual(fromJSON.Server.AllowedProjects, []string{"web", "ios"}) {
+		t.Errorf("AllowedProjects = %v", fromJSON.Server.AllowedProjects)
+	}
+	if fromJSON.Server.Host != "0.0.0.0" || fromJSON.Server.APIVersion != "v1" || fromJSON.Server.DefaultOrder != "desc" {
+		t.Errorf("defaults not applied: %+v", fromJSON.Server)
+	}
+}
+
+func TestLoadConfigWithoutFile(t *testing.T) {
+	cfg, err := loadConfigFile(t, "yaml", "")
+	if err != nil {
+		t.Fatalf("LoadConfig() without a file error = %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Log.Level != "info" {
+		t.Errorf("defaults not applied: port %d, log level %s", cfg.Server.Port, cfg.Log.Level)
+	}
+	// 建表工具默认在第一条语句失败时停止
+	if want := (DBInitConfig{StatementTimeout: 60, MaxFileBytes: 1 << 20, StopOnError: true}); cfg.DB.Init != want {
+		t.Errorf("DB.Init = %+v, want %+v", cfg.DB.Init, want)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ext     string
+		content string
+	}{
+		{"malformed json", "json", `{"server": {"port": 9090`},
+		{"malformed yaml", "yaml", "server:\n  port: [9090\n"},
+		{"invalid api version in json", "json", `{"server": {"api_version": "1"}}`},
+		{"invalid default order in yaml", "yaml", "server:\n  default_order: newest\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadConfigFile(t, tt.ext, tt.content); err == nil {
+				t.Error("LoadConfig() succeeded, want error")
+			}
+		})
+	}
+}
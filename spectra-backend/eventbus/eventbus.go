@@ -0,0 +1,341 @@
+package eventbus
+
+import (
+	"context"
+	"spectra-backend/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topic 事件主题，每种事件类型对应一个主题
+type Topic string
+
+// 各事件类型对应的主题
+const (
+	TopicErrorLog          Topic = models.EventTypeErrorLog
+	TopicPerformanceMetric Topic = models.EventTypePerformanceMetric
+	TopicUserAction        Topic = models.EventTypeUserAction
+	TopicCustomEvent       Topic = models.EventTypeCustomEvent
+	TopicPageStay          Topic = models.EventTypePageStay
+)
+
+// DefaultBufferSize 订阅者默认的缓冲区大小
+const DefaultBufferSize = 256
+
+// DefaultBatchMaxSize 开启批量发布但未指定批次大小时，单个批次最多包含的事件数
+const DefaultBatchMaxSize = 500
+
+// Event 总线上传递的事件
+// Payload 为对应模型的指针，例如 TopicErrorLog 对应 *models.ErrorLog
+type Event struct {
+	Topic     Topic
+	ProjectID string
+	Payload   any
+}
+
+// Subscription 订阅句柄
+// 由 Subscribe 创建的订阅逐个接收事件（C），由 SubscribeBatches 创建的订阅按批接收事件（Batches）
+type Subscription struct {
+	id      uint64
+	topics  map[Topic]struct{}
+	ch      chan Event
+	batches chan []Event
+	dropped atomic.Uint64
+	bus     *Bus
+	closed  bool // 通道已关闭，由 Bus.mu 保护
+}
+
+// C 返回逐个接收事件的通道，取消订阅或总线关闭后通道会被关闭；SubscribeBatches 创建的订阅返回 nil
+// 实时推送连接在通道关闭后应向客户端发送关闭通知（如 SSE 的 close 事件、WebSocket 的 1001 Going Away），再调用 Unsubscribe
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Batches 返回按批接收事件的通道，批内事件保持发布顺序，关闭规则与 C 相同；Subscribe 创建的订阅返回 nil
+// 未开启批量发布时每批只有一个事件；同一批次可能被多个订阅者共享，订阅者不能修改收到的切片
+func (s *Subscription) Batches() <-chan []Event {
+	return s.batches
+}
+
+// Dropped 返回因缓冲区已满而丢弃的事件数
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Unsubscribe 取消订阅，可重复调用
+func (s *Subscription) Unsubscribe() {
+	s.bus.unsubscribe(s.id)
+}
+
+// close 关闭订阅的通道，调用方须持有 Bus.mu 写锁
+func (s *Subscription) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	if s.ch != nil {
+		close(s.ch)
+	}
+	if s.batches != nil {
+		close(s.batches)
+	}
+}
+
+// deliver 将批次中订阅关注的事件非阻塞地发送给订阅者，缓冲区已满时丢弃并计数，调用方须持有 Bus.mu 读锁
+func (s *Subscription) deliver(events []Event) {
+	if s.batches == nil {
+		for _, event := range events {
+			if !s.matches(event.Topic) {
+				continue
+			}
+			select {
+			case s.ch <- event:
+			default:
+				s.dropped.Add(1)
+			}
+		}
+		return
+	}
+
+	matched := events
+	if len(s.topics) > 0 {
+		matched = make([]Event, 0, len(events))
+		for _, event := range events {
+			if s.matches(event.Topic) {
+				matched = append(matched, event)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	select {
+	case s.batches <- matched:
+	default:
+		s.dropped.Add(uint64(len(matched)))
+	}
+}
+
+// matches 判断订阅是否关注该主题，未指定主题时接收全部事件
+func (s *Subscription) matches(topic Topic) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	_, ok := s.topics[topic]
+	return ok
+}
+
+// Bus 进程内事件总线
+// 发布不会阻塞：订阅者缓冲区满时事件被丢弃并计数，慢消费者不会拖慢上报路径
+// 开启批量发布时事件先在 batchWindow 内攒批，再按发布顺序分发给订阅者，事件最多延迟 batchWindow
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[uint64]*Subscription
+	nextID uint64
+	closed bool
+	// drained 关闭过程中最后一个订阅者取消订阅时关闭，由 Shutdown 创建
+	drained chan struct{}
+
+	// batchWindow 批量发布的时间窗口，0 表示逐个分发
+	batchWindow  time.Duration
+	batchMaxSize int
+	// pendingMu 保护待分发批次，分发也在该锁内进行以保证批次之间的顺序；须先于 mu 获取
+	pendingMu sync.Mutex
+	pending   []Event
+	timer     *time.Timer
+}
+
+// New 创建逐个分发事件的事件总线
+func New() *Bus {
+	return &Bus{
+		subs: make(map[uint64]*Subscription),
+	}
+}
+
+// NewBatching 创建批量发布的事件总线，发布的事件攒满 window 或 maxSize 个后一起分发
+// window 小于等于0时与 New 相同；maxSize 小于等于0时使用 DefaultBatchMaxSize
+func NewBatching(window time.Duration, maxSize int) *Bus {
+	bus := New()
+	if window <= 0 {
+		return bus
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultBatchMaxSize
+	}
+	bus.batchWindow = window
+	bus.batchMaxSize = maxSize
+	return bus
+}
+
+// Subscribe 订阅指定主题并逐个接收事件，不传主题时订阅全部事件
+// buffer 小于等于0时使用 DefaultBufferSize
+func (b *Bus) Subscribe(buffer int, topics ...Topic) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	return b.subscribe(&Subscription{ch: make(chan Event, buffer)}, topics)
+}
+
+// SubscribeBatches 订阅指定主题并按批接收事件，适合 Webhook 等批量处理的消费者
+// buffer 为缓冲的批次数，小于等于0时使用 DefaultBufferSize；缓冲区满时整批丢弃，按事件数计入 Dropped
+func (b *Bus) SubscribeBatches(buffer int, topics ...Topic) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultBufferSize
+	}
+	return b.subscribe(&Subscription{batches: make(chan []Event, buffer)}, topics)
+}
+
+// subscribe 注册订阅，总线已关闭时立即关闭订阅的通道
+func (b *Bus) subscribe(sub *Subscription, topics []Topic) *Subscription {
+	sub.topics = make(map[Topic]struct{}, len(topics))
+	sub.bus = b
+	for _, topic := range topics {
+		sub.topics[topic] = struct{}{}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.close()
+		return sub
+	}
+	b.nextID++
+	sub.id = b.nextID
+	b.subs[sub.id] = sub
+	return sub
+}
+
+// unsubscribe 移除订阅并关闭其通道
+func (b *Bus) unsubscribe(id uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub, ok := b.subs[id]
+	if !ok {
+		return
+	}
+	delete(b.subs, id)
+	sub.close()
+	if b.drained != nil && len(b.subs) == 0 {
+		close(b.drained)
+		b.drained = nil
+	}
+}
+
+// Publish 向关注该主题的订阅者发布事件
+// 开启批量发布时事件加入待分发批次，批次达到 batchMaxSize 时立即分发，否则在第一个事件加入 batchWindow 后分发
+func (b *Bus) Publish(event Event) {
+	if b.batchWindow <= 0 {
+		b.dispatch([]Event{event})
+		return
+	}
+
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	b.pending = append(b.pending, event)
+	if len(b.pending) >= b.batchMaxSize {
+		if b.timer != nil {
+			b.timer.Stop()
+			b.timer = nil
+		}
+		b.flushLocked()
+		return
+	}
+	if b.timer == nil {
+		b.timer = time.AfterFunc(b.batchWindow, b.flush)
+	}
+}
+
+// flush 分发待分发批次，由批量发布的定时器调用
+func (b *Bus) flush() {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	b.timer = nil
+	b.flushLocked()
+}
+
+// flushLocked 分发并清空待分发批次，调用方须持有 pendingMu
+func (b *Bus) flushLocked() {
+	if len(b.pending) == 0 {
+		return
+	}
+	events := b.pending
+	b.pending = nil
+	b.dispatch(events)
+}
+
+// dispatch 将一批事件按顺序分发给所有订阅者
+func (b *Bus) dispatch(events []Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		sub.deliver(events)
+	}
+}
+
+// flushPending 停止批量发布的定时器并立即分发剩余事件，关闭总线前调用，避免最后一批事件丢失
+func (b *Bus) flushPending() {
+	b.pendingMu.Lock()
+	defer b.pendingMu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	b.flushLocked()
+}
+
+// SubscriberCount 返回当前订阅者数量
+func (b *Bus) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Shutdown 关闭总线并等待订阅者断开
+// 所有订阅通道立即关闭，通知实时推送连接向客户端发送关闭消息；订阅者调用 Unsubscribe 后视为已断开
+// 全部断开或 ctx 结束时返回，超时后仍未断开的订阅被强制移除并返回 ctx 的错误
+func (b *Bus) Shutdown(ctx context.Context) error {
+	b.flushPending()
+	b.mu.Lock()
+	b.closed = true
+	for _, sub := range b.subs {
+		sub.close()
+	}
+	if len(b.subs) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	if b.drained == nil {
+		b.drained = make(chan struct{})
+	}
+	drained := b.drained
+	b.mu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		b.Close()
+		return ctx.Err()
+	}
+}
+
+// Close 关闭总线及所有订阅通道，之后的订阅会立即得到已关闭的通道
+// 不等待订阅者断开，需要等待时使用 Shutdown
+func (b *Bus) Close() {
+	b.flushPending()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for id, sub := range b.subs {
+		delete(b.subs, id)
+		sub.close()
+	}
+	if b.drained != nil {
+		close(b.drained)
+		b.drained = nil
+	}
+}
@@ -0,0 +1,285 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// receiveTimeout 等待订阅者收到事件的最长时间
+const receiveTimeout = time.Second
+
+// receive 从订阅通道读取一个事件，超时或通道已关闭时终止测试
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.C():
+		if !ok {
+			t.Fatal("subscription channel closed, want an event")
+		}
+		return event
+	case <-time.After(receiveTimeout):
+		t.Fatal("timed out waiting for an event")
+		return Event{}
+	}
+}
+
+// assertEmpty 检查订阅通道中没有待读取的事件
+func assertEmpty(t *testing.T, sub *Subscription) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.C():
+		if ok {
+			t.Errorf("unexpected event %+v", event)
+		}
+	default:
+	}
+}
+
+func TestSubscribeFiltersTopics(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+	errorSub := bus.Subscribe(4, TopicErrorLog)
+	all := bus.Subscribe(4)
+
+	bus.Publish(Event{Topic: TopicErrorLog, ProjectID: "p1"})
+	bus.Publish(Event{Topic: TopicCustomEvent, ProjectID: "p2"})
+
+	if got := receive(t, errorSub); got.Topic != TopicErrorLog || got.ProjectID != "p1" {
+		t.Errorf("error log subscriber got %+v, want the error log event", got)
+	}
+	assertEmpty(t, errorSub)
+
+	for _, want := range []Topic{TopicErrorLog, TopicCustomEvent} {
+		if got := receive(t, all); got.Topic != want {
+			t.Errorf("subscriber without topics got %s, want %s", got.Topic, want)
+		}
+	}
+}
+
+func TestPublishDropsWhenBufferFull(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+	slow := bus.Subscribe(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			bus.Publish(Event{Topic: TopicErrorLog})
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(receiveTimeout):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+
+	if got := slow.Dropped(); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+	receive(t, slow)
+	assertEmpty(t, slow)
+}
+
+func TestUnsubscribeIsIdempotent(t *testing.T) {
+	bus := New()
+	defer bus.Close()
+	sub := bus.Subscribe(4)
+
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	if got := bus.SubscriberCount(); got != 0 {
+		t.Errorf("SubscriberCount() = %d, want 0", got)
+	}
+
+	bus.Publish(Event{Topic: TopicErrorLog})
+	if event, ok := <-sub.C(); ok {
+		t.Errorf("received %+v after Unsubscribe", event)
+	}
+}
+
+func TestPublishAfterClose(t *testing.T) {
+	bus := New()
+	sub := bus.Subscribe(4)
+	bus.Close()
+
+	bus.Publish(Event{Topic: TopicErrorLog})
+	if event, ok := <-sub.C(); ok {
+		t.Errorf("received %+v after Close", event)
+	}
+	// 关闭后取消订阅和新订阅都不应 panic，新订阅得到已关闭的通道
+	sub.Unsubscribe()
+	if _, ok := <-bus.Subscribe(4).C(); ok {
+		t.Error("Subscribe() after Close returned an open channel")
+	}
+}
+
+// receiveBatch 从批量订阅通道读取一个批次，超时或通道已关闭时终止测试
+func receiveBatch(t *testing.T, sub *Subscription) []Event {
+	t.Helper()
+	select {
+	case batch, ok := <-sub.Batches():
+		if !ok {
+			t.Fatal("batch channel closed, want a batch")
+		}
+		return batch
+	case <-time.After(receiveTimeout):
+		t.Fatal("timed out waiting for a batch")
+		return nil
+	}
+}
+
+// publishNumbered 依次发布 n 个事件，ProjectID 为发布序号，用于检查批内顺序
+func publishNumbered(bus *Bus, n int) {
+	for i := 0; i < n; i++ {
+		bus.Publish(Event{Topic: TopicErrorLog, ProjectID: strconv.Itoa(i)})
+	}
+}
+
+// assertOrdered 检查批次包含 n 个事件且保持发布顺序
+func assertOrdered(t *testing.T, batch []Event, n int) {
+	t.Helper()
+	if len(batch) != n {
+		t.Fatalf("batch has %d events, want %d", len(batch), n)
+	}
+	for i, event := range batch {
+		if event.ProjectID != strconv.Itoa(i) {
+			t.Errorf("batch[%d].ProjectID = %s, want %d", i, event.ProjectID, i)
+		}
+	}
+}
+
+func TestSubscribeBatchesFlushesAfterWindow(t *testing.T) {
+	const window = 20 * time.Millisecond
+	bus := NewBatching(window, 100)
+	defer bus.Close()
+	sub := bus.SubscribeBatches(4)
+
+	start := time.Now()
+	publishNumbered(bus, 3)
+	select {
+	case batch := <-sub.Batches():
+		t.Fatalf("batch of %d delivered before the window elapsed", len(batch))
+	default:
+	}
+
+	assertOrdered(t, receiveBatch(t, sub), 3)
+	if elapsed := time.Since(start); elapsed < window {
+		t.Errorf("batch delivered after %v, want at least %v", elapsed, window)
+	}
+}
+
+func TestSubscribeBatchesFlushesAtMaxSize(t *testing.T) {
+	// 时间窗口足够长，批次只能因达到 maxSize 而分发
+	bus := NewBatching(time.Hour, 3)
+	defer bus.Close()
+	sub := bus.SubscribeBatches(4)
+
+	publishNumbered(bus, 4)
+	assertOrdered(t, receiveBatch(t, sub), 3)
+	select {
+	case batch := <-sub.Batches():
+		t.Errorf("unexpected second batch %+v before the window elapsed", batch)
+	default:
+	}
+}
+
+func TestSubscribeBatchesFiltersTopics(t *testing.T) {
+	bus := NewBatching(time.Hour, 3)
+	defer bus.Close()
+	sub := bus.SubscribeBatches(4, TopicCustomEvent)
+
+	bus.Publish(Event{Topic: TopicErrorLog})
+	bus.Publish(Event{Topic: TopicCustomEvent, ProjectID: "p1"})
+	bus.Publish(Event{Topic: TopicErrorLog})
+
+	batch := receiveBatch(t, sub)
+	if len(batch) != 1 || batch[0].ProjectID != "p1" {
+		t.Errorf("batch = %+v, want only the custom event", batch)
+	}
+}
+
+func TestSubscribeBatchesNoFlushAfterUnsubscribe(t *testing.T) {
+	const window = 20 * time.Millisecond
+	bus := NewBatching(window, 100)
+	defer bus.Close()
+	sub := bus.SubscribeBatches(4)
+
+	publishNumbered(bus, 2)
+	sub.Unsubscribe()
+	// 等待定时器触发，向已关闭的通道发送会 panic
+	time.Sleep(3 * window)
+	if batch, ok := <-sub.Batches(); ok {
+		t.Errorf("received %+v after Unsubscribe", batch)
+	}
+}
+
+func TestSubscribeBatchesNoFlushAfterClose(t *testing.T) {
+	const window = 20 * time.Millisecond
+	bus := NewBatching(window, 100)
+	sub := bus.SubscribeBatches(4)
+
+	publishNumbered(bus, 2)
+	bus.Close()
+	// Close 立即分发剩余事件并停止定时器，之后不再有批次
+	assertOrdered(t, receiveBatch(t, sub), 2)
+	bus.Publish(Event{Topic: TopicErrorLog})
+	time.Sleep(3 * window)
+	if batch, ok := <-sub.Batches(); ok {
+		t.Errorf("received %+v after Close", batch)
+	}
+}
+
+func TestShutdownDeliversPendingBatches(t *testing.T) {
+	bus := NewBatching(time.Hour, 100)
+	sub := bus.SubscribeBatches(4)
+	publishNumbered(bus, 3)
+
+	// 订阅者读完剩余批次后在通道关闭时取消订阅，与实时推送连接的处理方式相同
+	received := make(chan []Event, 1)
+	go func() {
+		var events []Event
+		for batch := range sub.Batches() {
+			events = append(events, batch...)
+		}
+		sub.Unsubscribe()
+		received <- events
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), receiveTimeout)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	assertOrdered(t, <-received, 3)
+	if got := bus.SubscriberCount(); got != 0 {
+		t.Errorf("SubscriberCount() = %d, want 0", got)
+	}
+}
+
+func TestShutdownTimesOutOnStuckSubscriber(t *testing.T) {
+	bus := New()
+	// 订阅者从不取消订阅
+	bus.Subscribe(4)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- bus.Shutdown(ctx) }()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Shutdown() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(receiveTimeout):
+		t.Fatal("Shutdown() did not return after its context expired")
+	}
+	// 超时后未断开的订阅被强制移除
+	if got := bus.SubscriberCount(); got != 0 {
+		t.Errorf("SubscriberCount() = %d, want 0", got)
+	}
+}
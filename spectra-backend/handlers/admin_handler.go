@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"spectra-backend/middleware"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// drainWaitTimeout 排空接口等待进行中写入完成的最长时间
+const drainWaitTimeout = 30 * time.Second
+
+// AdminHandler 运维管理处理器
+type AdminHandler struct {
+	drain  *middleware.DrainGate
+	logger *zap.Logger
+}
+
+// NewAdminHandler 创建运维管理处理器实例
+func NewAdminHandler(drain *middleware.DrainGate, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		drain:  drain,
+		logger: logger,
+	}
+}
+
+// Drain 停止接受写入并等待进行中的写入完成
+// 全部完成返回 200；等待超时返回 202，此时已停止接受写入，可再次调用继续等待
+func (h *AdminHandler) Drain(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), drainWaitTimeout)
+	defer cancel()
+
+	err := h.drain.Drain(ctx)
+	_, inFlight := h.drain.State()
+	if err != nil {
+		loggerFrom(c, h.logger).Warn("Ingestion drain still waiting for in-flight writes", zap.Int("in_flight", inFlight))
+		c.JSON(http.StatusAccepted, gin.H{"status": "draining", "in_flight": inFlight})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Ingestion drained")
+	c.JSON(http.StatusOK, gin.H{"status": "drained", "in_flight": inFlight})
+}
+
+// Resume 恢复接受写入
+func (h *AdminHandler) Resume(c *gin.Context) {
+	h.drain.Resume()
+	loggerFrom(c, h.logger).Info("Ingestion resumed")
+	c.JSON(http.StatusOK, gin.H{"status": "accepting"})
+}
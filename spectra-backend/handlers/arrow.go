@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"spectra-backend/middleware"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// arrowBatchRows Arrow 流中每个 record batch 的行数，每写完一批即刷新到客户端
+const arrowBatchRows = 1024
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+)
+
+// arrowColumn 结构体字段与 Arrow 列的对应关系
+type arrowColumn struct {
+	index []int // reflect.Value.FieldByIndex 使用的字段路径，支持嵌入结构体
+	field arrow.Field
+}
+
+// arrowColumns 按 JSON 字段名生成 Arrow 列定义，嵌入结构体的字段展开为顶层列
+// 只支持平铺的标量字段，遇到嵌套结构体、切片等类型时返回错误，由调用方回退为 JSON
+func arrowColumns(t reflect.Type) ([]arrowColumn, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("arrow encoding requires a struct, got %s", t)
+	}
+
+	var columns []arrowColumn
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded, err := arrowColumns(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, column := range embedded {
+				column.index = append([]int{i}, column.index...)
+				columns = append(columns, column)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "" {
+			continue
+		}
+
+		dataType, err := arrowType(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+		columns = append(columns, arrowColumn{
+			index: []int{i},
+			field: arrow.Field{Name: name, Type: dataType},
+		})
+	}
+	return columns, nil
+}
+
+// arrowType 返回 Go 类型对应的 Arrow 类型，extra 等 JSON 字段以字符串保存
+func arrowType(t reflect.Type) (arrow.DataType, error) {
+	switch {
+	case t == timeType:
+		return &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}, nil
+	case t == rawJSONType:
+		return arrow.BinaryTypes.String, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return arrow.BinaryTypes.String, nil
+	case reflect.Bool:
+		return arrow.FixedWidthTypes.Boolean, nil
+	case reflect.Float32, reflect.Float64:
+		return arrow.PrimitiveTypes.Float64, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return arrow.PrimitiveTypes.Int64, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return arrow.PrimitiveTypes.Uint64, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+// appendArrowValue 将字段值追加到对应的列构建器
+func appendArrowValue(builder array.Builder, value reflect.Value) {
+	switch b := builder.(type) {
+	case *array.TimestampBuilder:
+		b.Append(arrow.Timestamp(value.Interface().(time.Time).UnixMilli()))
+	case *array.StringBuilder:
+		if value.Type() == rawJSONType {
+			if value.Len() == 0 {
+				b.AppendNull()
+				return
+			}
+			b.Append(string(value.Bytes()))
+			return
+		}
+		b.Append(value.String())
+	case *array.BooleanBuilder:
+		b.Append(value.Bool())
+	case *array.Float64Builder:
+		b.Append(value.Float())
+	case *array.Int64Builder:
+		b.Append(value.Int())
+	case *array.Uint64Builder:
+		b.Append(value.Uint())
+	}
+}
+
+// writeArrow 以 Arrow IPC 流格式写出列表，每 arrowBatchRows 行编码为一个 record batch 并立即刷新
+// 列表由仓储层完整查询后传入，内存中仍持有全部结果；分批写出只避免一次性编码整个响应
+// 元素类型无法编码为 Arrow 时返回 false 且不写出任何内容，由调用方回退为 JSON
+func writeArrow[T any](c *gin.Context, logger *zap.Logger, items []T) bool {
+	columns, err := arrowColumns(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		return false
+	}
+
+	fields := make([]arrow.Field, len(columns))
+	for i, column := range columns {
+		fields[i] = column.field
+	}
+	schema := arrow.NewSchema(fields, nil)
+
+	c.Header("Content-Type", middleware.MediaTypeArrowStream)
+	c.Status(http.StatusOK)
+
+	writer := ipc.NewWriter(c.Writer, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator))
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer builder.Release()
+
+	for start := 0; start < len(items); start += arrowBatchRows {
+		end := min(start+arrowBatchRows, len(items))
+		for _, item := range items[start:end] {
+			value := reflect.Indirect(reflect.ValueOf(item))
+			for i, column := range columns {
+				appendArrowValue(builder.Field(i), value.FieldByIndex(column.index))
+			}
+		}
+
+		record := builder.NewRecordBatch()
+		err := writer.Write(record)
+		record.Release()
+		if err != nil {
+			// 响应头已发出，只能中断连接并记录错误
+			loggerFrom(c, logger).Error("Failed to write arrow stream", zap.Error(err))
+			c.Abort()
+			return true
+		}
+		c.Writer.Flush()
+	}
+
+	// 没有数据时 Close 只写出 schema 和结束标记
+	if err := writer.Close(); err != nil {
+		loggerFrom(c, logger).Error("Failed to close arrow stream", zap.Error(err))
+	}
+	return true
+}
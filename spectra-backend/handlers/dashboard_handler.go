@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"spectra-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// DashboardHandler 看板处理器
+type DashboardHandler struct {
+	dashboardService services.DashboardService
+	logger           *zap.Logger
+}
+
+// NewDashboardHandler 创建看板处理器实例
+func NewDashboardHandler(dashboardService services.DashboardService, logger *zap.Logger) *DashboardHandler {
+	return &DashboardHandler{
+		dashboardService: dashboardService,
+		logger:           logger,
+	}
+}
+
+// GetSummary 获取项目看板摘要
+func (h *DashboardHandler) GetSummary(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		return
+	}
+	window := c.DefaultQuery("window", services.DefaultDashboardWindow)
+
+	summary, err := h.dashboardService.GetSummary(c.Request.Context(), projectID, window)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownWindow) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get dashboard summary",
+			zap.String("project_id", projectID),
+			zap.String("window", window),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get dashboard summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// RefreshSummaries 同步重新计算项目的看板摘要，用于回填数据后强制刷新缓存
+// 同一项目正在刷新时返回 409
+func (h *DashboardHandler) RefreshSummaries(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		return
+	}
+
+	refresh, err := h.dashboardService.RefreshProject(c.Request.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to refresh dashboard summaries",
+			zap.String("project_id", projectID),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh dashboard summaries"})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Dashboard summaries refreshed",
+		zap.String("project_id", projectID),
+		zap.Int64("duration_ms", refresh.DurationMs))
+	c.JSON(http.StatusOK, refresh)
+}
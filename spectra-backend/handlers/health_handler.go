@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"spectra-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// HealthHandler 健康检查处理器
+type HealthHandler struct {
+	healthService services.HealthService
+	logger        *zap.Logger
+}
+
+// NewHealthHandler 创建健康检查处理器实例
+func NewHealthHandler(healthService services.HealthService, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		healthService: healthService,
+		logger:        logger,
+	}
+}
+
+// DeepCheck 深度健康检查，写入并读回探针行，全部步骤通过返回 200，否则返回 503
+func (h *HealthHandler) DeepCheck(c *gin.Context) {
+	report := h.healthService.DeepCheck(c.Request.Context())
+	if report.Status != services.HealthStatusOK {
+		loggerFrom(c, h.logger).Warn("Deep health check failed", zap.Any("steps", report.Steps))
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -1,13 +1,21 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"spectra-backend/middleware"
 	"spectra-backend/models"
 	"spectra-backend/services"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
 )
 
@@ -15,29 +23,49 @@ import (
 type LogHandler struct {
 	logService services.LogService
 	logger     *zap.Logger
+	opts       LogHandlerOptions
+}
+
+// LogHandlerOptions 处理器的行为选项
+type LogHandlerOptions struct {
+	// StrictFields 为 true 时单条上报中出现未定义的顶层字段返回 422，否则忽略这些字段
+	StrictFields bool
+	// NoContent 为 true 时上报成功返回 204 且不带响应体，请求携带 verbose=true 时仍返回完整响应
+	NoContent bool
+	// SDKVersionHeader / PlatformHeader 请求体未上报 sdk_version / platform 时读取的请求头，为空时不读取
+	SDKVersionHeader string
+	PlatformHeader   string
+	// RefreshHints 列表查询响应的轮询间隔建议，为 nil 时不返回 X-Refresh-Interval 头
+	RefreshHints services.RefreshHintService
+	// DefaultOrder 列表查询未指定 order 参数时的排序方向，asc 或 desc，为空时为 desc
+	DefaultOrder string
+	// ProjectAllowed 流式上报逐个事件校验项目白名单，为 nil 时不限制
+	ProjectAllowed func(projectID string) bool
+	// ProjectPurge 为 true 时允许通过 DELETE /api/projects/:id 删除整个项目的数据
+	ProjectPurge bool
 }
 
 // NewLogHandler 创建日志处理器实例
-func NewLogHandler(logService services.LogService, logger *zap.Logger) *LogHandler {
+func NewLogHandler(logService services.LogService, logger *zap.Logger, opts LogHandlerOptions) *LogHandler {
 	return &LogHandler{
 		logService: logService,
 		logger:     logger,
+		opts:       opts,
 	}
 }
 
 // RecordErrorLog 记录错误日志
 func (h *LogHandler) RecordErrorLog(c *gin.Context) {
-	h.logger.Debug("RecordErrorLog called", zap.String("method", c.Request.Method), zap.String("content_type", c.GetHeader("Content-Type")))
+	loggerFrom(c, h.logger).Debug("RecordErrorLog called", zap.String("method", c.Request.Method), zap.String("content_type", c.GetHeader("Content-Type")))
 
 	var log models.ErrorLog
-	h.logger.Debug("Binding JSON request body")
-	if err := c.ShouldBindJSON(&log); err != nil {
-		h.logger.Error("Failed to bind error log", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	loggerFrom(c, h.logger).Debug("Binding JSON request body")
+	if err := h.bindEvent(c, &log); err != nil {
+		h.writeBindError(c, err, "Failed to bind error log")
 		return
 	}
 
-	h.logger.Debug("Successfully bound request body",
+	loggerFrom(c, h.logger).Debug("Successfully bound request body",
 		zap.String("project_id", log.ProjectID),
 		zap.String("session_id", log.SessionID),
 		zap.String("trace_id", log.TraceID),
@@ -49,364 +77,1232 @@ func (h *LogHandler) RecordErrorLog(c *gin.Context) {
 		zap.String("extra", string(log.Extra)))
 
 	if err := h.logService.RecordErrorLog(c.Request.Context(), &log); err != nil {
-		h.logger.Error("Failed to record error log 111", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record error log"})
+		h.writeRecordError(c, err, "Failed to record error log")
 		return
 	}
 
-	h.logger.Debug("Error log recorded successfully")
-	c.JSON(http.StatusCreated, gin.H{"message": "Error log recorded successfully"})
+	loggerFrom(c, h.logger).Debug("Error log recorded successfully")
+	h.writeRecorded(c, gin.H{"message": "Error log recorded successfully"})
 }
 
 // GetErrorLogs 获取错误日志列表
 func (h *LogHandler) GetErrorLogs(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		loggerFrom(c, h.logger).Debug("Invalid query for GetErrorLogs", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 调试：打印查询的项目ID
-	h.logger.Debug("GetErrorLogs called", zap.String("project_id", projectID))
+	severities, err := parseSeverities(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	startTime, endTime, err := parseTimeRange(c)
+	types, err := parseErrorTypes(c)
 	if err != nil {
-		h.logger.Error("Invalid time range for GetErrorLogs",
-			zap.String("project_id", projectID),
-			zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	logs, err := h.logService.GetErrorLogs(c.Request.Context(), projectID, startTime, endTime)
+	order, err := parseListOrder(c, h.opts.DefaultOrder)
 	if err != nil {
-		h.logger.Error("Failed to get error logs",
-			zap.String("project_id", projectID),
-			zap.Time("start_time", startTime),
-			zap.Time("end_time", endTime),
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trackSkippedRows(c)
+	var logs []*models.ErrorLog
+	if query.Since != nil {
+		logs, err = h.logService.GetErrorLogsSince(c.Request.Context(), query.ProjectIDs, *query.Since, query.Extra, severities, types, query.Limit)
+	} else {
+		logs, err = h.logService.GetErrorLogs(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Extra, severities, types, order)
+	}
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get error logs",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Time("start_time", query.Start),
+			zap.Time("end_time", query.End),
 			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get error logs"})
 		return
 	}
 
-	// 调试：输出查询结果条数
-	h.logger.Debug("GetErrorLogs succeeded",
-		zap.String("project_id", projectID),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
+	loggerFrom(c, h.logger).Debug("GetErrorLogs succeeded",
+		zap.Strings("project_id", query.ProjectIDs),
+		zap.Time("start_time", query.Start),
+		zap.Time("end_time", query.End),
 		zap.Int("count", len(logs)))
 
+	h.setRefreshHint(c, query.ProjectIDs)
+	writeList(c, h.logger, logs)
+}
+
+// GetSeverityBreakdown 获取按严重程度统计的错误数
+func (h *LogHandler) GetSeverityBreakdown(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.logService.GetSeverityBreakdown(c.Request.Context(), query.ProjectIDs, query.Start, query.End)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get severity breakdown",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get severity breakdown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetErrorsByRelease 按版本统计错误数和错误率，用于发现新版本引入的错误回归
+func (h *LogHandler) GetErrorsByRelease(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	releases, err := h.logService.GetErrorsByRelease(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Limit)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get errors by release",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get errors by release"})
+		return
+	}
+
+	c.JSON(http.StatusOK, releases)
+}
+
+// GetErrorLogByTraceID 根据 trace_id 获取错误日志
+func (h *LogHandler) GetErrorLogByTraceID(c *gin.Context) {
+	traceID := c.Param("trace_id")
+
+	log, err := h.logService.GetErrorLogByTraceID(c.Request.Context(), traceID)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get error log by trace_id", zap.String("trace_id", traceID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get error log"})
+		return
+	}
+	if log == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Error log not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+// GetErrorLogsByTraceIDs 根据多个 trace_id 批量获取错误日志，返回 trace_id 到错误日志列表的映射
+func (h *LogHandler) GetErrorLogsByTraceIDs(c *gin.Context) {
+	traceIDs, err := parseTraceIDs(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logs, err := h.logService.GetErrorLogsByTraceIDs(c.Request.Context(), traceIDs)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get error logs by trace_ids", zap.Strings("trace_id", traceIDs), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get error logs"})
+		return
+	}
+
 	c.JSON(http.StatusOK, logs)
 }
 
+// GetStackTrace 根据哈希获取错误日志引用的完整堆栈
+func (h *LogHandler) GetStackTrace(c *gin.Context) {
+	hash := c.Param("hash")
+
+	trace, err := h.logService.GetStackTrace(c.Request.Context(), hash)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStackHash) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get stack trace", zap.String("hash", hash), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get stack trace"})
+		return
+	}
+	if trace == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stack trace not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trace)
+}
+
+// GetErrorGroupTrend 获取单个错误分组按时间桶统计的出现次数
+func (h *LogHandler) GetErrorGroupTrend(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	interval, err := parseInterval(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, err := parseTimeZone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	fingerprint := c.Param("fingerprint")
+
+	trend, err := h.logService.GetErrorGroupTrend(c.Request.Context(), query.ProjectIDs, fingerprint, query.Start, query.End, interval, loc)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFingerprint) || errors.Is(err, services.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get error group trend",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("fingerprint", fingerprint),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get error group trend"})
+		return
+	}
+	if trend == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Error group not found in time range"})
+		return
+	}
+
+	c.JSON(http.StatusOK, trend)
+}
+
+// GetErrorLogsWithPerformance 获取错误日志及同一会话中在其之前最近的性能指标，最多 limit 条
+func (h *LogHandler) GetErrorLogsWithPerformance(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := h.logService.GetErrorLogsWithPerformance(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Limit)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get error logs with performance",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get error logs with performance"})
+		return
+	}
+
+	writeList(c, h.logger, results)
+}
+
+// GetCoOccurringErrors 获取与指定错误出现在同一会话中的其他错误
+func (h *LogHandler) GetCoOccurringErrors(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	result, err := h.logService.GetCoOccurringErrors(c.Request.Context(), query.ProjectIDs, name, query.Start, query.End)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get co-occurring errors",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("name", name),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get co-occurring errors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // RecordPerformanceMetric 记录性能指标
 func (h *LogHandler) RecordPerformanceMetric(c *gin.Context) {
 	var metric models.PerformanceMetric
-	if err := c.ShouldBindJSON(&metric); err != nil {
-		h.logger.Error("Failed to bind performance metric", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := h.bindEvent(c, &metric); err != nil {
+		h.writeBindError(c, err, "Failed to bind performance metric")
 		return
 	}
 
 	if err := h.logService.RecordPerformanceMetric(c.Request.Context(), &metric); err != nil {
-		h.logger.Error("Failed to record performance metric", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record performance metric"})
+		h.writeRecordError(c, err, "Failed to record performance metric")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Performance metric recorded successfully"})
+	h.writeRecorded(c, gin.H{"message": "Performance metric recorded successfully"})
 }
 
 // GetPerformanceMetrics 获取性能指标列表
 func (h *LogHandler) GetPerformanceMetrics(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		h.logger.Error("Missing project_id for performance metrics request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		loggerFrom(c, h.logger).Debug("Invalid query for GetPerformanceMetrics", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Debug: raw inputs
-	h.logger.Debug(
-		"GetPerformanceMetrics request",
-		zap.String("project_id", projectID),
-		zap.String("start_time_raw", c.Query("start_time")),
-		zap.String("end_time_raw", c.Query("end_time")),
-	)
-
-	startTime, endTime, err := parseTimeRange(c)
+	order, err := parseListOrder(c, h.opts.DefaultOrder, models.SortFieldValue)
 	if err != nil {
-		h.logger.Error(
-			"Failed to parse time range for performance metrics",
-			zap.String("project_id", projectID),
-			zap.String("start_time_raw", c.Query("start_time")),
-			zap.String("end_time_raw", c.Query("end_time")),
-			zap.Error(err),
-		)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.logger.Debug(
-		"Parsed time range for performance metrics",
-		zap.String("project_id", projectID),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
-
-	metrics, err := h.logService.GetPerformanceMetrics(c.Request.Context(), projectID, startTime, endTime)
+	trackSkippedRows(c)
+	var metrics []*models.PerformanceMetric
+	if query.Since != nil {
+		metrics, err = h.logService.GetPerformanceMetricsSince(c.Request.Context(), query.ProjectIDs, *query.Since, query.Extra, query.Limit)
+	} else {
+		metrics, err = h.logService.GetPerformanceMetrics(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Extra, order)
+	}
 	if err != nil {
-		h.logger.Error(
-			"Failed to get performance metrics",
-			zap.String("project_id", projectID),
-			zap.Time("start_time", startTime),
-			zap.Time("end_time", endTime),
-			zap.Error(err),
-		)
+		loggerFrom(c, h.logger).Error("Failed to get performance metrics",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Time("start_time", query.Start),
+			zap.Time("end_time", query.End),
+			zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get performance metrics"})
 		return
 	}
 
-	h.logger.Debug(
-		"Fetched performance metrics",
-		zap.String("project_id", projectID),
-		zap.Int("count", len(metrics)),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
+	loggerFrom(c, h.logger).Debug("GetPerformanceMetrics succeeded",
+		zap.Strings("project_id", query.ProjectIDs),
+		zap.Time("start_time", query.Start),
+		zap.Time("end_time", query.End),
+		zap.Int("count", len(metrics)))
 
-	c.JSON(http.StatusOK, metrics)
+	h.setRefreshHint(c, query.ProjectIDs)
+	writeList(c, h.logger, metrics)
 }
 
-// RecordUserAction 记录用户行为
-func (h *LogHandler) RecordUserAction(c *gin.Context) {
-	var action models.UserAction
-	if err := c.ShouldBindJSON(&action); err != nil {
-		h.logger.Error("Failed to bind user action", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+// GetPerformanceMetricByTraceID 根据 trace_id 获取性能指标
+func (h *LogHandler) GetPerformanceMetricByTraceID(c *gin.Context) {
+	traceID := c.Param("trace_id")
+
+	metric, err := h.logService.GetPerformanceMetricByTraceID(c.Request.Context(), traceID)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get performance metric by trace_id", zap.String("trace_id", traceID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get performance metric"})
 		return
 	}
-
-	if err := h.logService.RecordUserAction(c.Request.Context(), &action); err != nil {
-		h.logger.Error("Failed to record user action", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record user action"})
+	if metric == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Performance metric not found"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User action recorded successfully"})
+	c.JSON(http.StatusOK, metric)
 }
 
-// GetUserActions 获取用户行为列表
-func (h *LogHandler) GetUserActions(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		h.logger.Error("Missing project_id for user actions request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+// GetApdex 获取性能指标的 Apdex 评分
+func (h *LogHandler) GetApdex(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Debug: raw inputs
-	h.logger.Debug(
-		"GetUserActions request",
-		zap.String("project_id", projectID),
-		zap.String("start_time_raw", c.Query("start_time")),
-		zap.String("end_time_raw", c.Query("end_time")),
-	)
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	threshold, err := strconv.ParseFloat(c.Query("t"), 64)
+	if err != nil || threshold <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "t must be a positive number"})
+		return
+	}
 
-	startTime, endTime, err := parseTimeRange(c)
+	minCount, err := parseMinCount(c, 0)
 	if err != nil {
-		h.logger.Error(
-			"Failed to parse time range for user actions",
-			zap.String("project_id", projectID),
-			zap.String("start_time_raw", c.Query("start_time")),
-			zap.String("end_time_raw", c.Query("end_time")),
-			zap.Error(err),
-		)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.logger.Debug(
-		"Parsed time range for user actions",
-		zap.String("project_id", projectID),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
-
-	actions, err := h.logService.GetUserActions(c.Request.Context(), projectID, startTime, endTime)
+	apdex, err := h.logService.GetApdex(c.Request.Context(), query.ProjectIDs, name, threshold, minCount, query.Start, query.End)
 	if err != nil {
-		h.logger.Error(
-			"Failed to get user actions",
-			zap.String("project_id", projectID),
-			zap.Time("start_time", startTime),
-			zap.Time("end_time", endTime),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user actions"})
+		loggerFrom(c, h.logger).Error("Failed to get apdex",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("name", name),
+			zap.Float64("threshold", threshold),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get apdex"})
 		return
 	}
 
-	h.logger.Debug(
-		"Fetched user actions",
-		zap.String("project_id", projectID),
-		zap.Int("count", len(actions)),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
-
-	c.JSON(http.StatusOK, actions)
+	c.JSON(http.StatusOK, apdex)
 }
 
-// RecordCustomEvent 记录自定义事件
-func (h *LogHandler) RecordCustomEvent(c *gin.Context) {
-	var event models.CustomEvent
-	if err := c.ShouldBindJSON(&event); err != nil {
-		h.logger.Error("Failed to bind custom event", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+// GetSlowestURLs 获取性能指标分位值最高的页面，用于定位大多数用户访问都慢的页面
+func (h *LogHandler) GetSlowestURLs(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 处理自定义extra字段
-	if c.Query("parse_extra") == "true" {
-		// 这里可以根据需要解析额外的查询参数到extra字段
-		extra := make(map[string]interface{})
-		for key, values := range c.Request.URL.Query() {
-			if key != "parse_extra" && len(values) > 0 {
-				extra[key] = values[0]
-			}
-		}
-		if len(extra) > 0 {
-			extraData, _ := json.Marshal(extra)
-			event.Extra = extraData
+	name := c.Query("name")
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	quantile := defaultSlowURLQuantile
+	if raw := c.Query("q"); raw != "" {
+		quantile, err = strconv.ParseFloat(raw, 64)
+		if err != nil || quantile <= 0 || quantile >= 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "q must be a number between 0 and 1 (exclusive)"})
+			return
 		}
 	}
 
-	if err := h.logService.RecordCustomEvent(c.Request.Context(), &event); err != nil {
-		h.logger.Error("Failed to record custom event", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record custom event"})
+	minCount, err := parseMinCount(c, defaultSlowURLMinCount)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Custom event recorded successfully"})
-}
-
-// GetCustomEvents 获取自定义事件列表
-func (h *LogHandler) GetCustomEvents(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		h.logger.Error("Missing project_id for custom events request")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+	results, err := h.logService.GetSlowestURLsByPercentile(c.Request.Context(), query.ProjectIDs, name, quantile, minCount, query.Start, query.End, query.Limit)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get slowest urls",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("name", name),
+			zap.Float64("quantile", quantile),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get slowest urls"})
 		return
 	}
 
-	// Debug: raw inputs
-	h.logger.Debug(
-		"GetCustomEvents request",
-		zap.String("project_id", projectID),
-		zap.String("start_time_raw", c.Query("start_time")),
-		zap.String("end_time_raw", c.Query("end_time")),
-	)
+	writeList(c, h.logger, results)
+}
 
-	startTime, endTime, err := parseTimeRange(c)
+// GetWebVitalsScorecard 获取核心 Web Vitals 的良好/需改进/差占比
+func (h *LogHandler) GetWebVitalsScorecard(c *gin.Context) {
+	query, err := parseCommonQuery(c)
 	if err != nil {
-		h.logger.Error(
-			"Failed to parse time range for custom events",
-			zap.String("project_id", projectID),
-			zap.String("start_time_raw", c.Query("start_time")),
-			zap.String("end_time_raw", c.Query("end_time")),
-			zap.Error(err),
-		)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	h.logger.Debug(
-		"Parsed time range for custom events",
-		zap.String("project_id", projectID),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
+	minCount, err := parseMinCount(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	events, err := h.logService.GetCustomEvents(c.Request.Context(), projectID, startTime, endTime)
+	scorecard, err := h.logService.GetWebVitalsScorecard(c.Request.Context(), query.ProjectIDs, minCount, query.Start, query.End)
 	if err != nil {
-		h.logger.Error(
-			"Failed to get custom events",
-			zap.String("project_id", projectID),
-			zap.Time("start_time", startTime),
-			zap.Time("end_time", endTime),
-			zap.Error(err),
-		)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom events"})
+		loggerFrom(c, h.logger).Error("Failed to get web vitals scorecard",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get web vitals scorecard"})
 		return
 	}
 
-	h.logger.Debug(
-		"Fetched custom events",
-		zap.String("project_id", projectID),
-		zap.Int("count", len(events)),
-		zap.Time("start_time", startTime),
-		zap.Time("end_time", endTime),
-	)
+	c.JSON(http.StatusOK, scorecard)
+}
 
-	c.JSON(http.StatusOK, events)
+// GetMetricAliases 获取当前生效的指标别名表
+func (h *LogHandler) GetMetricAliases(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"aliases": h.logService.MetricAliases()})
 }
 
-// RecordPageStay 记录页面停留时长
-func (h *LogHandler) RecordPageStay(c *gin.Context) {
-	var pageStay models.PageStay
-	if err := c.ShouldBindJSON(&pageStay); err != nil {
-		h.logger.Error("Failed to bind page stay", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+// GetSchemaMetadata 获取各事件类型可查询的列，供看板动态构建查询条件
+func (h *LogHandler) GetSchemaMetadata(c *gin.Context) {
+	metadata, err := h.logService.GetSchemaMetadata(c.Request.Context())
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get schema metadata", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schema metadata"})
 		return
 	}
 
-	if err := h.logService.RecordPageStay(c.Request.Context(), &pageStay); err != nil {
-		h.logger.Error("Failed to record page stay", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record page stay"})
+	c.JSON(http.StatusOK, metadata)
+}
+
+// RecordUserAction 记录用户行为
+func (h *LogHandler) RecordUserAction(c *gin.Context) {
+	var action models.UserAction
+	if err := h.bindEvent(c, &action); err != nil {
+		h.writeBindError(c, err, "Failed to bind user action")
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Page stay recorded successfully"})
+	if err := h.logService.RecordUserAction(c.Request.Context(), &action); err != nil {
+		h.writeRecordError(c, err, "Failed to record user action")
+		return
+	}
+
+	h.writeRecorded(c, gin.H{"message": "User action recorded successfully"})
 }
 
-// GetAveragePageStay 获取平均页面停留时长
-func (h *LogHandler) GetAveragePageStay(c *gin.Context) {
-	projectID := c.Query("project_id")
-	if projectID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+// GetUserActions 获取用户行为列表
+func (h *LogHandler) GetUserActions(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		loggerFrom(c, h.logger).Debug("Invalid query for GetUserActions", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	startTime, endTime, err := parseTimeRange(c)
+	statusClasses, err := parseStatusClasses(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	average, err := h.logService.GetAveragePageStay(c.Request.Context(), projectID, startTime, endTime)
+	order, err := parseListOrder(c, h.opts.DefaultOrder, models.SortFieldValue)
 	if err != nil {
-		h.logger.Error("Failed to get average page stay", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get average page stay"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trackSkippedRows(c)
+	var actions []*models.UserAction
+	if query.Since != nil {
+		actions, err = h.logService.GetUserActionsSince(c.Request.Context(), query.ProjectIDs, *query.Since, query.Extra, statusClasses, query.Limit)
+	} else {
+		actions, err = h.logService.GetUserActions(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Extra, statusClasses, order)
+	}
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get user actions",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Time("start_time", query.Start),
+			zap.Time("end_time", query.End),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user actions"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"average_page_stay": average})
+	loggerFrom(c, h.logger).Debug("GetUserActions succeeded",
+		zap.Strings("project_id", query.ProjectIDs),
+		zap.Time("start_time", query.Start),
+		zap.Time("end_time", query.End),
+		zap.Int("count", len(actions)))
+
+	h.setRefreshHint(c, query.ProjectIDs)
+	writeList(c, h.logger, actions)
 }
 
-// parseTimeRange 解析时间范围参数
-func parseTimeRange(c *gin.Context) (time.Time, time.Time, error) {
-	startTimeStr := c.DefaultQuery("start_time", time.Now().AddDate(0, 0, -1).Format(time.RFC3339))
-	endTimeStr := c.DefaultQuery("end_time", time.Now().Format(time.RFC3339))
+// GetStatusClassBreakdown 获取按 HTTP 状态码类别统计的用户行为数
+func (h *LogHandler) GetStatusClassBreakdown(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	startTime, err := time.Parse(time.RFC3339, startTimeStr)
+	breakdown, err := h.logService.GetStatusClassBreakdown(c.Request.Context(), query.ProjectIDs, query.Start, query.End)
 	if err != nil {
-		return time.Time{}, time.Time{}, err
+		loggerFrom(c, h.logger).Error("Failed to get status class breakdown",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status class breakdown"})
+		return
 	}
 
-	endTime, err := time.Parse(time.RFC3339, endTimeStr)
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetUserActionByTraceID 根据 trace_id 获取用户行为
+func (h *LogHandler) GetUserActionByTraceID(c *gin.Context) {
+	traceID := c.Param("trace_id")
+
+	action, err := h.logService.GetUserActionByTraceID(c.Request.Context(), traceID)
 	if err != nil {
-		return time.Time{}, time.Time{}, err
+		loggerFrom(c, h.logger).Error("Failed to get user action by trace_id", zap.String("trace_id", traceID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user action"})
+		return
+	}
+	if action == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User action not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, action)
+}
+
+// RecordCustomEvent 记录自定义事件
+func (h *LogHandler) RecordCustomEvent(c *gin.Context) {
+	var event models.CustomEvent
+	if err := h.bindEvent(c, &event); err != nil {
+		h.writeBindError(c, err, "Failed to bind custom event")
+		return
+	}
+
+	// 处理自定义extra字段
+	if c.Query("parse_extra") == "true" {
+		// 这里可以根据需要解析额外的查询参数到extra字段
+		extra := make(map[string]interface{})
+		for key, values := range c.Request.URL.Query() {
+			if key != "parse_extra" && len(values) > 0 {
+				extra[key] = values[0]
+			}
+		}
+		if len(extra) > 0 {
+			extraData, _ := json.Marshal(extra)
+			event.Extra = extraData
+		}
+	}
+
+	if err := h.logService.RecordCustomEvent(c.Request.Context(), &event); err != nil {
+		h.writeRecordError(c, err, "Failed to record custom event")
+		return
+	}
+
+	h.writeRecorded(c, gin.H{"message": "Custom event recorded successfully"})
+}
+
+// GetCustomEvents 获取自定义事件列表
+func (h *LogHandler) GetCustomEvents(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		loggerFrom(c, h.logger).Debug("Invalid query for GetCustomEvents", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order, err := parseListOrder(c, h.opts.DefaultOrder)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trackSkippedRows(c)
+	var events []*models.CustomEvent
+	if query.Since != nil {
+		events, err = h.logService.GetCustomEventsSince(c.Request.Context(), query.ProjectIDs, *query.Since, query.Extra, query.Limit)
+	} else {
+		events, err = h.logService.GetCustomEvents(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Extra, order)
+	}
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get custom events",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Time("start_time", query.Start),
+			zap.Time("end_time", query.End),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom events"})
+		return
+	}
+
+	loggerFrom(c, h.logger).Debug("GetCustomEvents succeeded",
+		zap.Strings("project_id", query.ProjectIDs),
+		zap.Time("start_time", query.Start),
+		zap.Time("end_time", query.End),
+		zap.Int("count", len(events)))
+
+	h.setRefreshHint(c, query.ProjectIDs)
+	writeList(c, h.logger, events)
+}
+
+// RecordPageStay 记录页面停留时长
+func (h *LogHandler) RecordPageStay(c *gin.Context) {
+	var pageStay models.PageStay
+	if err := h.bindEvent(c, &pageStay); err != nil {
+		h.writeBindError(c, err, "Failed to bind page stay")
+		return
+	}
+
+	if err := h.logService.RecordPageStay(c.Request.Context(), &pageStay); err != nil {
+		h.writeRecordError(c, err, "Failed to record page stay")
+		return
+	}
+
+	h.writeRecorded(c, gin.H{"message": "Page stay recorded successfully"})
+}
+
+// GetAveragePageStay 获取平均页面停留时长
+func (h *LogHandler) GetAveragePageStay(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	minCount, err := parseMinCount(c, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	average, err := h.logService.GetAveragePageStay(c.Request.Context(), query.ProjectIDs, minCount, query.Start, query.End)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get average page stay", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get average page stay"})
+		return
+	}
+
+	c.JSON(http.StatusOK, average)
+}
+
+// GetOverview 一次返回各类事件的数量、平均页面停留时长和会话数，供看板顶部计数器使用
+func (h *LogHandler) GetOverview(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	overview, err := h.logService.GetOverview(c.Request.Context(), query.ProjectIDs, query.Start, query.End)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get overview",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get overview"})
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// GetDataRange 获取项目数据的时间范围
+func (h *LogHandler) GetDataRange(c *gin.Context) {
+	projectID := c.Param("id")
+
+	dataRange, err := h.logService.GetDataRange(c.Request.Context(), projectID)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get data range", zap.String("project_id", projectID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get data range"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dataRange)
+}
+
+// GetIngestionRate 获取所有事件表合计的写入量趋势，用于容量规划
+func (h *LogHandler) GetIngestionRate(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	interval, err := parseInterval(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, err := parseTimeZone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rate, err := h.logService.GetIngestionRate(c.Request.Context(), query.ProjectIDs, query.Start, query.End, interval, loc)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get ingestion rate",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ingestion rate"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rate)
+}
+
+// GetSessionCount 获取时间范围内的不同会话数，指定 interval 时同时返回按时间桶统计的活跃会话数
+func (h *LogHandler) GetSessionCount(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	interval, err := parseInterval(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, err := parseTimeZone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	count, err := h.logService.GetSessionCount(c.Request.Context(), query.ProjectIDs, query.Start, query.End, query.Extra, interval, loc)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidInterval) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get session count",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get session count"})
+		return
+	}
+
+	c.JSON(http.StatusOK, count)
+}
+
+// GetCrashFreeSessions 获取没有发生错误的会话和用户占比，severity 指定视为崩溃的严重程度，默认为 fatal 和 error
+func (h *LogHandler) GetCrashFreeSessions(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	severities, err := parseSeverities(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	stats, err := h.logService.GetCrashFreeSessions(c.Request.Context(), query.ProjectIDs, severities, query.Start, query.End, query.Extra)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get crash-free sessions",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get crash-free sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetSDKVersionBreakdown 按 SDK 版本和平台统计事件数，用于定位某个 SDK 版本引入的问题
+func (h *LogHandler) GetSDKVersionBreakdown(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	eventType, err := parseEventType(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.logService.GetSDKVersionBreakdown(c.Request.Context(), query.ProjectIDs, eventType, query.Start, query.End)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get sdk version breakdown",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("type", eventType),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get sdk version breakdown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetReferrerBreakdown 按来源域名统计流量和错误，没有 referrer 的事件归入 direct
+func (h *LogHandler) GetReferrerBreakdown(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	eventType, err := parseEventType(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.logService.GetEventsByReferrerDomain(c.Request.Context(), query.ProjectIDs, eventType, query.Start, query.End, query.Limit)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get referrer breakdown",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("type", eventType),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get referrer breakdown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetActivityHeatmap 按星期和小时统计事件数，用于热力图展示
+func (h *LogHandler) GetActivityHeatmap(c *gin.Context) {
+	query, err := parseCommonQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	eventType, err := parseEventType(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc, err := parseTimeZone(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	heatmap, err := h.logService.GetActivityHeatmap(c.Request.Context(), query.ProjectIDs, eventType, query.Start, query.End, loc)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get activity heatmap",
+			zap.Strings("project_id", query.ProjectIDs),
+			zap.String("type", eventType),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activity heatmap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, heatmap)
+}
+
+// GetSessionTimeline 获取会话在所有事件表中的时间线
+func (h *LogHandler) GetSessionTimeline(c *gin.Context) {
+	h.getTimeline(c, "session_id", h.logService.GetSessionTimeline)
+}
+
+// GetTraceTimeline 获取链路在所有事件表中的时间线
+func (h *LogHandler) GetTraceTimeline(c *gin.Context) {
+	h.getTimeline(c, "trace_id", h.logService.GetTraceTimeline)
+}
+
+// getTimeline 时间线查询的公共处理，partial=true 时允许部分表失败
+func (h *LogHandler) getTimeline(c *gin.Context, param string,
+	query func(ctx context.Context, projectID string, value string, allowPartial bool) (*models.Timeline, error)) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		return
+	}
+	value := c.Param(param)
+	allowPartial := c.Query("partial") == "true"
+
+	timeline, err := query(c.Request.Context(), projectID, value, allowPartial)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to get timeline",
+			zap.String("project_id", projectID),
+			zap.String(param, value),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get timeline"})
+		return
+	}
+	if timeline.Partial {
+		loggerFrom(c, h.logger).Warn("Timeline is partial",
+			zap.String("project_id", projectID),
+			zap.String(param, value),
+			zap.Strings("failed_tables", timeline.FailedTables))
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// RecordCompressedEvents 记录 gzip 压缩并 base64 编码的一批事件，供无法流式上报的嵌入式或原生客户端使用
+func (h *LogHandler) RecordCompressedEvents(c *gin.Context) {
+	var payload services.CompressedPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		h.writeBindError(c, err, "Failed to bind compressed events")
+		return
+	}
+
+	trackUserAgent(c)
+	counts, err := h.logService.RecordCompressed(c.Request.Context(), payload.Data)
+	if err != nil {
+		h.writeRecordError(c, err, "Failed to record compressed events")
+		return
+	}
+
+	h.writeRecorded(c, gin.H{"message": "Events recorded successfully", "counts": counts})
+}
+
+// IngestStream 流式上报 NDJSON 格式的混合类型事件
+// 无法解析或校验失败的行跳过并计入结果，请求本身成功时返回 200
+func (h *LogHandler) IngestStream(c *gin.Context) {
+	trackUserAgent(c)
+	result, err := h.logService.IngestStream(c.Request.Context(), c.Request.Body, h.opts.ProjectAllowed)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to ingest event stream",
+			zap.Int("lines", result.Lines),
+			zap.Int("ingested", result.Ingested),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to ingest event stream", "result": result})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Event stream ingested",
+		zap.Int("lines", result.Lines),
+		zap.Int("ingested", result.Ingested),
+		zap.Int("failed", result.Failed))
+	c.JSON(http.StatusOK, result)
+}
+
+// ImportEvents 导入 JSONL 格式的事件数据
+func (h *LogHandler) ImportEvents(c *gin.Context) {
+	result, err := h.logService.ImportEvents(c.Request.Context(), c.Request.Body)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to import events",
+			zap.Int("lines", result.Lines),
+			zap.Int("imported", result.Imported),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import events", "result": result})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Events imported",
+		zap.Int("lines", result.Lines),
+		zap.Int("imported", result.Imported),
+		zap.Int("failed", result.Failed))
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteUserData 删除指定用户的全部数据
+func (h *LogHandler) DeleteUserData(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		return
+	}
+	userID := c.Param("user_id")
+
+	summary, err := h.logService.DeleteByUser(c.Request.Context(), projectID, userID)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to delete user data",
+			zap.String("project_id", projectID),
+			zap.String("user_id", userID),
+			zap.Any("summary", summary),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user data"})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("User data deletion submitted",
+		zap.String("project_id", projectID),
+		zap.String("user_id", userID),
+		zap.Uint64("total_rows", summary.TotalRows))
+	c.JSON(http.StatusOK, summary)
+}
+
+// DeleteSessionData 删除指定会话的全部数据
+func (h *LogHandler) DeleteSessionData(c *gin.Context) {
+	projectID := c.Query("project_id")
+	if projectID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "project_id is required"})
+		return
+	}
+	sessionID := c.Param("session_id")
+
+	summary, err := h.logService.DeleteBySession(c.Request.Context(), projectID, sessionID)
+	if err != nil {
+		loggerFrom(c, h.logger).Error("Failed to delete session data",
+			zap.String("project_id", projectID),
+			zap.String("session_id", sessionID),
+			zap.Any("summary", summary),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete session data"})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Session data deletion submitted",
+		zap.String("project_id", projectID),
+		zap.String("session_id", sessionID),
+		zap.Uint64("total_rows", summary.TotalRows))
+	c.JSON(http.StatusOK, summary)
+}
+
+// DeleteProject 删除整个项目在所有事件表中的数据，请求体中的 confirm 必须与项目ID一致
+// 未开启 auth.allow_project_purge 时返回 403
+func (h *LogHandler) DeleteProject(c *gin.Context) {
+	if !h.opts.ProjectPurge {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project purge is disabled"})
+		return
+	}
+	projectID := c.Param("id")
+
+	var req services.ProjectPurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.writeBindError(c, err, "Failed to bind project purge request")
+		return
+	}
+
+	summary, err := h.logService.DeleteProject(c.Request.Context(), projectID, req.Confirm)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			h.writeBindError(c, err, "Invalid project purge request")
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to purge project",
+			zap.String("project_id", projectID),
+			zap.Any("summary", summary),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge project"})
+		return
+	}
+
+	loggerFrom(c, h.logger).Warn("Project purge submitted",
+		zap.String("project_id", projectID),
+		zap.Int("tables", len(summary.Tables)),
+		zap.Uint64("total_rows", summary.TotalRows))
+	c.JSON(http.StatusOK, summary)
+}
+
+// SetRetention 修改所有事件表的数据保留天数
+func (h *LogHandler) SetRetention(c *gin.Context) {
+	var req services.RetentionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.writeBindError(c, err, "Failed to bind retention request")
+		return
+	}
+
+	policy, err := h.logService.SetRetention(c.Request.Context(), *req.Days)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			h.writeBindError(c, err, "Invalid retention request")
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to set retention",
+			zap.Int("days", *req.Days),
+			zap.Any("policy", policy),
+			zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set retention", "policy": policy})
+		return
+	}
+
+	loggerFrom(c, h.logger).Info("Retention updated", zap.Int("days", policy.Days))
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetTableStats 返回各数据表的行数和磁盘占用
+// 数据库用户没有 system.parts 读取权限时返回 503 并说明需要的授权
+func (h *LogHandler) GetTableStats(c *gin.Context) {
+	stats, err := h.logService.GetStorageStats(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrSystemTablesDenied) {
+			loggerFrom(c, h.logger).Warn("Storage stats unavailable", zap.Error(err))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": services.ErrSystemTablesDenied.Error()})
+			return
+		}
+		loggerFrom(c, h.logger).Error("Failed to get storage stats", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// writeRecordError 根据服务层错误类型返回上报失败响应
+// 批量超限返回 413，不同项目数达到上限返回 429，校验失败返回 422 并指明字段，其余错误返回 500
+func (h *LogHandler) writeRecordError(c *gin.Context, err error, message string) {
+	var tooLargeErr *services.BatchTooLargeError
+	if errors.As(err, &tooLargeErr) {
+		loggerFrom(c, h.logger).Warn(message, zap.Error(err))
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":     tooLargeErr.Error(),
+			"max_items": tooLargeErr.MaxItems,
+			"max_bytes": tooLargeErr.MaxBytes,
+		})
+		return
+	}
+
+	var projectLimitErr *services.ProjectLimitError
+	if errors.As(err, &projectLimitErr) {
+		loggerFrom(c, h.logger).Warn(message, zap.Error(err))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": projectLimitErr.Error(), "max_projects": projectLimitErr.MaxProjects})
+		return
+	}
+
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		loggerFrom(c, h.logger).Warn(message, zap.String("field", validationErr.Field), zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+		return
+	}
+
+	loggerFrom(c, h.logger).Error(message, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
+
+// bindEvent 读取上报请求体，按 schema_version 转换为当前结构后绑定
+// 严格模式下先检查未定义的顶层字段；性能指标自定义了 UnmarshalJSON，
+// json.Decoder.DisallowUnknownFields 对其不生效，因此按结构体字段逐个比对
+// 绑定成功后从请求头补充 SDK 信息，并在请求上下文中附加 User-Agent
+func (h *LogHandler) bindEvent(c *gin.Context, event any) error {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	body, err = services.UpgradeSchema(body)
+	if err != nil {
+		return err
+	}
+	if h.opts.StrictFields {
+		if field := unknownField(body, event); field != "" {
+			return &services.ValidationError{Field: field, Message: "unknown field"}
+		}
+	}
+	if err := binding.JSON.BindBody(body, event); err != nil {
+		return err
+	}
+	h.fillSDK(c, event)
+	trackUserAgent(c)
+	return nil
+}
+
+// sdkFiller 可由请求头补充 SDK 信息的事件，所有嵌入 models.BaseLog 的事件都满足
+type sdkFiller interface {
+	FillSDK(version, platform string)
+}
+
+// fillSDK 使用配置的请求头补充请求体中未上报的 sdk_version 和 platform
+func (h *LogHandler) fillSDK(c *gin.Context, event any) {
+	filler, ok := event.(sdkFiller)
+	if !ok {
+		return
+	}
+	var version, platform string
+	if h.opts.SDKVersionHeader != "" {
+		version = strings.TrimSpace(c.GetHeader(h.opts.SDKVersionHeader))
+	}
+	if h.opts.PlatformHeader != "" {
+		platform = strings.TrimSpace(c.GetHeader(h.opts.PlatformHeader))
+	}
+	filler.FillSDK(version, platform)
+}
+
+// writeRecorded 返回上报成功的响应
+// 开启 NoContent 时返回 204 以节省高频上报的带宽，调试时可通过 verbose=true 获取完整响应
+func (h *LogHandler) writeRecorded(c *gin.Context, body gin.H) {
+	if h.opts.NoContent && c.Query("verbose") != "true" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusCreated, body)
+}
+
+// writeBindError 根据绑定错误类型返回响应
+// 请求体超出 middleware.MaxBodyBytes 的限制返回 413，字段校验失败和版本不兼容返回 422，其余（如 JSON 格式错误）返回 400
+func (h *LogHandler) writeBindError(c *gin.Context, err error, message string) {
+	if limit, ok := middleware.BodyTooLarge(err); ok {
+		loggerFrom(c, h.logger).Warn(message, zap.Error(err))
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large", "max_bytes": limit})
+		return
+	}
+
+	var validationErr *services.ValidationError
+	if errors.As(err, &validationErr) {
+		loggerFrom(c, h.logger).Warn(message, zap.String("field", validationErr.Field), zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": validationErr.Error(), "field": validationErr.Field})
+		return
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		errs := fieldErrors(fieldErrs)
+		loggerFrom(c, h.logger).Warn(message, zap.Any("errors", errs))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  fmt.Sprintf("%s %s", errs[0].Field, errs[0].Message),
+			"field":  errs[0].Field,
+			"errors": errs,
+		})
+		return
 	}
 
-	return startTime, endTime, nil
+	loggerFrom(c, h.logger).Error(message, zap.Error(err))
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 }
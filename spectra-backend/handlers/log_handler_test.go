@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"spectra-backend/config"
+	"spectra-backend/middleware"
+	"spectra-backend/models"
+	"spectra-backend/repository"
+	"spectra-backend/services"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// fakeRepository 记录写入事件的仓储，未覆盖的方法调用时 panic
+type fakeRepository struct {
+	repository.LogRepository
+
+	mu      sync.Mutex
+	saved   []any
+	batches []*models.EventBatch
+}
+
+func (r *fakeRepository) record(event any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, event)
+	return nil
+}
+
+func (r *fakeRepository) SaveErrorLog(_ context.Context, log *models.ErrorLog) error {
+	return r.record(log)
+}
+
+func (r *fakeRepository) SavePerformanceMetric(_ context.Context, metric *models.PerformanceMetric) error {
+	return r.record(metric)
+}
+
+func (r *fakeRepository) SaveCustomEvent(_ context.Context, event *models.CustomEvent) error {
+	return r.record(event)
+}
+
+func (r *fakeRepository) SaveBatch(_ context.Context, batch *models.EventBatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+// newTestLogHandler 使用 fakeRepository 和真实的日志服务创建处理器
+func newTestLogHandler(t *testing.T, repo *fakeRepository, ingest config.IngestConfig, opts LogHandlerOptions) *LogHandler {
+	t.Helper()
+	if ingest.IDScheme == "" {
+		ingest.IDScheme = "none"
+	}
+	service, err := services.NewLogService(repo, nil, ingest, nil, nil)
+	if err != nil {
+		t.Fatalf("NewLogService() error = %v", err)
+	}
+	t.Cleanup(service.Close)
+	return NewLogHandler(service, zap.NewNop(), opts)
+}
+
+// serve 以 handler 处理一个请求并返回响应
+func serve(handler gin.HandlerFunc, method, target string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, "/*path", handler)
+	req := httptest.NewRequest(method, target, body)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decodeBody 将 JSON 响应体解析为 map
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not a JSON object: %s", w.Body)
+	}
+	return body
+}
+
+// gzipBase64 将数据 gzip 压缩后 base64 编码，作为压缩上报的 data 字段
+func gzipBase64(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestRecordCompressedEventsTooLarge(t *testing.T) {
+	repo := &fakeRepository{}
+	handler := newTestLogHandler(t, repo, config.IngestConfig{MaxBatchItems: 2, MaxBatchBytes: 4096}, LogHandlerOptions{})
+
+	payload := gzipBase64(t, `[
+		{"type":"custom_event","data":{"project_id":"web","name":"a"}},
+		{"type":"custom_event","data":{"project_id":"web","name":"b"}},
+		{"type":"custom_event","data":{"project_id":"web","name":"c"}}
+	]`)
+	w := serve(handler.RecordCompressedEvents, http.MethodPost, "/api/events/compressed", strings.NewReader(`{"data":"`+payload+`"}`), nil)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status = %d, want 413 (body %s)", w.Code, w.Body)
+	}
+	body := decodeBody(t, w)
+	if body["max_items"] != float64(2) || body["max_bytes"] != float64(4096) {
+		t.Errorf("response = %v, want both limits", body)
+	}
+	if msg, _ := body["error"].(string); !strings.Contains(msg, "2 items") || !strings.Contains(msg, "4096 decoded bytes") {
+		t.Errorf("error message %q does not state both limits", msg)
+	}
+	if len(repo.batches) != 0 {
+		t.Errorf("saved %d batches, want none", len(repo.batches))
+	}
+}
+
+func TestStrictFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    func(*LogHandler) gin.HandlerFunc
+		body       string
+		strict     bool
+		wantStatus int
+		wantField  string
+	}{
+		{"lenient ignores unknown field", func(h *LogHandler) gin.HandlerFunc { return h.RecordCustomEvent },
+			`{"project_id":"web","name":"signup","color":"red"}`, false, http.StatusCreated, ""},
+		{"strict rejects unknown field", func(h *LogHandler) gin.HandlerFunc { return h.RecordCustomEvent },
+			`{"project_id":"web","name":"signup","color":"red"}`, true, http.StatusUnprocessableEntity, "color"},
+		{"strict accepts known fields", func(h *LogHandler) gin.HandlerFunc { return h.RecordCustomEvent },
+			`{"project_id":"web","name":"signup","message":"ok","extra":{"color":"red"}}`, true, http.StatusCreated, ""},
+		{"strict matches field names case-insensitively", func(h *LogHandler) gin.HandlerFunc { return h.RecordCustomEvent },
+			`{"Project_ID":"web","name":"signup"}`, true, http.StatusCreated, ""},
+		// 性能指标自定义了 UnmarshalJSON，严格模式仍需生效
+		{"strict rejects unknown field on metric", func(h *LogHandler) gin.HandlerFunc { return h.RecordPerformanceMetric },
+			`{"project_id":"web","name":"lcp","value":1.5,"unit":"ms"}`, true, http.StatusUnprocessableEntity, "unit"},
+		{"lenient ignores unknown field on metric", func(h *LogHandler) gin.HandlerFunc { return h.RecordPerformanceMetric },
+			`{"project_id":"web","name":"lcp","value":1.5,"unit":"ms"}`, false, http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			handler := newTestLogHandler(t, repo, config.IngestConfig{}, LogHandlerOptions{StrictFields: tt.strict})
+			w := serve(tt.handler(handler), http.MethodPost, "/api/events", strings.NewReader(tt.body), nil)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if tt.wantField != "" {
+				if body := decodeBody(t, w); body["field"] != tt.wantField {
+					t.Errorf("field = %v, want %s", body["field"], tt.wantField)
+				}
+				if len(repo.saved) != 0 {
+					t.Errorf("saved %d events, want none", len(repo.saved))
+				}
+			}
+		})
+	}
+}
+
+func TestRecordBodyTooLarge(t *testing.T) {
+	const limit = 64
+	large := strings.Repeat("x", limit)
+	tests := []struct {
+		name    string
+		handler func(*LogHandler) gin.HandlerFunc
+		body    string
+	}{
+		{"single event", func(h *LogHandler) gin.HandlerFunc { return h.RecordCustomEvent },
+			`{"project_id":"web","name":"signup","message":"` + large + `"}`},
+		{"compressed", func(h *LogHandler) gin.HandlerFunc { return h.RecordCompressedEvents },
+			`{"data":"` + large + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			handler := newTestLogHandler(t, repo, config.IngestConfig{}, LogHandlerOptions{})
+			router := gin.New()
+			router.POST("/api/events", middleware.MaxBodyBytes(limit), tt.handler(handler))
+
+			// 不带 Content-Length，由处理器读取请求体时发现超限
+			req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(tt.body))
+			req.ContentLength = -1
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("status = %d, want 413 (body %s)", w.Code, w.Body)
+			}
+			if body := decodeBody(t, w); body["max_bytes"] != float64(limit) {
+				t.Errorf("response = %v, want max_bytes %d", body, limit)
+			}
+			if len(repo.saved) != 0 || len(repo.batches) != 0 {
+				t.Errorf("saved events for an oversized body")
+			}
+		})
+	}
+}
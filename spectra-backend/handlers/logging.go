@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"spectra-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// loggerFrom 返回携带 request_id、method、path 的请求级日志记录器
+// 未挂载 RequestLogger 中间件时（如单独构造的处理器）退回 fallback
+func loggerFrom(c *gin.Context, fallback *zap.Logger) *zap.Logger {
+	return middleware.LoggerFrom(c, fallback)
+}
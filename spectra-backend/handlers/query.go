@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"spectra-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxProjectIDs 单次查询允许的最大项目数
+const maxProjectIDs = 20
+
+// 分页参数限制
+const (
+	defaultQueryLimit = 100
+	maxQueryLimit     = 1000
+)
+
+// 错误列表按类型过滤的限制
+const (
+	maxErrorTypes      = 20
+	maxErrorTypeLength = 128
+)
+
+// maxTraceIDs 按多个 trace_id 批量查询时单个请求最多包含的 trace_id 数
+const maxTraceIDs = 100
+
+// 最慢页面查询的默认分位数和每个页面的最少样本数；其他聚合接口默认不检查样本数
+const (
+	defaultSlowURLQuantile = 0.95
+	defaultSlowURLMinCount = 20
+)
+
+// defaultQueryWindow 未指定 start_time 时向前查询的时间范围
+const defaultQueryWindow = 24 * time.Hour
+
+// extra 过滤参数的限制
+const (
+	extraParamPrefix    = "extra."
+	maxExtraFilters     = 5
+	maxExtraPathDepth   = 5
+	maxExtraFilterValue = 256
+)
+
+// extraKeyPattern extra 过滤中单层键名允许的字符
+var extraKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,64}$`)
+
+// commonQuery 查询接口共用的参数
+type commonQuery struct {
+	ProjectIDs []string
+	Start      time.Time
+	End        time.Time
+	// Limit 单次返回的最大条数，用于增量轮询和按数量截断的统计查询
+	Limit int
+	// Since 增量轮询起点，来自 since_timestamp 和 since_trace_id，未指定时为 nil
+	Since *models.EventCursor
+	// Extra 按 extra 字段过滤，来自 extra.<path> 参数、sdk_version、platform 等列过滤参数和 exclude_bots，多个条件之间为 AND
+	Extra []models.ExtraFilter
+}
+
+// parseCommonQuery 解析并校验 project_id、start_time、end_time、limit、since_timestamp、since_trace_id、extra.<path>、sdk_version、platform 和 exclude_bots 参数
+// 所有查询接口通过此函数解析参数，保证校验规则和错误信息一致
+func parseCommonQuery(c *gin.Context) (*commonQuery, error) {
+	projectIDs, err := parseProjectIDs(c.Query("project_id"))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	start, err := parseQueryTime(c.Query("start_time"), "start_time", now.Add(-defaultQueryWindow))
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseQueryTime(c.Query("end_time"), "end_time", now)
+	if err != nil {
+		return nil, err
+	}
+	if start.After(end) {
+		return nil, errors.New("start_time must not be after end_time")
+	}
+
+	limit := defaultQueryLimit
+	if raw := c.Query("limit"); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxQueryLimit {
+			return nil, fmt.Errorf("limit must be an integer between 1 and %d", maxQueryLimit)
+		}
+	}
+
+	since, err := parseSince(c)
+	if err != nil {
+		return nil, err
+	}
+
+	extra, err := parseExtraFilters(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commonQuery{
+		ProjectIDs: projectIDs,
+		Start:      start,
+		End:        end,
+		Limit:      limit,
+		Since:      since,
+		Extra:      extra,
+	}, nil
+}
+
+// parseSince 解析增量轮询参数，since_trace_id 必须与 since_timestamp 一起使用
+func parseSince(c *gin.Context) (*models.EventCursor, error) {
+	rawTimestamp := c.Query("since_timestamp")
+	traceID := c.Query("since_trace_id")
+	if rawTimestamp == "" {
+		if traceID != "" {
+			return nil, errors.New("since_trace_id requires since_timestamp")
+		}
+		return nil, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, rawTimestamp)
+	if err != nil {
+		return nil, errors.New("since_timestamp must be an RFC3339 timestamp")
+	}
+	return &models.EventCursor{Timestamp: timestamp, TraceID: traceID}, nil
+}
+
+// parseExtraFilters 解析 extra 过滤参数
+// extra.<path>=<value> 比较字段值，extra.<path>[]=<value> 判断数组字段是否包含该值，path 中的层级用 . 分隔
+// sdk_version=<value>、platform=<value> 按同名列精确匹配，不计入 extra 过滤条件的数量限制
+// exclude_bots=true 时排除上报时 User-Agent 被识别为爬虫的事件，同样不计入数量限制
+func parseExtraFilters(c *gin.Context) ([]models.ExtraFilter, error) {
+	params := c.Request.URL.Query()
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if strings.HasPrefix(key, extraParamPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	// 按参数名排序，生成的 SQL 稳定
+	sort.Strings(keys)
+
+	var filters []models.ExtraFilter
+	for _, key := range keys {
+		op := models.ExtraFilterEquals
+		rawPath := strings.TrimPrefix(key, extraParamPrefix)
+		if strings.HasSuffix(rawPath, "[]") {
+			op = models.ExtraFilterContains
+			rawPath = strings.TrimSuffix(rawPath, "[]")
+		}
+
+		path := strings.Split(rawPath, ".")
+		if len(path) > maxExtraPathDepth {
+			return nil, fmt.Errorf("%s: path must have at most %d levels", key, maxExtraPathDepth)
+		}
+		for _, segment := range path {
+			if !extraKeyPattern.MatchString(segment) {
+				return nil, fmt.Errorf("%s: path segments may only contain letters, digits, '_' and '-'", key)
+			}
+		}
+
+		for _, value := range params[key] {
+			if len(value) > maxExtraFilterValue {
+				return nil, fmt.Errorf("%s: value must be at most %d characters", key, maxExtraFilterValue)
+			}
+			filters = append(filters, models.ExtraFilter{Path: path, Op: op, Value: value})
+		}
+	}
+
+	if len(filters) > maxExtraFilters {
+		return nil, fmt.Errorf("at most %d extra filters are allowed", maxExtraFilters)
+	}
+
+	for _, column := range models.FilterColumns {
+		value := c.Query(column)
+		if value == "" {
+			continue
+		}
+		if len(value) > maxExtraFilterValue {
+			return nil, fmt.Errorf("%s: value must be at most %d characters", column, maxExtraFilterValue)
+		}
+		filters = append(filters, models.ExtraFilter{Column: column, Value: value})
+	}
+
+	if c.Query("exclude_bots") == "true" {
+		filters = append(filters, models.ExcludeBotsFilter)
+	}
+	return filters, nil
+}
+
+// parseProjectIDs 解析逗号分隔的 project_id 参数，去除空值和重复项
+func parseProjectIDs(raw string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var projectIDs []string
+	for _, projectID := range strings.Split(raw, ",") {
+		projectID = strings.TrimSpace(projectID)
+		if projectID == "" {
+			continue
+		}
+		if _, ok := seen[projectID]; ok {
+			continue
+		}
+		seen[projectID] = struct{}{}
+		projectIDs = append(projectIDs, projectID)
+	}
+
+	if len(projectIDs) == 0 {
+		return nil, errors.New("project_id is required")
+	}
+	if len(projectIDs) > maxProjectIDs {
+		return nil, fmt.Errorf("at most %d project_id values are allowed", maxProjectIDs)
+	}
+	return projectIDs, nil
+}
+
+// parseQueryTime 解析 RFC3339 时间参数，为空时使用默认值
+func parseQueryTime(raw string, name string, fallback time.Time) (time.Time, error) {
+	if raw == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// parseInterval 解析趋势查询的 interval 参数（如 5m、1h），未指定时返回 0，由服务层自动选择
+func parseInterval(c *gin.Context) (time.Duration, error) {
+	raw := c.Query("interval")
+	if raw == "" {
+		return 0, nil
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, errors.New("interval must be a duration such as 5m or 1h")
+	}
+	return interval, nil
+}
+
+// parseSeverities 解析逗号分隔的 severity 参数，去除空值和重复项，未指定时返回 nil
+func parseSeverities(c *gin.Context) ([]string, error) {
+	var severities []string
+	for _, severity := range strings.Split(c.Query("severity"), ",") {
+		severity = strings.ToLower(strings.TrimSpace(severity))
+		if severity == "" || slices.Contains(severities, severity) {
+			continue
+		}
+		if !models.IsErrorSeverity(severity) {
+			return nil, fmt.Errorf("severity must be one of %s", strings.Join(models.ErrorSeverities, ", "))
+		}
+		severities = append(severities, severity)
+	}
+	return severities, nil
+}
+
+// parseErrorTypes 解析错误类型过滤参数，type 可重复出现或以逗号分隔，去除空值和重复项，未指定时返回 nil
+func parseErrorTypes(c *gin.Context) ([]string, error) {
+	var types []string
+	for _, raw := range c.QueryArray("type") {
+		for _, errorType := range strings.Split(raw, ",") {
+			errorType = strings.TrimSpace(errorType)
+			if errorType == "" || slices.Contains(types, errorType) {
+				continue
+			}
+			if len(errorType) > maxErrorTypeLength {
+				return nil, fmt.Errorf("type must be at most %d characters", maxErrorTypeLength)
+			}
+			types = append(types, errorType)
+		}
+	}
+	if len(types) > maxErrorTypes {
+		return nil, fmt.Errorf("at most %d types can be queried at once", maxErrorTypes)
+	}
+	return types, nil
+}
+
+// parseListOrder 解析列表查询的 sort 和 order 参数
+// sort 为 timestamp 或 fields 中的字段，未指定时按 timestamp 排序；order 为 asc 或 desc，未指定时使用 defaultOrder
+// 增量轮询固定按时间正序返回，不能与 sort、order 同时使用
+func parseListOrder(c *gin.Context, defaultOrder string, fields ...string) (models.ListOrder, error) {
+	order := models.ListOrder{Field: models.SortFieldTimestamp}
+	sortField, direction := c.Query("sort"), strings.ToLower(c.Query("order"))
+	if c.Query("since_timestamp") != "" && (sortField != "" || direction != "") {
+		return order, errors.New("sort and order cannot be used with since_timestamp")
+	}
+
+	if sortField != "" {
+		allowed := append([]string{models.SortFieldTimestamp}, fields...)
+		if !slices.Contains(allowed, sortField) {
+			return order, fmt.Errorf("sort must be one of %s", strings.Join(allowed, ", "))
+		}
+		order.Field = sortField
+	}
+
+	if direction == "" {
+		direction = defaultOrder
+	}
+	switch direction {
+	case models.SortOrderAsc:
+	case models.SortOrderDesc, "":
+		order.Desc = true
+	default:
+		return order, fmt.Errorf("order must be %s or %s", models.SortOrderAsc, models.SortOrderDesc)
+	}
+	return order, nil
+}
+
+// parseMinCount 解析 min_count 参数指定的最少样本数，未指定时返回 defaultCount
+func parseMinCount(c *gin.Context, defaultCount int) (int, error) {
+	raw := c.Query("min_count")
+	if raw == "" {
+		return defaultCount, nil
+	}
+	minCount, err := strconv.Atoi(raw)
+	if err != nil || minCount < 1 {
+		return 0, errors.New("min_count must be a positive integer")
+	}
+	return minCount, nil
+}
+
+// parseTraceIDs 解析请求体中的 trace_id 数组，去除空值和重复项，不能为空且不超过 maxTraceIDs 个
+func parseTraceIDs(c *gin.Context) ([]string, error) {
+	var raw []string
+	if err := c.ShouldBindJSON(&raw); err != nil {
+		return nil, errors.New("request body must be a JSON array of trace IDs")
+	}
+
+	var traceIDs []string
+	for _, traceID := range raw {
+		traceID = strings.TrimSpace(traceID)
+		if traceID != "" && !slices.Contains(traceIDs, traceID) {
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+	if len(traceIDs) == 0 {
+		return nil, errors.New("at least one trace ID is required")
+	}
+	if len(traceIDs) > maxTraceIDs {
+		return nil, fmt.Errorf("at most %d trace IDs are allowed per request", maxTraceIDs)
+	}
+	return traceIDs, nil
+}
+
+// parseEventType 解析 type 参数指定的事件类型，未指定时返回空字符串
+func parseEventType(c *gin.Context) (string, error) {
+	eventType := c.Query("type")
+	if eventType != "" && !models.IsEventType(eventType) {
+		return "", fmt.Errorf("type must be one of %s", strings.Join(models.EventTypes, ", "))
+	}
+	return eventType, nil
+}
+
+// parseStatusClasses 解析逗号分隔的 status_class 参数（如 4xx,5xx），去除空值和重复项，未指定时返回 nil
+func parseStatusClasses(c *gin.Context) ([]int, error) {
+	var classes []int
+	for _, raw := range strings.Split(c.Query("status_class"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		class, ok := models.ParseStatusClass(raw)
+		if !ok {
+			return nil, errors.New("status_class must be one of 1xx, 2xx, 3xx, 4xx, 5xx")
+		}
+		if !slices.Contains(classes, class) {
+			classes = append(classes, class)
+		}
+	}
+	return classes, nil
+}
+
+// parseTimeZone 解析时间桶所在时区的 tz 参数（IANA 名称，如 Asia/Shanghai），未指定时为 UTC
+func parseTimeZone(c *gin.Context) (*time.Location, error) {
+	raw := c.Query("tz")
+	if raw == "" || raw == "UTC" {
+		return time.UTC, nil
+	}
+	// Local 取决于服务器配置，不作为合法取值
+	if raw == "Local" {
+		return nil, errors.New("tz must be an IANA time zone name such as Asia/Shanghai")
+	}
+	loc, err := time.LoadLocation(raw)
+	if err != nil {
+		return nil, errors.New("tz must be an IANA time zone name such as Asia/Shanghai")
+	}
+	return loc, nil
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"spectra-backend/models"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newQueryContext 创建携带指定查询字符串的 gin 上下文
+func newQueryContext(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/error-logs?"+rawQuery, nil)
+	return c
+}
+
+func TestParseCommonQuery(t *testing.T) {
+	query, err := parseCommonQuery(newQueryContext(
+		"project_id=web,%20app,web&start_time=2024-01-01T00:00:00Z&end_time=2024-01-02T00:00:00Z&limit=50" +
+			"&since_timestamp=2024-01-01T12:00:00.5Z&since_trace_id=t1&extra.user.plan=pro&extra.tags[]=beta&platform=ios&exclude_bots=true"))
+	if err != nil {
+		t.Fatalf("parseCommonQuery() error = %v", err)
+	}
+
+	if want := []string{"web", "app"}; !reflect.DeepEqual(query.ProjectIDs, want) {
+		t.Errorf("ProjectIDs = %v, want %v", query.ProjectIDs, want)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); !query.Start.Equal(want) {
+		t.Errorf("Start = %s, want %s", query.Start, want)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC); !query.End.Equal(want) {
+		t.Errorf("End = %s, want %s", query.End, want)
+	}
+	if query.Limit != 50 {
+		t.Errorf("Limit = %d, want 50", query.Limit)
+	}
+	wantSince := &models.EventCursor{Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 500_000_000, time.UTC), TraceID: "t1"}
+	if query.Since == nil || !query.Since.Timestamp.Equal(wantSince.Timestamp) || query.Since.TraceID != wantSince.TraceID {
+		t.Errorf("Since = %+v, want %+v", query.Since, wantSince)
+	}
+	wantExtra := []models.ExtraFilter{
+		{Path: []string{"tags"}, Op: models.ExtraFilterContains, Value: "beta"},
+		{Path: []string{"user", "plan"}, Op: models.ExtraFilterEquals, Value: "pro"},
+		{Column: "platform", Value: "ios"},
+		models.ExcludeBotsFilter,
+	}
+	if !reflect.DeepEqual(query.Extra, wantExtra) {
+		t.Errorf("Extra = %+v, want %+v", query.Extra, wantExtra)
+	}
+}
+
+func TestParseCommonQueryDefaults(t *testing.T) {
+	before := time.Now()
+	query, err := parseCommonQuery(newQueryContext("project_id=web"))
+	if err != nil {
+		t.Fatalf("parseCommonQuery() error = %v", err)
+	}
+	after := time.Now()
+
+	if query.Limit != defaultQueryLimit {
+		t.Errorf("Limit = %d, want %d", query.Limit, defaultQueryLimit)
+	}
+	if query.End.Before(before) || query.End.After(after) {
+		t.Errorf("End = %s, want the current time", query.End)
+	}
+	if got := query.End.Sub(query.Start); got != defaultQueryWindow {
+		t.Errorf("End - Start = %s, want %s", got, defaultQueryWindow)
+	}
+	if query.Since != nil || query.Extra != nil {
+		t.Errorf("Since = %v, Extra = %v, want both unset", query.Since, query.Extra)
+	}
+}
+
+func TestParseCommonQueryErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantErr string
+	}{
+		{"missing project_id", "", "project_id is required"},
+		{"blank project_id", "project_id=%20,,", "project_id is required"},
+		{"too many project_id", "project_id=" + manyProjectIDs(maxProjectIDs+1), "at most 20 project_id values are allowed"},
+		{"invalid start_time", "project_id=web&start_time=yesterday", "start_time must be an RFC3339 timestamp"},
+		{"invalid end_time", "project_id=web&end_time=2024-01-01", "end_time must be an RFC3339 timestamp"},
+		{"start after end", "project_id=web&start_time=2024-01-02T00:00:00Z&end_time=2024-01-01T00:00:00Z", "start_time must not be after end_time"},
+		{"limit not a number", "project_id=web&limit=ten", "limit must be an integer between 1 and 1000"},
+		{"limit zero", "project_id=web&limit=0", "limit must be an integer between 1 and 1000"},
+		{"limit too large", "project_id=web&limit=1001", "limit must be an integer between 1 and 1000"},
+		{"since_trace_id alone", "project_id=web&since_trace_id=t1", "since_trace_id requires since_timestamp"},
+		{"invalid since_timestamp", "project_id=web&since_timestamp=now", "since_timestamp must be an RFC3339 timestamp"},
+		{"invalid extra path", "project_id=web&extra.a%20b=1", "extra.a b: path segments may only contain letters, digits, '_' and '-'"},
+		{"extra path too deep", "project_id=web&extra.a.b.c.d.e.f=1", "extra.a.b.c.d.e.f: path must have at most 5 levels"},
+		{"too many extra filters", "project_id=web&extra.a=1&extra.b=2&extra.c=3&extra.d=4&extra.e=5&extra.f=6", "at most 5 extra filters are allowed"},
+		{"column value too long", "project_id=web&sdk_version=" + strings.Repeat("x", maxExtraFilterValue+1), "sdk_version: value must be at most 256 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCommonQuery(newQueryContext(tt.query))
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseCommonQuery(%q) error = %v, want %q", tt.query, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// manyProjectIDs 生成 n 个不同项目ID组成的 project_id 参数值
+func manyProjectIDs(n int) string {
+	projectIDs := make([]string, n)
+	for i := range projectIDs {
+		projectIDs[i] = "p" + strconv.Itoa(i)
+	}
+	return strings.Join(projectIDs, ",")
+}
+
+func TestParseCommonQueryBoundaries(t *testing.T) {
+	for _, limit := range []string{"1", "1000"} {
+		if _, err := parseCommonQuery(newQueryContext("project_id=web&limit=" + limit)); err != nil {
+			t.Errorf("limit=%s error = %v", limit, err)
+		}
+	}
+	if _, err := parseCommonQuery(newQueryContext("project_id=" + manyProjectIDs(maxProjectIDs))); err != nil {
+		t.Errorf("%d project_id values error = %v", maxProjectIDs, err)
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	tests := []struct {
+		tz      string
+		want    string
+		wantErr bool
+	}{
+		{"", "UTC", false},
+		{"UTC", "UTC", false},
+		{"Asia/Shanghai", "Asia/Shanghai", false},
+		{"America/New_York", "America/New_York", false},
+		{"Local", "", true},
+		{"Mars/Olympus", "", true},
+		{"+08:00", "", true},
+	}
+
+	for _, tt := range tests {
+		loc, err := parseTimeZone(newQueryContext("tz=" + url.QueryEscape(tt.tz)))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimeZone(%q) = %s, want error", tt.tz, loc)
+			}
+			continue
+		}
+		if err != nil || loc.String() != tt.want {
+			t.Errorf("parseTimeZone(%q) = %v, %v, want %s", tt.tz, loc, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrorTypes(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"type=js_error", []string{"js_error"}, false},
+		{"type=js_error&type=promise_rejection", []string{"js_error", "promise_rejection"}, false},
+		{"type=js_error,promise_rejection", []string{"js_error", "promise_rejection"}, false},
+		{"type=js_error,%20,js_error&type=", []string{"js_error"}, false},
+		{"type=" + strings.Repeat("a", maxErrorTypeLength+1), nil, true},
+		{"type=" + manyErrorTypes(maxErrorTypes), nil, false},
+		{"type=" + manyErrorTypes(maxErrorTypes+1), nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseErrorTypes(newQueryContext(tt.query))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseErrorTypes(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseErrorTypes(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		if tt.query == "" && got != nil {
+			t.Errorf("parseErrorTypes(\"\") = %v, want nil", got)
+		}
+	}
+}
+
+// manyErrorTypes 生成 n 个不重复的错误类型，以逗号分隔
+func manyErrorTypes(n int) string {
+	types := make([]string, n)
+	for i := range types {
+		types[i] = fmt.Sprintf("type_%d", i)
+	}
+	return strings.Join(types, ",")
+}
+
+func TestParseMinCount(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultSlowURLMinCount, false},
+		{"min_count=1", 1, false},
+		{"min_count=20", 20, false},
+		{"min_count=0", 0, true},
+		{"min_count=-5", 0, true},
+		{"min_count=1.5", 0, true},
+		{"min_count=many", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseMinCount(newQueryContext(tt.query), defaultSlowURLMinCount)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseMinCount(%q) = %d, %v, want %d (error %v)", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseExtraFiltersExcludeBots(t *testing.T) {
+	// exclude_bots 不计入 extra 过滤条件的数量限制
+	var full []string
+	for i := 0; i < maxExtraFilters; i++ {
+		full = append(full, fmt.Sprintf("extra.k%d=v", i))
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"exclude_bots=true", true},
+		{"exclude_bots=false", false},
+		{"exclude_bots=1", false},
+		{"", false},
+		{strings.Join(full, "&") + "&exclude_bots=true", true},
+	}
+
+	for _, tt := range tests {
+		filters, err := parseExtraFilters(newQueryContext(tt.query))
+		if err != nil {
+			t.Errorf("parseExtraFilters(%q) error = %v", tt.query, err)
+			continue
+		}
+		got := false
+		for _, filter := range filters {
+			if reflect.DeepEqual(filter, models.ExcludeBotsFilter) {
+				got = true
+			}
+		}
+		if got != tt.want {
+			t.Errorf("parseExtraFilters(%q) excludes bots = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+	"spectra-backend/middleware"
+	"spectra-backend/models"
+	"spectra-backend/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// writeList 按协商的格式返回列表，包装格式中附带数量和分页游标
+// 裸数组格式保持原有输出不变；包装格式中 nil 切片返回空数组
+// Accept 头要求 Arrow IPC 流格式且元素为平铺结构时以 Arrow 流写出，否则回退为 JSON
+// 查询跳过了无法解析的行时，通过 X-Skipped-Rows 头和包装格式的 meta.skipped 返回跳过的行数
+func writeList[T any](c *gin.Context, logger *zap.Logger, items []T) {
+	// 同一 URL 的响应随 Accept 变化，告知缓存按 Accept 区分
+	c.Header("Vary", "Accept")
+	skipped := services.SkippedRows(c.Request.Context())
+	if skipped > 0 {
+		c.Header("X-Skipped-Rows", strconv.FormatInt(skipped, 10))
+	}
+	if middleware.AcceptsArrow(c) && writeArrow(c, logger, items) {
+		return
+	}
+	if !middleware.UseEnvelope(c) {
+		c.JSON(http.StatusOK, items)
+		return
+	}
+
+	if items == nil {
+		items = []T{}
+	}
+
+	c.JSON(http.StatusOK, models.ListResponse{
+		Data: items,
+		Meta: models.ListMeta{Count: len(items), Skipped: skipped},
+	})
+}
+
+// trackSkippedRows 为列表查询的请求上下文附加跳过行计数器，须在调用服务之前执行
+func trackSkippedRows(c *gin.Context) {
+	c.Request = c.Request.WithContext(services.WithSkippedRows(c.Request.Context()))
+}
+
+// trackUserAgent 为上报请求的上下文附加请求 User-Agent，开启 ingest.user_agent 时服务据此补充设备信息，须在调用服务之前执行
+func trackUserAgent(c *gin.Context) {
+	c.Request = c.Request.WithContext(services.WithUserAgent(c.Request.Context(), c.Request.UserAgent()))
+}
+
+// setRefreshHint 在列表响应头 X-Refresh-Interval 中返回建议的轮询间隔（秒），须在写出响应之前调用
+// 计算失败只记录日志，不影响列表结果
+func (h *LogHandler) setRefreshHint(c *gin.Context, projectIDs []string) {
+	if h.opts.RefreshHints == nil {
+		return
+	}
+	interval, err := h.opts.RefreshHints.Suggest(c.Request.Context(), projectIDs)
+	if err != nil {
+		loggerFrom(c, h.logger).Warn("Failed to compute refresh hint", zap.Strings("project_id", projectIDs), zap.Error(err))
+		return
+	}
+	c.Header("X-Refresh-Interval", strconv.Itoa(int(interval/time.Second)))
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError 单个字段的校验失败信息
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// 校验错误中使用 JSON 字段名，与 SDK 上报的字段保持一致
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+// jsonFieldName 返回结构体字段的 JSON 名称，未设置 json tag 时使用字段名
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
+	return name
+}
+
+// fieldErrors 将 validator 的校验错误转换为逐字段的错误列表
+func fieldErrors(errs validator.ValidationErrors) []FieldError {
+	result := make([]FieldError, 0, len(errs))
+	for _, err := range errs {
+		result = append(result, FieldError{
+			Field:   err.Field(),
+			Rule:    err.Tag(),
+			Message: fieldErrorMessage(err),
+		})
+	}
+	return result
+}
+
+// fieldErrorMessage 生成单个校验规则的错误描述
+func fieldErrorMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", err.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", err.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", err.Param())
+	default:
+		return fmt.Sprintf("failed on %s validation", err.Tag())
+	}
+}
+
+// unknownField 返回请求体中目标结构体未定义的第一个顶层字段（按字段名排序），没有时返回空字符串
+// 嵌入结构体的字段视为顶层字段；请求体不是 JSON 对象时交由后续绑定报错
+func unknownField(body []byte, event any) string {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return ""
+	}
+
+	known := make(map[string]struct{})
+	collectJSONFields(reflect.TypeOf(event), known)
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := known[strings.ToLower(key)]; !ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// collectJSONFields 收集结构体（含嵌入结构体）的 JSON 字段名，统一转为小写
+// encoding/json 匹配字段名时不区分大小写，这里保持一致
+func collectJSONFields(t reflect.Type, known map[string]struct{}) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			collectJSONFields(field.Type, known)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name := jsonFieldName(field); name != "" {
+			known[strings.ToLower(name)] = struct{}{}
+		}
+	}
+}
@@ -1,18 +1,30 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os/signal"
 	"spectra-backend/config"
 	"spectra-backend/middleware"
 	"spectra-backend/router"
+	"syscall"
 	"time"
+	// 内置时区数据库，容器镜像中没有 tzdata 时 tz 参数仍可用
+	_ "time/tzdata"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
+// shutdownTimeout 关闭服务时等待进行中请求完成的最长时间
+const shutdownTimeout = 15 * time.Second
+
+// streamDrainTimeout 关闭服务时等待实时推送连接断开的最长时间，超时后强制关闭订阅
+const streamDrainTimeout = 5 * time.Second
+
 func main() {
 
 	// 加载配置
@@ -23,32 +35,78 @@ func main() {
 
 	// 初始化日志
 	logger := middleware.InitLogger()
-	defer logger.Sync()
+	// 退出前刷新日志，确保关闭过程中的最后几条日志写出；限制等待时间，日志输出阻塞时仍能退出
+	defer func() {
+		if err := middleware.SyncLogger(logger, time.Duration(cfg.Log.SyncTimeout)*time.Millisecond); err != nil {
+			log.Printf("Failed to flush logs: %v", err)
+		}
+	}()
 
 	r := gin.Default()
 
-	// 配置 CORS
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost:5174", "http://localhost:3000"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	// 只信任配置中的反向代理，否则访问日志和限流中的客户端 IP 可能来自伪造的 X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
+
+	// 配置 CORS，上报接口和查询/管理接口使用不同策略
+	r.Use(middleware.CORS(cfg.CORS, router.IsIngestRequest))
+
+	// 请求级日志：生成请求 ID，处理器日志和访问日志都携带 request_id
+	r.Use(middleware.RequestLogger(logger))
+	r.Use(middleware.GinLogger(logger, cfg.Log))
+
+	// 调试用的上报请求体和响应体日志，默认关闭，仅在 debug 日志级别生效
+	r.Use(middleware.BodyLogger(logger, cfg.Log.BodyLog, router.IsIngestRequest))
+
+	// 全局并发上限，突发流量时直接拒绝超出的请求，健康检查不受限制
+	r.Use(middleware.InFlightLimit(cfg.Server.MaxInFlight, router.IsHealthRequest))
 
-	r.Use(middleware.GinLogger(logger))
+	// 查询请求超时后取消 ClickHouse 查询并返回 504，限制最坏情况下的响应时间
+	r.Use(middleware.QueryTimeout(logger, time.Duration(cfg.Server.RequestTimeout)*time.Second, router.IsHealthRequest))
 
-	// 静态文件和模板
-	r.Static("/static", "./static")
-	r.LoadHTMLGlob("templates/*")
+	// 静态文件和模板，缺失时不影响 API
+	router.LoadAssets(r, logger)
 
-	router.SetupRoutes(r, cfg, logger)
+	drainStreams, shutdown := router.SetupRoutes(r, cfg, logger)
 
 	// 启动服务器
 	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	logger.Info("Starting server", zap.String("address", serverAddr))
-	if err := r.Run(serverAddr); err != nil {
-		logger.Fatal("Failed to start server", zap.Error(err))
+	server := &http.Server{
+		Addr:         serverAddr,
+		Handler:      r,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+	}
+
+	// 长连接不会随 server.Shutdown 自行结束：开始关闭时先通知订阅者发送关闭消息并断开，避免部署时客户端集中报错重连
+	server.RegisterOnShutdown(func() {
+		drainCtx, cancel := context.WithTimeout(context.Background(), streamDrainTimeout)
+		defer cancel()
+		if err := drainStreams(drainCtx); err != nil {
+			logger.Warn("Streaming subscribers did not disconnect in time", zap.Error(err))
+		}
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		logger.Info("Starting server", zap.String("address", serverAddr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", zap.Error(err))
+		}
+	}()
+
+	// 收到退出信号后停止接收新请求，等待进行中的请求和写入完成
+	<-ctx.Done()
+	logger.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Failed to shut down server gracefully", zap.Error(err))
 	}
+	shutdown()
+	logger.Info("Server stopped")
 }
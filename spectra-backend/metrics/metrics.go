@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 所有指标的统一前缀
+const namespace = "spectra"
+
+// ExtraSizeBytes 上报事件 Extra 字段的字节数分布，按事件类型区分
+var ExtraSizeBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "ingest_extra_size_bytes",
+	Help:      "Size in bytes of the extra JSON payload of ingested events.",
+	Buckets:   prometheus.ExponentialBuckets(64, 4, 8), // 64B ~ 1MB
+}, []string{"type"})
+
+// ClockSkewedEvents 客户端时间与服务端接收时间相差超过阈值的事件数
+// action 为 flagged（只记录偏差）或 corrected（已替换为服务端时间）
+var ClockSkewedEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ingest_clock_skewed_events_total",
+	Help:      "Number of ingested events whose client timestamp differs from server receive time beyond the threshold.",
+}, []string{"type", "action"})
+
+// RejectedEventTimestamps 因事件时间超出 ingest.event_age 范围被拒绝的事件数
+// reason 为 too_old（早于 max_age）或 in_future（晚于 max_future）
+var RejectedEventTimestamps = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ingest_rejected_timestamps_total",
+	Help:      "Number of ingested events rejected because their timestamp is too old or too far in the future.",
+}, []string{"type", "reason"})
+
+// RejectedProjects 因不同项目数达到 ingest.project_limit.max_projects 被拒绝的事件数
+var RejectedProjects = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ingest_rejected_projects_total",
+	Help:      "Number of ingested events rejected because the distinct project limit was reached.",
+})
+
+// TruncatedURLs 因超过 ingest.max_url_length 被截断的 URL 数，field 为 url 或 referrer
+var TruncatedURLs = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "ingest_truncated_urls_total",
+	Help:      "Number of ingested URL and referrer values truncated to the configured maximum length.",
+}, []string{"type", "field"})
+
+// InsertWorkers 批量写入工作池的大小
+var InsertWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "insert_workers",
+	Help:      "Number of workers in the batch insert pool.",
+})
+
+// InsertWorkersBusy 正在执行写入的工作协程数，与 InsertWorkers 之比即为利用率
+var InsertWorkersBusy = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "insert_workers_busy",
+	Help:      "Number of batch insert workers currently executing an insert.",
+})
+
+// InsertQueueWaitSeconds 写入任务从提交到被工作协程取走的等待时间
+var InsertQueueWaitSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "insert_queue_wait_seconds",
+	Help:      "Time batch insert jobs wait for a free worker.",
+	Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8), // 1ms ~ 16s
+})
+
+// InsertBatcherFlushes 单条上报批量合并的刷新次数，reason 为 interval、size 或 shutdown
+var InsertBatcherFlushes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "insert_batcher_flushes_total",
+	Help:      "Number of merged batches flushed by the single-event insert batcher.",
+}, []string{"reason"})
+
+// InsertBatcherEvents 经批量合并写入的单条上报事件数
+var InsertBatcherEvents = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "insert_batcher_events_total",
+	Help:      "Number of single-event writes merged into batched inserts.",
+})
+
+// InsertBatcherInsertsAvoided 批量合并节省的插入次数，每次插入至少产生一个 part，即少创建的 part 数下限
+var InsertBatcherInsertsAvoided = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "insert_batcher_inserts_avoided_total",
+	Help:      "Number of INSERT statements (and thus new parts) avoided by merging single-event writes.",
+})
+
+// AnalyticsQueriesInFlight 正在执行的聚合查询请求数
+var AnalyticsQueriesInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "analytics_queries_in_flight",
+	Help:      "Number of analytics query requests currently executing.",
+})
+
+// AnalyticsQueriesRejected 因并发已满且排队超时被拒绝的聚合查询请求数
+var AnalyticsQueriesRejected = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "analytics_queries_rejected_total",
+	Help:      "Number of analytics query requests rejected because the concurrency limit was reached.",
+})
+
+// HTTPRequestsInFlight 计入全局并发上限的正在处理的请求数，健康检查和指标接口不计入
+var HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "http_requests_in_flight",
+	Help:      "Number of HTTP requests currently being served, excluding health checks.",
+})
+
+// HTTPRequestsShed 因超出全局并发上限被拒绝的请求数
+var HTTPRequestsShed = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "http_requests_shed_total",
+	Help:      "Number of HTTP requests rejected because the global in-flight limit was reached.",
+})
+
+// HTTPRequestTimeouts 超过 server.request_timeout 或 server.group_timeouts 被取消并返回 504 的请求数
+var HTTPRequestTimeouts = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "http_request_timeouts_total",
+	Help:      "Number of requests cancelled and answered with 504 because they exceeded the request timeout.",
+})
+
+// QueryRowsSkipped 列表查询中因无法解析而被跳过的行数，按表区分
+var QueryRowsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "query_rows_skipped_total",
+	Help:      "Number of rows skipped by list queries because they could not be scanned.",
+}, []string{"table"})
+
+// Handler 返回 Prometheus 指标抓取处理器
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Goroutines 当前 goroutine 数，持续增长通常意味着流式连接或后台任务泄漏
+var Goroutines = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "goroutines",
+	Help:      "Number of goroutines currently running.",
+})
+
+// DBConnections 数据库连接池中的连接数，按状态区分：open / in_use / idle
+var DBConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "db_connections",
+	Help:      "Number of database connections by state.",
+}, []string{"state"})
+
+// DBWaitCount 因连接池耗尽而等待连接的累计次数
+var DBWaitCount = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "db_wait_count",
+	Help:      "Cumulative number of times a query waited for a free database connection.",
+})
+
+// DBWaitSeconds 等待连接的累计时间
+var DBWaitSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "db_wait_seconds",
+	Help:      "Cumulative time spent waiting for a free database connection.",
+})
+
+// StreamSubscribers 事件总线上的活跃订阅者数，实时推送连接断开后未取消订阅会导致持续增长
+var StreamSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "stream_subscribers",
+	Help:      "Number of active event bus subscribers.",
+})
+
+// RuntimeSources 运行时指标的数据来源，为 nil 的来源不采集
+type RuntimeSources struct {
+	DBStats     func() sql.DBStats
+	Subscribers func() int
+}
+
+// RunRuntimeGauges 立即采集一次运行时指标，之后每隔 interval 采集，阻塞直到 ctx 取消
+func RunRuntimeGauges(ctx context.Context, interval time.Duration, sources RuntimeSources) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		collectRuntimeGauges(sources)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectRuntimeGauges 采集一次运行时指标
+func collectRuntimeGauges(sources RuntimeSources) {
+	Goroutines.Set(float64(runtime.NumGoroutine()))
+
+	if sources.DBStats != nil {
+		stats := sources.DBStats()
+		DBConnections.WithLabelValues("open").Set(float64(stats.OpenConnections))
+		DBConnections.WithLabelValues("in_use").Set(float64(stats.InUse))
+		DBConnections.WithLabelValues("idle").Set(float64(stats.Idle))
+		DBWaitCount.Set(float64(stats.WaitCount))
+		DBWaitSeconds.Set(stats.WaitDuration.Seconds())
+	}
+
+	if sources.Subscribers != nil {
+		StreamSubscribers.Set(float64(sources.Subscribers()))
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBodyBytes 请求体大小限制中间件，请求体超过 limit 字节时返回 413，limit 小于等于0时不限制
+// Content-Length 已超限的请求直接拒绝；其余请求的请求体由 http.MaxBytesReader 包装，
+// 后续中间件和处理器读取超限时得到 *http.MaxBytesError，见 BodyTooLarge
+func MaxBodyBytes(limit int64) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			abortBodyTooLarge(c, limit)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// BodyTooLarge 判断读取请求体的错误是否因超出 MaxBodyBytes 的限制，返回该限制
+func BodyTooLarge(err error) (int64, bool) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return tooLarge.Limit, true
+	}
+	return 0, false
+}
+
+// abortBodyTooLarge 返回 413，max_bytes 给出请求体的字节数上限
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large", "max_bytes": limit})
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"spectra-backend/config"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// BodyLogger 调试用的请求体和响应体日志中间件，用于排查 SDK 接入时事件未入库的问题
+// 只对 isIngest 返回 true 的上报请求生效；未开启或日志级别高于 debug 时返回空操作中间件，不读取也不缓存请求体
+// 请求体在处理器读取时旁路复制，响应体在写出时旁路复制，均只保留前 cfg.MaxBytes 字节，
+// cfg.RedactFields 中的 JSON 字段值替换为占位值后再记录
+func BodyLogger(logger *zap.Logger, cfg config.BodyLogConfig, isIngest func(path, method string) bool) gin.HandlerFunc {
+	if !cfg.Enabled || !logger.Core().Enabled(zapcore.DebugLevel) {
+		return func(c *gin.Context) { c.Next() }
+	}
+	logger.Warn("Request and response body logging is enabled, do not use in production",
+		zap.Int("max_bytes", cfg.MaxBytes))
+	redact := newBodyRedactor(cfg.RedactFields)
+
+	return func(c *gin.Context) {
+		if !isIngest(c.Request.URL.Path, c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		request := &cappedBuffer{limit: cfg.MaxBytes}
+		if c.Request.Body != nil {
+			c.Request.Body = teeReadCloser{Reader: io.TeeReader(c.Request.Body, request), Closer: c.Request.Body}
+		}
+		response := &cappedBuffer{limit: cfg.MaxBytes}
+		c.Writer = &teeWriter{ResponseWriter: c.Writer, copy: response}
+
+		c.Next()
+
+		LoggerFrom(c, logger).Debug("HTTP body",
+			zap.Int("status", c.Writer.Status()),
+			zap.String("request_body", redact(request.String())),
+			zap.Bool("request_truncated", request.truncated),
+			zap.String("response_body", redact(response.String())),
+			zap.Bool("response_truncated", response.truncated))
+	}
+}
+
+// cappedBuffer 只保留前 limit 字节的缓冲区，超出部分丢弃并标记截断
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 始终返回完整长度，避免截断影响被复制的读写流程
+func (b *cappedBuffer) Write(data []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if len(data) > remaining {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(data[:remaining])
+		}
+		return len(data), nil
+	}
+	b.buf.Write(data)
+	return len(data), nil
+}
+
+// String 返回缓存的内容，非 UTF-8 文本（如压缩后的请求体）只记录长度
+func (b *cappedBuffer) String() string {
+	data := b.buf.Bytes()
+	if b.truncated {
+		// 截断可能切断多字节字符，去掉末尾不完整的部分
+		for len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "[binary body]"
+	}
+	return string(data)
+}
+
+// teeReadCloser 读取请求体时同时写入缓冲区，关闭时关闭原始请求体
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// teeWriter 写出响应体时同时写入缓冲区
+type teeWriter struct {
+	gin.ResponseWriter
+	copy io.Writer
+}
+
+func (w *teeWriter) Write(data []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(data)
+	w.copy.Write(data[:n])
+	return n, err
+}
+
+func (w *teeWriter) WriteString(s string) (int, error) {
+	n, err := w.ResponseWriter.WriteString(s)
+	io.WriteString(w.copy, s[:n])
+	return n, err
+}
+
+// newBodyRedactor 按字段名脱敏 JSON 文本中的字符串值，字段名不区分大小写
+// 基于正则而不是解析 JSON，截断后不完整的内容同样能脱敏
+func newBodyRedactor(fields []string) func(string) string {
+	if len(fields) == 0 {
+		return func(body string) string { return body }
+	}
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	pattern := regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"?`)
+	return func(body string) string {
+		return pattern.ReplaceAllString(body, `${1}"`+redactedHeaderValue+`"`)
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"bytes"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bufferedWriter 缓存响应体，待处理器执行完毕后由中间件处理并写出
+// 状态码和响应头仍写入原始 ResponseWriter，在首次写出响应体前不会发送
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
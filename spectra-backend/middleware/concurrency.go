@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"net/http"
+	"spectra-backend/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsLimit 聚合查询并发限制中间件，同时最多执行 maxConcurrent 个请求
+// 超出上限的请求最多排队等待 queueTimeout，仍未获得执行名额时返回 503；maxConcurrent 小于 1 时不限制
+func AnalyticsLimit(maxConcurrent int, queueTimeout time.Duration) gin.HandlerFunc {
+	if maxConcurrent < 1 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, maxConcurrent)
+	return func(c *gin.Context) {
+		if !acquireSlot(c, slots, queueTimeout) {
+			metrics.AnalyticsQueriesRejected.Inc()
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Too many concurrent analytics queries"})
+			return
+		}
+		metrics.AnalyticsQueriesInFlight.Inc()
+		defer func() {
+			metrics.AnalyticsQueriesInFlight.Dec()
+			<-slots
+		}()
+
+		c.Next()
+	}
+}
+
+// InFlightLimit 全局并发限制中间件，同时最多处理 maxInFlight 个请求，超出时不排队直接返回 503
+// exempt 返回 true 的请求（如健康检查）不计入也不受限制；maxInFlight 小于 1 时不限制
+func InFlightLimit(maxInFlight int, exempt func(path string, method string) bool) gin.HandlerFunc {
+	if maxInFlight < 1 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, maxInFlight)
+	return func(c *gin.Context) {
+		if exempt != nil && exempt(c.Request.URL.Path, c.Request.Method) {
+			c.Next()
+			return
+		}
+		if !acquireSlot(c, slots, 0) {
+			metrics.HTTPRequestsShed.Inc()
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is overloaded, retry later"})
+			return
+		}
+		metrics.HTTPRequestsInFlight.Inc()
+		defer func() {
+			metrics.HTTPRequestsInFlight.Dec()
+			<-slots
+		}()
+
+		c.Next()
+	}
+}
+
+// acquireSlot 获取执行名额，最多等待 timeout；请求被取消时放弃等待
+func acquireSlot(c *gin.Context, slots chan struct{}, timeout time.Duration) bool {
+	select {
+	case slots <- struct{}{}:
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"spectra-backend/config"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS 跨域中间件，按请求选择上报策略或查询/管理策略
+// 预检请求没有匹配的路由，因此必须挂在引擎上全局生效，由 isIngest 根据路径和方法判断请求所属的路由组
+// 预检请求按 Access-Control-Request-Method 中声明的方法判断
+func CORS(cfg config.CORSConfig, isIngest func(path string, method string) bool) gin.HandlerFunc {
+	api := cors.New(corsConfig(cfg.API, []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}))
+	ingest := cors.New(corsConfig(cfg.Ingest, []string{"POST", "OPTIONS"}))
+
+	return func(c *gin.Context) {
+		method := c.Request.Method
+		if method == http.MethodOptions {
+			if requested := c.GetHeader("Access-Control-Request-Method"); requested != "" {
+				method = requested
+			}
+		}
+
+		if isIngest(c.Request.URL.Path, method) {
+			ingest(c)
+		} else {
+			api(c)
+		}
+	}
+}
+
+// corsConfig 将配置转换为 cors 中间件配置
+func corsConfig(policy config.CORSPolicy, methods []string) cors.Config {
+	corsCfg := cors.Config{
+		AllowMethods:     methods,
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", TraceIDHeader, TraceparentHeader},
+		ExposeHeaders:    []string{"Content-Length", RequestIDHeader},
+		AllowCredentials: policy.AllowCredentials,
+		MaxAge:           time.Duration(policy.MaxAge) * time.Second,
+	}
+
+	for _, origin := range policy.AllowOrigins {
+		if origin == "*" {
+			corsCfg.AllowAllOrigins = true
+			return corsCfg
+		}
+	}
+	corsCfg.AllowOrigins = policy.AllowOrigins
+	return corsCfg
+}
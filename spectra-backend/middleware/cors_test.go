@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"spectra-backend/config"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testCORSConfig 与默认配置相同：查询接口只允许看板来源并携带凭证，上报接口允许任意来源且不携带凭证
+var testCORSConfig = config.CORSConfig{
+	API:    config.CORSPolicy{AllowOrigins: []string{"http://dashboard.test"}, AllowCredentials: true, MaxAge: 3600},
+	Ingest: config.CORSPolicy{AllowOrigins: []string{"*"}, MaxAge: 86400},
+}
+
+// newCORSRouter 创建挂载 CORS 中间件的路由，POST /api/error-logs 视为上报请求
+func newCORSRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(CORS(testCORSConfig, func(path string, method string) bool {
+		return path == "/api/error-logs" && method == http.MethodPost
+	}))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.POST("/api/error-logs", ok)
+	router.GET("/api/error-logs", ok)
+	return router
+}
+
+func TestCORSPreflight(t *testing.T) {
+	tests := []struct {
+		name            string
+		origin          string
+		method          string
+		wantStatus      int
+		wantOrigin      string
+		wantCredentials string
+		wantMaxAge      string
+	}{
+		{"ingest from any origin", "https://customer.example", http.MethodPost, http.StatusNoContent, "*", "", "86400"},
+		{"api from dashboard", "http://dashboard.test", http.MethodGet, http.StatusNoContent, "http://dashboard.test", "true", "3600"},
+		{"api from other origin", "https://customer.example", http.MethodGet, http.StatusForbidden, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/error-logs", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			w := httptest.NewRecorder()
+			newCORSRouter().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			headers := map[string]string{
+				"Access-Control-Allow-Origin":      tt.wantOrigin,
+				"Access-Control-Allow-Credentials": tt.wantCredentials,
+				"Access-Control-Max-Age":           tt.wantMaxAge,
+			}
+			for name, want := range headers {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	tests := []struct {
+		method          string
+		wantOrigin      string
+		wantCredentials string
+	}{
+		{http.MethodPost, "*", ""},
+		{http.MethodGet, "http://dashboard.test", "true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/error-logs", nil)
+			req.Header.Set("Origin", "http://dashboard.test")
+			w := httptest.NewRecorder()
+			newCORSRouter().ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag 条件请求中间件
+// 对 200 响应按响应体计算 ETag，请求头 If-None-Match 命中时返回 304 且不返回响应体
+// 响应会被完整缓存后才写出，不要用于流式接口；请求 Arrow 流格式时不计算 ETag
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) || AcceptsArrow(c) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if original.Status() != http.StatusOK {
+			original.Write(writer.body.Bytes())
+			return
+		}
+
+		sum := sha256.Sum256(writer.body.Bytes())
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		original.Header().Set("ETag", etag)
+
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			original.Header().Del("Content-Type")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.Write(writer.body.Bytes())
+	}
+}
+
+// etagMatches 判断 If-None-Match 请求头是否包含指定 ETag，按弱比较处理 W/ 前缀
+func etagMatches(header string, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
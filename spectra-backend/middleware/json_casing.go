@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSON 响应键名风格
+const (
+	CasingSnake = "snake"
+	CasingCamel = "camel"
+)
+
+// opaqueJSONKeys 值为用户数据或以数据为键的字段，其内部键名保持原样
+// 例如 extra 是 SDK 上报的原始 JSON，counts 的键是事件类型
+var opaqueJSONKeys = map[string]struct{}{
+	"extra":   {},
+	"aliases": {},
+	"counts":  {},
+	"headers": {},
+}
+
+// JSONCasing JSON 响应键名风格中间件，默认使用 snake_case
+// Accept 头中任一媒体类型带有 casing=camel 或 casing=snake 参数时按参数选择，
+// 例如 Accept: application/json; casing=camel，否则使用 defaultCasing
+// camelCase 响应会被完整缓存后转换再写出，不要用于流式接口
+func JSONCasing(defaultCasing string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept")
+		// Arrow 流格式不是 JSON，跳过缓存直接流式写出
+		if requestedCasing(c.GetHeader("Accept"), defaultCasing) != CasingCamel || AcceptsArrow(c) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		body := writer.body.Bytes()
+		if strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") {
+			if converted, err := camelizeJSON(body); err == nil {
+				body = converted
+			}
+		}
+		original.Write(body)
+	}
+}
+
+// requestedCasing 从 Accept 头的 casing 参数中解析键名风格
+func requestedCasing(accept string, defaultCasing string) string {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		switch params["casing"] {
+		case CasingCamel:
+			return CasingCamel
+		case CasingSnake:
+			return CasingSnake
+		}
+	}
+	return defaultCasing
+}
+
+// camelizeJSON 将 JSON 中对象的键名由 snake_case 转换为 camelCase，保持键的顺序
+// opaqueJSONKeys 中字段的值原样保留
+func camelizeJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var out bytes.Buffer
+	out.Grow(len(data))
+	if err := copyJSONValue(decoder, &out, true); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// copyJSONValue 从 decoder 读取一个完整的值写入 out，transform 为 true 时转换对象键名
+func copyJSONValue(decoder *json.Decoder, out *bytes.Buffer, transform bool) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		switch value {
+		case '{':
+			out.WriteByte('{')
+			for first := true; decoder.More(); first = false {
+				keyToken, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				key := keyToken.(string)
+				_, opaque := opaqueJSONKeys[key]
+				if transform {
+					key = snakeToCamel(key)
+				}
+				if !first {
+					out.WriteByte(',')
+				}
+				writeJSONString(out, key)
+				out.WriteByte(':')
+				if err := copyJSONValue(decoder, out, transform && !opaque); err != nil {
+					return err
+				}
+			}
+			out.WriteByte('}')
+		case '[':
+			out.WriteByte('[')
+			for first := true; decoder.More(); first = false {
+				if !first {
+					out.WriteByte(',')
+				}
+				if err := copyJSONValue(decoder, out, transform); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		}
+		// 读取对应的结束符
+		_, err := decoder.Token()
+		return err
+	case string:
+		writeJSONString(out, value)
+	case json.Number:
+		out.WriteString(value.String())
+	case bool:
+		if value {
+			out.WriteString("true")
+		} else {
+			out.WriteString("false")
+		}
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+// writeJSONString 写出 JSON 字符串，转义规则与 encoding/json 一致
+func writeJSONString(out *bytes.Buffer, s string) {
+	encoded, _ := json.Marshal(s)
+	out.Write(encoded)
+}
+
+// snakeToCamel 将 snake_case 转换为 camelCase，例如 project_id -> projectId
+// 不含下划线或以下划线开头的键保持不变
+func snakeToCamel(key string) string {
+	if !strings.Contains(key, "_") || strings.HasPrefix(key, "_") {
+		return key
+	}
+
+	parts := strings.Split(key, "_")
+	var b strings.Builder
+	b.Grow(len(key))
+	b.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]))
+		b.WriteString(part[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"spectra-backend/models"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSnakeToCamel(t *testing.T) {
+	tests := map[string]string{
+		"project_id":       "projectId",
+		"p95_latency_ms":   "p95LatencyMs",
+		"next_cursor":      "nextCursor",
+		"name":             "name",
+		"alreadyCamel":     "alreadyCamel",
+		"_internal":        "_internal",
+		"double__score":    "doubleScore",
+		"trailing_":        "trailing",
+		"low_confidence_x": "lowConfidenceX",
+	}
+	for key, want := range tests {
+		if got := snakeToCamel(key); got != want {
+			t.Errorf("snakeToCamel(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestCamelizeJSONKeepsOpaqueValues(t *testing.T) {
+	input := `{"data":[{"project_id":"web","extra":{"user_plan":"pro"}}],"meta":{"next_cursor":null,"skipped":0},` +
+		`"counts":{"error_log":1},"value":1.50,"ok":true,"label":"snake_case value"}`
+	want := `{"data":[{"projectId":"web","extra":{"user_plan":"pro"}}],"meta":{"nextCursor":null,"skipped":0},` +
+		`"counts":{"error_log":1},"value":1.50,"ok":true,"label":"snake_case value"}`
+
+	got, err := camelizeJSON([]byte(input))
+	if err != nil {
+		t.Fatalf("camelizeJSON() error = %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("camelizeJSON() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRequestedCasing(t *testing.T) {
+	tests := []struct {
+		accept        string
+		defaultCasing string
+		want          string
+	}{
+		{"", CasingSnake, CasingSnake},
+		{"", CasingCamel, CasingCamel},
+		{"application/json; casing=camel", CasingSnake, CasingCamel},
+		{"application/json;casing=snake", CasingCamel, CasingSnake},
+		{"text/html, application/vnd.spectra.v2+json; casing=camel", CasingSnake, CasingCamel},
+		{"application/json; casing=kebab", CasingSnake, CasingSnake},
+		{"not a media type;;", CasingSnake, CasingSnake},
+	}
+	for _, tt := range tests {
+		if got := requestedCasing(tt.accept, tt.defaultCasing); got != tt.want {
+			t.Errorf("requestedCasing(%q, %s) = %s, want %s", tt.accept, tt.defaultCasing, got, tt.want)
+		}
+	}
+}
+
+func TestJSONCasingOnModel(t *testing.T) {
+	action := models.UserAction{
+		BaseLog: models.BaseLog{
+			Timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			ProjectID:  "web",
+			SessionID:  "s1",
+			SDKVersion: "1.2.0",
+			Extra:      json.RawMessage(`{"request_id":"r1"}`),
+		},
+		Method: "GET",
+		Status: 200,
+	}
+
+	tests := []struct {
+		name          string
+		defaultCasing string
+		accept        string
+		wantKeys      []string
+		absentKeys    []string
+	}{
+		{"snake by default", CasingSnake, "", []string{"project_id", "session_id", "sdk_version"}, []string{"projectId"}},
+		{"camel via Accept", CasingSnake, "application/json; casing=camel", []string{"projectId", "sessionId", "sdkVersion"}, []string{"project_id"}},
+		{"camel via config", CasingCamel, "", []string{"projectId", "sessionId", "sdkVersion"}, []string{"project_id"}},
+		{"snake via Accept overrides config", CasingCamel, "application/json; casing=snake", []string{"project_id"}, []string{"projectId"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(JSONCasing(tt.defaultCasing))
+			router.GET("/action", func(c *gin.Context) { c.JSON(http.StatusOK, action) })
+
+			req := httptest.NewRequest(http.MethodGet, "/action", nil)
+			req.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response is not JSON: %s", w.Body)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := body[key]; !ok {
+					t.Errorf("response missing key %q: %s", key, w.Body)
+				}
+			}
+			for _, key := range tt.absentKeys {
+				if _, ok := body[key]; ok {
+					t.Errorf("response has unexpected key %q: %s", key, w.Body)
+				}
+			}
+			// extra 是用户上报的原始数据，键名不转换
+			if extra, _ := body["extra"].(map[string]any); extra["request_id"] != "r1" {
+				t.Errorf("extra keys were transformed: %v", body["extra"])
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("Vary = %q, want Accept", got)
+			}
+		})
+	}
+}
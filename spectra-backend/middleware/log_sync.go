@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SyncLogger 刷新日志缓冲区，最多等待 timeout，超时后返回错误，不再等待输出完成
+// 日志输出阻塞（如标准输出的管道无人读取）时避免进程无法退出；timeout 不大于 0 时一直等待
+// 标准输出和标准错误不支持 fsync 时返回的错误不代表日志丢失，会被忽略
+func SyncLogger(logger *zap.Logger, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- ignoreStdSyncErrors(logger.Sync())
+	}()
+	if timeout <= 0 {
+		return <-done
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("log sync did not finish within %s", timeout)
+	}
+}
+
+// ignoreStdSyncErrors 去掉 Sync 对 /dev/stdout、/dev/stderr 返回的 EINVAL / ENOTTY 错误
+// 输出为终端或管道时 fsync 在 Linux 上返回 invalid argument，在 macOS 上返回 inappropriate ioctl for device
+// zap 合并多个输出的错误，逐个判断，仍有其他错误时返回这些错误
+func ignoreStdSyncErrors(err error) error {
+	if err == nil {
+		return nil
+	}
+	errs := []error{err}
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = multi.Unwrap()
+	}
+
+	var remaining []error
+	for _, err := range errs {
+		if !isStdSyncError(err) {
+			remaining = append(remaining, err)
+		}
+	}
+	return errors.Join(remaining...)
+}
+
+// isStdSyncError 判断是否为标准输出或标准错误不支持 fsync 的错误
+func isStdSyncError(err error) bool {
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) || (pathErr.Path != "/dev/stdout" && pathErr.Path != "/dev/stderr") {
+		return false
+	}
+	return errors.Is(pathErr.Err, syscall.EINVAL) || errors.Is(pathErr.Err, syscall.ENOTTY)
+}
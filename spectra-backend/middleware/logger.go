@@ -1,8 +1,10 @@
 package middleware
 
 import (
+    "net/http"
     "os"
     "spectra-backend/config"
+    "strings"
     "time"
 
     "github.com/gin-gonic/gin"
@@ -18,11 +20,12 @@ func InitLogger() *zap.Logger {
 		// 如果加载配置失败，使用默认值
 		cfg = &config.Config{
 			Log: config.LogConfig{
-				Level:    "info",
-				Path:     "./logs/app.log",
-				MaxSize:  500,
-				MaxAge:   30,
-				Compress: true,
+				Level:      "info",
+				Path:       "./logs/app.log",
+				MaxSize:    500,
+				MaxAge:     30,
+				MaxBackups: 10,
+				Compress:   true,
 			},
 		}
 	}
@@ -42,28 +45,59 @@ func InitLogger() *zap.Logger {
 		level = zap.InfoLevel
 	}
 
-    // 文件输出（JSON编码）
-    fileWS := getLogWriter(cfg.Log.Path, cfg.Log.MaxSize, cfg.Log.MaxAge, 10)
-    fileEncoder := getJSONEncoder()
-    fileCore := zapcore.NewCore(fileEncoder, fileWS, level)
-
-    // 控制台输出（开发环境启用，Console编码更易读）
-    var core zapcore.Core
-    if cfg.App.Environment == "development" {
-        consoleWS := zapcore.AddSync(os.Stdout)
-        consoleEncoder := getConsoleEncoder()
-        consoleCore := zapcore.NewCore(consoleEncoder, consoleWS, level)
-        core = zapcore.NewTee(fileCore, consoleCore)
-    } else {
-        core = fileCore
-    }
+	// 确定输出目标：未配置时开发环境同时输出到文件和控制台，其他环境只写文件
+	output := cfg.Log.Output
+	if output == "" {
+		if cfg.App.Environment == "development" {
+			output = "both"
+		} else {
+			output = "file"
+		}
+	}
+
+	var cores []zapcore.Core
+
+	// 文件输出（JSON编码）
+	if output == "file" || output == "both" {
+		fileWS := getLogWriter(cfg.Log)
+		fileEncoder := getJSONEncoder()
+		cores = append(cores, zapcore.NewCore(fileEncoder, fileWS, level))
+	}
+
+	// 标准输出：开发环境使用Console编码更易读，其他环境使用JSON编码便于容器日志采集
+	if output == "stdout" || output == "both" {
+		consoleWS := zapcore.AddSync(os.Stdout)
+		stdoutEncoder := getJSONEncoder()
+		if cfg.App.Environment == "development" {
+			stdoutEncoder = getConsoleEncoder()
+		}
+		cores = append(cores, zapcore.NewCore(stdoutEncoder, consoleWS, level))
+	}
+
+	// 无法识别的输出配置退回到文件输出
+	if len(cores) == 0 {
+		fileWS := getLogWriter(cfg.Log)
+		cores = append(cores, zapcore.NewCore(getJSONEncoder(), fileWS, level))
+	}
+	core := zapcore.NewTee(cores...)
 
     logger := zap.New(core, zap.AddCaller())
 
 	return logger
 }
 
-func GinLogger(logger *zap.Logger) gin.HandlerFunc {
+// redactedHeaderValue 被脱敏的请求头在访问日志中的占位值
+const redactedHeaderValue = "[REDACTED]"
+
+// GinLogger 访问日志中间件，挂载在 RequestLogger 之后时使用请求级日志记录器
+// 请求携带 X-Trace-Id 或 traceparent 时记录 trace_id 字段，便于与事件表中的 trace_id 关联
+// cfg.AccessLogHeaders 开启时记录请求头，cfg.RedactHeaders 中的请求头只记录占位值
+func GinLogger(logger *zap.Logger, cfg config.LogConfig) gin.HandlerFunc {
+	redact := make(map[string]struct{}, len(cfg.RedactHeaders))
+	for _, name := range cfg.RedactHeaders {
+		redact[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next()
@@ -71,25 +105,59 @@ func GinLogger(logger *zap.Logger) gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		logger.Info("HTTP Request",
+		requestLogger := LoggerFrom(c, nil)
+		if requestLogger == nil {
+			// 请求级日志记录器已包含 method 和 path
+			requestLogger = logger.With(
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+			)
+		}
+
+		fields := []zap.Field{
 			zap.Int("status", status),
-			zap.String("method", c.Request.Method),
-			zap.String("path", c.Request.URL.Path),
 			zap.String("ip", c.ClientIP()),
 			zap.Duration("latency", latency),
-		)
+		}
+		if traceID := requestTraceID(c); traceID != "" {
+			fields = append(fields, zap.String("trace_id", traceID))
+		}
+		if cfg.AccessLogHeaders {
+			fields = append(fields, zap.Any("headers", redactHeaders(c.Request.Header, redact)))
+		}
+		requestLogger.Info("HTTP Request", fields...)
 	}
 }
 
-func getLogWriter(filePath string, maxSize, maxAge, maxBackups int) zapcore.WriteSyncer {
-    lumberJackLogger := &lumberjack.Logger{
-        Filename:   filePath,
-        MaxSize:    maxSize,
-        MaxAge:     maxAge,
-        MaxBackups: maxBackups,
-        Compress:   true,
+// redactHeaders 复制请求头用于记录日志，redact 中的请求头替换为占位值
+// redact 的键必须是规范化的请求头名称
+func redactHeaders(header http.Header, redact map[string]struct{}) map[string]string {
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		if _, ok := redact[http.CanonicalHeaderKey(name)]; ok {
+			result[name] = redactedHeaderValue
+			continue
+		}
+		result[name] = strings.Join(values, ", ")
+	}
+	return result
+}
+
+// getLogWriter 按日志配置创建带滚动切割的文件输出
+func getLogWriter(cfg config.LogConfig) zapcore.WriteSyncer {
+    return zapcore.AddSync(newRotatingLogger(cfg))
+}
+
+// newRotatingLogger 将日志配置转换为 lumberjack 滚动配置
+// MaxBackups 为 0 时保留全部旧文件，仅受 MaxAge 限制
+func newRotatingLogger(cfg config.LogConfig) *lumberjack.Logger {
+    return &lumberjack.Logger{
+        Filename:   cfg.Path,
+        MaxSize:    cfg.MaxSize,
+        MaxAge:     cfg.MaxAge,
+        MaxBackups: cfg.MaxBackups,
+        Compress:   cfg.Compress,
     }
-    return zapcore.AddSync(lumberJackLogger)
 }
 
 // 文件 JSON 编码器
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"spectra-backend/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newBufferLogger 创建输出 JSON 到内存的日志记录器，用于检查日志内容
+func newBufferLogger() (*zap.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), zapcore.DebugLevel)
+	return zap.New(core), &buf
+}
+
+// serveLogged 经过 RequestLogger 和 GinLogger 处理一个携带指定请求头的请求，返回日志输出
+func serveLogged(cfg config.LogConfig, headers map[string]string) string {
+	logger, buf := newBufferLogger()
+	router := gin.New()
+	router.Use(RequestLogger(logger), GinLogger(logger, cfg))
+	router.GET("/api/error-logs", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/error-logs", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return buf.String()
+}
+
+func TestGinLoggerRedactsHeaders(t *testing.T) {
+	cfg := config.LogConfig{
+		AccessLogHeaders: true,
+		// 配置中的名称不区分大小写
+		RedactHeaders: []string{"authorization", "COOKIE", "X-Api-Key"},
+	}
+	output := serveLogged(cfg, map[string]string{
+		"Authorization": "Bearer secret-token",
+		"Cookie":        "session=secret-session",
+		"X-API-Key":     "secret-key",
+		"User-Agent":    "test-agent",
+	})
+
+	if strings.Contains(output, "secret") {
+		t.Errorf("redacted header value leaked into log output: %s", output)
+	}
+	if got := strings.Count(output, redactedHeaderValue); got != 3 {
+		t.Errorf("found %d redacted placeholders, want 3: %s", got, output)
+	}
+	if !strings.Contains(output, "test-agent") {
+		t.Errorf("non-redacted header missing from log output: %s", output)
+	}
+}
+
+func TestGinLoggerOmitsHeadersByDefault(t *testing.T) {
+	output := serveLogged(config.LogConfig{}, map[string]string{"Authorization": "Bearer secret-token"})
+
+	if strings.Contains(output, "secret") || strings.Contains(output, `"headers"`) {
+		t.Errorf("headers logged although access_log_headers is off: %s", output)
+	}
+	if !strings.Contains(output, `"msg":"HTTP Request"`) {
+		t.Errorf("access log entry missing: %s", output)
+	}
+}
+
+func TestNewRotatingLogger(t *testing.T) {
+	tests := []config.LogConfig{
+		{Path: "./logs/app.log", MaxSize: 100, MaxAge: 7, MaxBackups: 3, Compress: false},
+		{Path: "/var/log/spectra.log", MaxSize: 500, MaxAge: 30, MaxBackups: 0, Compress: true},
+	}
+
+	for _, cfg := range tests {
+		writer := newRotatingLogger(cfg)
+		if writer.Filename != cfg.Path || writer.MaxSize != cfg.MaxSize || writer.MaxAge != cfg.MaxAge ||
+			writer.MaxBackups != cfg.MaxBackups || writer.Compress != cfg.Compress {
+			t.Errorf("newRotatingLogger(%+v) = {Filename:%s MaxSize:%d MaxAge:%d MaxBackups:%d Compress:%v}",
+				cfg, writer.Filename, writer.MaxSize, writer.MaxAge, writer.MaxBackups, writer.Compress)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectAllowlist 上报项目白名单中间件
+// 读取请求体中的 project_id，不在白名单中的请求返回 403；白名单为空时不做限制
+// 请求体读取后会被还原，后续处理器仍可正常绑定；请求体须先由 MaxBodyBytes 限制大小，超限时返回 413
+// canonical 用于规范化白名单和请求中的项目标识符（如转为小写），为 nil 时按原值比较
+func ProjectAllowlist(allowed []string, canonical func(string) string) gin.HandlerFunc {
+	return ProjectAllowlistFunc(allowed, canonical, eventProjectID)
+}
+
+// ProjectAllowlistFunc 与 ProjectAllowlist 相同，但由 extract 从请求体中提取项目标识符
+// 用于请求体不是单个事件的接口（如压缩上报），请求中的所有项目都必须在白名单中
+// extract 返回错误时交给处理器返回绑定错误
+func ProjectAllowlistFunc(allowed []string, canonical func(string) string, extract func(body []byte) ([]string, error)) gin.HandlerFunc {
+	isAllowed := ProjectAllowed(allowed, canonical)
+	if isAllowed == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if limit, ok := BodyTooLarge(err); ok {
+			abortBodyTooLarge(c, limit)
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// 解析失败交给处理器返回绑定错误
+		projectIDs, err := extract(body)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		for _, projectID := range projectIDs {
+			if !isAllowed(projectID) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "project_id is not allowed"})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// ProjectAllowed 返回判断项目是否在白名单中的函数，白名单为空时返回 nil 表示不做限制
+// 用于无法在中间件中读取完整请求体的接口（如流式上报），由处理器逐个事件校验
+func ProjectAllowed(allowed []string, canonical func(string) string) func(projectID string) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	if canonical == nil {
+		canonical = func(projectID string) string { return projectID }
+	}
+
+	allowedSet := make(map[string]struct{}, len(allowed))
+	for _, projectID := range allowed {
+		allowedSet[canonical(projectID)] = struct{}{}
+	}
+	return func(projectID string) bool {
+		_, ok := allowedSet[canonical(projectID)]
+		return ok
+	}
+}
+
+// eventProjectID 提取单个事件请求体中的 project_id
+func eventProjectID(body []byte) ([]string, error) {
+	var payload struct {
+		ProjectID       string `json:"project_id"`
+		LegacyProjectID string `json:"projectId"` // v1 SDK 的字段名
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+
+	projectID := payload.ProjectID
+	if projectID == "" {
+		projectID = payload.LegacyProjectID
+	}
+	return []string{projectID}, nil
+}
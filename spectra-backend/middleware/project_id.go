@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectIDQuery 校验并规范化查询参数 project_id（可逗号分隔多个）
+// 任一值不符合格式时返回 400；规范化后与原值不同时改写查询参数，后续处理器读到的是规范化后的值
+// 必须在首次调用 c.Query 之前执行，gin 会缓存解析后的查询参数
+func ProjectIDQuery(normalize func(string) (string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		query := c.Request.URL.Query()
+		raw, ok := query["project_id"]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		changed := false
+		for i, value := range raw {
+			parts := strings.Split(value, ",")
+			for j, part := range parts {
+				trimmed := strings.TrimSpace(part)
+				if trimmed == "" {
+					continue
+				}
+				normalized, err := normalize(trimmed)
+				if err != nil {
+					c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": "project_id"})
+					return
+				}
+				if normalized != part {
+					parts[j] = normalized
+					changed = true
+				}
+			}
+			raw[i] = strings.Join(parts, ",")
+		}
+
+		if changed {
+			c.Request.URL.RawQuery = query.Encode()
+		}
+		c.Next()
+	}
+}
+
+// ProjectIDParam 校验并规范化路径参数 name 中的项目标识符，不符合格式时返回 400
+func ProjectIDParam(name string, normalize func(string) (string, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for i, param := range c.Params {
+			if param.Key != name {
+				continue
+			}
+			normalized, err := normalize(param.Value)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error(), "field": name})
+				return
+			}
+			c.Params[i].Value = normalized
+		}
+		c.Next()
+	}
+}
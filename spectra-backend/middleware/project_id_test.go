@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testNormalize 模拟默认的 project_id 规则并转为小写
+func testNormalize(projectID string) (string, error) {
+	if !regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`).MatchString(projectID) {
+		return "", fmt.Errorf("invalid project_id %q", projectID)
+	}
+	return strings.ToLower(projectID), nil
+}
+
+func TestProjectIDQuery(t *testing.T) {
+	tests := []struct {
+		query      string
+		wantStatus int
+		want       string
+	}{
+		{"project_id=web", http.StatusOK, "web"},
+		{"project_id=Web,APP", http.StatusOK, "web,app"},
+		{"", http.StatusOK, ""},
+		{"project_id=web%20app", http.StatusBadRequest, ""},
+		{"project_id=web,a%2Fb", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.GET("/logs", ProjectIDQuery(testNormalize), func(c *gin.Context) {
+			c.String(http.StatusOK, c.Query("project_id"))
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/logs?"+tt.query, nil))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%q: status = %d, want %d (body %s)", tt.query, w.Code, tt.wantStatus, w.Body)
+			continue
+		}
+		if tt.wantStatus == http.StatusOK && w.Body.String() != tt.want {
+			t.Errorf("%q: handler saw project_id %q, want %q", tt.query, w.Body, tt.want)
+		}
+	}
+}
+
+func TestProjectIDParam(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantStatus int
+		want       string
+	}{
+		{"/projects/Web-App", http.StatusOK, "web-app"},
+		{"/projects/web%20app", http.StatusBadRequest, ""},
+		{"/projects/" + strings.Repeat("a", 65), http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		router := gin.New()
+		router.GET("/projects/:id", ProjectIDParam("id", testNormalize), func(c *gin.Context) {
+			c.String(http.StatusOK, c.Param("id"))
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.path, w.Code, tt.wantStatus, w.Body)
+			continue
+		}
+		if tt.wantStatus == http.StatusOK && w.Body.String() != tt.want {
+			t.Errorf("%s: handler saw id %q, want %q", tt.path, w.Body, tt.want)
+		}
+	}
+}
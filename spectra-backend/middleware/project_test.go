@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newAllowlistRouter 创建挂载项目白名单的路由，处理器返回读到的请求体，用于确认请求体已被还原
+func newAllowlistRouter(allowed []string) *gin.Engine {
+	router := gin.New()
+	router.POST("/events", ProjectAllowlist(allowed, strings.ToLower), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+	return router
+}
+
+func TestProjectAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		body    string
+		want    int
+	}{
+		{"allowed project", []string{"web"}, `{"project_id":"web"}`, http.StatusOK},
+		{"allowed after canonicalization", []string{"Web"}, `{"project_id":"WEB"}`, http.StatusOK},
+		{"allowed legacy field", []string{"web"}, `{"projectId":"web"}`, http.StatusOK},
+		{"denied project", []string{"web"}, `{"project_id":"other"}`, http.StatusForbidden},
+		{"denied missing project", []string{"web"}, `{}`, http.StatusForbidden},
+		{"empty allowlist allows all", nil, `{"project_id":"other"}`, http.StatusOK},
+		{"invalid body passed to handler", []string{"web"}, `{`, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tt.body))
+			newAllowlistRouter(tt.allowed).ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+			if w.Code == http.StatusOK && w.Body.String() != tt.body {
+				t.Errorf("handler read body %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+func TestProjectAllowed(t *testing.T) {
+	if ProjectAllowed(nil, nil) != nil {
+		t.Fatal("ProjectAllowed(nil) should return nil for an empty allowlist")
+	}
+
+	isAllowed := ProjectAllowed([]string{"web", "app"}, nil)
+	for projectID, want := range map[string]bool{"web": true, "app": true, "WEB": false, "": false} {
+		if got := isAllowed(projectID); got != want {
+			t.Errorf("isAllowed(%q) = %v, want %v", projectID, got, want)
+		}
+	}
+}
+
+func TestProjectAllowlistBodyLimit(t *testing.T) {
+	const limit = 32
+	router := gin.New()
+	router.POST("/events", MaxBodyBytes(limit), ProjectAllowlist([]string{"web"}, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	large := `{"project_id":"web","message":"` + strings.Repeat("x", limit) + `"}`
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength bool
+		want          int
+	}{
+		{"within limit", `{"project_id":"web"}`, true, http.StatusOK},
+		{"content length over limit", large, true, http.StatusRequestEntityTooLarge},
+		// 没有 Content-Length 的请求在读取时才发现超限
+		{"chunked body over limit", large, false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(tt.body))
+			if !tt.contentLength {
+				req.ContentLength = -1
+			}
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusRequestEntityTooLarge && !strings.Contains(w.Body.String(), `"max_bytes":32`) {
+				t.Errorf("body = %s, want max_bytes", w.Body)
+			}
+		})
+	}
+}
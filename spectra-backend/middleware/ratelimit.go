@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit 全局令牌桶限流中间件，所有请求共享同一个桶
+// 每 every 补充一个令牌，最多累积 burst 个，超出时返回 429 并通过 Retry-After 告知等待秒数
+func RateLimit(every time.Duration, burst int) gin.HandlerFunc {
+	limiter := rate.NewLimiter(rate.Every(every), burst)
+
+	return func(c *gin.Context) {
+		reservation := limiter.Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader 请求 ID 的请求头和响应头名称
+const RequestIDHeader = "X-Request-ID"
+
+// LoggerContextKey 请求级日志记录器在 gin 上下文中的键
+const LoggerContextKey = "logger"
+
+// requestIDPattern 允许沿用的上游请求 ID 格式，不符合时重新生成，避免日志注入
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// RequestLogger 请求级日志中间件
+// 沿用请求头中的 X-Request-ID 或生成新的请求 ID 并写回响应头，
+// 创建携带 request_id、method、path 字段的子日志记录器存入 gin 上下文，处理器通过它输出的日志自动关联到同一请求
+func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		c.Set(LoggerContextKey, logger.With(
+			zap.String("request_id", requestID),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+		))
+
+		c.Next()
+	}
+}
+
+// LoggerFrom 返回 RequestLogger 注入的请求级日志记录器，未注入时返回 fallback
+func LoggerFrom(c *gin.Context, fallback *zap.Logger) *zap.Logger {
+	if value, ok := c.Get(LoggerContextKey); ok {
+		if logger, ok := value.(*zap.Logger); ok {
+			return logger
+		}
+	}
+	return fallback
+}
+
+// newRequestID 生成 16 字节随机请求 ID
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 列表响应格式对应的媒体类型
+const (
+	// MediaTypeV1 裸数组格式
+	MediaTypeV1 = "application/vnd.spectra.v1+json"
+	// MediaTypeV2 {"data":[...],"meta":{...}} 包装格式
+	MediaTypeV2 = "application/vnd.spectra.v2+json"
+	// MediaTypeArrowStream Apache Arrow IPC 流格式，按批次流式写出，不使用包装格式
+	MediaTypeArrowStream = "application/vnd.apache.arrow.stream"
+)
+
+// EnvelopeContextKey 是否使用包装格式在 gin 上下文中的键
+const EnvelopeContextKey = "response_envelope"
+
+// ResponseFormat 协商列表接口的响应格式
+// Accept 头包含 MediaTypeV2 时使用包装格式，包含 MediaTypeV1 时使用裸数组，否则使用配置的默认格式
+func ResponseFormat(envelopeByDefault bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		envelope := envelopeByDefault
+		accept := c.GetHeader("Accept")
+		switch {
+		case strings.Contains(accept, MediaTypeV2):
+			envelope = true
+		case strings.Contains(accept, MediaTypeV1):
+			envelope = false
+		}
+		c.Set(EnvelopeContextKey, envelope)
+
+		c.Next()
+	}
+}
+
+// UseEnvelope 返回当前请求是否使用包装格式，未挂载 ResponseFormat 时返回 false
+func UseEnvelope(c *gin.Context) bool {
+	return c.GetBool(EnvelopeContextKey)
+}
+
+// AcceptsArrow 判断请求的 Accept 头是否要求 Arrow IPC 流格式
+func AcceptsArrow(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), MediaTypeArrowStream)
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"spectra-backend/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// requestTimeoutKey gin 上下文中保存当前请求超时状态的键
+const requestTimeoutKey = "spectra.request_timeout"
+
+// requestTimeout 请求超时状态，由最外层的超时中间件创建，内层路由组的超时据此覆盖外层设置
+type requestTimeout struct {
+	// parent 设置截止时间前的请求上下文，路由组超时从它派生，不受外层截止时间约束
+	parent  context.Context
+	writer  *timeoutWriter
+	timeout time.Duration
+}
+
+// QueryTimeout 查询请求超时中间件，为 GET 请求的上下文设置截止时间
+// 仓储层的查询均通过 QueryContext 使用请求上下文，超时后驱动取消正在执行的 ClickHouse 查询并释放连接，
+// 处理器随后写出的错误响应被丢弃，统一返回 504；超时前已开始写出的响应不受影响
+// 上报、导入等写入请求以及 exempt 返回 true 的请求（如健康检查）不受限制；timeout 不大于 0 时不限制
+// 路由组通过 GroupTimeout 设置的超时优先于此处的全局超时
+func QueryTimeout(logger *zap.Logger, timeout time.Duration, exempt func(path string, method string) bool) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || (exempt != nil && exempt(c.Request.URL.Path, c.Request.Method)) {
+			c.Next()
+			return
+		}
+		applyTimeout(c, logger, timeout)
+	}
+}
+
+// GroupTimeout 路由组超时中间件，为组内所有请求（包括上报等写入请求）设置截止时间，超时返回 504
+// 与 QueryTimeout 同时生效时以路由组的超时为准，无论长短，使上报路由保持较短的超时而聚合查询可以更长；
+// timeout 不大于 0 时不限制，但仍受全局超时约束
+func GroupTimeout(logger *zap.Logger, timeout time.Duration) gin.HandlerFunc {
+	if timeout <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		applyTimeout(c, logger, timeout)
+	}
+}
+
+// applyTimeout 为请求设置截止时间并执行后续处理器
+// 外层中间件已设置过超时时，只从原始请求上下文重新派生截止时间，504 响应仍由外层写出
+func applyTimeout(c *gin.Context, logger *zap.Logger, timeout time.Duration) {
+	if value, ok := c.Get(requestTimeoutKey); ok {
+		state := value.(*requestTimeout)
+		ctx, cancel := context.WithTimeout(state.parent, timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		state.writer.ctx = ctx
+		state.timeout = timeout
+		c.Next()
+		return
+	}
+
+	parent := c.Request.Context()
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+	c.Writer = writer
+	state := &requestTimeout{parent: parent, writer: writer, timeout: timeout}
+	c.Set(requestTimeoutKey, state)
+
+	c.Next()
+
+	c.Writer = writer.ResponseWriter
+	if !writer.timedOut && (writer.Written() || !errors.Is(writer.ctx.Err(), context.DeadlineExceeded)) {
+		return
+	}
+	metrics.HTTPRequestTimeouts.Inc()
+	LoggerFrom(c, logger).Warn("Request timed out, query cancelled", zap.Duration("timeout", state.timeout))
+	c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+}
+
+// timeoutWriter 请求超时后丢弃处理器写出的响应，由 QueryTimeout 改写为 504
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+	// timedOut 超时后处理器尝试写出过响应
+	timedOut bool
+}
+
+// expired 判断请求是否已超时且响应尚未开始写出
+func (w *timeoutWriter) expired() bool {
+	if w.timedOut {
+		return true
+	}
+	if !w.ResponseWriter.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+	}
+	return w.timedOut
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.expired() {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.expired() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush 超时后不刷新，避免流式响应（如 Arrow）经 gin 的 Flush 提前写出状态码而无法改写为 504
+func (w *timeoutWriter) Flush() {
+	if w.expired() {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// serveWithTimeout 经过 middlewares 处理一个请求，handler 在请求上下文结束后执行 after
+// 返回响应和上下文结束时的错误
+func serveWithTimeout(method string, after func(c *gin.Context), middlewares ...gin.HandlerFunc) (*httptest.ResponseRecorder, error) {
+	var ctxErr error
+	router := gin.New()
+	handlers := append(middlewares, func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		ctxErr = c.Request.Context().Err()
+		after(c)
+	})
+	router.Handle(method, "/api/error-logs", handlers...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, "/api/error-logs", nil))
+	return w, ctxErr
+}
+
+func TestQueryTimeoutReturns504(t *testing.T) {
+	tests := []struct {
+		name  string
+		after func(c *gin.Context)
+	}{
+		{"error response", func(c *gin.Context) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "context deadline exceeded"})
+		}},
+		{"late success", func(c *gin.Context) {
+			c.String(http.StatusOK, "late")
+		}},
+		{"late write and flush", func(c *gin.Context) {
+			c.Status(http.StatusOK)
+			c.Writer.WriteHeaderNow()
+			c.Writer.Write([]byte("late"))
+			c.Writer.Flush()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, ctxErr := serveWithTimeout(http.MethodGet, tt.after, QueryTimeout(zap.NewNop(), 10*time.Millisecond, nil))
+
+			if !errors.Is(ctxErr, context.DeadlineExceeded) {
+				t.Errorf("request context error = %v, want deadline exceeded", ctxErr)
+			}
+			if w.Code != http.StatusGatewayTimeout {
+				t.Errorf("status = %d, want 504", w.Code)
+			}
+			if body := w.Body.String(); strings.Contains(body, "late") || strings.Contains(body, "context deadline") {
+				t.Errorf("write after the deadline reached the client: %s", body)
+			}
+			if !strings.Contains(w.Body.String(), "Request timed out") {
+				t.Errorf("body = %s, want timeout error", w.Body)
+			}
+		})
+	}
+}
+
+func TestQueryTimeoutKeepsStartedResponse(t *testing.T) {
+	router := gin.New()
+	router.GET("/api/error-logs", QueryTimeout(zap.NewNop(), 10*time.Millisecond, nil), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("early")
+		c.Writer.Flush()
+		<-c.Request.Context().Done()
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/error-logs", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "early" {
+		t.Errorf("response = %d %q, want 200 early", w.Code, w.Body)
+	}
+}
+
+func TestQueryTimeoutSkipsWritesAndExempt(t *testing.T) {
+	exempt := func(path, method string) bool { return path == "/health" }
+	for _, tt := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/api/error-logs"},
+		{http.MethodGet, "/health"},
+	} {
+		var deadline bool
+		router := gin.New()
+		router.Handle(tt.method, tt.path, QueryTimeout(zap.NewNop(), time.Millisecond, exempt), func(c *gin.Context) {
+			_, deadline = c.Request.Context().Deadline()
+			c.Status(http.StatusOK)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if deadline || w.Code != http.StatusOK {
+			t.Errorf("%s %s: deadline set %v, status %d, want no timeout", tt.method, tt.path, deadline, w.Code)
+		}
+	}
+}
+
+func TestGroupTimeoutOverridesQueryTimeout(t *testing.T) {
+	finish := func(c *gin.Context) { c.String(http.StatusOK, "done") }
+
+	// 路由组的超时更短：POST 请求也会超时
+	w, ctxErr := serveWithTimeout(http.MethodPost, finish, QueryTimeout(zap.NewNop(), time.Hour, nil), GroupTimeout(zap.NewNop(), 10*time.Millisecond))
+	if w.Code != http.StatusGatewayTimeout || !errors.Is(ctxErr, context.DeadlineExceeded) {
+		t.Errorf("shorter group timeout: status %d, context error %v, want 504", w.Code, ctxErr)
+	}
+
+	// 路由组的超时更长：不受全局超时约束
+	router := gin.New()
+	router.GET("/api/stats", QueryTimeout(zap.NewNop(), 10*time.Millisecond, nil), GroupTimeout(zap.NewNop(), time.Hour), func(c *gin.Context) {
+		time.Sleep(30 * time.Millisecond)
+		if err := c.Request.Context().Err(); err != nil {
+			t.Errorf("request context error = %v, want group timeout to apply", err)
+		}
+		finish(c)
+	})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/stats", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "done" {
+		t.Errorf("longer group timeout: response %d %q, want 200 done", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 携带客户端追踪 ID 的请求头
+const (
+	// TraceIDHeader SDK 直接上报的追踪 ID，与事件中的 trace_id 相同
+	TraceIDHeader = "X-Trace-Id"
+	// TraceparentHeader W3C Trace Context 请求头，格式为 version-trace_id-parent_id-flags
+	TraceparentHeader = "traceparent"
+)
+
+// traceIDPattern 允许记录的 X-Trace-Id 格式，不符合时忽略，避免日志注入
+var traceIDPattern = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// traceparentPattern W3C traceparent 格式，第二段为 32 位十六进制的 trace-id
+var traceparentPattern = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}`)
+
+// requestTraceID 从请求头中提取客户端追踪 ID，优先使用 X-Trace-Id，其次为 traceparent 中的 trace-id
+// 格式不合法或 trace-id 全为 0 时视为未携带，返回空字符串
+func requestTraceID(c *gin.Context) string {
+	if traceID := strings.TrimSpace(c.GetHeader(TraceIDHeader)); traceIDPattern.MatchString(traceID) {
+		return traceID
+	}
+
+	match := traceparentPattern.FindStringSubmatch(strings.TrimSpace(c.GetHeader(TraceparentHeader)))
+	if match == nil || strings.Trim(match[1], "0") == "" {
+		return ""
+	}
+	return match[1]
+}
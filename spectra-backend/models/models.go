@@ -1,28 +1,102 @@
 package models
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // BaseLog 基础日志结构，包含所有表共有的字段
 type BaseLog struct {
-	Timestamp time.Time          `json:"timestamp"`
-	ProjectID string             `json:"project_id"`
-	SessionID string             `json:"session_id"`
-	TraceID   string             `json:"trace_id"`
-	UserID    string             `json:"user_id"`
-	URL       string             `json:"url"`
-	Referrer  string             `json:"referrer"`
-	Type      string             `json:"type"`
-	Name      string             `json:"name"`
-	Extra     json.RawMessage    `json:"extra"`
+	Timestamp  time.Time       `json:"timestamp"`
+	ProjectID  string          `json:"project_id" binding:"required"`
+	SessionID  string          `json:"session_id"`
+	TraceID    string          `json:"trace_id"`
+	UserID     string          `json:"user_id"`
+	URL        string          `json:"url"`
+	Referrer   string          `json:"referrer"`
+	SDKVersion string          `json:"sdk_version"` // 上报 SDK 的版本，可由请求头补充，旧版 SDK 为空
+	Platform   string          `json:"platform"`    // 上报 SDK 的运行平台（如 web、ios、android），可由请求头补充
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Extra      json.RawMessage `json:"extra"`
+}
+
+// FillSDK 在请求体未上报时使用 version 和 platform 补充 SDK 信息，请求体中的值优先
+func (b *BaseLog) FillSDK(version, platform string) {
+	if b.SDKVersion == "" {
+		b.SDKVersion = version
+	}
+	if b.Platform == "" {
+		b.Platform = platform
+	}
 }
 
 // ErrorLog 错误日志表对应的结构体
 type ErrorLog struct {
 	BaseLog
-	Message string `json:"message"`
+	Message   string `json:"message"`
+	Severity  string `json:"severity"`   // 严重程度，取值见 ErrorSeverities，未上报时为 error
+	StackHash string `json:"stack_hash"` // extra.stack 的 SHA-256，完整堆栈保存在 stack_traces 表中，没有堆栈时为空
+	// Stack 从 extra 中提取的完整堆栈，只用于写入 stack_traces 表，不出现在请求和响应中
+	Stack string `json:"-"`
+}
+
+// 错误日志的严重程度
+const (
+	SeverityFatal   = "fatal"
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ErrorSeverities 所有合法的严重程度，按从高到低排列
+var ErrorSeverities = []string{SeverityFatal, SeverityError, SeverityWarning, SeverityInfo}
+
+// IsErrorSeverity 判断是否为合法的严重程度
+func IsErrorSeverity(severity string) bool {
+	for _, s := range ErrorSeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// SeverityCount 单个严重程度的错误数
+type SeverityCount struct {
+	Severity string `json:"severity"`
+	Count    uint64 `json:"count"`
+}
+
+// SeverityBreakdown 时间范围内按严重程度统计的错误数，包含所有严重程度，没有数据的计数为 0
+type SeverityBreakdown struct {
+	ProjectIDs []string        `json:"project_ids"`
+	StartTime  time.Time       `json:"start_time"`
+	EndTime    time.Time       `json:"end_time"`
+	Total      uint64          `json:"total"`
+	Severities []SeverityCount `json:"severities"`
+}
+
+// ErrorWithPerformance 错误日志及同一会话中在其之前最近的一条性能指标
+// 会话中没有更早的性能指标时 Performance 为 nil
+type ErrorWithPerformance struct {
+	Error       *ErrorLog          `json:"error"`
+	Performance *PerformanceMetric `json:"performance"`
+}
+
+// StackTrace 按哈希去重保存的完整堆栈
+type StackTrace struct {
+	Hash     string    `json:"hash"`
+	Stack    string    `json:"stack"`
+	LastSeen time.Time `json:"last_seen"`
 }
 
 // PerformanceMetric 性能指标表对应的结构体
@@ -31,15 +105,87 @@ type PerformanceMetric struct {
 	Value float64 `json:"value"`
 }
 
+// UnmarshalJSON 兼容部分统计库以字符串上报的 value（如 "123.4"）
+// NaN 和 Inf 无法写入 ClickHouse，解析时直接拒绝
+func (m *PerformanceMetric) UnmarshalJSON(data []byte) error {
+	type alias PerformanceMetric
+	aux := struct {
+		*alias
+		Value json.RawMessage `json:"value"`
+	}{alias: (*alias)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	raw := bytes.TrimSpace(aux.Value)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		m.Value = 0
+		return nil
+	}
+
+	var value float64
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return err
+		}
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+		if err != nil {
+			return fmt.Errorf("invalid value %q: must be a number", text)
+		}
+		value = parsed
+	} else if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("invalid value %s: must be a number", raw)
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("invalid value %s: must be a finite number", raw)
+	}
+	m.Value = value
+	return nil
+}
+
 // UserAction 用户行为表对应的结构体
 type UserAction struct {
 	BaseLog
 	Message string  `json:"message"`
 	Method  string  `json:"method"`
-	Status  uint16  `json:"status"`
+	Status  uint16  `json:"status" binding:"omitempty,min=100,max=599"` // 仅 api_timing 有值
 	Value   float64 `json:"value"`
 }
 
+// StatusClasses 用户行为 HTTP 状态码的所有类别，1 表示 1xx，依此类推
+var StatusClasses = []int{1, 2, 3, 4, 5}
+
+// ParseStatusClass 解析 1xx~5xx 形式的状态码类别，不区分大小写
+func ParseStatusClass(class string) (int, bool) {
+	if len(class) != 3 || class[0] < '1' || class[0] > '5' || !strings.EqualFold(class[1:], "xx") {
+		return 0, false
+	}
+	return int(class[0] - '0'), true
+}
+
+// StatusClassName 返回状态码类别的名称，如 5 对应 5xx
+func StatusClassName(class int) string {
+	return strconv.Itoa(class) + "xx"
+}
+
+// StatusClassCount 单个状态码类别的用户行为数
+type StatusClassCount struct {
+	Class string `json:"class"`
+	Count uint64 `json:"count"`
+}
+
+// StatusClassBreakdown 时间范围内按 HTTP 状态码类别统计的用户行为数，不含没有状态码的行为
+// 包含所有类别，没有数据的计数为 0
+type StatusClassBreakdown struct {
+	ProjectIDs []string           `json:"project_ids"`
+	StartTime  time.Time          `json:"start_time"`
+	EndTime    time.Time          `json:"end_time"`
+	Total      uint64             `json:"total"`
+	Classes    []StatusClassCount `json:"classes"`
+}
+
 // CustomEvent 自定义事件表对应的结构体
 type CustomEvent struct {
 	BaseLog
@@ -50,4 +196,560 @@ type CustomEvent struct {
 type PageStay struct {
 	BaseLog
 	Value float64 `json:"value"`
-}
\ No newline at end of file
+}
+
+// TableDeletion 单张表的删除结果
+type TableDeletion struct {
+	Table      string `json:"table"`
+	Rows       uint64 `json:"rows"`
+	MutationID string `json:"mutation_id,omitempty"` // 提交的删除 mutation，可在 system.mutations 中查询进度，未查到时为空
+}
+
+// DeletionSummary 用户/会话/项目数据删除的结果汇总
+type DeletionSummary struct {
+	ProjectID string          `json:"project_id"`
+	Field     string          `json:"field"` // user_id / session_id / project_id
+	Value     string          `json:"value"`
+	Tables    []TableDeletion `json:"tables"`
+	TotalRows uint64          `json:"total_rows"`
+}
+
+// RetentionPolicy 事件表的数据保留策略
+type RetentionPolicy struct {
+	Days   int      `json:"days"` // 保留天数，0 表示不自动删除
+	Tables []string `json:"tables"`
+}
+
+// TableStats 单张表的存储统计，只统计活跃的数据分区
+type TableStats struct {
+	Table             string     `json:"table"`
+	Rows              uint64     `json:"rows"`
+	BytesOnDisk       uint64     `json:"bytes_on_disk"`
+	UncompressedBytes uint64     `json:"uncompressed_bytes"`
+	Parts             uint64     `json:"parts"`
+	LastModified      *time.Time `json:"last_modified"` // 最近一次写入或合并的时间，没有数据时为 null
+}
+
+// StorageStats Spectra 各数据表的存储统计，按磁盘占用降序排列
+type StorageStats struct {
+	Tables           []TableStats `json:"tables"`
+	TotalRows        uint64       `json:"total_rows"`
+	TotalBytesOnDisk uint64       `json:"total_bytes_on_disk"`
+}
+
+// ColumnMeta 事件表中一列的元数据
+type ColumnMeta struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`         // ClickHouse 类型，如 LowCardinality(String)
+	Filterable   bool   `json:"filterable"`   // 列表接口可按该列过滤
+	Aggregatable bool   `json:"aggregatable"` // 分位数、平均值等聚合接口基于该列计算
+}
+
+// EventTypeSchema 某类事件对应的表及其列，列按表定义顺序排列
+type EventTypeSchema struct {
+	Type    string       `json:"type"`
+	Table   string       `json:"table"`
+	Columns []ColumnMeta `json:"columns"`
+}
+
+// SchemaMetadata 所有事件表的列元数据，按 EventTypes 顺序排列
+type SchemaMetadata struct {
+	EventTypes  []EventTypeSchema `json:"event_types"`
+	GeneratedAt time.Time         `json:"generated_at"` // 从 ClickHouse 读取的时间，结果会缓存一段时间
+}
+
+// 事件类型标识，用于导入和批量上报时区分记录所属的表
+const (
+	EventTypeErrorLog          = "error_log"
+	EventTypePerformanceMetric = "performance_metric"
+	EventTypeUserAction        = "user_action"
+	EventTypeCustomEvent       = "custom_event"
+	EventTypePageStay          = "page_stay"
+)
+
+// EventTypes 所有事件类型，按事件表的遍历顺序排列
+var EventTypes = []string{EventTypeErrorLog, EventTypePerformanceMetric, EventTypeUserAction, EventTypeCustomEvent, EventTypePageStay}
+
+// IsEventType 判断是否为合法的事件类型
+func IsEventType(eventType string) bool {
+	return slices.Contains(EventTypes, eventType)
+}
+
+// EventEnvelope 带类型标记的事件包装
+type EventEnvelope struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// EventBatch 按表分组的一批事件
+type EventBatch struct {
+	ErrorLogs          []*ErrorLog
+	PerformanceMetrics []*PerformanceMetric
+	UserActions        []*UserAction
+	CustomEvents       []*CustomEvent
+	PageStays          []*PageStay
+}
+
+// Decode 按类型解析事件包装，返回对应模型的指针
+func (e *EventEnvelope) Decode() (any, error) {
+	var event any
+	switch e.Type {
+	case EventTypeErrorLog:
+		event = &ErrorLog{}
+	case EventTypePerformanceMetric:
+		event = &PerformanceMetric{}
+	case EventTypeUserAction:
+		event = &UserAction{}
+	case EventTypeCustomEvent:
+		event = &CustomEvent{}
+	case EventTypePageStay:
+		event = &PageStay{}
+	default:
+		return nil, fmt.Errorf("unknown event type %q", e.Type)
+	}
+	if err := json.Unmarshal(e.Data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Add 将事件加入对应分组，event 必须是事件模型的指针
+func (b *EventBatch) Add(event any) error {
+	switch e := event.(type) {
+	case *ErrorLog:
+		b.ErrorLogs = append(b.ErrorLogs, e)
+	case *PerformanceMetric:
+		b.PerformanceMetrics = append(b.PerformanceMetrics, e)
+	case *UserAction:
+		b.UserActions = append(b.UserActions, e)
+	case *CustomEvent:
+		b.CustomEvents = append(b.CustomEvents, e)
+	case *PageStay:
+		b.PageStays = append(b.PageStays, e)
+	default:
+		return fmt.Errorf("unsupported event %T", event)
+	}
+	return nil
+}
+
+// Len 返回批次中的事件总数
+func (b *EventBatch) Len() int {
+	return len(b.ErrorLogs) + len(b.PerformanceMetrics) + len(b.UserActions) +
+		len(b.CustomEvents) + len(b.PageStays)
+}
+
+// Counts 返回批次中各类型事件的数量
+func (b *EventBatch) Counts() map[string]int {
+	return map[string]int{
+		EventTypeErrorLog:          len(b.ErrorLogs),
+		EventTypePerformanceMetric: len(b.PerformanceMetrics),
+		EventTypeUserAction:        len(b.UserActions),
+		EventTypeCustomEvent:       len(b.CustomEvents),
+		EventTypePageStay:          len(b.PageStays),
+	}
+}
+
+// IngestionRate 所有事件表合计的写入量趋势，没有数据的桶计数为 0
+type IngestionRate struct {
+	ProjectIDs []string      `json:"project_ids"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Interval   string        `json:"interval"`
+	Timezone   string        `json:"timezone"`
+	Total      uint64        `json:"total"`
+	Buckets    []TrendBucket `json:"buckets"`
+}
+
+// SessionCount 时间范围内的不同会话数，session_id 为空的事件不计入
+// 指定时间桶时 Buckets 为每个桶内活跃的会话数，跨桶的会话在每个桶中各计一次，桶计数之和可能大于 Total
+type SessionCount struct {
+	ProjectIDs []string      `json:"project_ids"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Total      uint64        `json:"total"`
+	Interval   string        `json:"interval,omitempty"`
+	Timezone   string        `json:"timezone,omitempty"`
+	Buckets    []TrendBucket `json:"buckets,omitempty"`
+}
+
+// CrashFreeStats 时间范围内没有发生错误的会话和用户占比，只统计 Severities 中的错误
+// 会话和用户按 (project_id, session_id) / (project_id, user_id) 去重，标识为空的事件不计入
+type CrashFreeStats struct {
+	ProjectIDs        []string  `json:"project_ids"`
+	StartTime         time.Time `json:"start_time"`
+	EndTime           time.Time `json:"end_time"`
+	Severities        []string  `json:"severities"`
+	Sessions          uint64    `json:"sessions"`
+	CrashedSessions   uint64    `json:"crashed_sessions"`
+	CrashFreeSessions *float64  `json:"crash_free_sessions"` // 没有会话时为 null
+	Users             uint64    `json:"users"`
+	CrashedUsers      uint64    `json:"crashed_users"`
+	CrashFreeUsers    *float64  `json:"crash_free_users"` // 没有用户时为 null
+}
+
+// SDKVersionCount 某个 SDK 版本和平台的事件数，旧版 SDK 未上报时版本和平台为空
+type SDKVersionCount struct {
+	SDKVersion string    `json:"sdk_version"`
+	Platform   string    `json:"platform"`
+	Count      uint64    `json:"count"`
+	FirstSeen  time.Time `json:"first_seen"` // 时间范围内该版本最早的事件时间
+	LastSeen   time.Time `json:"last_seen"`
+}
+
+// SDKVersionBreakdown 按 SDK 版本和平台统计的事件数，按事件数降序排列
+type SDKVersionBreakdown struct {
+	ProjectIDs []string          `json:"project_ids"`
+	Type       string            `json:"type,omitempty"` // 统计的事件类型，为空表示所有事件表
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	Versions   []SDKVersionCount `json:"versions"`
+}
+
+// ReleaseErrorCount 某个版本（sdk_version）的错误数和事件数
+type ReleaseErrorCount struct {
+	Release   string    `json:"release"` // 旧版 SDK 未上报版本时为空
+	Events    uint64    `json:"events"`  // 该版本在所有事件表中的事件数，含错误
+	Errors    uint64    `json:"errors"`
+	ErrorRate float64   `json:"error_rate"` // errors / events
+	Elevated  bool      `json:"elevated"`   // 错误率明显高于所有版本的整体错误率
+	FirstSeen time.Time `json:"first_seen"` // 时间范围内该版本最早的事件时间
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// ReleaseErrors 按版本统计的错误数，用于发现新版本引入的错误回归
+type ReleaseErrors struct {
+	ProjectIDs []string            `json:"project_ids"`
+	StartTime  time.Time           `json:"start_time"`
+	EndTime    time.Time           `json:"end_time"`
+	ErrorRate  float64             `json:"error_rate"` // 所有版本合计的错误率，没有事件时为 0
+	Releases   []ReleaseErrorCount `json:"releases"`
+}
+
+// DirectReferrer 没有 referrer 或 referrer 无法解析出域名的事件归入的来源
+const DirectReferrer = "direct"
+
+// ReferrerCount 某个来源域名的事件数、错误数和会话数
+type ReferrerCount struct {
+	Domain   string `json:"domain"` // 没有 referrer 时为 direct
+	Events   uint64 `json:"events"`
+	Errors   uint64 `json:"errors"`
+	Sessions uint64 `json:"sessions"` // 按 (project_id, session_id) 去重，session_id 为空的事件不计入
+}
+
+// ReferrerBreakdown 按来源域名统计的流量和错误，按事件数降序排列
+type ReferrerBreakdown struct {
+	ProjectIDs []string        `json:"project_ids"`
+	Type       string          `json:"type,omitempty"` // 统计的事件类型，为空表示所有事件表
+	StartTime  time.Time       `json:"start_time"`
+	EndTime    time.Time       `json:"end_time"`
+	Referrers  []ReferrerCount `json:"referrers"`
+}
+
+// HeatmapCell 活跃度热力图的一个单元格
+type HeatmapCell struct {
+	DayOfWeek int    `json:"day_of_week"` // 1~7，1 为周一，7 为周日
+	Hour      int    `json:"hour"`        // 0~23
+	Count     uint64 `json:"count"`
+}
+
+// ActivityHeatmap 按星期和小时统计的事件数，固定包含 7×24 个单元格，没有数据的计数为 0
+type ActivityHeatmap struct {
+	ProjectIDs []string      `json:"project_ids"`
+	StartTime  time.Time     `json:"start_time"`
+	EndTime    time.Time     `json:"end_time"`
+	Type       string        `json:"type"` // 统计的事件类型，为空表示所有事件表
+	Timezone   string        `json:"timezone"`
+	Total      uint64        `json:"total"`
+	Cells      []HeatmapCell `json:"cells"` // 按星期、小时升序排列
+}
+
+// EventCursor 增量轮询的起点，即客户端已看到的最后一个事件
+// TraceID 为空时返回时间严格晚于 Timestamp 的事件，否则按 (timestamp, trace_id) 比较
+type EventCursor struct {
+	Timestamp time.Time
+	TraceID   string
+}
+
+// Extra 过滤的匹配方式
+// ExtraFilterNotEquals 不对应查询参数，只用于服务端生成的条件（如 exclude_bots），字段不存在的事件视为不相等
+const (
+	ExtraFilterEquals    = "eq"
+	ExtraFilterContains  = "has"
+	ExtraFilterNotEquals = "ne"
+)
+
+// User-Agent 解析结果在 extra 中的设备类型字段，识别为爬虫或脚本时取值为 DeviceTypeBot
+const (
+	ExtraDeviceTypeKey = "device_type"
+	DeviceTypeBot      = "bot"
+)
+
+// ExcludeBotsFilter 排除 User-Agent 被识别为爬虫或脚本的事件，依赖 ingest.user_agent 在上报时写入的 extra.device_type
+var ExcludeBotsFilter = ExtraFilter{Path: []string{ExtraDeviceTypeKey}, Op: ExtraFilterNotEquals, Value: DeviceTypeBot}
+
+// ExtraFilter 按 extra 中某个字段过滤事件
+// Path 为逐层的键名；Op 为 ExtraFilterEquals 时比较字段值，为 ExtraFilterContains 时判断数组字段是否包含 Value
+type ExtraFilter struct {
+	Path  []string
+	Op    string
+	Value string
+	// Column 非空时按事件表的该列精确匹配 Value，忽略 Path 和 Op，取值见 FilterColumns
+	Column string
+}
+
+// FilterColumns 可以通过 ExtraFilter.Column 过滤的事件表列，对应同名查询参数
+var FilterColumns = []string{"sdk_version", "platform"}
+
+// 列表查询的排序字段，value 只存在于性能指标和用户行为表
+const (
+	SortFieldTimestamp = "timestamp"
+	SortFieldValue     = "value"
+)
+
+// 列表查询的排序方向
+const (
+	SortOrderAsc  = "asc"
+	SortOrderDesc = "desc"
+)
+
+// ListOrder 列表查询的排序方式，Field 为空时按 timestamp 排序
+type ListOrder struct {
+	Field string
+	Desc  bool
+}
+
+// ListMeta 列表响应的元数据
+type ListMeta struct {
+	Count      int     `json:"count"`
+	NextCursor *string `json:"next_cursor"` // 没有下一页时为 null
+	Skipped    int64   `json:"skipped"`     // 因无法解析而跳过的行数
+}
+
+// ListResponse 列表接口的包装格式
+type ListResponse struct {
+	Data any      `json:"data"`
+	Meta ListMeta `json:"meta"`
+}
+
+// ImportLineError 导入时单行的错误信息
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult JSONL 导入结果
+type ImportResult struct {
+	Lines    int               `json:"lines"`
+	Imported int               `json:"imported"`
+	Failed   int               `json:"failed"`
+	Counts   map[string]int    `json:"counts"`
+	Errors   []ImportLineError `json:"errors"`
+}
+
+// StreamIngestResult NDJSON 流式上报结果
+// ErrorCounts 按事件类型统计失败的行数，无法解析或类型不合法的行归入 StreamUnknownType
+type StreamIngestResult struct {
+	Lines       int               `json:"lines"`
+	Ingested    int               `json:"ingested"`
+	Failed      int               `json:"failed"`
+	Counts      map[string]int    `json:"counts"`
+	ErrorCounts map[string]int    `json:"error_counts"`
+	Errors      []ImportLineError `json:"errors"`
+}
+
+// StreamUnknownType 流式上报中无法确定事件类型的行在 ErrorCounts 中的键
+const StreamUnknownType = "unknown"
+
+// DataRange 项目数据的时间范围，项目没有数据时两个时间均为 null
+type DataRange struct {
+	ProjectID    string     `json:"project_id"`
+	MinTimestamp *time.Time `json:"min_timestamp"`
+	MaxTimestamp *time.Time `json:"max_timestamp"`
+}
+
+// ApdexScore Apdex 评分及其组成，满意为 value ≤ T，容忍为 T < value ≤ 4T
+type ApdexScore struct {
+	Name       string   `json:"name"`
+	Threshold  float64  `json:"threshold"`
+	Satisfied  uint64   `json:"satisfied"`
+	Tolerating uint64   `json:"tolerating"`
+	Frustrated uint64   `json:"frustrated"`
+	Total      uint64   `json:"total"`
+	Score      *float64 `json:"score"` // 没有样本时为 null
+	// LowConfidence 样本数少于请求的 min_count，评分可能只反映个别样本
+	LowConfidence bool `json:"low_confidence"`
+}
+
+// URLPercentile 单个页面某性能指标的分位值，URL 已去掉查询参数和锚点
+type URLPercentile struct {
+	URL   string  `json:"url"`
+	Value float64 `json:"value"`
+	Count uint64  `json:"count"`
+}
+
+// Web Vitals 评级
+const (
+	WebVitalRatingGood             = "good"
+	WebVitalRatingNeedsImprovement = "needs-improvement"
+	WebVitalRatingPoor             = "poor"
+)
+
+// WebVitalScore 单个 Web Vitals 指标的分档样本数和占比
+type WebVitalScore struct {
+	Name                  string   `json:"name"`
+	GoodThreshold         float64  `json:"good_threshold"`
+	PoorThreshold         float64  `json:"poor_threshold"`
+	Good                  uint64   `json:"good"`
+	NeedsImprovement      uint64   `json:"needs_improvement"`
+	Poor                  uint64   `json:"poor"`
+	Total                 uint64   `json:"total"`
+	GoodRatio             *float64 `json:"good_ratio"` // 以下字段没有样本时为 null
+	NeedsImprovementRatio *float64 `json:"needs_improvement_ratio"`
+	PoorRatio             *float64 `json:"poor_ratio"`
+	P75                   *float64 `json:"p75"`
+	Rating                *string  `json:"rating"`         // 按 p75 评级
+	LowConfidence         bool     `json:"low_confidence"` // 样本数少于请求的 min_count
+}
+
+// PageStayAverage 平均页面停留时长及样本数
+type PageStayAverage struct {
+	AveragePageStay float64 `json:"average_page_stay"` // 没有样本时为 0
+	Count           uint64  `json:"count"`
+	LowConfidence   bool    `json:"low_confidence"` // 样本数少于请求的 min_count
+}
+
+// WebVitalsScorecard 核心 Web Vitals 评分卡
+type WebVitalsScorecard struct {
+	StartTime time.Time       `json:"start_time"`
+	EndTime   time.Time       `json:"end_time"`
+	Metrics   []WebVitalScore `json:"metrics"`
+}
+
+// ErrorCount 按错误分组统计的错误计数
+type ErrorCount struct {
+	Fingerprint string `json:"fingerprint"`
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Message     string `json:"message"`
+	Count       uint64 `json:"count"`
+}
+
+// ErrorFingerprint 计算错误分组的指纹，(type, name, message) 相同的错误属于同一分组
+// 指纹为 type、name、message 以 \x00 连接后的 MD5 十六进制小写形式，与仓库层 SQL 中的计算方式一致
+func ErrorFingerprint(errorType, name, message string) string {
+	sum := md5.Sum([]byte(errorType + "\x00" + name + "\x00" + message))
+	return hex.EncodeToString(sum[:])
+}
+
+// TrendBucket 趋势中单个时间桶的计数
+type TrendBucket struct {
+	Time  time.Time `json:"time"`
+	Count uint64    `json:"count"`
+}
+
+// ErrorGroupTrend 单个错误分组在时间范围内按时间桶统计的出现次数，没有数据的桶计数为 0
+type ErrorGroupTrend struct {
+	Fingerprint string        `json:"fingerprint"`
+	Type        string        `json:"type"`
+	Name        string        `json:"name"`
+	Message     string        `json:"message"`
+	StartTime   time.Time     `json:"start_time"`
+	EndTime     time.Time     `json:"end_time"`
+	Interval    string        `json:"interval"`
+	Timezone    string        `json:"timezone"`
+	Total       uint64        `json:"total"`
+	Buckets     []TrendBucket `json:"buckets"`
+}
+
+// CoOccurringError 与目标错误出现在同一会话中的其他错误
+type CoOccurringError struct {
+	Name        string `json:"name"`
+	Sessions    uint64 `json:"sessions"`    // 同时出现该错误和目标错误的会话数
+	Occurrences uint64 `json:"occurrences"` // 这些会话中该错误的出现次数
+}
+
+// ErrorCoOccurrence 目标错误所在会话中的其他错误统计，按会话数降序排列
+type ErrorCoOccurrence struct {
+	Name      string             `json:"name"`
+	StartTime time.Time          `json:"start_time"`
+	EndTime   time.Time          `json:"end_time"`
+	Sessions  uint64             `json:"sessions"` // 出现目标错误的会话数
+	Errors    []CoOccurringError `json:"errors"`
+}
+
+// Overview 各类事件在时间范围内的数量，用于看板顶部的计数器
+type Overview struct {
+	ProjectIDs         []string  `json:"project_ids"`
+	StartTime          time.Time `json:"start_time"`
+	EndTime            time.Time `json:"end_time"`
+	ErrorLogs          uint64    `json:"error_logs"`
+	PerformanceMetrics uint64    `json:"performance_metrics"`
+	UserActions        uint64    `json:"user_actions"`
+	CustomEvents       uint64    `json:"custom_events"`
+	PageStays          uint64    `json:"page_stays"`
+	AveragePageStay    float64   `json:"average_page_stay"`
+	Sessions           uint64    `json:"sessions"` // 时间范围内有事件的不同会话数
+}
+
+// DashboardSummary 项目看板摘要
+type DashboardSummary struct {
+	ProjectID       string       `json:"project_id"`
+	Window          string       `json:"window"`
+	StartTime       time.Time    `json:"start_time"`
+	EndTime         time.Time    `json:"end_time"`
+	ErrorCount      uint64       `json:"error_count"`
+	AveragePageStay float64      `json:"average_page_stay"`
+	ActiveUsers     uint64       `json:"active_users"`
+	TopErrors       []ErrorCount `json:"top_errors"`
+	ComputedAt      time.Time    `json:"computed_at"`
+	Cached          bool         `json:"cached"`
+}
+
+// SummaryRefresh 手动刷新看板摘要的结果
+type SummaryRefresh struct {
+	ProjectID  string    `json:"project_id"`
+	Windows    []string  `json:"windows"` // 已刷新的时间窗口
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// TimelineEvent 时间线中的单个事件
+type TimelineEvent struct {
+	Kind      string    `json:"kind"` // 事件类型，取值同 EventType* 常量
+	Timestamp time.Time `json:"timestamp"`
+	Event     any       `json:"event"`
+}
+
+// Timeline 某个会话或链路在所有事件表中的事件，按时间升序排列
+type Timeline struct {
+	ProjectID string          `json:"project_id"`
+	Field     string          `json:"field"` // session_id / trace_id
+	Value     string          `json:"value"`
+	Events    []TimelineEvent `json:"events"`
+	// Partial 为 true 时部分表查询失败，FailedTables 列出失败的表
+	Partial      bool     `json:"partial"`
+	FailedTables []string `json:"failed_tables,omitempty"`
+}
+
+// HealthCanary 深度健康检查写入的探针行
+type HealthCanary struct {
+	Timestamp time.Time       `json:"timestamp"`
+	ID        string          `json:"id"`
+	Extra     json.RawMessage `json:"extra"`
+}
+
+// HealthCheckStep 深度健康检查中单个步骤的结果
+type HealthCheckStep struct {
+	Name       string `json:"name"` // ping / write / read / verify
+	OK         bool   `json:"ok"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// DeepHealthReport 深度健康检查报告
+type DeepHealthReport struct {
+	Status     string            `json:"status"` // ok / fail
+	CheckedAt  time.Time         `json:"checked_at"`
+	DurationMs int64             `json:"duration_ms"`
+	Steps      []HealthCheckStep `json:"steps"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPerformanceMetricUnmarshalValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"float", `123.4`, 123.4, false},
+		{"integer", `123`, 123, false},
+		{"negative", `-1.5`, -1.5, false},
+		{"exponent", `1e3`, 1000, false},
+		{"string float", `"123.4"`, 123.4, false},
+		{"string integer", `"42"`, 42, false},
+		{"string with spaces", `" 7.5 "`, 7.5, false},
+		{"null", `null`, 0, false},
+		{"string not a number", `"abc"`, 0, true},
+		{"empty string", `""`, 0, true},
+		{"string NaN", `"NaN"`, 0, true},
+		{"string Inf", `"Inf"`, 0, true},
+		{"string -Infinity", `"-Infinity"`, 0, true},
+		{"boolean", `true`, 0, true},
+		{"object", `{}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var metric PerformanceMetric
+			err := json.Unmarshal([]byte(`{"project_id":"web","name":"LCP","value":`+tt.value+`}`), &metric)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(value=%s) succeeded with %v, want error", tt.value, metric.Value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(value=%s) error = %v", tt.value, err)
+			}
+			if metric.Value != tt.want {
+				t.Errorf("Value = %v, want %v", metric.Value, tt.want)
+			}
+			if metric.ProjectID != "web" || metric.Name != "LCP" {
+				t.Errorf("other fields not decoded: %+v", metric)
+			}
+		})
+	}
+}
+
+func TestPerformanceMetricUnmarshalMissingValue(t *testing.T) {
+	var metric PerformanceMetric
+	if err := json.Unmarshal([]byte(`{"project_id":"web","name":"LCP"}`), &metric); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if metric.Value != 0 {
+		t.Errorf("Value = %v, want 0", metric.Value)
+	}
+}
@@ -0,0 +1,814 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"spectra-backend/models"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// topErrorsLimit 看板摘要中返回的高频错误数量
+const topErrorsLimit = 5
+
+// coOccurringErrorsLimit 共现错误查询最多返回的错误名称数量
+const coOccurringErrorsLimit = 50
+
+// errorFingerprintExpr 错误分组指纹的 SQL 表达式，与 models.ErrorFingerprint 的计算方式一致
+const errorFingerprintExpr = "lower(hex(MD5(concat(type, char(0), name, char(0), message))))"
+
+// GetActiveProjects 获取指定时间之后有数据写入的项目列表
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - since: 起始时间
+//
+// 返回:
+//   - []string: 项目标识符列表
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetActiveProjects(ctx context.Context, since time.Time) ([]string, error) {
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables))
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf("SELECT DISTINCT project_id FROM %s WHERE timestamp >= ?", table))
+		args = append(args, since)
+	}
+	query := fmt.Sprintf("SELECT DISTINCT project_id FROM (%s) ORDER BY project_id", strings.Join(subqueries, " UNION ALL "))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active projects: %w", err)
+	}
+	defer rows.Close()
+
+	var projectIDs []string
+	for rows.Next() {
+		var projectID string
+		if err := rows.Scan(&projectID); err != nil {
+			return nil, fmt.Errorf("failed to scan active project: %w", err)
+		}
+		projectIDs = append(projectIDs, projectID)
+	}
+	return projectIDs, rows.Err()
+}
+
+// GetDashboardSummary 计算指定项目在时间范围内的看板摘要
+// 包括错误数、平均页面停留时长、活跃用户数和高频错误
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectID: 项目标识符
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - *models.DashboardSummary: 看板摘要，Window 和缓存相关字段由服务层填充
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetDashboardSummary(ctx context.Context, projectID string, startTime, endTime time.Time) (*models.DashboardSummary, error) {
+	ctx = r.readContext(ctx)
+	summary := &models.DashboardSummary{
+		ProjectID: projectID,
+		StartTime: startTime,
+		EndTime:   endTime,
+		TopErrors: []models.ErrorCount{},
+	}
+
+	// 错误总数
+	errorCountQuery := `SELECT count() FROM error_logs WHERE project_id = ? AND timestamp >= ? AND timestamp <= ?`
+	if err := r.DB.QueryRowContext(ctx, errorCountQuery, projectID, startTime, endTime).Scan(&summary.ErrorCount); err != nil {
+		return nil, fmt.Errorf("failed to query error count: %w", err)
+	}
+
+	// 平均页面停留时长，没有数据时 avg 返回 nan，转换为 0；FINAL 去重同一会话同一页面的多次上报
+	avgQuery := `SELECT ifNotFinite(avg(value), 0) FROM page_stay FINAL WHERE project_id = ? AND timestamp >= ? AND timestamp <= ?`
+	if err := r.DB.QueryRowContext(ctx, avgQuery, projectID, startTime, endTime).Scan(&summary.AveragePageStay); err != nil {
+		return nil, fmt.Errorf("failed to query average page stay: %w", err)
+	}
+
+	// 活跃用户数，合并所有事件表中的非空 user_id
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables)*3)
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT user_id FROM %s WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? AND user_id != ''", table))
+		args = append(args, projectID, startTime, endTime)
+	}
+	usersQuery := fmt.Sprintf("SELECT uniqExact(user_id) FROM (%s)", strings.Join(subqueries, " UNION ALL "))
+	if err := r.DB.QueryRowContext(ctx, usersQuery, args...).Scan(&summary.ActiveUsers); err != nil {
+		return nil, fmt.Errorf("failed to query active users: %w", err)
+	}
+
+	// 高频错误
+	topErrorsQuery := fmt.Sprintf(`SELECT %s AS fingerprint, type, name, message, count() AS cnt
+		FROM error_logs
+		WHERE project_id = ? AND timestamp >= ? AND timestamp <= ?
+		GROUP BY type, name, message
+		ORDER BY cnt DESC
+		LIMIT ?`, errorFingerprintExpr)
+	rows, err := r.DB.QueryContext(ctx, topErrorsQuery, projectID, startTime, endTime, topErrorsLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top errors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var errorCount models.ErrorCount
+		if err := rows.Scan(&errorCount.Fingerprint, &errorCount.Type, &errorCount.Name, &errorCount.Message, &errorCount.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan top error: %w", err)
+		}
+		summary.TopErrors = append(summary.TopErrors, errorCount)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate top errors: %w", err)
+	}
+
+	return summary, nil
+}
+
+// GetOverview 并发统计各事件表在时间范围内的事件数、平均页面停留时长和会话数
+// 参数:
+//   - ctx: 上下文对象，所有查询共享其超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - *models.Overview: 各类事件的数量，任一查询失败即取消其余查询并返回错误
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetOverview(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.Overview, error) {
+	overview := &models.Overview{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+	}
+	where := fmt.Sprintf("WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?", inPlaceholders(len(projectIDs)))
+	args := projectArgs(projectIDs, startTime, endTime)
+
+	counts := []struct {
+		table string
+		dest  *uint64
+	}{
+		{"error_logs", &overview.ErrorLogs},
+		{"performance_metrics", &overview.PerformanceMetrics},
+		{"user_actions", &overview.UserActions},
+		{"custom_events", &overview.CustomEvents},
+	}
+
+	group, groupCtx := errgroup.WithContext(r.readContext(ctx))
+	for _, count := range counts {
+		group.Go(func() error {
+			query := fmt.Sprintf("SELECT count() FROM %s %s", count.table, where)
+			if err := r.DB.QueryRowContext(groupCtx, query, args...).Scan(count.dest); err != nil {
+				return fmt.Errorf("failed to count %s: %w", count.table, err)
+			}
+			return nil
+		})
+	}
+	group.Go(func() error {
+		query, sessionArgs := sessionsSubquery(projectIDs, startTime, endTime, nil)
+		query = fmt.Sprintf("SELECT uniqExact(project_id, session_id) FROM (%s)", query)
+		if err := r.DB.QueryRowContext(groupCtx, query, sessionArgs...).Scan(&overview.Sessions); err != nil {
+			return fmt.Errorf("failed to count sessions: %w", err)
+		}
+		return nil
+	})
+	// 页面停留与平均值使用同一查询，FINAL 去重同一会话同一页面的多次上报，没有数据时 avg 返回 nan，转换为 0
+	group.Go(func() error {
+		query := fmt.Sprintf("SELECT count(), ifNotFinite(avg(value), 0) FROM page_stay FINAL %s", where)
+		if err := r.DB.QueryRowContext(groupCtx, query, args...).Scan(&overview.PageStays, &overview.AveragePageStay); err != nil {
+			return fmt.Errorf("failed to count page_stay: %w", err)
+		}
+		return nil
+	})
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	return overview, nil
+}
+
+// GetSeverityCounts 按严重程度统计时间范围内的错误数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - []models.SeverityCount: 只包含有数据的严重程度，空值由服务层补齐
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetSeverityCounts(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]models.SeverityCount, error) {
+	query := fmt.Sprintf(`SELECT severity, count()
+		FROM error_logs
+		WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?
+		GROUP BY severity`, inPlaceholders(len(projectIDs)))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, startTime, endTime)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query severity counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []models.SeverityCount
+	for rows.Next() {
+		var count models.SeverityCount
+		if err := rows.Scan(&count.Severity, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan severity count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate severity counts: %w", err)
+	}
+	return counts, nil
+}
+
+// GetStatusClassCounts 按 HTTP 状态码类别统计时间范围内的用户行为数，没有状态码的行为不参与统计
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - map[int]uint64: 状态码类别（5 表示 5xx）到行为数的映射，只包含有数据的类别
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetStatusClassCounts(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (map[int]uint64, error) {
+	query := fmt.Sprintf(`SELECT intDiv(status, 100) AS class, count()
+		FROM user_actions
+		WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ? AND status > 0
+		GROUP BY class`, inPlaceholders(len(projectIDs)))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, startTime, endTime)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status class counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]uint64)
+	for rows.Next() {
+		var class uint16
+		var count uint64
+		if err := rows.Scan(&class, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan status class count: %w", err)
+		}
+		counts[int(class)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate status class counts: %w", err)
+	}
+	return counts, nil
+}
+
+// trendBucketExpr 返回时间桶的 SQL 表达式及其参数
+// 非 UTC 时区先取该时区的本地时钟读数再按纪元对齐，天级时间桶的边界为当地零点，结果需经 localBucketTime 转换
+func trendBucketExpr(interval time.Duration, loc *time.Location) (string, []any) {
+	seconds := int64(interval / time.Second)
+	if loc == time.UTC {
+		return fmt.Sprintf("toStartOfInterval(timestamp, INTERVAL %d SECOND)", seconds), nil
+	}
+	return fmt.Sprintf("toStartOfInterval(toDateTime(formatDateTime(timestamp, '%%Y-%%m-%%d %%H:%%i:%%S', ?), 'UTC'), INTERVAL %d SECOND)", seconds),
+		[]any{loc.String()}
+}
+
+// localBucketTime 将以 UTC 表示的本地时钟读数转换为 loc 时区中的时刻
+func localBucketTime(bucket time.Time, loc *time.Location) time.Time {
+	b := bucket.UTC()
+	return time.Date(b.Year(), b.Month(), b.Day(), b.Hour(), b.Minute(), b.Second(), 0, loc)
+}
+
+// GetErrorGroupTrend 按时间桶统计单个错误分组的出现次数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - fingerprint: 错误分组指纹，见 models.ErrorFingerprint
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - interval: 时间桶大小，按 loc 时区的本地时间对齐
+//   - loc: 时间桶所在时区
+//
+// 返回:
+//   - *models.ErrorGroupTrend: 只包含有数据的时间桶，空桶由服务层补齐；时间范围内没有该分组时为 nil
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetErrorGroupTrend(ctx context.Context, projectIDs []string, fingerprint string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.ErrorGroupTrend, error) {
+	bucketExpr, args := trendBucketExpr(interval, loc)
+	query := fmt.Sprintf(`SELECT %s AS bucket, count(), any(type), any(name), any(message)
+		FROM error_logs
+		WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ? AND %s = ?
+		GROUP BY bucket
+		ORDER BY bucket`, bucketExpr, inPlaceholders(len(projectIDs)), errorFingerprintExpr)
+	args = append(args, projectArgs(projectIDs, startTime, endTime, fingerprint)...)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error group trend: %w", err)
+	}
+	defer rows.Close()
+
+	var trend *models.ErrorGroupTrend
+	for rows.Next() {
+		var bucket models.TrendBucket
+		var errorType, name, message string
+		if err := rows.Scan(&bucket.Time, &bucket.Count, &errorType, &name, &message); err != nil {
+			return nil, fmt.Errorf("failed to scan error group trend: %w", err)
+		}
+		bucket.Time = localBucketTime(bucket.Time, loc)
+		if trend == nil {
+			trend = &models.ErrorGroupTrend{
+				Fingerprint: fingerprint,
+				Type:        errorType,
+				Name:        name,
+				Message:     message,
+			}
+		}
+		trend.Buckets = append(trend.Buckets, bucket)
+		trend.Total += bucket.Count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate error group trend: %w", err)
+	}
+	return trend, nil
+}
+
+// GetIngestionRate 按时间桶统计所有事件表合计写入的事件数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - interval: 时间桶大小，按 loc 时区的本地时间对齐
+//   - loc: 时间桶所在时区
+//
+// 返回:
+//   - []models.TrendBucket: 只包含有数据的时间桶，空桶由服务层补齐
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetIngestionRate(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) ([]models.TrendBucket, error) {
+	bucketExpr, args := trendBucketExpr(interval, loc)
+	subqueries := make([]string, 0, len(eventTables))
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT timestamp FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf(`SELECT %s AS bucket, count()
+		FROM (%s)
+		GROUP BY bucket
+		ORDER BY bucket`, bucketExpr, strings.Join(subqueries, " UNION ALL "))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ingestion rate: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []models.TrendBucket
+	for rows.Next() {
+		var bucket models.TrendBucket
+		if err := rows.Scan(&bucket.Time, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan ingestion rate: %w", err)
+		}
+		bucket.Time = localBucketTime(bucket.Time, loc)
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate ingestion rate: %w", err)
+	}
+	return buckets, nil
+}
+
+// sessionsSubquery 返回合并所有事件表中非空会话的子查询及其参数，结果包含 project_id、session_id、timestamp 三列
+// 不同项目可能使用相同的 session_id，统计时按 (project_id, session_id) 去重；filters 对每张表的事件生效
+func sessionsSubquery(projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter) (string, []any) {
+	filterSQL, filterArgs := extraFilterSQL(filters)
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables)*(len(projectIDs)+2+len(filterArgs)))
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT project_id, session_id, timestamp FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ? AND session_id != ''%s",
+			table, inPlaceholders(len(projectIDs)), filterSQL))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+		args = append(args, filterArgs...)
+	}
+	return strings.Join(subqueries, " UNION ALL "), args
+}
+
+// GetSessionCount 统计时间范围内所有事件表中的不同会话数，session_id 为空的事件不计入
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - filters: extra 和列过滤条件，为空时统计全部事件
+//
+// 返回:
+//   - uint64: 不同会话数
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetSessionCount(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter) (uint64, error) {
+	subquery, args := sessionsSubquery(projectIDs, startTime, endTime, filters)
+	query := fmt.Sprintf("SELECT uniqExact(project_id, session_id) FROM (%s)", subquery)
+
+	var count uint64
+	if err := r.DB.QueryRowContext(r.readContext(ctx), query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to query session count: %w", err)
+	}
+	return count, nil
+}
+
+// GetCrashFreeCounts 统计时间范围内的不同会话数和用户数，以及其中发生过指定严重程度错误的会话数和用户数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - severities: 视为崩溃的错误严重程度，不能为空
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - filters: extra 和列过滤条件，为空时统计全部事件
+//
+// 返回:
+//   - *models.CrashFreeStats: 只填充会话数和用户数，占比由服务层计算
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetCrashFreeCounts(ctx context.Context, projectIDs []string, severities []string, startTime, endTime time.Time, filters []models.ExtraFilter) (*models.CrashFreeStats, error) {
+	filterSQL, filterArgs := extraFilterSQL(filters)
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables)*(len(projectIDs)+2+len(filterArgs))+len(severities))
+	for _, table := range eventTables {
+		crashed := "0"
+		if table == eventTypeTables[models.EventTypeErrorLog] {
+			crashed = fmt.Sprintf("severity IN (%s)", inPlaceholders(len(severities)))
+			for _, severity := range severities {
+				args = append(args, severity)
+			}
+		}
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT project_id, session_id, user_id, %s AS crashed FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?%s",
+			crashed, table, inPlaceholders(len(projectIDs)), filterSQL))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+		args = append(args, filterArgs...)
+	}
+	query := fmt.Sprintf(`SELECT
+			uniqExactIf(project_id, session_id, session_id != ''),
+			uniqExactIf(project_id, session_id, session_id != '' AND crashed),
+			uniqExactIf(project_id, user_id, user_id != ''),
+			uniqExactIf(project_id, user_id, user_id != '' AND crashed)
+		FROM (%s)`, strings.Join(subqueries, " UNION ALL "))
+
+	stats := &models.CrashFreeStats{ProjectIDs: projectIDs, StartTime: startTime, EndTime: endTime, Severities: severities}
+	if err := r.DB.QueryRowContext(r.readContext(ctx), query, args...).Scan(
+		&stats.Sessions, &stats.CrashedSessions, &stats.Users, &stats.CrashedUsers); err != nil {
+		return nil, fmt.Errorf("failed to query crash-free counts: %w", err)
+	}
+	return stats, nil
+}
+
+// GetSessionCountBuckets 按时间桶统计活跃的不同会话数，跨桶的会话在每个桶中各计一次
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - interval: 时间桶大小，按 loc 时区的本地时间对齐
+//   - loc: 时间桶所在时区
+//   - filters: extra 和列过滤条件，为空时统计全部事件
+//
+// 返回:
+//   - []models.TrendBucket: 只包含有数据的时间桶，空桶由服务层补齐
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetSessionCountBuckets(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location, filters []models.ExtraFilter) ([]models.TrendBucket, error) {
+	bucketExpr, args := trendBucketExpr(interval, loc)
+	subquery, subqueryArgs := sessionsSubquery(projectIDs, startTime, endTime, filters)
+	query := fmt.Sprintf(`SELECT %s AS bucket, uniqExact(project_id, session_id)
+		FROM (%s)
+		GROUP BY bucket
+		ORDER BY bucket`, bucketExpr, subquery)
+	args = append(args, subqueryArgs...)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session count buckets: %w", err)
+	}
+	defer rows.Close()
+
+	var buckets []models.TrendBucket
+	for rows.Next() {
+		var bucket models.TrendBucket
+		if err := rows.Scan(&bucket.Time, &bucket.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan session count bucket: %w", err)
+		}
+		bucket.Time = localBucketTime(bucket.Time, loc)
+		buckets = append(buckets, bucket)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate session count buckets: %w", err)
+	}
+	return buckets, nil
+}
+
+// CountEvents 统计时间范围内所有事件表合计的事件数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - uint64: 五张事件表的事件数之和，不去重
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) CountEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (uint64, error) {
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables)*(len(projectIDs)+2))
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT count() AS cnt FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf("SELECT sum(cnt) FROM (%s)", strings.Join(subqueries, " UNION ALL "))
+
+	var count uint64
+	if err := r.DB.QueryRowContext(r.readContext(ctx), query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	return count, nil
+}
+
+// GetActivityHeatmap 按星期和小时统计事件数，用于流量规律分析
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - eventType: 统计的事件类型，为空时统计所有事件表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - loc: 星期和小时按该时区的本地时间计算
+//
+// 返回:
+//   - []models.HeatmapCell: 只包含有数据的单元格，空单元格由服务层补齐
+//   - error: 事件类型不合法或查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetActivityHeatmap(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, loc *time.Location) ([]models.HeatmapCell, error) {
+	tables := eventTables
+	if eventType != "" {
+		table, ok := eventTypeTables[eventType]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		tables = []string{table}
+	}
+
+	args := []any{loc.String(), loc.String()}
+	subqueries := make([]string, 0, len(tables))
+	for _, table := range tables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT timestamp FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf(`SELECT toDayOfWeek(toTimeZone(timestamp, ?)) AS day, toHour(toTimeZone(timestamp, ?)) AS hour, count()
+		FROM (%s)
+		GROUP BY day, hour
+		ORDER BY day, hour`, strings.Join(subqueries, " UNION ALL "))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activity heatmap: %w", err)
+	}
+	defer rows.Close()
+
+	var cells []models.HeatmapCell
+	for rows.Next() {
+		var day, hour uint8
+		var count uint64
+		if err := rows.Scan(&day, &hour, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan activity heatmap: %w", err)
+		}
+		cells = append(cells, models.HeatmapCell{DayOfWeek: int(day), Hour: int(hour), Count: count})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate activity heatmap: %w", err)
+	}
+	return cells, nil
+}
+
+// GetSDKVersionBreakdown 按 SDK 版本和平台统计事件数，用于定位某个 SDK 版本引入的问题
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - eventType: 统计的事件类型，为空时统计所有事件表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - []models.SDKVersionCount: 按事件数降序排列，未上报版本的事件归入版本和平台为空的一项
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetSDKVersionBreakdown(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time) ([]models.SDKVersionCount, error) {
+	tables := eventTables
+	if eventType != "" {
+		table, ok := eventTypeTables[eventType]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		tables = []string{table}
+	}
+
+	var args []any
+	subqueries := make([]string, 0, len(tables))
+	for _, table := range tables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT sdk_version, platform, timestamp FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf(`SELECT sdk_version, platform, count() AS cnt, min(timestamp), max(timestamp)
+		FROM (%s)
+		GROUP BY sdk_version, platform
+		ORDER BY cnt DESC, sdk_version, platform`, strings.Join(subqueries, " UNION ALL "))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sdk version breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []models.SDKVersionCount
+	for rows.Next() {
+		var version models.SDKVersionCount
+		if err := rows.Scan(&version.SDKVersion, &version.Platform, &version.Count, &version.FirstSeen, &version.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan sdk version breakdown: %w", err)
+		}
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sdk version breakdown: %w", err)
+	}
+	return versions, nil
+}
+
+// GetEventsByReferrerDomain 按 referrer 的域名统计事件数、错误数和会话数，用于分析流量来源
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - eventType: 统计的事件类型，为空时统计所有事件表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - limit: 最多返回的域名数
+//
+// 返回:
+//   - []models.ReferrerCount: 按事件数降序排列，没有 referrer 或无法解析出域名的事件归入 direct
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetEventsByReferrerDomain(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, limit int) ([]models.ReferrerCount, error) {
+	tables := eventTables
+	if eventType != "" {
+		table, ok := eventTypeTables[eventType]
+		if !ok {
+			return nil, fmt.Errorf("unknown event type %q", eventType)
+		}
+		tables = []string{table}
+	}
+
+	var args []any
+	subqueries := make([]string, 0, len(tables))
+	for _, table := range tables {
+		isError := 0
+		if table == "error_logs" {
+			isError = 1
+		}
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT domain(referrer) AS d, project_id, session_id, %d AS is_error FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			isError, table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf(`SELECT if(d = '', ?, d) AS source, count() AS cnt, sum(is_error), uniqExactIf((project_id, session_id), session_id != '')
+		FROM (%s)
+		GROUP BY source
+		ORDER BY cnt DESC, source
+		LIMIT ?`, strings.Join(subqueries, " UNION ALL "))
+	args = append([]any{models.DirectReferrer}, args...)
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query referrer breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var referrers []models.ReferrerCount
+	for rows.Next() {
+		var referrer models.ReferrerCount
+		if err := rows.Scan(&referrer.Domain, &referrer.Events, &referrer.Errors, &referrer.Sessions); err != nil {
+			return nil, fmt.Errorf("failed to scan referrer breakdown: %w", err)
+		}
+		referrers = append(referrers, referrer)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate referrer breakdown: %w", err)
+	}
+	return referrers, nil
+}
+
+// GetErrorsByRelease 按 sdk_version 统计错误数和所有事件表中的事件数，用于版本健康度监控
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - limit: 最多返回的版本数
+//
+// 返回:
+//   - []models.ReleaseErrorCount: 按错误数降序排列，未计算错误率；未上报版本的事件归入版本为空的一项
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetErrorsByRelease(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]models.ReleaseErrorCount, error) {
+	var args []any
+	subqueries := make([]string, 0, len(eventTables))
+	for _, table := range eventTables {
+		isError := 0
+		if table == "error_logs" {
+			isError = 1
+		}
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT sdk_version, timestamp, %d AS is_error FROM %s WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?",
+			isError, table, inPlaceholders(len(projectIDs))))
+		args = append(args, projectArgs(projectIDs, startTime, endTime)...)
+	}
+	query := fmt.Sprintf(`SELECT sdk_version, count() AS cnt, sum(is_error) AS errors, min(timestamp), max(timestamp)
+		FROM (%s)
+		GROUP BY sdk_version
+		ORDER BY errors DESC, cnt DESC, sdk_version
+		LIMIT ?`, strings.Join(subqueries, " UNION ALL "))
+	args = append(args, limit)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query errors by release: %w", err)
+	}
+	defer rows.Close()
+
+	var releases []models.ReleaseErrorCount
+	for rows.Next() {
+		var release models.ReleaseErrorCount
+		if err := rows.Scan(&release.Release, &release.Events, &release.Errors, &release.FirstSeen, &release.LastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan errors by release: %w", err)
+		}
+		releases = append(releases, release)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate errors by release: %w", err)
+	}
+	return releases, nil
+}
+
+// GetCoOccurringErrors 统计与指定错误出现在同一会话中的其他错误
+// 先找出时间范围内包含 errorName 的会话，再按错误名称统计这些会话中的其他错误；session_id 为空的错误不参与统计
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表，会话按 (project_id, session_id) 区分
+//   - errorName: 目标错误名称
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - *models.ErrorCoOccurrence: 按会话数降序排列，最多 coOccurringErrorsLimit 个错误名称
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetCoOccurringErrors(ctx context.Context, projectIDs []string, errorName string, startTime, endTime time.Time) (*models.ErrorCoOccurrence, error) {
+	ctx = r.readContext(ctx)
+	sessionFilter := fmt.Sprintf(`project_id IN (%s) AND timestamp >= ? AND timestamp <= ? AND session_id != ''`,
+		inPlaceholders(len(projectIDs)))
+
+	result := &models.ErrorCoOccurrence{
+		Name:      errorName,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Errors:    []models.CoOccurringError{},
+	}
+
+	sessionsQuery := fmt.Sprintf(`SELECT uniqExact(project_id, session_id) FROM error_logs WHERE %s AND name = ?`, sessionFilter)
+	if err := r.DB.QueryRowContext(ctx, sessionsQuery, projectArgs(projectIDs, startTime, endTime, errorName)...).Scan(&result.Sessions); err != nil {
+		return nil, fmt.Errorf("failed to count error sessions: %w", err)
+	}
+	if result.Sessions == 0 {
+		return result, nil
+	}
+
+	query := fmt.Sprintf(`SELECT name, uniqExact(project_id, session_id) AS sessions, count() AS occurrences
+		FROM error_logs
+		WHERE %s AND name != ?
+			AND (project_id, session_id) IN (SELECT project_id, session_id FROM error_logs WHERE %s AND name = ?)
+		GROUP BY name
+		ORDER BY sessions DESC, occurrences DESC, name
+		LIMIT %d`, sessionFilter, sessionFilter, coOccurringErrorsLimit)
+
+	args := projectArgs(projectIDs, startTime, endTime, errorName)
+	args = append(args, projectArgs(projectIDs, startTime, endTime, errorName)...)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query co-occurring errors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var coOccurring models.CoOccurringError
+		if err := rows.Scan(&coOccurring.Name, &coOccurring.Sessions, &coOccurring.Occurrences); err != nil {
+			return nil, fmt.Errorf("failed to scan co-occurring error: %w", err)
+		}
+		result.Errors = append(result.Errors, coOccurring)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate co-occurring errors: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"spectra-backend/models"
+	"time"
+)
+
+// errorPerformanceLookback 关联性能指标时向前查找的最长时间，限制 JOIN 右表的扫描范围
+const errorPerformanceLookback = time.Hour
+
+// GetErrorLogsWithPerformance 获取时间范围内的错误日志，并通过 ASOF JOIN 关联同一会话中在错误之前（含同一时刻）最近的一条性能指标
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表，会话按 (project_id, session_id) 区分
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - limit: 最多返回的错误数，按时间倒序
+//
+// 返回:
+//   - []*models.ErrorWithPerformance: 错误及其性能上下文，没有 session_id 或会话中没有更早的性能指标时 Performance 为 nil
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetErrorLogsWithPerformance(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]*models.ErrorWithPerformance, error) {
+	// join_use_nulls 使未匹配的右表列为 NULL，以区分没有性能指标和性能指标为零值
+	query := fmt.Sprintf(`SELECT e.timestamp, e.project_id, e.session_id, e.trace_id, e.user_id, e.url, e.referrer, e.sdk_version, e.platform, e.type, e.name, e.message,
+			e.severity, e.stack_hash, e.extra,
+			p.timestamp, p.trace_id, p.user_id, p.url, p.referrer, p.sdk_version, p.platform, p.type, p.name, p.value, p.extra
+		FROM (
+			SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message,
+				severity, stack_hash, CAST(extra AS String) AS extra
+			FROM error_logs
+			WHERE project_id IN (%[1]s) AND timestamp >= ? AND timestamp <= ?
+		) AS e
+		ASOF LEFT JOIN (
+			SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value,
+				CAST(extra AS String) AS extra
+			FROM performance_metrics
+			WHERE project_id IN (%[1]s) AND timestamp >= ? AND timestamp <= ? AND session_id != ''
+		) AS p
+		ON e.project_id = p.project_id AND e.session_id = p.session_id AND e.timestamp >= p.timestamp
+		ORDER BY e.timestamp DESC
+		LIMIT %[2]d
+		SETTINGS join_use_nulls = 1`, inPlaceholders(len(projectIDs)), limit)
+
+	args := projectArgs(projectIDs, startTime, endTime)
+	args = append(args, projectArgs(projectIDs, startTime.Add(-errorPerformanceLookback), endTime)...)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error logs with performance: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*models.ErrorWithPerformance
+	for rows.Next() {
+		var log models.ErrorLog
+		var extraStr sql.NullString
+		var metricTime sql.NullTime
+		var metricTraceID, metricUserID, metricURL, metricReferrer, metricSDKVersion, metricPlatform, metricType, metricName, metricExtra sql.NullString
+		var metricValue sql.NullFloat64
+		if err := rows.Scan(&log.Timestamp, &log.ProjectID, &log.SessionID, &log.TraceID, &log.UserID,
+			&log.URL, &log.Referrer, &log.SDKVersion, &log.Platform, &log.Type, &log.Name, &log.Message, &log.Severity, &log.StackHash, &extraStr,
+			&metricTime, &metricTraceID, &metricUserID, &metricURL, &metricReferrer, &metricSDKVersion, &metricPlatform, &metricType, &metricName,
+			&metricValue, &metricExtra); err != nil {
+			return nil, fmt.Errorf("failed to scan error log with performance: %w", err)
+		}
+		log.Extra = timelineExtra(extraStr)
+
+		result := &models.ErrorWithPerformance{Error: &log}
+		if metricTime.Valid {
+			result.Performance = &models.PerformanceMetric{
+				BaseLog: models.BaseLog{
+					Timestamp:  metricTime.Time,
+					ProjectID:  log.ProjectID,
+					SessionID:  log.SessionID,
+					TraceID:    metricTraceID.String,
+					UserID:     metricUserID.String,
+					URL:        metricURL.String,
+					Referrer:   metricReferrer.String,
+					SDKVersion: metricSDKVersion.String,
+					Platform:   metricPlatform.String,
+					Type:       metricType.String,
+					Name:       metricName.String,
+					Extra:      timelineExtra(metricExtra),
+				},
+				Value: metricValue.Float64,
+			}
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate error logs with performance: %w", err)
+	}
+	return results, nil
+}
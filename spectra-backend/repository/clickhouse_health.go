@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"spectra-backend/models"
+)
+
+// Ping 检查数据库连接是否可用
+func (r *ClickHouseRepository) Ping(ctx context.Context) error {
+	if err := r.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// WarmUp 同时建立 n 个连接并逐个 ping，完成后归还连接池作为空闲连接，避免启动后的首批请求建立连接
+// n 超过连接池最大空闲连接数时按上限处理，返回实际预热成功的连接数
+func (r *ClickHouseRepository) WarmUp(ctx context.Context, n int) (int, error) {
+	n = min(n, maxIdleConns)
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	// 先持有全部连接再归还，连接池才会建立 n 个不同的连接
+	for len(conns) < n {
+		conn, err := r.DB.Conn(ctx)
+		if err != nil {
+			return len(conns), fmt.Errorf("failed to open warm-up connection: %w", err)
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return len(conns) - 1, fmt.Errorf("failed to ping warm-up connection: %w", err)
+		}
+	}
+	return len(conns), nil
+}
+
+// SaveHealthCanary 写入深度健康检查探针行
+// 与事件写入使用相同的时间戳和 extra 处理方式，以覆盖完整的写入路径
+func (r *ClickHouseRepository) SaveHealthCanary(ctx context.Context, canary *models.HealthCanary) error {
+	query := `INSERT INTO health_canary (timestamp, id, extra) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?)`
+
+	_, err := r.DB.ExecContext(ctx, query, timestampArg(canary.Timestamp), canary.ID, normalizeJSONRawMessage(canary.Extra))
+	if err != nil {
+		return fmt.Errorf("failed to save health canary: %w", err)
+	}
+	return nil
+}
+
+// GetHealthCanary 按 ID 读取探针行，不存在时返回 nil, nil
+func (r *ClickHouseRepository) GetHealthCanary(ctx context.Context, id string) (*models.HealthCanary, error) {
+	query := `SELECT timestamp, id, CAST(extra AS String) FROM health_canary WHERE id = ? LIMIT 1`
+
+	var canary models.HealthCanary
+	var extraStr sql.NullString
+	err := r.DB.QueryRowContext(ctx, query, id).Scan(&canary.Timestamp, &canary.ID, &extraStr)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get health canary: %w", err)
+	}
+
+	if extraStr.Valid {
+		canary.Extra = json.RawMessage(extraStr.String)
+	}
+	return &canary, nil
+}
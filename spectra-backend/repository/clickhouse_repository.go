@@ -11,8 +11,8 @@ import (
     "strings"
     "time"
 
-    // 匿名导入 ClickHouse 驱动以确保驱动被正确注册
-    _ "github.com/ClickHouse/clickhouse-go/v2"
+    // 导入 ClickHouse 驱动，同时注册 database/sql 驱动
+    "github.com/ClickHouse/clickhouse-go/v2"
     "go.uber.org/zap"
 )
 
@@ -22,10 +22,20 @@ import (
 // ClickHouseRepository 是Repository接口的ClickHouse具体实现
 // 负责与ClickHouse数据库进行交互，执行所有数据存取操作
 type ClickHouseRepository struct {
-	DB     *sql.DB     // 数据库连接对象
-	Logger *zap.Logger // 日志记录器
+	DB             *sql.DB             // 数据库连接对象
+	Logger         *zap.Logger         // 日志记录器
+	QuerySettings  clickhouse.Settings // 读查询附带的ClickHouse设置
+	InsertSettings clickhouse.Settings // 单条写入附带的ClickHouse设置，未开启 async_insert 时为空
+	// SkipMalformedRows 列表查询遇到无法解析的行时跳过并计数，而不是让整个查询失败
+	SkipMalformedRows bool
+
+	inserts *insertPool // 批量写入工作池
+	stacks  *stackCache // 近期已写入的堆栈哈希，避免重复写入相同堆栈
 }
 
+// maxIdleConns 连接池保留的最大空闲连接数，也是启动预热连接数的上限
+const maxIdleConns = 5
+
 // NewClickHouseRepository 创建ClickHouse仓库实例
 // 参数:
 //   - cfg: 应用程序配置，包含数据库连接信息
@@ -60,7 +70,7 @@ func NewClickHouseRepository(cfg *config.Config, logger *zap.Logger) (*ClickHous
 
 	// 设置连接池参数
 	db.SetMaxOpenConns(10)                 // 最大打开连接数
-	db.SetMaxIdleConns(5)                  // 最大空闲连接数
+	db.SetMaxIdleConns(maxIdleConns)       // 最大空闲连接数
 	db.SetConnMaxLifetime(time.Minute * 5) // 连接最大生命周期
 
 	// 测试数据库连接是否正常
@@ -71,13 +81,58 @@ func NewClickHouseRepository(cfg *config.Config, logger *zap.Logger) (*ClickHous
 	}
 
 	logger.Info("Successfully connected to ClickHouse database")
+
+	// 转换读查询设置，例如 max_execution_time、max_memory_usage
+	querySettings := make(clickhouse.Settings, len(cfg.DB.QuerySettings))
+	for key, value := range cfg.DB.QuerySettings {
+		querySettings[key] = value
+	}
+
+	// 开启 async_insert 时为单条写入附加服务端缓冲设置
+	var insertSettings clickhouse.Settings
+	if cfg.DB.AsyncInsert {
+		waitForAsyncInsert := 0
+		if cfg.DB.AsyncInsertWait {
+			waitForAsyncInsert = 1
+		}
+		insertSettings = clickhouse.Settings{
+			"async_insert":          1,
+			"wait_for_async_insert": waitForAsyncInsert,
+		}
+		logger.Info("ClickHouse async insert enabled", zap.Bool("wait_for_async_insert", cfg.DB.AsyncInsertWait))
+	}
+
 	// 返回初始化成功的仓库实例
 	return &ClickHouseRepository{
-		DB:     db,
-		Logger: logger,
+		DB:             db,
+		Logger:         logger,
+		QuerySettings:  querySettings,
+		InsertSettings: insertSettings,
+		inserts:        newInsertPool(cfg.DB.InsertWorkers),
+		stacks:         newStackCache(),
+
+		SkipMalformedRows: cfg.DB.SkipMalformedRows,
 	}, nil
 }
 
+// readContext 为读查询附加配置的ClickHouse设置
+// 只用于 Get* 方法，写入路径不受这些限制影响
+func (r *ClickHouseRepository) readContext(ctx context.Context) context.Context {
+	if len(r.QuerySettings) == 0 {
+		return ctx
+	}
+	return clickhouse.Context(ctx, clickhouse.WithSettings(r.QuerySettings))
+}
+
+// insertContext 为单条写入附加 async_insert 设置
+// 只用于 Save* 单条写入方法，批量写入已在客户端分批，不受影响
+func (r *ClickHouseRepository) insertContext(ctx context.Context) context.Context {
+	if len(r.InsertSettings) == 0 {
+		return ctx
+	}
+	return clickhouse.Context(ctx, clickhouse.WithSettings(r.InsertSettings))
+}
+
 // maskPassword 隐藏DSN中的密码信息，避免敏感数据泄露到日志中
 // 参数:
 //   - dsn: 原始DSN字符串
@@ -106,6 +161,119 @@ func maskPassword(dsn string) string {
 	return dsn[:passwordStart] + "***" + dsn[passwordStart+passwordEnd:]
 }
 
+// 各事件表的插入语句，单条保存和批量保存共用
+// timestamp 以毫秒时间戳传入，见 timestampArg
+const (
+	insertErrorLogQuery          = `INSERT INTO error_logs (timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, severity, stack_hash, extra) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertPerformanceMetricQuery = `INSERT INTO performance_metrics (timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, extra) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertUserActionQuery        = `INSERT INTO user_actions (timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, method, status, value, extra) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertCustomEventQuery       = `INSERT INTO custom_events (timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, extra) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertPageStayQuery          = `INSERT INTO page_stay (timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, extra, version) VALUES (fromUnixTimestamp64Milli(toInt64(?)), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+)
+
+// timestampArg 将时间转换为毫秒时间戳
+// 驱动绑定 time.Time 参数时只保留到秒，同一秒内的事件会失去先后顺序，
+// 以毫秒整数传入可保留 DateTime64(3) 的精度，批量写入时驱动同样按毫秒解析
+func timestampArg(t time.Time) int64 {
+	return t.UnixMilli()
+}
+
+// errorLogArgs 按插入语句的列顺序展开错误日志字段
+func errorLogArgs(log *models.ErrorLog) []any {
+	// 规范化 Extra 字段，兼容字符串和对象两种输入
+	extraStr := normalizeJSONRawMessage(log.Extra)
+	return []any{
+		timestampArg(log.Timestamp), log.ProjectID, log.SessionID, log.TraceID, log.UserID,
+		log.URL, log.Referrer, log.SDKVersion, log.Platform, log.Type, log.Name, log.Message, log.Severity, log.StackHash, extraStr,
+	}
+}
+
+// performanceMetricArgs 按插入语句的列顺序展开性能指标字段
+func performanceMetricArgs(metric *models.PerformanceMetric) []any {
+	return []any{
+		timestampArg(metric.Timestamp), metric.ProjectID, metric.SessionID, metric.TraceID, metric.UserID,
+		metric.URL, metric.Referrer, metric.SDKVersion, metric.Platform, metric.Type, metric.Name, metric.Value, extraOrEmpty(metric.Extra),
+	}
+}
+
+// userActionArgs 按插入语句的列顺序展开用户行为字段
+func userActionArgs(action *models.UserAction) []any {
+	return []any{
+		timestampArg(action.Timestamp), action.ProjectID, action.SessionID, action.TraceID, action.UserID,
+		action.URL, action.Referrer, action.SDKVersion, action.Platform, action.Type, action.Name, action.Message, action.Method,
+		action.Status, action.Value, extraOrEmpty(action.Extra),
+	}
+}
+
+// userActionSelectColumns 查询用户行为时选取的列，顺序与 scanUserAction 的扫描顺序一致
+// 除 extra 转为字符串外，列顺序与 insertUserActionQuery 和 userActionArgs 相同，修改时三处需同步
+const userActionSelectColumns = "timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, method, status, value, CAST(extra AS String)"
+
+// rowScanner *sql.Row 和 *sql.Rows 共有的扫描方法
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUserAction 按 userActionSelectColumns 的列顺序扫描一行用户行为，extra 为空时使用空对象
+func scanUserAction(row rowScanner) (*models.UserAction, error) {
+	var action models.UserAction
+	var extraStr sql.NullString
+	err := row.Scan(
+		&action.Timestamp, &action.ProjectID, &action.SessionID, &action.TraceID, &action.UserID,
+		&action.URL, &action.Referrer, &action.SDKVersion, &action.Platform, &action.Type, &action.Name, &action.Message, &action.Method,
+		&action.Status, &action.Value, &extraStr)
+	if err != nil {
+		return nil, err
+	}
+	if extraStr.Valid {
+		action.Extra = json.RawMessage(extraStr.String)
+	} else {
+		action.Extra = json.RawMessage("{}")
+	}
+	return &action, nil
+}
+
+// customEventArgs 按插入语句的列顺序展开自定义事件字段
+func customEventArgs(event *models.CustomEvent) []any {
+	return []any{
+		timestampArg(event.Timestamp), event.ProjectID, event.SessionID, event.TraceID, event.UserID,
+		event.URL, event.Referrer, event.SDKVersion, event.Platform, event.Type, event.Name, event.Message, extraOrEmpty(event.Extra),
+	}
+}
+
+// pageStayArgs 按插入语句的列顺序展开页面停留字段
+// 最后一列 version 为写入时间（毫秒），page_stay 表按 (session_id, url) 去重时保留 version 最大的行
+func pageStayArgs(pageStay *models.PageStay) []any {
+	return []any{
+		timestampArg(pageStay.Timestamp), pageStay.ProjectID, pageStay.SessionID, pageStay.TraceID, pageStay.UserID,
+		pageStay.URL, pageStay.Referrer, pageStay.SDKVersion, pageStay.Platform, pageStay.Type, pageStay.Name, pageStay.Value, extraOrEmpty(pageStay.Extra),
+		uint64(time.Now().UnixMilli()),
+	}
+}
+
+// extraOrEmpty 将Extra字段转换为字符串，如果为空则使用空JSON对象
+func extraOrEmpty(raw json.RawMessage) string {
+	extraStr := string(raw)
+	if extraStr == "" {
+		extraStr = "{}"
+	}
+	return extraStr
+}
+
+// inPlaceholders 生成变长 IN 子句的占位符，例如 "?, ?, ?"
+func inPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// projectArgs 将项目ID列表展开为查询参数，并追加其余参数
+func projectArgs(projectIDs []string, rest ...any) []any {
+	args := make([]any, 0, len(projectIDs)+len(rest))
+	for _, projectID := range projectIDs {
+		args = append(args, projectID)
+	}
+	return append(args, rest...)
+}
+
 // SaveErrorLog 保存错误日志到数据库
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
@@ -114,20 +282,17 @@ func maskPassword(dsn string) string {
 // 返回:
 //   - error: 保存过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) SaveErrorLog(ctx context.Context, log *models.ErrorLog) error {
-    // 定义SQL插入语句，包含错误日志的所有字段
-    query := `INSERT INTO error_logs (timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-    // 规范化 Extra 字段，兼容字符串和对象两种输入
-    extraStr := normalizeJSONRawMessage(log.Extra)
+	// 先写入堆栈，保证错误行引用的堆栈已存在
+	if err := r.saveStackTraces(ctx, []*models.ErrorLog{log}); err != nil {
+		return err
+	}
 
-    // 执行插入操作，使用ExecContext支持上下文取消和超时
-    _, err := r.DB.ExecContext(ctx, query,
-        log.Timestamp, log.ProjectID, log.SessionID, log.TraceID, log.UserID,
-        log.URL, log.Referrer, log.Type, log.Name, log.Message, extraStr)
-    if err != nil {
-        return fmt.Errorf("failed to save error log: %w", err)
-    }
-    return nil
+	// 执行插入操作，使用ExecContext支持上下文取消和超时
+	_, err := r.DB.ExecContext(r.insertContext(ctx), insertErrorLogQuery, errorLogArgs(log)...)
+	if err != nil {
+		return fmt.Errorf("failed to save error log: %w", err)
+	}
+	return nil
 }
 
 // normalizeJSONRawMessage 将 RawMessage 规范化为 ClickHouse JSON 列可接受的对象文本
@@ -157,22 +322,31 @@ func normalizeJSONRawMessage(raw json.RawMessage) string {
 // GetErrorLogs 获取指定项目在时间范围内的错误日志列表
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
+//   - filters: extra 字段过滤条件，为空时不过滤
+//   - severities: 严重程度过滤条件，为空时不过滤
+//   - types: 错误类型过滤条件，为空时返回所有类型
+//   - order: 排序方式
 //
 // 返回:
 //   - []*models.ErrorLog: 错误日志列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetErrorLogs(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.ErrorLog, error) {
-    // 定义SQL查询语句，按时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, CAST(extra AS String) 
+func (r *ClickHouseRepository) GetErrorLogs(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, severities []string, types []string, order models.ListOrder) ([]*models.ErrorLog, error) {
+    extraCondition, extraArgs := extraFilterSQL(filters)
+    severityCondition, severityArgs := severityFilterSQL(severities)
+    typeCondition, typeArgs := errorTypeFilterSQL(types)
+    extraCondition += severityCondition + typeCondition
+    extraArgs = append(append(extraArgs, severityArgs...), typeArgs...)
+    // 定义SQL查询语句，按 order 排序，默认按时间倒序
+    query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, severity, stack_hash, CAST(extra AS String) 
         FROM error_logs 
-        WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?%s 
+        ORDER BY %s`, inPlaceholders(len(projectIDs)), extraCondition, orderBySQL(order))
 
 	// 执行查询，使用QueryContext支持上下文取消和超时
-	rows, err := r.DB.QueryContext(ctx, query, projectID, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, append(projectArgs(projectIDs, startTime, endTime), extraArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query error logs: %w", err)
 	}
@@ -185,8 +359,11 @@ func (r *ClickHouseRepository) GetErrorLogs(ctx context.Context, projectID strin
         var extraStr sql.NullString
         err := rows.Scan(
             &log.Timestamp, &log.ProjectID, &log.SessionID, &log.TraceID, &log.UserID,
-            &log.URL, &log.Referrer, &log.Type, &log.Name, &log.Message, &extraStr)
+            &log.URL, &log.Referrer, &log.SDKVersion, &log.Platform, &log.Type, &log.Name, &log.Message, &log.Severity, &log.StackHash, &extraStr)
         if err != nil {
+            if r.skipRow(ctx, "error_logs", err) {
+                continue
+            }
             return nil, fmt.Errorf("failed to scan error log: %w", err)
         }
         if extraStr.Valid {
@@ -209,7 +386,7 @@ func (r *ClickHouseRepository) GetErrorLogs(ctx context.Context, projectID strin
 //   - error: 查询过程中的错误信息，成功或未找到则为nil
 func (r *ClickHouseRepository) GetErrorLogByTraceID(ctx context.Context, traceID string) (*models.ErrorLog, error) {
 	// 定义SQL查询语句，使用LIMIT 1确保只返回一个结果
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, CAST(extra AS String) 
+    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, severity, stack_hash, CAST(extra AS String) 
         FROM error_logs 
         WHERE trace_id = ? 
         LIMIT 1`
@@ -217,9 +394,9 @@ func (r *ClickHouseRepository) GetErrorLogByTraceID(ctx context.Context, traceID
 	var log models.ErrorLog
 	// 使用QueryRowContext执行查询并直接扫描结果
     var extraStr sql.NullString
-    err := r.DB.QueryRowContext(ctx, query, traceID).Scan(
+    err := r.DB.QueryRowContext(r.readContext(ctx), query, traceID).Scan(
         &log.Timestamp, &log.ProjectID, &log.SessionID, &log.TraceID, &log.UserID,
-        &log.URL, &log.Referrer, &log.Type, &log.Name, &log.Message, &extraStr)
+        &log.URL, &log.Referrer, &log.SDKVersion, &log.Platform, &log.Type, &log.Name, &log.Message, &log.Severity, &log.StackHash, &extraStr)
 
     if err != nil {
         if err == sql.ErrNoRows {
@@ -236,6 +413,60 @@ func (r *ClickHouseRepository) GetErrorLogByTraceID(ctx context.Context, traceID
 	return &log, nil
 }
 
+// traceErrorLogsLimit 按多个 traceID 查询时每个 traceID 最多返回的错误日志数
+const traceErrorLogsLimit = 100
+
+// GetErrorLogsByTraceIDs 批量获取多个 traceID 的错误日志
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - traceIDs: 跟踪标识符列表，不能为空
+//
+// 返回:
+//   - []*models.ErrorLog: 按 trace_id、时间升序排列，每个 traceID 最多 traceErrorLogsLimit 条
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetErrorLogsByTraceIDs(ctx context.Context, traceIDs []string) ([]*models.ErrorLog, error) {
+	query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, severity, stack_hash, CAST(extra AS String)
+		FROM error_logs
+		WHERE trace_id IN (%s)
+		ORDER BY trace_id, timestamp
+		LIMIT %d BY trace_id`, inPlaceholders(len(traceIDs)), traceErrorLogsLimit)
+	args := make([]any, len(traceIDs))
+	for i, traceID := range traceIDs {
+		args[i] = traceID
+	}
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query error logs by trace ids: %w", err)
+	}
+	defer rows.Close()
+
+	var logs []*models.ErrorLog
+	for rows.Next() {
+		var log models.ErrorLog
+		var extraStr sql.NullString
+		err := rows.Scan(
+			&log.Timestamp, &log.ProjectID, &log.SessionID, &log.TraceID, &log.UserID,
+			&log.URL, &log.Referrer, &log.SDKVersion, &log.Platform, &log.Type, &log.Name, &log.Message, &log.Severity, &log.StackHash, &extraStr)
+		if err != nil {
+			if r.skipRow(ctx, "error_logs", err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to scan error log: %w", err)
+		}
+		if extraStr.Valid {
+			log.Extra = json.RawMessage(extraStr.String)
+		} else {
+			log.Extra = json.RawMessage("{}")
+		}
+		logs = append(logs, &log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate error logs by trace ids: %w", err)
+	}
+	return logs, nil
+}
+
 // SavePerformanceMetric 保存性能指标数据到数据库
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
@@ -244,19 +475,8 @@ func (r *ClickHouseRepository) GetErrorLogByTraceID(ctx context.Context, traceID
 // 返回:
 //   - error: 保存过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) SavePerformanceMetric(ctx context.Context, metric *models.PerformanceMetric) error {
-	// 定义SQL插入语句，包含性能指标的所有字段
-	query := `INSERT INTO performance_metrics (timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	// 将Extra字段转换为字符串，如果为空则使用空JSON对象
-	extraStr := string(metric.Extra)
-	if extraStr == "" {
-		extraStr = "{}"
-	}
-
 	// 执行插入操作
-	_, err := r.DB.ExecContext(ctx, query,
-		metric.Timestamp, metric.ProjectID, metric.SessionID, metric.TraceID, metric.UserID,
-		metric.URL, metric.Referrer, metric.Type, metric.Name, metric.Value, extraStr)
+	_, err := r.DB.ExecContext(r.insertContext(ctx), insertPerformanceMetricQuery, performanceMetricArgs(metric)...)
 	if err != nil {
 		return fmt.Errorf("failed to save performance metric: %w", err)
 	}
@@ -266,22 +486,25 @@ func (r *ClickHouseRepository) SavePerformanceMetric(ctx context.Context, metric
 // GetPerformanceMetrics 获取指定项目在时间范围内的性能指标列表
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
+//   - filters: extra 字段过滤条件，为空时不过滤
+//   - order: 排序方式
 //
 // 返回:
 //   - []*models.PerformanceMetric: 性能指标列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetPerformanceMetrics(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
-    // 定义SQL查询语句，按时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, CAST(extra AS String)
+func (r *ClickHouseRepository) GetPerformanceMetrics(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.PerformanceMetric, error) {
+    extraCondition, extraArgs := extraFilterSQL(filters)
+    // 定义SQL查询语句，按 order 排序，默认按时间倒序
+    query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, CAST(extra AS String)
         FROM performance_metrics 
-        WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?%s 
+        ORDER BY %s`, inPlaceholders(len(projectIDs)), extraCondition, orderBySQL(order))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, append(projectArgs(projectIDs, startTime, endTime), extraArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query performance metrics: %w", err)
 	}
@@ -294,8 +517,11 @@ func (r *ClickHouseRepository) GetPerformanceMetrics(ctx context.Context, projec
         var extraStr sql.NullString
         err := rows.Scan(
             &metric.Timestamp, &metric.ProjectID, &metric.SessionID, &metric.TraceID, &metric.UserID,
-            &metric.URL, &metric.Referrer, &metric.Type, &metric.Name, &metric.Value, &extraStr)
+            &metric.URL, &metric.Referrer, &metric.SDKVersion, &metric.Platform, &metric.Type, &metric.Name, &metric.Value, &extraStr)
         if err != nil {
+            if r.skipRow(ctx, "performance_metrics", err) {
+                continue
+            }
             return nil, fmt.Errorf("failed to scan performance metric: %w", err)
         }
         if extraStr.Valid {
@@ -308,10 +534,43 @@ func (r *ClickHouseRepository) GetPerformanceMetrics(ctx context.Context, projec
     return metrics, nil
 }
 
+// GetPerformanceMetricByTraceID 根据traceID获取特定的性能指标
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - traceID: 唯一的跟踪标识符
+//
+// 返回:
+//   - *models.PerformanceMetric: 性能指标对象，如果不存在则为nil
+//   - error: 查询过程中的错误信息，成功或未找到则为nil
+func (r *ClickHouseRepository) GetPerformanceMetricByTraceID(ctx context.Context, traceID string) (*models.PerformanceMetric, error) {
+	query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, CAST(extra AS String)
+		FROM performance_metrics
+		WHERE trace_id = ?
+		LIMIT 1`
+
+	var metric models.PerformanceMetric
+	var extraStr sql.NullString
+	err := r.DB.QueryRowContext(r.readContext(ctx), query, traceID).Scan(
+		&metric.Timestamp, &metric.ProjectID, &metric.SessionID, &metric.TraceID, &metric.UserID,
+		&metric.URL, &metric.Referrer, &metric.SDKVersion, &metric.Platform, &metric.Type, &metric.Name, &metric.Value, &extraStr)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query performance metric by traceID: %w", err)
+	}
+	if extraStr.Valid {
+		metric.Extra = json.RawMessage(extraStr.String)
+	} else {
+		metric.Extra = json.RawMessage("{}")
+	}
+	return &metric, nil
+}
+
 // GetPerformanceMetricsByType 获取指定项目、指定类型在时间范围内的性能指标
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - metricType: 性能指标类型名称
 //   - startTime: 开始时间
 //   - endTime: 结束时间
@@ -319,15 +578,15 @@ func (r *ClickHouseRepository) GetPerformanceMetrics(ctx context.Context, projec
 // 返回:
 //   - []*models.PerformanceMetric: 性能指标列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetPerformanceMetricsByType(ctx context.Context, projectID string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
+func (r *ClickHouseRepository) GetPerformanceMetricsByType(ctx context.Context, projectIDs []string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
     // 定义SQL查询语句，按类型和时间范围筛选，时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, CAST(extra AS String) 
+    query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, CAST(extra AS String) 
         FROM performance_metrics 
-        WHERE project_id = ? AND name = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ? 
+        ORDER BY timestamp DESC`, inPlaceholders(len(projectIDs)))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, metricType, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, metricType, startTime, endTime)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query performance metrics by type: %w", err)
 	}
@@ -340,8 +599,11 @@ func (r *ClickHouseRepository) GetPerformanceMetricsByType(ctx context.Context,
         var extraStr sql.NullString
         err := rows.Scan(
             &metric.Timestamp, &metric.ProjectID, &metric.SessionID, &metric.TraceID, &metric.UserID,
-            &metric.URL, &metric.Referrer, &metric.Type, &metric.Name, &metric.Value, &extraStr)
+            &metric.URL, &metric.Referrer, &metric.SDKVersion, &metric.Platform, &metric.Type, &metric.Name, &metric.Value, &extraStr)
         if err != nil {
+            if r.skipRow(ctx, "performance_metrics", err) {
+                continue
+            }
             return nil, fmt.Errorf("failed to scan performance metric: %w", err)
         }
         if extraStr.Valid {
@@ -354,6 +616,134 @@ func (r *ClickHouseRepository) GetPerformanceMetricsByType(ctx context.Context,
     return metrics, nil
 }
 
+// GetApdex 统计指定性能指标在时间范围内的 Apdex 各分段样本数
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - metricName: 性能指标名称，例如 LCP
+//   - threshold: Apdex 阈值 T
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - *models.ApdexScore: 满意/容忍/失望样本数，评分由服务层计算
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetApdex(ctx context.Context, projectIDs []string, metricName string, threshold float64, startTime, endTime time.Time) (*models.ApdexScore, error) {
+	query := fmt.Sprintf(`SELECT countIf(value <= ?), countIf(value > ? AND value <= ?), count()
+		FROM performance_metrics
+		WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ?`, inPlaceholders(len(projectIDs)))
+	args := append([]any{threshold, threshold, 4 * threshold}, projectArgs(projectIDs, metricName, startTime, endTime)...)
+
+	apdex := &models.ApdexScore{Name: metricName, Threshold: threshold}
+	err := r.DB.QueryRowContext(r.readContext(ctx), query, args...).Scan(&apdex.Satisfied, &apdex.Tolerating, &apdex.Total)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query apdex: %w", err)
+	}
+	apdex.Frustrated = apdex.Total - apdex.Satisfied - apdex.Tolerating
+	return apdex, nil
+}
+
+// GetSlowestURLsByPercentile 按页面分组计算性能指标的分位值，返回分位值最高的页面
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - metricName: 性能指标名称，例如 TTFB
+//   - quantile: 分位数，取值 (0, 1)，由调用方校验
+//   - minCount: 页面的最少样本数，样本过少的页面分位值不可信，不参与排序
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//   - limit: 最多返回的页面数
+//
+// 返回:
+//   - []*models.URLPercentile: 按分位值降序排列，URL 已去掉查询参数和锚点
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetSlowestURLsByPercentile(ctx context.Context, projectIDs []string, metricName string, quantile float64, minCount int, startTime, endTime time.Time, limit int) ([]*models.URLPercentile, error) {
+	// 分位数是聚合函数的参数，不能使用占位符，已由调用方校验范围
+	query := fmt.Sprintf(`SELECT cutQueryStringAndFragment(url) AS page, quantile(%s)(value) AS v, count() AS c
+		FROM performance_metrics
+		WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ? AND url != ''
+		GROUP BY page
+		HAVING c >= ?
+		ORDER BY v DESC, page
+		LIMIT ?`, strconv.FormatFloat(quantile, 'f', -1, 64), inPlaceholders(len(projectIDs)))
+	args := append(projectArgs(projectIDs, metricName, startTime, endTime), minCount, limit)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query slowest urls: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*models.URLPercentile, 0, limit)
+	for rows.Next() {
+		item := &models.URLPercentile{}
+		if err := rows.Scan(&item.URL, &item.Value, &item.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan slowest url: %w", err)
+		}
+		results = append(results, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate slowest urls: %w", err)
+	}
+	return results, nil
+}
+
+// GetWebVitals 按阈值统计多个性能指标在时间范围内的良好/需改进/差样本数和 p75
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectIDs: 项目标识符列表
+//   - vitals: 需要统计的指标，Name、GoodThreshold、PoorThreshold 由调用方填写
+//   - startTime: 开始时间
+//   - endTime: 结束时间
+//
+// 返回:
+//   - []models.WebVitalScore: 与 vitals 顺序一致，填充了各分档样本数；有样本时填充 P75，占比和评级由服务层计算
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetWebVitals(ctx context.Context, projectIDs []string, vitals []models.WebVitalScore, startTime, endTime time.Time) ([]models.WebVitalScore, error) {
+	if len(vitals) == 0 {
+		return []models.WebVitalScore{}, nil
+	}
+
+	// 每个指标的阈值不同，各自聚合为一行后合并
+	subqueries := make([]string, 0, len(vitals))
+	args := make([]any, 0, len(vitals)*(len(projectIDs)+8))
+	for i, vital := range vitals {
+		subqueries = append(subqueries, fmt.Sprintf(`SELECT %d AS idx, countIf(value <= ?), countIf(value > ? AND value <= ?), countIf(value > ?), count(), quantile(0.75)(value)
+			FROM performance_metrics
+			WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ?`, i, inPlaceholders(len(projectIDs))))
+		args = append(args, vital.GoodThreshold, vital.GoodThreshold, vital.PoorThreshold, vital.PoorThreshold)
+		args = append(args, projectArgs(projectIDs, vital.Name, startTime, endTime)...)
+	}
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), strings.Join(subqueries, " UNION ALL "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query web vitals: %w", err)
+	}
+	defer rows.Close()
+
+	scores := append([]models.WebVitalScore{}, vitals...)
+	for rows.Next() {
+		var idx uint8
+		var score models.WebVitalScore
+		var p75 float64
+		if err := rows.Scan(&idx, &score.Good, &score.NeedsImprovement, &score.Poor, &score.Total, &p75); err != nil {
+			return nil, fmt.Errorf("failed to scan web vitals: %w", err)
+		}
+		if int(idx) >= len(scores) {
+			continue
+		}
+		target := &scores[idx]
+		target.Good, target.NeedsImprovement, target.Poor, target.Total = score.Good, score.NeedsImprovement, score.Poor, score.Total
+		if score.Total > 0 {
+			target.P75 = &p75
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate web vitals: %w", err)
+	}
+	return scores, nil
+}
+
 // SaveUserAction 保存用户行为数据到数据库
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
@@ -362,20 +752,8 @@ func (r *ClickHouseRepository) GetPerformanceMetricsByType(ctx context.Context,
 // 返回:
 //   - error: 保存过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) SaveUserAction(ctx context.Context, action *models.UserAction) error {
-	// 定义SQL插入语句，包含用户行为的所有字段
-	query := `INSERT INTO user_actions (timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, method, status, value, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	// 将Extra字段转换为字符串，如果为空则使用空JSON对象
-	extraStr := string(action.Extra)
-	if extraStr == "" {
-		extraStr = "{}"
-	}
-
 	// 执行插入操作
-	_, err := r.DB.ExecContext(ctx, query,
-		action.Timestamp, action.ProjectID, action.SessionID, action.TraceID, action.UserID,
-		action.URL, action.Referrer, action.Type, action.Name, action.Message, action.Method,
-		action.Status, action.Value, extraStr)
+	_, err := r.DB.ExecContext(r.insertContext(ctx), insertUserActionQuery, userActionArgs(action)...)
 	if err != nil {
 		return fmt.Errorf("failed to save user action: %w", err)
 	}
@@ -385,22 +763,29 @@ func (r *ClickHouseRepository) SaveUserAction(ctx context.Context, action *model
 // GetUserActions 获取指定项目在时间范围内的用户行为列表
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
+//   - filters: extra 字段过滤条件，为空时不过滤
+//   - statusClasses: HTTP 状态码类别过滤条件，5 表示 5xx，为空时不过滤
+//   - order: 排序方式
 //
 // 返回:
 //   - []*models.UserAction: 用户行为列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetUserActions(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.UserAction, error) {
-    // 定义SQL查询语句，按时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, method, status, value, CAST(extra AS String) 
+func (r *ClickHouseRepository) GetUserActions(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, statusClasses []int, order models.ListOrder) ([]*models.UserAction, error) {
+    extraCondition, extraArgs := extraFilterSQL(filters)
+    statusCondition, statusArgs := statusClassFilterSQL(statusClasses)
+    extraCondition += statusCondition
+    extraArgs = append(extraArgs, statusArgs...)
+    // 定义SQL查询语句，按 order 排序，默认按时间倒序
+    query := fmt.Sprintf(`SELECT %s
         FROM user_actions 
-        WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?%s 
+        ORDER BY %s`, userActionSelectColumns, inPlaceholders(len(projectIDs)), extraCondition, orderBySQL(order))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, append(projectArgs(projectIDs, startTime, endTime), extraArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user actions: %w", err)
 	}
@@ -408,30 +793,47 @@ func (r *ClickHouseRepository) GetUserActions(ctx context.Context, projectID str
 
 	var actions []*models.UserAction
 	// 遍历查询结果
-    for rows.Next() {
-        var action models.UserAction
-        var extraStr sql.NullString
-        err := rows.Scan(
-            &action.Timestamp, &action.ProjectID, &action.SessionID, &action.TraceID, &action.UserID,
-            &action.URL, &action.Referrer, &action.Type, &action.Name, &action.Message, &action.Method,
-            &action.Status, &action.Value, &extraStr)
-        if err != nil {
-            return nil, fmt.Errorf("failed to scan user action: %w", err)
-        }
-        if extraStr.Valid {
-            action.Extra = json.RawMessage(extraStr.String)
-        } else {
-            action.Extra = json.RawMessage("{}")
-        }
-        actions = append(actions, &action)
-    }
+	for rows.Next() {
+		action, err := scanUserAction(rows)
+		if err != nil {
+			if r.skipRow(ctx, "user_actions", err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to scan user action: %w", err)
+		}
+		actions = append(actions, action)
+	}
     return actions, nil
 }
 
+// GetUserActionByTraceID 根据traceID获取特定的用户行为
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - traceID: 唯一的跟踪标识符
+//
+// 返回:
+//   - *models.UserAction: 用户行为对象，如果不存在则为nil
+//   - error: 查询过程中的错误信息，成功或未找到则为nil
+func (r *ClickHouseRepository) GetUserActionByTraceID(ctx context.Context, traceID string) (*models.UserAction, error) {
+	query := `SELECT ` + userActionSelectColumns + `
+		FROM user_actions
+		WHERE trace_id = ?
+		LIMIT 1`
+
+	action, err := scanUserAction(r.DB.QueryRowContext(r.readContext(ctx), query, traceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query user action by traceID: %w", err)
+	}
+	return action, nil
+}
+
 // GetUserActionsByType 获取指定项目、指定类型在时间范围内的用户行为
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - actionType: 用户行为类型名称
 //   - startTime: 开始时间
 //   - endTime: 结束时间
@@ -439,15 +841,15 @@ func (r *ClickHouseRepository) GetUserActions(ctx context.Context, projectID str
 // 返回:
 //   - []*models.UserAction: 用户行为列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetUserActionsByType(ctx context.Context, projectID string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error) {
+func (r *ClickHouseRepository) GetUserActionsByType(ctx context.Context, projectIDs []string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error) {
     // 定义SQL查询语句，按类型和时间范围筛选，时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, method, status, value, CAST(extra AS String) 
+    query := fmt.Sprintf(`SELECT %s
         FROM user_actions 
-        WHERE project_id = ? AND name = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ? 
+        ORDER BY timestamp DESC`, userActionSelectColumns, inPlaceholders(len(projectIDs)))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, actionType, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, actionType, startTime, endTime)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query user actions by type: %w", err)
 	}
@@ -455,23 +857,16 @@ func (r *ClickHouseRepository) GetUserActionsByType(ctx context.Context, project
 
 	var actions []*models.UserAction
 	// 遍历查询结果
-    for rows.Next() {
-        var action models.UserAction
-        var extraStr sql.NullString
-        err := rows.Scan(
-            &action.Timestamp, &action.ProjectID, &action.SessionID, &action.TraceID, &action.UserID,
-            &action.URL, &action.Referrer, &action.Type, &action.Name, &action.Message, &action.Method,
-            &action.Status, &action.Value, &extraStr)
-        if err != nil {
-            return nil, fmt.Errorf("failed to scan user action: %w", err)
-        }
-        if extraStr.Valid {
-            action.Extra = json.RawMessage(extraStr.String)
-        } else {
-            action.Extra = json.RawMessage("{}")
-        }
-        actions = append(actions, &action)
-    }
+	for rows.Next() {
+		action, err := scanUserAction(rows)
+		if err != nil {
+			if r.skipRow(ctx, "user_actions", err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to scan user action: %w", err)
+		}
+		actions = append(actions, action)
+	}
     return actions, nil
 }
 
@@ -483,19 +878,8 @@ func (r *ClickHouseRepository) GetUserActionsByType(ctx context.Context, project
 // 返回:
 //   - error: 保存过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) SaveCustomEvent(ctx context.Context, event *models.CustomEvent) error {
-	// 定义SQL插入语句，包含自定义事件的所有字段
-	query := `INSERT INTO custom_events (timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	// 将Extra字段转换为字符串，如果为空则使用空JSON对象
-	extraStr := string(event.Extra)
-	if extraStr == "" {
-		extraStr = "{}"
-	}
-
 	// 执行插入操作
-	_, err := r.DB.ExecContext(ctx, query,
-		event.Timestamp, event.ProjectID, event.SessionID, event.TraceID, event.UserID,
-		event.URL, event.Referrer, event.Type, event.Name, event.Message, extraStr)
+	_, err := r.DB.ExecContext(r.insertContext(ctx), insertCustomEventQuery, customEventArgs(event)...)
 	if err != nil {
 		return fmt.Errorf("failed to save custom event: %w", err)
 	}
@@ -505,22 +889,25 @@ func (r *ClickHouseRepository) SaveCustomEvent(ctx context.Context, event *model
 // GetCustomEvents 获取指定项目在时间范围内的自定义事件列表
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
+//   - filters: extra 字段过滤条件，为空时不过滤
+//   - order: 排序方式
 //
 // 返回:
 //   - []*models.CustomEvent: 自定义事件列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetCustomEvents(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
-    // 定义SQL查询语句，按时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, CAST(extra AS String) 
+func (r *ClickHouseRepository) GetCustomEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.CustomEvent, error) {
+    extraCondition, extraArgs := extraFilterSQL(filters)
+    // 定义SQL查询语句，按 order 排序，默认按时间倒序
+    query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, CAST(extra AS String) 
         FROM custom_events 
-        WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?%s 
+        ORDER BY %s`, inPlaceholders(len(projectIDs)), extraCondition, orderBySQL(order))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, append(projectArgs(projectIDs, startTime, endTime), extraArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query custom events: %w", err)
 	}
@@ -533,8 +920,11 @@ func (r *ClickHouseRepository) GetCustomEvents(ctx context.Context, projectID st
         var extraStr sql.NullString
         err := rows.Scan(
             &event.Timestamp, &event.ProjectID, &event.SessionID, &event.TraceID, &event.UserID,
-            &event.URL, &event.Referrer, &event.Type, &event.Name, &event.Message, &extraStr)
+            &event.URL, &event.Referrer, &event.SDKVersion, &event.Platform, &event.Type, &event.Name, &event.Message, &extraStr)
         if err != nil {
+            if r.skipRow(ctx, "custom_events", err) {
+                continue
+            }
             return nil, fmt.Errorf("failed to scan custom event: %w", err)
         }
         if extraStr.Valid {
@@ -550,7 +940,7 @@ func (r *ClickHouseRepository) GetCustomEvents(ctx context.Context, projectID st
 // GetCustomEventsByName 获取指定项目、指定名称在时间范围内的自定义事件
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - eventName: 自定义事件名称
 //   - startTime: 开始时间
 //   - endTime: 结束时间
@@ -558,15 +948,15 @@ func (r *ClickHouseRepository) GetCustomEvents(ctx context.Context, projectID st
 // 返回:
 //   - []*models.CustomEvent: 自定义事件列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetCustomEventsByName(ctx context.Context, projectID string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
+func (r *ClickHouseRepository) GetCustomEventsByName(ctx context.Context, projectIDs []string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
     // 定义SQL查询语句，按名称和时间范围筛选，时间倒序排列
-    query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, message, CAST(extra AS String) 
+    query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, CAST(extra AS String) 
         FROM custom_events 
-        WHERE project_id = ? AND name = ? AND timestamp >= ? AND timestamp <= ? 
-        ORDER BY timestamp DESC`
+        WHERE project_id IN (%s) AND name = ? AND timestamp >= ? AND timestamp <= ? 
+        ORDER BY timestamp DESC`, inPlaceholders(len(projectIDs)))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, eventName, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, eventName, startTime, endTime)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query custom events by name: %w", err)
 	}
@@ -579,8 +969,11 @@ func (r *ClickHouseRepository) GetCustomEventsByName(ctx context.Context, projec
         var extraStr sql.NullString
         err := rows.Scan(
             &event.Timestamp, &event.ProjectID, &event.SessionID, &event.TraceID, &event.UserID,
-            &event.URL, &event.Referrer, &event.Type, &event.Name, &event.Message, &extraStr)
+            &event.URL, &event.Referrer, &event.SDKVersion, &event.Platform, &event.Type, &event.Name, &event.Message, &extraStr)
         if err != nil {
+            if r.skipRow(ctx, "custom_events", err) {
+                continue
+            }
             return nil, fmt.Errorf("failed to scan custom event: %w", err)
         }
         if extraStr.Valid {
@@ -601,19 +994,8 @@ func (r *ClickHouseRepository) GetCustomEventsByName(ctx context.Context, projec
 // 返回:
 //   - error: 保存过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) SavePageStay(ctx context.Context, pageStay *models.PageStay) error {
-	// 定义SQL插入语句，包含页面停留数据的所有字段
-	query := `INSERT INTO page_stay (timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, extra) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-
-	// 将Extra字段转换为字符串，如果为空则使用空JSON对象
-	extraStr := string(pageStay.Extra)
-	if extraStr == "" {
-		extraStr = "{}"
-	}
-
 	// 执行插入操作
-	_, err := r.DB.ExecContext(ctx, query,
-		pageStay.Timestamp, pageStay.ProjectID, pageStay.SessionID, pageStay.TraceID, pageStay.UserID,
-		pageStay.URL, pageStay.Referrer, pageStay.Type, pageStay.Name, pageStay.Value, extraStr)
+	_, err := r.DB.ExecContext(r.insertContext(ctx), insertPageStayQuery, pageStayArgs(pageStay)...)
 	if err != nil {
 		return fmt.Errorf("failed to save page stay: %w", err)
 	}
@@ -623,22 +1005,22 @@ func (r *ClickHouseRepository) SavePageStay(ctx context.Context, pageStay *model
 // GetPageStays 获取指定项目在时间范围内的页面停留时间列表
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
 //
 // 返回:
 //   - []*models.PageStay: 页面停留时间列表
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetPageStays(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PageStay, error) {
-	// 定义SQL查询语句，按时间倒序排列
-	query := `SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, type, name, value, extra 
-		FROM page_stay 
-		WHERE project_id = ? AND timestamp >= ? AND timestamp <= ? 
-		ORDER BY timestamp DESC`
+func (r *ClickHouseRepository) GetPageStays(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]*models.PageStay, error) {
+	// 定义SQL查询语句，按时间倒序排列；FINAL 保证同一会话同一页面只返回最新的停留时长
+	query := fmt.Sprintf(`SELECT timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, extra 
+		FROM page_stay FINAL
+		WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ? 
+		ORDER BY timestamp DESC`, inPlaceholders(len(projectIDs)))
 
 	// 执行查询
-	rows, err := r.DB.QueryContext(ctx, query, projectID, startTime, endTime)
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, projectArgs(projectIDs, startTime, endTime)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query page stays: %w", err)
 	}
@@ -648,12 +1030,22 @@ func (r *ClickHouseRepository) GetPageStays(ctx context.Context, projectID strin
 	// 遍历查询结果
 	for rows.Next() {
 		var stay models.PageStay
+		var extraStr sql.NullString
 		err := rows.Scan(
 			&stay.Timestamp, &stay.ProjectID, &stay.SessionID, &stay.TraceID, &stay.UserID,
-			&stay.URL, &stay.Referrer, &stay.Type, &stay.Name, &stay.Value, &stay.Extra)
+			&stay.URL, &stay.Referrer, &stay.SDKVersion, &stay.Platform, &stay.Type, &stay.Name, &stay.Value, &extraStr)
 		if err != nil {
+			if r.skipRow(ctx, "page_stay", err) {
+				continue
+			}
 			return nil, fmt.Errorf("failed to scan page stay: %w", err)
 		}
+		// 与其他事件表一致，extra 为空时返回空对象
+		if extraStr.Valid && extraStr.String != "" {
+			stay.Extra = json.RawMessage(extraStr.String)
+		} else {
+			stay.Extra = json.RawMessage("{}")
+		}
 		stays = append(stays, &stay)
 	}
 	return stays, nil
@@ -662,33 +1054,326 @@ func (r *ClickHouseRepository) GetPageStays(ctx context.Context, projectID strin
 // GetAveragePageStay 获取指定项目在时间范围内的平均页面停留时间
 // 参数:
 //   - ctx: 上下文对象，用于控制请求超时和取消
-//   - projectID: 项目标识符
+//   - projectIDs: 项目标识符列表
 //   - startTime: 开始时间
 //   - endTime: 结束时间
 //
 // 返回:
-//   - float64: 平均页面停留时间（秒）
+//   - *models.PageStayAverage: 平均页面停留时间（秒）及参与计算的样本数，没有样本时平均值为 0
 //   - error: 查询过程中的错误信息，成功则为nil
-func (r *ClickHouseRepository) GetAveragePageStay(ctx context.Context, projectID string, startTime, endTime time.Time) (float64, error) {
-	// 使用ClickHouse的avg函数计算平均值
-	query := `SELECT avg(value) FROM page_stay WHERE project_id = ? AND timestamp >= ? AND timestamp <= ?`
-	var avg float64
+func (r *ClickHouseRepository) GetAveragePageStay(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.PageStayAverage, error) {
+	// 使用ClickHouse的avg函数计算平均值，FINAL 去重后同一会话同一页面只计入最新的停留时长
+	// 没有样本时 avg 返回 nan，按样本数返回 0
+	query := fmt.Sprintf(`SELECT if(count() = 0, 0, avg(value)), count() FROM page_stay FINAL WHERE project_id IN (%s) AND timestamp >= ? AND timestamp <= ?`, inPlaceholders(len(projectIDs)))
+	average := &models.PageStayAverage{}
 	// 执行聚合查询
-	err := r.DB.QueryRowContext(ctx, query, projectID, startTime, endTime).Scan(&avg)
+	err := r.DB.QueryRowContext(r.readContext(ctx), query, projectArgs(projectIDs, startTime, endTime)...).Scan(&average.AveragePageStay, &average.Count)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// 如果没有数据，返回0
-			return 0, nil
+			return average, nil
 		}
-		return 0, fmt.Errorf("failed to query average page stay: %w", err)
+		return nil, fmt.Errorf("failed to query average page stay: %w", err)
+	}
+	return average, nil
+}
+
+// GetDataRange 获取指定项目在所有事件表中最早和最晚的事件时间
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectID: 项目标识符
+//
+// 返回:
+//   - *models.DataRange: 数据时间范围，项目没有数据时时间字段为nil
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetDataRange(ctx context.Context, projectID string) (*models.DataRange, error) {
+	// 每张表各自聚合后再合并；空表的 min/max 会返回默认值，因此带上行数过滤
+	subqueries := make([]string, 0, len(eventTables))
+	args := make([]any, 0, len(eventTables))
+	for _, table := range eventTables {
+		subqueries = append(subqueries, fmt.Sprintf(
+			"SELECT min(timestamp) AS min_ts, max(timestamp) AS max_ts, count() AS cnt FROM %s WHERE project_id = ?", table))
+		args = append(args, projectID)
+	}
+	query := fmt.Sprintf(`SELECT sum(cnt), min(min_ts), max(max_ts)
+		FROM (%s)
+		WHERE cnt > 0`, strings.Join(subqueries, " UNION ALL "))
+
+	var total uint64
+	var minTs, maxTs time.Time
+	err := r.DB.QueryRowContext(r.readContext(ctx), query, args...).Scan(&total, &minTs, &maxTs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data range: %w", err)
+	}
+
+	dataRange := &models.DataRange{ProjectID: projectID}
+	if total > 0 {
+		dataRange.MinTimestamp = &minTs
+		dataRange.MaxTimestamp = &maxTs
+	}
+	return dataRange, nil
+}
+
+// SaveBatch 批量保存一批事件
+// 每张表的数据在一个事务中追加，提交时以单次批量插入发送到ClickHouse
+// 各表的写入通过写入工作池并发执行
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - batch: 按表分组的事件批次
+//
+// 返回:
+//   - error: 保存过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) SaveBatch(ctx context.Context, batch *models.EventBatch) error {
+	errorLogRows := make([][]any, 0, len(batch.ErrorLogs))
+	for _, log := range batch.ErrorLogs {
+		errorLogRows = append(errorLogRows, errorLogArgs(log))
+	}
+	metricRows := make([][]any, 0, len(batch.PerformanceMetrics))
+	for _, metric := range batch.PerformanceMetrics {
+		metricRows = append(metricRows, performanceMetricArgs(metric))
+	}
+	actionRows := make([][]any, 0, len(batch.UserActions))
+	for _, action := range batch.UserActions {
+		actionRows = append(actionRows, userActionArgs(action))
+	}
+	eventRows := make([][]any, 0, len(batch.CustomEvents))
+	for _, event := range batch.CustomEvents {
+		eventRows = append(eventRows, customEventArgs(event))
 	}
-	return avg, nil
+	pageStayRows := make([][]any, 0, len(batch.PageStays))
+	for _, pageStay := range batch.PageStays {
+		pageStayRows = append(pageStayRows, pageStayArgs(pageStay))
+	}
+
+	stackRows, stackHashes := r.newStackTraceRows(batch.ErrorLogs)
+
+	inserts := []struct {
+		table string
+		query string
+		rows  [][]any
+	}{
+		{"stack_traces", insertStackTraceQuery, stackRows},
+		{"error_logs", insertErrorLogQuery, errorLogRows},
+		{"performance_metrics", insertPerformanceMetricQuery, metricRows},
+		{"user_actions", insertUserActionQuery, actionRows},
+		{"custom_events", insertCustomEventQuery, eventRows},
+		{"page_stay", insertPageStayQuery, pageStayRows},
+	}
+	// 各表写入提交到工作池并发执行，全部完成后返回第一个错误
+	results := make([]<-chan error, len(inserts))
+	for i, insert := range inserts {
+		if len(insert.rows) == 0 {
+			continue
+		}
+		results[i] = r.inserts.submit(ctx, func(ctx context.Context) error {
+			return r.insertBatch(ctx, insert.query, insert.rows)
+		})
+	}
+
+	var firstErr error
+	for i, result := range results {
+		if result == nil {
+			continue
+		}
+		if err := <-result; err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to save %s batch: %w", inserts[i].table, err)
+		}
+	}
+	if firstErr == nil {
+		r.stacks.addAll(stackHashes, time.Now())
+	}
+	return firstErr
+}
+
+// insertBatch 在事务中预编译插入语句并逐行追加，提交时一次性写入
+func (r *ClickHouseRepository) insertBatch(ctx context.Context, query string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin batch: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, args := range rows {
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to append batch row: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	return nil
+}
+
+// eventTables 所有事件表名称，跨表操作按此顺序遍历
+var eventTables = []string{"error_logs", "performance_metrics", "user_actions", "custom_events", "page_stay"}
+
+// eventTypeTables 事件类型对应的表名
+var eventTypeTables = map[string]string{
+	models.EventTypeErrorLog:          "error_logs",
+	models.EventTypePerformanceMetric: "performance_metrics",
+	models.EventTypeUserAction:        "user_actions",
+	models.EventTypeCustomEvent:       "custom_events",
+	models.EventTypePageStay:          "page_stay",
+}
+
+// retentionTables 受数据保留策略管理的表，按 timestamp 列过期
+var retentionTables = append(append([]string{}, eventTables...), "stack_traces")
+
+// DeleteByUser 删除指定项目下某个用户在所有事件表中的数据
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectID: 项目标识符
+//   - userID: 用户标识符
+//
+// 返回:
+//   - *models.DeletionSummary: 各表受影响的行数汇总
+//   - error: 删除过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) DeleteByUser(ctx context.Context, projectID string, userID string) (*models.DeletionSummary, error) {
+	return r.deleteByColumn(ctx, projectID, "user_id", userID)
+}
+
+// DeleteBySession 删除指定项目下某个会话在所有事件表中的数据
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectID: 项目标识符
+//   - sessionID: 会话标识符
+//
+// 返回:
+//   - *models.DeletionSummary: 各表受影响的行数汇总
+//   - error: 删除过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) DeleteBySession(ctx context.Context, projectID string, sessionID string) (*models.DeletionSummary, error) {
+	return r.deleteByColumn(ctx, projectID, "session_id", sessionID)
+}
+
+// DeleteProject 删除指定项目在所有事件表中的全部数据，用于项目下线
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - projectID: 项目标识符
+//
+// 返回:
+//   - *models.DeletionSummary: 各表受影响的行数和 mutation ID 汇总
+//   - error: 删除过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) DeleteProject(ctx context.Context, projectID string) (*models.DeletionSummary, error) {
+	summary := &models.DeletionSummary{
+		ProjectID: projectID,
+		Field:     "project_id",
+		Value:     projectID,
+		Tables:    []models.TableDeletion{},
+	}
+	return summary, r.deleteWhere(ctx, summary, "project_id = ?", projectID)
+}
+
+// deleteByColumn 在所有事件表上按列值执行 ALTER TABLE ... DELETE
+// column 必须是调用方写死的列名，不能来自用户输入
+func (r *ClickHouseRepository) deleteByColumn(ctx context.Context, projectID, column, value string) (*models.DeletionSummary, error) {
+	summary := &models.DeletionSummary{
+		ProjectID: projectID,
+		Field:     column,
+		Value:     value,
+		Tables:    []models.TableDeletion{},
+	}
+	condition := fmt.Sprintf("project_id = ? AND %s = ?", column)
+	return summary, r.deleteWhere(ctx, summary, condition, projectID, value)
+}
+
+// deleteWhere 在所有事件表上按条件执行 ALTER TABLE ... DELETE，结果追加到 summary 中
+// condition 必须由调用方写死，参数通过 args 绑定；没有匹配行的表不提交 mutation
+// ClickHouse 的 DELETE 是异步 mutation，返回时数据可能尚未物理删除
+func (r *ClickHouseRepository) deleteWhere(ctx context.Context, summary *models.DeletionSummary, condition string, args ...any) error {
+	for _, table := range eventTables {
+		// 先统计匹配行数，没有数据的表不提交 mutation
+		var rows uint64
+		countQuery := fmt.Sprintf("SELECT count() FROM %s WHERE %s", table, condition)
+		if err := r.DB.QueryRowContext(ctx, countQuery, args...).Scan(&rows); err != nil {
+			return fmt.Errorf("failed to count rows in %s: %w", table, err)
+		}
+		if rows == 0 {
+			continue
+		}
+
+		submitted := time.Now().Truncate(time.Second)
+		deleteQuery := fmt.Sprintf("ALTER TABLE %s DELETE WHERE %s", table, condition)
+		if _, err := r.DB.ExecContext(ctx, deleteQuery, args...); err != nil {
+			return fmt.Errorf("failed to delete from %s: %w", table, err)
+		}
+		mutationID := r.latestMutationID(ctx, table, submitted)
+
+		r.Logger.Info("Submitted delete mutation",
+			zap.String("table", table),
+			zap.String("project_id", summary.ProjectID),
+			zap.String("field", summary.Field),
+			zap.String("mutation_id", mutationID),
+			zap.Uint64("rows", rows))
+		summary.Tables = append(summary.Tables, models.TableDeletion{Table: table, Rows: rows, MutationID: mutationID})
+		summary.TotalRows += rows
+	}
+	return nil
+}
+
+// latestMutationID 查询表上 since 之后提交的最新 mutation 的 ID，可用于在 system.mutations 中跟踪删除进度
+// ALTER TABLE 不返回 mutation ID，同一张表上同时有其他 mutation 提交时可能取到别的 ID；查询失败时返回空字符串
+func (r *ClickHouseRepository) latestMutationID(ctx context.Context, table string, since time.Time) string {
+	query := `
+		SELECT mutation_id
+		FROM system.mutations
+		WHERE database = currentDatabase() AND table = ? AND create_time >= ?
+		ORDER BY create_time DESC
+		LIMIT 1
+	`
+	var mutationID string
+	if err := r.DB.QueryRowContext(ctx, query, table, since).Scan(&mutationID); err != nil {
+		if err != sql.ErrNoRows {
+			r.Logger.Warn("Failed to look up delete mutation ID", zap.String("table", table), zap.Error(err))
+		}
+		return ""
+	}
+	return mutationID
+}
+
+// SetRetention 修改所有事件表和堆栈表的 TTL，超过保留天数的数据由 ClickHouse 在后台合并时删除
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - days: 保留天数，0 表示移除 TTL，不再自动删除
+//
+// 返回:
+//   - *models.RetentionPolicy: 已修改的表，中途失败时只包含之前成功的表
+//   - error: 修改过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) SetRetention(ctx context.Context, days int) (*models.RetentionPolicy, error) {
+	policy := &models.RetentionPolicy{Days: days, Tables: []string{}}
+
+	for _, table := range retentionTables {
+		query := fmt.Sprintf("ALTER TABLE %s MODIFY TTL toDateTime(timestamp) + INTERVAL %d DAY", table, days)
+		if days == 0 {
+			query = fmt.Sprintf("ALTER TABLE %s REMOVE TTL", table)
+		}
+		if _, err := r.DB.ExecContext(ctx, query); err != nil {
+			return policy, fmt.Errorf("failed to set retention on %s: %w", table, err)
+		}
+
+		r.Logger.Info("Updated table retention", zap.String("table", table), zap.Int("days", days))
+		policy.Tables = append(policy.Tables, table)
+	}
+
+	return policy, nil
 }
 
 // Close 关闭数据库连接
-// 释放所有资源，包括连接池中的连接
+// 先等待写入工作池中进行中的批量写入完成，再释放所有资源，包括连接池中的连接
 // 返回:
 //   - error: 关闭过程中的错误信息，成功则为nil
 func (r *ClickHouseRepository) Close() error {
+	r.inserts.close()
 	return r.DB.Close()
 }
@@ -0,0 +1,483 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"spectra-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testDSNEnv 集成测试使用的 ClickHouse DSN 环境变量，库中须已执行 SQL/init.sql 和全部迁移脚本
+// 未设置时跳过需要数据库的测试，例如 SPECTRA_TEST_CLICKHOUSE_DSN="clickhouse://localhost:9000/spectra_test"
+const testDSNEnv = "SPECTRA_TEST_CLICKHOUSE_DSN"
+
+// openTestRepository 连接 testDSNEnv 指定的 ClickHouse，未设置时跳过测试
+func openTestRepository(t *testing.T) *ClickHouseRepository {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping ClickHouse integration test", testDSNEnv)
+	}
+
+	db, err := sql.Open("clickhouse", dsn)
+	if err != nil {
+		t.Fatalf("failed to open ClickHouse: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		t.Fatalf("failed to ping ClickHouse: %v", err)
+	}
+	repo := &ClickHouseRepository{
+		DB:      db,
+		Logger:  zap.NewNop(),
+		inserts: newInsertPool(1),
+		stacks:  newStackCache(),
+	}
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+// testProjectID 为每次测试生成独立的项目ID，测试之间不共享数据
+func testProjectID(t *testing.T) string {
+	t.Helper()
+	return fmt.Sprintf("test-%d", time.Now().UnixNano())
+}
+
+func TestTimestampArgKeepsMilliseconds(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 678_900_000, time.UTC)
+	if got, want := timestampArg(ts), ts.UnixMilli(); got != want {
+		t.Errorf("timestampArg() = %d, want %d", got, want)
+	}
+	if got := timestampArg(ts.Add(10*time.Millisecond)) - timestampArg(ts); got != 10 {
+		t.Errorf("events 10ms apart differ by %dms after conversion", got)
+	}
+}
+
+func TestTimelineKeepsSubSecondOrder(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	projectID := testProjectID(t)
+	first := time.Now().UTC().Truncate(time.Second).Add(500 * time.Millisecond)
+	second := first.Add(10 * time.Millisecond)
+
+	// 先写入较晚的事件，确认返回顺序取决于时间而不是写入顺序
+	for _, event := range []*models.CustomEvent{
+		{BaseLog: models.BaseLog{Timestamp: second, ProjectID: projectID, SessionID: "s1", Name: "second"}},
+		{BaseLog: models.BaseLog{Timestamp: first, ProjectID: projectID, SessionID: "s1", Name: "first"}},
+	} {
+		if err := repo.SaveCustomEvent(ctx, event); err != nil {
+			t.Fatalf("SaveCustomEvent() error = %v", err)
+		}
+	}
+
+	timeline, err := repo.GetTimeline(ctx, projectID, TimelineFieldSession, "s1", false)
+	if err != nil {
+		t.Fatalf("GetTimeline() error = %v", err)
+	}
+	if len(timeline.Events) != 2 {
+		t.Fatalf("got %d timeline events, want 2", len(timeline.Events))
+	}
+	for i, want := range []time.Time{first, second} {
+		if got := timeline.Events[i].Timestamp; !got.Equal(want) {
+			t.Errorf("event %d timestamp = %s, want %s", i, got.Format(time.RFC3339Nano), want.Format(time.RFC3339Nano))
+		}
+	}
+}
+
+func TestTrendBucketExpr(t *testing.T) {
+	expr, args := trendBucketExpr(time.Hour, time.UTC)
+	if expr != "toStartOfInterval(timestamp, INTERVAL 3600 SECOND)" || args != nil {
+		t.Errorf("UTC bucket = %q %v", expr, args)
+	}
+
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	expr, args = trendBucketExpr(24*time.Hour, shanghai)
+	want := "toStartOfInterval(toDateTime(formatDateTime(timestamp, '%Y-%m-%d %H:%i:%S', ?), 'UTC'), INTERVAL 86400 SECOND)"
+	if expr != want || len(args) != 1 || args[0] != "Asia/Shanghai" {
+		t.Errorf("Asia/Shanghai bucket = %q %v, want %q [Asia/Shanghai]", expr, args, want)
+	}
+}
+
+func TestLocalBucketTime(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+	// ClickHouse 返回以 UTC 表示的当地时钟读数，2024-01-02 00:00 对应上海当地零点，即 UTC 前一天 16:00
+	got := localBucketTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), shanghai)
+	if want := time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("localBucketTime() = %s, want %s", got, want)
+	}
+}
+
+func TestIngestionRateDayBoundaryInTimeZone(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	projectID := testProjectID(t)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("failed to load time zone: %v", err)
+	}
+
+	// 两个事件在 UTC 同属 1 月 1 日，在上海分属 1 月 1 日和 1 月 2 日
+	for _, ts := range []time.Time{
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC),
+	} {
+		event := &models.CustomEvent{BaseLog: models.BaseLog{Timestamp: ts, ProjectID: projectID, Name: "tz"}}
+		if err := repo.SaveCustomEvent(ctx, event); err != nil {
+			t.Fatalf("SaveCustomEvent() error = %v", err)
+		}
+	}
+
+	start, end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	for loc, want := range map[*time.Location][]time.Time{
+		time.UTC: {time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		shanghai: {time.Date(2024, 1, 1, 0, 0, 0, 0, shanghai), time.Date(2024, 1, 2, 0, 0, 0, 0, shanghai)},
+	} {
+		buckets, err := repo.GetIngestionRate(ctx, []string{projectID}, start, end, 24*time.Hour, loc)
+		if err != nil {
+			t.Fatalf("GetIngestionRate(%s) error = %v", loc, err)
+		}
+		if len(buckets) != len(want) {
+			t.Fatalf("GetIngestionRate(%s) = %v, want buckets %v", loc, buckets, want)
+		}
+		for i, bucket := range buckets {
+			if !bucket.Time.Equal(want[i]) {
+				t.Errorf("%s bucket %d = %s, want %s", loc, i, bucket.Time, want[i])
+			}
+		}
+	}
+}
+
+func TestGetErrorLogsTypeFilterSQL(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	tests := []struct {
+		name     string
+		types    []string
+		wantSQL  string
+		wantArgs []any
+	}{
+		{"no types", nil, "", nil},
+		{"one type", []string{"js_error"}, "AND type IN (?)", []any{"js_error"}},
+		{"two types", []string{"js_error", "promise_rejection"}, "AND type IN (?, ?)", []any{"js_error", "promise_rejection"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, fake := openFakeRepository(t, errorLogColumns, nil)
+			if _, err := repo.GetErrorLogs(context.Background(), []string{"web"}, start, end, nil, nil, tt.types, models.ListOrder{}); err != nil {
+				t.Fatalf("GetErrorLogs() error = %v", err)
+			}
+
+			query := fake.lastQuery(t)
+			if tt.wantSQL == "" && strings.Contains(query.query, "type IN") {
+				t.Errorf("query filters by type without types: %s", query.query)
+			}
+			if tt.wantSQL != "" && !strings.Contains(query.query, tt.wantSQL) {
+				t.Errorf("query missing %q: %s", tt.wantSQL, query.query)
+			}
+			// 类型值只作为参数传入，不拼接进 SQL
+			for _, errorType := range tt.types {
+				if strings.Contains(query.query, errorType) {
+					t.Errorf("type %q was inlined into SQL: %s", errorType, query.query)
+				}
+			}
+			// 前三个参数依次为 project_id、开始时间和结束时间
+			if got := query.args[3:]; len(got) != len(tt.wantArgs) || (len(got) > 0 && !reflect.DeepEqual(got, tt.wantArgs)) {
+				t.Errorf("type args = %v, want %v", got, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGetErrorLogsByTypes(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	projectID := testProjectID(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, errorType := range []string{"js_error", "promise_rejection", "resource_error"} {
+		log := &models.ErrorLog{BaseLog: models.BaseLog{Timestamp: now, ProjectID: projectID, Type: errorType}, Message: errorType}
+		if err := repo.SaveErrorLog(ctx, log); err != nil {
+			t.Fatalf("SaveErrorLog() error = %v", err)
+		}
+	}
+
+	logs, err := repo.GetErrorLogs(ctx, []string{projectID}, now.Add(-time.Minute), now.Add(time.Minute), nil, nil,
+		[]string{"js_error", "promise_rejection"}, models.ListOrder{})
+	if err != nil {
+		t.Fatalf("GetErrorLogs() error = %v", err)
+	}
+	got := make(map[string]bool)
+	for _, log := range logs {
+		got[log.Type] = true
+	}
+	if len(logs) != 2 || !got["js_error"] || !got["promise_rejection"] {
+		t.Errorf("GetErrorLogs() returned types %v, want js_error and promise_rejection", got)
+	}
+
+	all, err := repo.GetErrorLogs(ctx, []string{projectID}, now.Add(-time.Minute), now.Add(time.Minute), nil, nil, nil, models.ListOrder{})
+	if err != nil {
+		t.Fatalf("GetErrorLogs() without types error = %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("GetErrorLogs() without types returned %d logs, want 3", len(all))
+	}
+}
+
+func TestGetSlowestURLsMinCount(t *testing.T) {
+	repo, fake := openFakeRepository(t, []string{"page", "v", "c"}, nil)
+	end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.GetSlowestURLsByPercentile(context.Background(), []string{"web"}, "lcp", 0.75, 20, end.Add(-time.Hour), end, 10); err != nil {
+		t.Fatalf("GetSlowestURLsByPercentile() error = %v", err)
+	}
+
+	// 样本数等于 min_count 的页面保留，少于的被 HAVING 排除
+	query := fake.lastQuery(t)
+	if !strings.Contains(query.query, "HAVING c >= ?") {
+		t.Errorf("query missing min_count condition: %s", query.query)
+	}
+	if got := query.args[len(query.args)-2:]; got[0] != 20 || got[1] != 10 {
+		t.Errorf("min_count and limit args = %v, want [20 10]", got)
+	}
+}
+
+func TestGetSlowestURLsMinCountBoundary(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	projectID := testProjectID(t)
+	now := time.Now().UTC()
+
+	samples := map[string]int{"/below": 2, "/at": 3, "/above": 4}
+	for page, count := range samples {
+		for i := 0; i < count; i++ {
+			metric := &models.PerformanceMetric{BaseLog: models.BaseLog{Timestamp: now, ProjectID: projectID, Name: "lcp", URL: "https://example.com" + page}, Value: 100}
+			if err := repo.SavePerformanceMetric(ctx, metric); err != nil {
+				t.Fatalf("SavePerformanceMetric() error = %v", err)
+			}
+		}
+	}
+
+	results, err := repo.GetSlowestURLsByPercentile(ctx, []string{projectID}, "lcp", 0.75, 3, now.Add(-time.Minute), now.Add(time.Minute), 10)
+	if err != nil {
+		t.Fatalf("GetSlowestURLsByPercentile() error = %v", err)
+	}
+	got := make(map[string]uint64)
+	for _, result := range results {
+		got[result.URL] = result.Count
+	}
+	if len(got) != 2 || got["https://example.com/at"] != 3 || got["https://example.com/above"] != 4 {
+		t.Errorf("GetSlowestURLsByPercentile() = %v, want /at and /above only", got)
+	}
+}
+
+// argsScanner 把插入参数当作查询结果扫描的 rowScanner，用于检查插入和查询的列顺序是否一致
+// timestamp 参数为毫秒时间戳，扫描时转换回 time.Time；其余参数的类型必须与扫描目标一致
+type argsScanner struct {
+	args []any
+}
+
+func (s argsScanner) Scan(dest ...any) error {
+	if len(dest) != len(s.args) {
+		return fmt.Errorf("scanning %d columns from %d args", len(dest), len(s.args))
+	}
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *time.Time:
+			millis, ok := s.args[i].(int64)
+			if !ok {
+				return fmt.Errorf("column %d: cannot scan %T into time", i, s.args[i])
+			}
+			*d = time.UnixMilli(millis).UTC()
+		case *sql.NullString:
+			if err := d.Scan(s.args[i]); err != nil {
+				return fmt.Errorf("column %d: %w", i, err)
+			}
+		default:
+			target := reflect.ValueOf(d).Elem()
+			value := reflect.ValueOf(s.args[i])
+			if value.Type() != target.Type() {
+				return fmt.Errorf("column %d: cannot scan %T into %s", i, s.args[i], target.Type())
+			}
+			target.Set(value)
+		}
+	}
+	return nil
+}
+
+// testUserAction 每个字段取不同值的用户行为，列顺序错位时扫描结果必然不同
+func testUserAction(projectID string, ts time.Time) *models.UserAction {
+	return &models.UserAction{
+		BaseLog: models.BaseLog{
+			Timestamp:  ts,
+			ProjectID:  projectID,
+			SessionID:  "session",
+			TraceID:    "trace-" + projectID,
+			UserID:     "user",
+			URL:        "https://example.com/checkout",
+			Referrer:   "https://example.com/cart",
+			SDKVersion: "1.2.3",
+			Platform:   "web",
+			Type:       "api_timing",
+			Name:       "POST /api/orders",
+			Extra:      json.RawMessage(`{"order_id":"o1"}`),
+		},
+		Message: "created",
+		Method:  "POST",
+		Status:  201,
+		Value:   123.5,
+	}
+}
+
+func TestScanUserActionMatchesArgs(t *testing.T) {
+	want := testUserAction("web", time.Date(2024, 1, 2, 3, 4, 5, 678_000_000, time.UTC))
+
+	got, err := scanUserAction(argsScanner{args: userActionArgs(want)})
+	if err != nil {
+		t.Fatalf("scanUserAction() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scanUserAction(userActionArgs(action)) =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestUserActionColumnsMatchInsert(t *testing.T) {
+	insertColumns := insertUserActionQuery[strings.Index(insertUserActionQuery, "(")+1 : strings.Index(insertUserActionQuery, ")")]
+	selectColumns := strings.Replace(userActionSelectColumns, "CAST(extra AS String)", "extra", 1)
+	if insertColumns != selectColumns {
+		t.Errorf("insert columns %q differ from select columns %q", insertColumns, selectColumns)
+	}
+	if n := len(strings.Split(insertColumns, ",")); n != len(userActionArgs(&models.UserAction{})) {
+		t.Errorf("insert has %d columns but userActionArgs returns %d values", n, len(userActionArgs(&models.UserAction{})))
+	}
+}
+
+func TestUserActionRoundTrip(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	want := testUserAction(testProjectID(t), time.Now().UTC().Truncate(time.Millisecond))
+
+	if err := repo.SaveUserAction(ctx, want); err != nil {
+		t.Fatalf("SaveUserAction() error = %v", err)
+	}
+	got, err := repo.GetUserActionByTraceID(ctx, want.TraceID)
+	if err != nil {
+		t.Fatalf("GetUserActionByTraceID() error = %v", err)
+	}
+	if got == nil {
+		t.Fatal("GetUserActionByTraceID() returned no action")
+	}
+	got.Timestamp = got.Timestamp.UTC()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestExcludeBotsFilterSQL(t *testing.T) {
+	condition, args := extraFilterSQL([]models.ExtraFilter{models.ExcludeBotsFilter})
+	if want := " AND JSONExtractRaw(CAST(extra AS String), ?) NOT IN (?, ?)"; condition != want {
+		t.Errorf("extraFilterSQL() = %q, want %q", condition, want)
+	}
+	if want := []any{models.ExtraDeviceTypeKey, `"bot"`, `"bot"`}; !reflect.DeepEqual(args, want) {
+		t.Errorf("extraFilterSQL() args = %v, want %v", args, want)
+	}
+}
+
+func TestGetCustomEventsExcludeBots(t *testing.T) {
+	repo := openTestRepository(t)
+	ctx := context.Background()
+	projectID := testProjectID(t)
+	now := time.Now().UTC()
+
+	// 没有 device_type 的事件来自未开启 User-Agent 解析时的上报，不视为爬虫
+	for name, extra := range map[string]string{
+		"googlebot": `{"browser":"bot","os":"bot","device_type":"bot"}`,
+		"human":     `{"browser":"Chrome","os":"Windows","device_type":"desktop"}`,
+		"untagged":  `{}`,
+	} {
+		event := &models.CustomEvent{BaseLog: models.BaseLog{Timestamp: now, ProjectID: projectID, Name: name, Extra: json.RawMessage(extra)}}
+		if err := repo.SaveCustomEvent(ctx, event); err != nil {
+			t.Fatalf("SaveCustomEvent() error = %v", err)
+		}
+	}
+
+	events, err := repo.GetCustomEvents(ctx, []string{projectID}, now.Add(-time.Minute), now.Add(time.Minute),
+		[]models.ExtraFilter{models.ExcludeBotsFilter}, models.ListOrder{})
+	if err != nil {
+		t.Fatalf("GetCustomEvents() error = %v", err)
+	}
+	names := make(map[string]bool)
+	for _, event := range events {
+		names[event.Name] = true
+	}
+	if len(events) != 2 || !names["human"] || !names["untagged"] {
+		t.Errorf("GetCustomEvents(exclude bots) = %v, want human and untagged", names)
+	}
+}
+
+// pageStayColumns GetPageStays 查询的列
+var pageStayColumns = []string{"timestamp", "project_id", "session_id", "trace_id", "user_id", "url", "referrer",
+	"sdk_version", "platform", "type", "name", "value", "extra"}
+
+func TestGetPageStaysEmptyExtra(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	row := func(extra driver.Value) []driver.Value {
+		return []driver.Value{ts, "web", "s1", "t1", "u1", "/", "", "1.0.0", "web", "page_stay", "stay", 12.5, extra}
+	}
+	tests := []struct {
+		name  string
+		extra driver.Value
+		want  string
+	}{
+		{"null", nil, "{}"},
+		{"empty string", "", "{}"},
+		{"object", `{"a":1}`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, _ := openFakeRepository(t, pageStayColumns, [][]driver.Value{row(tt.extra)})
+			stays, err := repo.GetPageStays(context.Background(), []string{"web"}, ts, ts.Add(time.Hour))
+			if err != nil {
+				t.Fatalf("GetPageStays() error = %v", err)
+			}
+			if len(stays) != 1 {
+				t.Fatalf("GetPageStays() returned %d rows, want 1", len(stays))
+			}
+			if got := string(stays[0].Extra); got != tt.want {
+				t.Errorf("Extra = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSavePageStayEmptyExtra(t *testing.T) {
+	repo, fake := openFakeRepository(t, nil, nil)
+	stay := &models.PageStay{BaseLog: models.BaseLog{Timestamp: time.Now(), ProjectID: "web", URL: "/"}, Value: 3}
+	if err := repo.SavePageStay(context.Background(), stay); err != nil {
+		t.Fatalf("SavePageStay() error = %v", err)
+	}
+
+	query := fake.lastQuery(t)
+	if query.query != insertPageStayQuery {
+		t.Fatalf("query = %q, want insertPageStayQuery", query.query)
+	}
+	// extra 是倒数第二个参数，最后一个为 version
+	if got := query.args[len(query.args)-2]; got != "{}" {
+		t.Errorf("extra arg = %#v, want \"{}\"", got)
+	}
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// 启动时表结构检查的处理方式
+const (
+	SchemaCheckWarn   = "warn"   // 只记录缺失的排序键和跳数索引
+	SchemaCheckCreate = "create" // 自动添加缺失的跳数索引，排序键无法修改仍只记录
+	SchemaCheckOff    = "off"    // 不检查
+)
+
+// skipIndex 事件表应有的跳数索引，与 SQL/migrations/007_skip_indexes.sql 保持一致
+type skipIndex struct {
+	Name       string
+	Definition string // ADD INDEX 中索引名之后的部分
+}
+
+// expectedSortingKeys 事件表排序键应有的前缀，按项目和时间范围查询依赖它走主键
+var expectedSortingKeys = map[string]string{
+	"error_logs":          "project_id, timestamp",
+	"performance_metrics": "project_id, timestamp",
+	"user_actions":        "project_id, timestamp",
+	"custom_events":       "project_id, timestamp",
+	"page_stay":           "project_id",
+}
+
+// expectedSkipIndexes 事件表应有的跳数索引，page_stay 的 type / name 为固定值，不需要索引
+var expectedSkipIndexes = map[string][]skipIndex{
+	"error_logs": {
+		{Name: "idx_type", Definition: "type TYPE set(100) GRANULARITY 4"},
+		{Name: "idx_name", Definition: "name TYPE bloom_filter(0.01) GRANULARITY 4"},
+	},
+	"performance_metrics": {{Name: "idx_name", Definition: "name TYPE set(100) GRANULARITY 4"}},
+	"user_actions":        {{Name: "idx_name", Definition: "name TYPE set(100) GRANULARITY 4"}},
+	"custom_events":       {{Name: "idx_name", Definition: "name TYPE bloom_filter(0.01) GRANULARITY 4"}},
+}
+
+// SchemaIssue 表结构检查发现的问题
+type SchemaIssue struct {
+	Table   string
+	Problem string
+	Fixed   bool // 已自动添加缺失的索引
+}
+
+// CheckSchema 检查事件表的排序键和跳数索引是否与迁移脚本一致
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - create: 为 true 时自动添加缺失的跳数索引，只对之后写入的数据生效，已有数据需执行迁移脚本中的 MATERIALIZE INDEX
+//
+// 返回:
+//   - []SchemaIssue: 发现的问题，表结构符合预期时为空
+//   - error: 查询系统表失败或添加索引失败时返回错误，已发现的问题仍会返回
+func (r *ClickHouseRepository) CheckSchema(ctx context.Context, create bool) ([]SchemaIssue, error) {
+	args := make([]any, len(eventTables))
+	for i, table := range eventTables {
+		args[i] = table
+	}
+
+	sortingKeys := make(map[string]string, len(eventTables))
+	query := fmt.Sprintf(`SELECT name, sorting_key FROM system.tables WHERE database = currentDatabase() AND name IN (%s)`, inPlaceholders(len(eventTables)))
+	if err := r.scanPairs(ctx, query, args, sortingKeys); err != nil {
+		return nil, fmt.Errorf("failed to query sorting keys: %w", err)
+	}
+
+	indexes := make(map[string]string)
+	query = fmt.Sprintf(`SELECT concat(table, '.', name), type FROM system.data_skipping_indices WHERE database = currentDatabase() AND table IN (%s)`, inPlaceholders(len(eventTables)))
+	if err := r.scanPairs(ctx, query, args, indexes); err != nil {
+		return nil, fmt.Errorf("failed to query skip indexes: %w", err)
+	}
+
+	var issues []SchemaIssue
+	for _, table := range eventTables {
+		key, ok := sortingKeys[table]
+		if !ok {
+			issues = append(issues, SchemaIssue{Table: table, Problem: "table does not exist"})
+			continue
+		}
+		if want := expectedSortingKeys[table]; key != want && !strings.HasPrefix(key, want+", ") {
+			issues = append(issues, SchemaIssue{Table: table, Problem: fmt.Sprintf("sorting key is (%s), expected it to start with (%s)", key, want)})
+		}
+
+		for _, index := range expectedSkipIndexes[table] {
+			if _, ok := indexes[table+"."+index.Name]; ok {
+				continue
+			}
+			issue := SchemaIssue{Table: table, Problem: fmt.Sprintf("missing skip index %s (%s)", index.Name, index.Definition)}
+			if create {
+				ddl := fmt.Sprintf("ALTER TABLE %s ADD INDEX IF NOT EXISTS %s %s", table, index.Name, index.Definition)
+				if _, err := r.DB.ExecContext(ctx, ddl); err != nil {
+					return append(issues, issue), fmt.Errorf("failed to add skip index %s to %s: %w", index.Name, table, err)
+				}
+				issue.Fixed = true
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// scanPairs 执行返回两列字符串的查询，结果写入 dst
+func (r *ClickHouseRepository) scanPairs(ctx context.Context, query string, args []any, dst map[string]string) error {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		dst[key] = value
+	}
+	return rows.Err()
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"spectra-backend/models"
+)
+
+// queryEventsSince 查询单张事件表中轮询位置之后的事件，按 (timestamp, trace_id) 升序排列
+// 列定义和扫描逻辑与时间线共用 timelineSources；filter 为以 " AND " 开头的附加条件，filterArgs 为其参数
+func (r *ClickHouseRepository) queryEventsSince(ctx context.Context, table string, projectIDs []string, since models.EventCursor, filter string, filterArgs []any, limit int) ([]models.TimelineEvent, error) {
+	var source *timelineSource
+	for i := range timelineSources {
+		if timelineSources[i].table == table {
+			source = &timelineSources[i]
+			break
+		}
+	}
+	if source == nil {
+		return nil, fmt.Errorf("unsupported table %q", table)
+	}
+
+	// 携带 trace_id 时按 (timestamp, trace_id) 比较，同一毫秒内的事件不会被跳过
+	condition := "timestamp > fromUnixTimestamp64Milli(toInt64(?))"
+	args := projectArgs(projectIDs, timestampArg(since.Timestamp))
+	if since.TraceID != "" {
+		condition = "(timestamp, trace_id) > (fromUnixTimestamp64Milli(toInt64(?)), ?)"
+		args = append(args, since.TraceID)
+	}
+	condition += filter
+	args = append(args, filterArgs...)
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE project_id IN (%s) AND %s ORDER BY timestamp, trace_id LIMIT %d`,
+		source.columns, table, inPlaceholders(len(projectIDs)), condition, limit)
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s since cursor: %w", table, err)
+	}
+	defer rows.Close()
+
+	var events []models.TimelineEvent
+	for rows.Next() {
+		event, err := source.scan(rows)
+		if err != nil {
+			if r.skipRow(ctx, table, err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s: %w", table, err)
+	}
+	return events, nil
+}
+
+// eventsAs 取出时间线事件中的具体事件
+func eventsAs[T any](events []models.TimelineEvent) []*T {
+	result := make([]*T, 0, len(events))
+	for _, event := range events {
+		result = append(result, event.Event.(*T))
+	}
+	return result
+}
+
+// GetErrorLogsSince 获取轮询位置之后的错误日志，按时间升序，最多 limit 条
+// severities、types 非空时只返回这些严重程度和类型的错误
+func (r *ClickHouseRepository) GetErrorLogsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, severities []string, types []string, limit int) ([]*models.ErrorLog, error) {
+	filter, filterArgs := extraFilterSQL(filters)
+	severityFilter, severityArgs := severityFilterSQL(severities)
+	typeFilter, typeArgs := errorTypeFilterSQL(types)
+	filterArgs = append(append(filterArgs, severityArgs...), typeArgs...)
+	events, err := r.queryEventsSince(ctx, "error_logs", projectIDs, since, filter+severityFilter+typeFilter, filterArgs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return eventsAs[models.ErrorLog](events), nil
+}
+
+// GetPerformanceMetricsSince 获取轮询位置之后的性能指标，按时间升序，最多 limit 条
+func (r *ClickHouseRepository) GetPerformanceMetricsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.PerformanceMetric, error) {
+	filter, filterArgs := extraFilterSQL(filters)
+	events, err := r.queryEventsSince(ctx, "performance_metrics", projectIDs, since, filter, filterArgs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return eventsAs[models.PerformanceMetric](events), nil
+}
+
+// GetUserActionsSince 获取轮询位置之后的用户行为，按时间升序，最多 limit 条
+// statusClasses 非空时只返回这些 HTTP 状态码类别的行为
+func (r *ClickHouseRepository) GetUserActionsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, statusClasses []int, limit int) ([]*models.UserAction, error) {
+	filter, filterArgs := extraFilterSQL(filters)
+	statusFilter, statusArgs := statusClassFilterSQL(statusClasses)
+	events, err := r.queryEventsSince(ctx, "user_actions", projectIDs, since, filter+statusFilter, append(filterArgs, statusArgs...), limit)
+	if err != nil {
+		return nil, err
+	}
+	return eventsAs[models.UserAction](events), nil
+}
+
+// GetCustomEventsSince 获取轮询位置之后的自定义事件，按时间升序，最多 limit 条
+func (r *ClickHouseRepository) GetCustomEventsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.CustomEvent, error) {
+	filter, filterArgs := extraFilterSQL(filters)
+	events, err := r.queryEventsSince(ctx, "custom_events", projectIDs, since, filter, filterArgs, limit)
+	if err != nil {
+		return nil, err
+	}
+	return eventsAs[models.CustomEvent](events), nil
+}
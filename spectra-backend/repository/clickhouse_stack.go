@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"spectra-backend/models"
+	"sync"
+	"time"
+)
+
+// insertStackTraceQuery 堆栈表插入语句，timestamp 为最近一次写入时间，ReplacingMergeTree 按此保留最新一行
+const insertStackTraceQuery = `INSERT INTO stack_traces (hash, stack, timestamp) VALUES (?, ?, fromUnixTimestamp64Milli(toInt64(?)))`
+
+const (
+	// stackCacheTTL 已写入的堆栈在此时间内不再重复写入
+	// 过期后重新写入以刷新 timestamp，保证仍被引用的堆栈不会先于错误行被 TTL 删除
+	stackCacheTTL = time.Hour
+	// stackCacheSize 缓存的堆栈哈希上限，超出时清理过期项，仍然超出则清空
+	stackCacheSize = 10000
+)
+
+// stackCache 近期已写入的堆栈哈希，只用于减少重复写入，丢失不影响正确性
+type stackCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// newStackCache 创建空的堆栈哈希缓存
+func newStackCache() *stackCache {
+	return &stackCache{seen: make(map[string]time.Time)}
+}
+
+// contains 判断哈希是否在有效期内写入过
+func (c *stackCache) contains(hash string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	written, ok := c.seen[hash]
+	return ok && now.Sub(written) < stackCacheTTL
+}
+
+// addAll 记录写入成功的哈希
+func (c *stackCache) addAll(hashes []string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, hash := range hashes {
+		if len(c.seen) >= stackCacheSize {
+			c.evict(now)
+		}
+		c.seen[hash] = now
+	}
+}
+
+// evict 清理过期项，仍然超出上限时清空，调用方需持有锁
+func (c *stackCache) evict(now time.Time) {
+	for hash, written := range c.seen {
+		if now.Sub(written) >= stackCacheTTL {
+			delete(c.seen, hash)
+		}
+	}
+	if len(c.seen) >= stackCacheSize {
+		c.seen = make(map[string]time.Time)
+	}
+}
+
+// newStackTraceRows 返回需要写入的堆栈行和对应哈希，同一批中重复的堆栈和近期已写入的堆栈会被跳过
+func (r *ClickHouseRepository) newStackTraceRows(logs []*models.ErrorLog) ([][]any, []string) {
+	now := time.Now()
+	var rows [][]any
+	var hashes []string
+	pending := make(map[string]struct{})
+	for _, log := range logs {
+		if log.StackHash == "" || log.Stack == "" {
+			continue
+		}
+		if _, ok := pending[log.StackHash]; ok || r.stacks.contains(log.StackHash, now) {
+			continue
+		}
+		pending[log.StackHash] = struct{}{}
+		rows = append(rows, []any{log.StackHash, log.Stack, timestampArg(now)})
+		hashes = append(hashes, log.StackHash)
+	}
+	return rows, hashes
+}
+
+// saveStackTraces 写入错误日志中尚未保存的堆栈
+func (r *ClickHouseRepository) saveStackTraces(ctx context.Context, logs []*models.ErrorLog) error {
+	rows, hashes := r.newStackTraceRows(logs)
+	for _, args := range rows {
+		if _, err := r.DB.ExecContext(r.insertContext(ctx), insertStackTraceQuery, args...); err != nil {
+			return fmt.Errorf("failed to save stack trace: %w", err)
+		}
+	}
+	r.stacks.addAll(hashes, time.Now())
+	return nil
+}
+
+// GetStackTrace 根据哈希获取完整堆栈
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//   - hash: 堆栈哈希，见 models.ErrorLog.StackHash
+//
+// 返回:
+//   - *models.StackTrace: 堆栈对象，如果不存在则为nil
+//   - error: 查询过程中的错误信息，成功或未找到则为nil
+func (r *ClickHouseRepository) GetStackTrace(ctx context.Context, hash string) (*models.StackTrace, error) {
+	// 合并前可能存在多行，取最近写入的一行
+	query := `SELECT hash, argMax(stack, timestamp), max(timestamp)
+		FROM stack_traces
+		WHERE hash = ?
+		GROUP BY hash`
+
+	var trace models.StackTrace
+	err := r.DB.QueryRowContext(r.readContext(ctx), query, hash).Scan(&trace.Hash, &trace.Stack, &trace.LastSeen)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to query stack trace: %w", err)
+	}
+	return &trace, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"spectra-backend/models"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// ErrSystemTablesDenied 数据库用户没有读取 system.parts 的权限
+var ErrSystemTablesDenied = errors.New("the ClickHouse user is not allowed to read system.parts, grant SELECT ON system.parts to it")
+
+// accessDeniedCode ClickHouse 的 ACCESS_DENIED 错误码
+const accessDeniedCode = 497
+
+// GetStorageStats 从 system.parts 统计各数据表的行数和磁盘占用
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//
+// 返回:
+//   - *models.StorageStats: 包含所有数据表（事件表和 stack_traces），没有数据的表计数为 0，按磁盘占用降序排列
+//   - error: 没有 system.parts 读取权限时返回 ErrSystemTablesDenied，其他查询错误原样包装
+func (r *ClickHouseRepository) GetStorageStats(ctx context.Context) (*models.StorageStats, error) {
+	tables := retentionTables
+	args := make([]any, len(tables))
+	for i, table := range tables {
+		args[i] = table
+	}
+	query := fmt.Sprintf(`SELECT table, sum(rows), sum(bytes_on_disk), sum(data_uncompressed_bytes), count(), max(modification_time)
+		FROM system.parts
+		WHERE active AND database = currentDatabase() AND table IN (%s)
+		GROUP BY table`, inPlaceholders(len(tables)))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		var exception *clickhouse.Exception
+		if errors.As(err, &exception) && exception.Code == accessDeniedCode {
+			return nil, fmt.Errorf("%w: %s", ErrSystemTablesDenied, exception.Message)
+		}
+		return nil, fmt.Errorf("failed to query storage stats: %w", err)
+	}
+	defer rows.Close()
+
+	byTable := make(map[string]models.TableStats, len(tables))
+	for rows.Next() {
+		var stats models.TableStats
+		var lastModified time.Time
+		if err := rows.Scan(&stats.Table, &stats.Rows, &stats.BytesOnDisk, &stats.UncompressedBytes, &stats.Parts, &lastModified); err != nil {
+			return nil, fmt.Errorf("failed to scan storage stats: %w", err)
+		}
+		stats.LastModified = &lastModified
+		byTable[stats.Table] = stats
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate storage stats: %w", err)
+	}
+
+	result := &models.StorageStats{Tables: make([]models.TableStats, 0, len(tables))}
+	for _, table := range tables {
+		stats, ok := byTable[table]
+		if !ok {
+			stats = models.TableStats{Table: table}
+		}
+		result.Tables = append(result.Tables, stats)
+		result.TotalRows += stats.Rows
+		result.TotalBytesOnDisk += stats.BytesOnDisk
+	}
+	sort.SliceStable(result.Tables, func(i, j int) bool {
+		return result.Tables[i].BytesOnDisk > result.Tables[j].BytesOnDisk
+	})
+	return result, nil
+}
+
+// GetEventTableColumns 从 system.columns 读取各事件表的列名和类型
+// 参数:
+//   - ctx: 上下文对象，用于控制请求超时和取消
+//
+// 返回:
+//   - []models.EventTypeSchema: 按 models.EventTypes 顺序排列，列按表定义顺序排列，只填充列名和类型；表不存在时列为空
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetEventTableColumns(ctx context.Context) ([]models.EventTypeSchema, error) {
+	args := make([]any, len(eventTables))
+	for i, table := range eventTables {
+		args[i] = table
+	}
+	query := fmt.Sprintf(`SELECT table, name, type
+		FROM system.columns
+		WHERE database = currentDatabase() AND table IN (%s)
+		ORDER BY table, position`, inPlaceholders(len(eventTables)))
+
+	rows, err := r.DB.QueryContext(r.readContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table columns: %w", err)
+	}
+	defer rows.Close()
+
+	byTable := make(map[string][]models.ColumnMeta, len(eventTables))
+	for rows.Next() {
+		var table string
+		var column models.ColumnMeta
+		if err := rows.Scan(&table, &column.Name, &column.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan table column: %w", err)
+		}
+		byTable[table] = append(byTable[table], column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate table columns: %w", err)
+	}
+
+	schemas := make([]models.EventTypeSchema, 0, len(models.EventTypes))
+	for _, eventType := range models.EventTypes {
+		table := eventTypeTables[eventType]
+		columns := byTable[table]
+		if columns == nil {
+			columns = []models.ColumnMeta{}
+		}
+		schemas = append(schemas, models.EventTypeSchema{Type: eventType, Table: table, Columns: columns})
+	}
+	return schemas, nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"spectra-backend/models"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// timelineTableLimit 时间线中每张表最多返回的事件数
+const timelineTableLimit = 1000
+
+// 时间线支持的关联字段
+const (
+	TimelineFieldSession = "session_id"
+	TimelineFieldTrace   = "trace_id"
+)
+
+// timelineSource 单张表的时间线查询定义
+type timelineSource struct {
+	table   string
+	columns string
+	// final 查询时使用 FINAL 去重，用于 ReplacingMergeTree 表
+	final bool
+	scan  func(rows *sql.Rows) (models.TimelineEvent, error)
+}
+
+// timelineSources 参与时间线合并的事件表
+var timelineSources = []timelineSource{
+	{
+		table:   "error_logs",
+		columns: "timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, severity, stack_hash, CAST(extra AS String)",
+		scan: func(rows *sql.Rows) (models.TimelineEvent, error) {
+			var log models.ErrorLog
+			var extraStr sql.NullString
+			err := rows.Scan(&log.Timestamp, &log.ProjectID, &log.SessionID, &log.TraceID, &log.UserID,
+				&log.URL, &log.Referrer, &log.SDKVersion, &log.Platform, &log.Type, &log.Name, &log.Message, &log.Severity, &log.StackHash, &extraStr)
+			log.Extra = timelineExtra(extraStr)
+			return models.TimelineEvent{Kind: models.EventTypeErrorLog, Timestamp: log.Timestamp, Event: &log}, err
+		},
+	},
+	{
+		table:   "performance_metrics",
+		columns: "timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, CAST(extra AS String)",
+		scan: func(rows *sql.Rows) (models.TimelineEvent, error) {
+			var metric models.PerformanceMetric
+			var extraStr sql.NullString
+			err := rows.Scan(&metric.Timestamp, &metric.ProjectID, &metric.SessionID, &metric.TraceID, &metric.UserID,
+				&metric.URL, &metric.Referrer, &metric.SDKVersion, &metric.Platform, &metric.Type, &metric.Name, &metric.Value, &extraStr)
+			metric.Extra = timelineExtra(extraStr)
+			return models.TimelineEvent{Kind: models.EventTypePerformanceMetric, Timestamp: metric.Timestamp, Event: &metric}, err
+		},
+	},
+	{
+		table:   "user_actions",
+		columns: userActionSelectColumns,
+		scan: func(rows *sql.Rows) (models.TimelineEvent, error) {
+			action, err := scanUserAction(rows)
+			if err != nil {
+				return models.TimelineEvent{}, err
+			}
+			return models.TimelineEvent{Kind: models.EventTypeUserAction, Timestamp: action.Timestamp, Event: action}, nil
+		},
+	},
+	{
+		table:   "custom_events",
+		columns: "timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, message, CAST(extra AS String)",
+		scan: func(rows *sql.Rows) (models.TimelineEvent, error) {
+			var event models.CustomEvent
+			var extraStr sql.NullString
+			err := rows.Scan(&event.Timestamp, &event.ProjectID, &event.SessionID, &event.TraceID, &event.UserID,
+				&event.URL, &event.Referrer, &event.SDKVersion, &event.Platform, &event.Type, &event.Name, &event.Message, &extraStr)
+			event.Extra = timelineExtra(extraStr)
+			return models.TimelineEvent{Kind: models.EventTypeCustomEvent, Timestamp: event.Timestamp, Event: &event}, err
+		},
+	},
+	{
+		table:   "page_stay",
+		columns: "timestamp, project_id, session_id, trace_id, user_id, url, referrer, sdk_version, platform, type, name, value, CAST(extra AS String)",
+		final:   true,
+		scan: func(rows *sql.Rows) (models.TimelineEvent, error) {
+			var pageStay models.PageStay
+			var extraStr sql.NullString
+			err := rows.Scan(&pageStay.Timestamp, &pageStay.ProjectID, &pageStay.SessionID, &pageStay.TraceID, &pageStay.UserID,
+				&pageStay.URL, &pageStay.Referrer, &pageStay.SDKVersion, &pageStay.Platform, &pageStay.Type, &pageStay.Name, &pageStay.Value, &extraStr)
+			pageStay.Extra = timelineExtra(extraStr)
+			return models.TimelineEvent{Kind: models.EventTypePageStay, Timestamp: pageStay.Timestamp, Event: &pageStay}, err
+		},
+	},
+}
+
+// timelineExtra 将查询到的 extra 转换为 JSON，空值使用空对象
+func timelineExtra(extraStr sql.NullString) json.RawMessage {
+	if extraStr.Valid {
+		return json.RawMessage(extraStr.String)
+	}
+	return json.RawMessage("{}")
+}
+
+// GetTimeline 并发查询所有事件表中某个会话或链路的事件，合并后按时间升序返回
+// 参数:
+//   - ctx: 上下文对象，所有表的查询共享其超时和取消
+//   - projectID: 项目标识符
+//   - field: 关联字段，TimelineFieldSession 或 TimelineFieldTrace
+//   - value: 关联字段的值
+//   - allowPartial: 为 true 时单表失败不影响整体，结果中标记 Partial；为 false 时任一表失败即取消其余查询并返回错误
+//
+// 返回:
+//   - *models.Timeline: 合并后的时间线
+//   - error: 查询过程中的错误信息，成功则为nil
+func (r *ClickHouseRepository) GetTimeline(ctx context.Context, projectID string, field string, value string, allowPartial bool) (*models.Timeline, error) {
+	if field != TimelineFieldSession && field != TimelineFieldTrace {
+		return nil, fmt.Errorf("unsupported timeline field %q", field)
+	}
+
+	timeline := &models.Timeline{
+		ProjectID: projectID,
+		Field:     field,
+		Value:     value,
+		Events:    []models.TimelineEvent{},
+	}
+	results := make([][]models.TimelineEvent, len(timelineSources))
+	var mu sync.Mutex
+
+	group, groupCtx := errgroup.WithContext(r.readContext(ctx))
+	for i, source := range timelineSources {
+		group.Go(func() error {
+			events, err := r.queryTimelineSource(groupCtx, source, projectID, field, value)
+			if err != nil {
+				if !allowPartial {
+					return err
+				}
+				mu.Lock()
+				timeline.Partial = true
+				timeline.FailedTables = append(timeline.FailedTables, source.table)
+				mu.Unlock()
+				return nil
+			}
+			results[i] = events
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+
+	for _, events := range results {
+		timeline.Events = append(timeline.Events, events...)
+	}
+	// 时间相同时保持表的固定顺序，结果稳定
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].Timestamp.Before(timeline.Events[j].Timestamp)
+	})
+	sort.Strings(timeline.FailedTables)
+	return timeline, nil
+}
+
+// queryTimelineSource 查询单张表中的时间线事件
+func (r *ClickHouseRepository) queryTimelineSource(ctx context.Context, source timelineSource, projectID string, field string, value string) ([]models.TimelineEvent, error) {
+	from := source.table
+	if source.final {
+		from += " FINAL"
+	}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE project_id = ? AND %s = ? ORDER BY timestamp LIMIT %d`,
+		source.columns, from, field, timelineTableLimit)
+
+	rows, err := r.DB.QueryContext(ctx, query, projectID, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s timeline: %w", source.table, err)
+	}
+	defer rows.Close()
+
+	var events []models.TimelineEvent
+	for rows.Next() {
+		event, err := source.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s timeline event: %w", source.table, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate %s timeline: %w", source.table, err)
+	}
+	return events, nil
+}
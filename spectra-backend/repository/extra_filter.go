@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"spectra-backend/models"
+	"strings"
+)
+
+// extraFilterSQL 生成 extra 过滤条件，返回以 " AND " 开头的 SQL 片段及其参数，没有过滤条件时返回空串
+// 键名和值全部通过占位符传入；值同时按 JSON 字符串和原始字面量匹配，数字、布尔值与字符串均可命中
+// 设置了 Column 的条件按该列精确匹配，列名必须在 models.FilterColumns 中，否则忽略
+func extraFilterSQL(filters []models.ExtraFilter) (string, []any) {
+	var sb strings.Builder
+	var args []any
+	for _, filter := range filters {
+		if filter.Column != "" {
+			if slices.Contains(models.FilterColumns, filter.Column) {
+				fmt.Fprintf(&sb, " AND %s = ?", filter.Column)
+				args = append(args, filter.Value)
+			}
+			continue
+		}
+		path := inPlaceholders(len(filter.Path))
+		quoted, raw := extraFilterValues(filter.Value)
+		switch filter.Op {
+		case models.ExtraFilterContains:
+			fmt.Fprintf(&sb, " AND hasAny(JSONExtractArrayRaw(CAST(extra AS String), %s), [?, ?])", path)
+		case models.ExtraFilterNotEquals:
+			fmt.Fprintf(&sb, " AND JSONExtractRaw(CAST(extra AS String), %s) NOT IN (?, ?)", path)
+		default:
+			fmt.Fprintf(&sb, " AND JSONExtractRaw(CAST(extra AS String), %s) IN (?, ?)", path)
+		}
+		for _, key := range filter.Path {
+			args = append(args, key)
+		}
+		args = append(args, quoted, raw)
+	}
+	return sb.String(), args
+}
+
+// extraFilterValues 返回值的 JSON 字符串形式和原始字面量形式
+// 原始形式不是合法的 JSON 标量时退化为 JSON 字符串，避免误匹配对象或数组
+func extraFilterValues(value string) (string, string) {
+	// 与 SDK 序列化一致，不转义 HTML 字符
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+
+	var scalar any
+	if err := json.Unmarshal([]byte(value), &scalar); err != nil {
+		return quoted, quoted
+	}
+	switch scalar.(type) {
+	case float64, bool, nil:
+		return quoted, value
+	default:
+		return quoted, quoted
+	}
+}
+
+// sortColumns 排序字段与列名的白名单，排序列不能使用占位符，只从这里取列名拼接
+var sortColumns = map[string]string{
+	models.SortFieldTimestamp: "timestamp",
+	models.SortFieldValue:     "value",
+}
+
+// orderBySQL 生成 ORDER BY 之后的排序子句，未知字段按 timestamp 排序
+// 按 value 排序时以 timestamp 作为第二排序列，同值事件的顺序保持稳定
+func orderBySQL(order models.ListOrder) string {
+	direction := "ASC"
+	if order.Desc {
+		direction = "DESC"
+	}
+	column, ok := sortColumns[order.Field]
+	if !ok || column == "timestamp" {
+		return "timestamp " + direction
+	}
+	return column + " " + direction + ", timestamp " + direction
+}
+
+// severityFilterSQL 生成错误严重程度过滤条件，返回以 " AND " 开头的 SQL 片段及其参数，为空时不过滤
+func severityFilterSQL(severities []string) (string, []any) {
+	return inFilterSQL("severity", severities)
+}
+
+// errorTypeFilterSQL 生成错误类型过滤条件，返回以 " AND " 开头的 SQL 片段及其参数，为空时不过滤
+func errorTypeFilterSQL(types []string) (string, []any) {
+	return inFilterSQL("type", types)
+}
+
+// inFilterSQL 生成 column IN (...) 条件，值全部通过占位符传入；column 只能是代码中的固定列名
+func inFilterSQL(column string, values []string) (string, []any) {
+	if len(values) == 0 {
+		return "", nil
+	}
+	args := make([]any, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return fmt.Sprintf(" AND %s IN (%s)", column, inPlaceholders(len(values))), args
+}
+
+// statusClassFilterSQL 生成 HTTP 状态码类别过滤条件，classes 中 5 表示 5xx，为空时不过滤
+func statusClassFilterSQL(classes []int) (string, []any) {
+	if len(classes) == 0 {
+		return "", nil
+	}
+	args := make([]any, 0, len(classes))
+	for _, class := range classes {
+		args = append(args, class)
+	}
+	return fmt.Sprintf(" AND intDiv(status, 100) IN (%s)", inPlaceholders(len(classes))), args
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// fakeQuery 假数据库收到的一次查询
+type fakeQuery struct {
+	query string
+	args  []any
+}
+
+// fakeDB 不连接 ClickHouse 的 database/sql 驱动，记录收到的查询并为每次查询返回相同的预设结果
+type fakeDB struct {
+	mu      sync.Mutex
+	queries []fakeQuery
+	columns []string
+	rows    [][]driver.Value
+}
+
+// openFakeRepository 创建使用 fakeDB 的仓库，查询返回 columns 和 rows
+func openFakeRepository(t *testing.T, columns []string, rows [][]driver.Value) (*ClickHouseRepository, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{columns: columns, rows: rows}
+	db := sql.OpenDB(fake)
+	t.Cleanup(func() { db.Close() })
+	return &ClickHouseRepository{DB: db, Logger: zap.NewNop(), inserts: newInsertPool(1), stacks: newStackCache()}, fake
+}
+
+// lastQuery 返回最近一次查询，没有查询时终止测试
+func (f *fakeDB) lastQuery(t *testing.T) fakeQuery {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.queries) == 0 {
+		t.Fatal("no query was executed")
+	}
+	return f.queries[len(f.queries)-1]
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+
+func (f *fakeDB) Driver() driver.Driver { return nil }
+
+// fakeConn fakeDB 的连接，只支持 QueryContext 和 ExecContext
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB does not support prepared statements")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("fakeDB does not support transactions")
+}
+
+// CheckNamedValue 原样接受所有参数，包括 database/sql 默认不支持的切片
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.record(query, args)
+	return &fakeRows{columns: c.db.columns, rows: c.db.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.record(query, args)
+	return driver.RowsAffected(0), nil
+}
+
+// record 记录一次查询及其参数
+func (c *fakeConn) record(query string, args []driver.NamedValue) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.queries = append(c.db.queries, fakeQuery{query: query, args: values})
+}
+
+// fakeRows 预设的结果集
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"spectra-backend/metrics"
+	"sync"
+	"time"
+)
+
+// errInsertPoolClosed 写入池已关闭，不再接受新的任务
+var errInsertPoolClosed = errors.New("insert pool is closed")
+
+// insertJob 单个写入任务
+type insertJob struct {
+	ctx      context.Context
+	run      func(ctx context.Context) error
+	enqueued time.Time
+	done     chan error
+}
+
+// insertPool 固定大小的写入工作池，各表的批量写入并发执行，慢表不会阻塞其他表
+type insertPool struct {
+	jobs chan insertJob
+	wg   sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newInsertPool 创建并启动写入工作池，size 小于 1 时按 1 处理
+func newInsertPool(size int) *insertPool {
+	if size < 1 {
+		size = 1
+	}
+
+	pool := &insertPool{jobs: make(chan insertJob)}
+	metrics.InsertWorkers.Set(float64(size))
+	pool.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go pool.worker()
+	}
+	return pool
+}
+
+// worker 循环执行任务，直到任务通道关闭
+func (p *insertPool) worker() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		metrics.InsertQueueWaitSeconds.Observe(time.Since(job.enqueued).Seconds())
+		metrics.InsertWorkersBusy.Inc()
+		job.done <- job.run(job.ctx)
+		metrics.InsertWorkersBusy.Dec()
+	}
+}
+
+// submit 提交写入任务，返回接收执行结果的通道
+// 所有工作协程都忙时阻塞等待，ctx 取消或工作池关闭时直接返回错误
+func (p *insertPool) submit(ctx context.Context, run func(ctx context.Context) error) <-chan error {
+	done := make(chan error, 1)
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		done <- errInsertPoolClosed
+		return done
+	}
+
+	select {
+	case p.jobs <- insertJob{ctx: ctx, run: run, enqueued: time.Now(), done: done}:
+	case <-ctx.Done():
+		done <- ctx.Err()
+	}
+	return done
+}
+
+// close 停止接受新任务，并等待已提交的任务全部完成
+func (p *insertPool) close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.jobs)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
@@ -10,28 +10,86 @@ import (
 type LogRepository interface {
 	// ErrorLog 相关方法
 	SaveErrorLog(ctx context.Context, log *models.ErrorLog) error
-	GetErrorLogs(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.ErrorLog, error)
+	GetErrorLogs(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, severities []string, types []string, order models.ListOrder) ([]*models.ErrorLog, error)
+	GetErrorLogsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, severities []string, types []string, limit int) ([]*models.ErrorLog, error)
 	GetErrorLogByTraceID(ctx context.Context, traceID string) (*models.ErrorLog, error)
+	GetErrorLogsByTraceIDs(ctx context.Context, traceIDs []string) ([]*models.ErrorLog, error)
+	GetErrorGroupTrend(ctx context.Context, projectIDs []string, fingerprint string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.ErrorGroupTrend, error)
+	GetCoOccurringErrors(ctx context.Context, projectIDs []string, errorName string, startTime, endTime time.Time) (*models.ErrorCoOccurrence, error)
+	GetErrorLogsWithPerformance(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]*models.ErrorWithPerformance, error)
+	GetSeverityCounts(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]models.SeverityCount, error)
+	GetStackTrace(ctx context.Context, hash string) (*models.StackTrace, error)
 
 	// PerformanceMetric 相关方法
 	SavePerformanceMetric(ctx context.Context, metric *models.PerformanceMetric) error
-	GetPerformanceMetrics(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
-	GetPerformanceMetricsByType(ctx context.Context, projectID string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetrics(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetricsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetricByTraceID(ctx context.Context, traceID string) (*models.PerformanceMetric, error)
+	GetPerformanceMetricsByType(ctx context.Context, projectIDs []string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
+	GetApdex(ctx context.Context, projectIDs []string, metricName string, threshold float64, startTime, endTime time.Time) (*models.ApdexScore, error)
+	GetWebVitals(ctx context.Context, projectIDs []string, vitals []models.WebVitalScore, startTime, endTime time.Time) ([]models.WebVitalScore, error)
+	GetSlowestURLsByPercentile(ctx context.Context, projectIDs []string, metricName string, quantile float64, minCount int, startTime, endTime time.Time, limit int) ([]*models.URLPercentile, error)
 
 	// UserAction 相关方法
 	SaveUserAction(ctx context.Context, action *models.UserAction) error
-	GetUserActions(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.UserAction, error)
-	GetUserActionsByType(ctx context.Context, projectID string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error)
+	GetUserActions(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, statusClasses []int, order models.ListOrder) ([]*models.UserAction, error)
+	GetUserActionsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, statusClasses []int, limit int) ([]*models.UserAction, error)
+	GetUserActionByTraceID(ctx context.Context, traceID string) (*models.UserAction, error)
+	GetStatusClassCounts(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (map[int]uint64, error)
+	GetUserActionsByType(ctx context.Context, projectIDs []string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error)
 
 	// CustomEvent 相关方法
 	SaveCustomEvent(ctx context.Context, event *models.CustomEvent) error
-	GetCustomEvents(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
-	GetCustomEventsByName(ctx context.Context, projectID string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
+	GetCustomEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.CustomEvent, error)
+	GetCustomEventsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.CustomEvent, error)
+	GetCustomEventsByName(ctx context.Context, projectIDs []string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
 
 	// PageStay 相关方法
 	SavePageStay(ctx context.Context, pageStay *models.PageStay) error
-	GetPageStays(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PageStay, error)
-	GetAveragePageStay(ctx context.Context, projectID string, startTime, endTime time.Time) (float64, error)
+	GetPageStays(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]*models.PageStay, error)
+	GetAveragePageStay(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.PageStayAverage, error)
+
+	// 时间线相关方法
+	GetTimeline(ctx context.Context, projectID string, field string, value string, allowPartial bool) (*models.Timeline, error)
+
+	// 项目相关方法
+	GetDataRange(ctx context.Context, projectID string) (*models.DataRange, error)
+
+	// 看板相关方法
+	GetActiveProjects(ctx context.Context, since time.Time) ([]string, error)
+	GetDashboardSummary(ctx context.Context, projectID string, startTime, endTime time.Time) (*models.DashboardSummary, error)
+	GetOverview(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.Overview, error)
+
+	// 运维统计相关方法
+	GetIngestionRate(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) ([]models.TrendBucket, error)
+	GetActivityHeatmap(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, loc *time.Location) ([]models.HeatmapCell, error)
+	GetSDKVersionBreakdown(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time) ([]models.SDKVersionCount, error)
+	GetEventsByReferrerDomain(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, limit int) ([]models.ReferrerCount, error)
+	GetErrorsByRelease(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]models.ReleaseErrorCount, error)
+	CountEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (uint64, error)
+	GetSessionCount(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter) (uint64, error)
+	GetSessionCountBuckets(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location, filters []models.ExtraFilter) ([]models.TrendBucket, error)
+	GetCrashFreeCounts(ctx context.Context, projectIDs []string, severities []string, startTime, endTime time.Time, filters []models.ExtraFilter) (*models.CrashFreeStats, error)
+
+	// 批量写入方法
+	SaveBatch(ctx context.Context, batch *models.EventBatch) error
+
+	// 数据删除相关方法
+	DeleteByUser(ctx context.Context, projectID string, userID string) (*models.DeletionSummary, error)
+	DeleteBySession(ctx context.Context, projectID string, sessionID string) (*models.DeletionSummary, error)
+	DeleteProject(ctx context.Context, projectID string) (*models.DeletionSummary, error)
+
+	// 数据保留相关方法
+	SetRetention(ctx context.Context, days int) (*models.RetentionPolicy, error)
+
+	// 存储统计相关方法
+	GetStorageStats(ctx context.Context) (*models.StorageStats, error)
+	GetEventTableColumns(ctx context.Context) ([]models.EventTypeSchema, error)
+
+	// 健康检查相关方法
+	Ping(ctx context.Context) error
+	SaveHealthCanary(ctx context.Context, canary *models.HealthCanary) error
+	GetHealthCanary(ctx context.Context, id string) (*models.HealthCanary, error)
 
 	// 通用方法
 	Close() error
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"spectra-backend/metrics"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// skippedRowsKey 上下文中跳过行计数器的键
+type skippedRowsKey struct{}
+
+// WithSkippedRows 返回携带跳过行计数器的上下文
+// 列表查询跳过无法解析的行时累加计数，调用方在查询结束后通过 SkippedRows 读取
+func WithSkippedRows(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skippedRowsKey{}, new(atomic.Int64))
+}
+
+// SkippedRows 返回上下文中累计跳过的行数，上下文未携带计数器时返回 0
+func SkippedRows(ctx context.Context) int64 {
+	if counter, ok := ctx.Value(skippedRowsKey{}).(*atomic.Int64); ok {
+		return counter.Load()
+	}
+	return 0
+}
+
+// skipRow 处理列表查询中单行的扫描错误
+// 开启 SkipMalformedRows 时记录日志和指标、累加计数并返回 true，调用方跳过该行继续遍历；否则返回 false，由调用方中止查询
+func (r *ClickHouseRepository) skipRow(ctx context.Context, table string, err error) bool {
+	if !r.SkipMalformedRows {
+		return false
+	}
+	r.Logger.Warn("Skipping malformed row", zap.String("table", table), zap.Error(err))
+	metrics.QueryRowsSkipped.WithLabelValues(table).Inc()
+	if counter, ok := ctx.Value(skippedRowsKey{}).(*atomic.Int64); ok {
+		counter.Add(1)
+	}
+	return true
+}
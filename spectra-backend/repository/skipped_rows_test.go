@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"spectra-backend/models"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// errorLogColumns GetErrorLogs 查询的列
+var errorLogColumns = []string{"timestamp", "project_id", "session_id", "trace_id", "user_id", "url", "referrer",
+	"sdk_version", "platform", "type", "name", "message", "severity", "stack_hash", "extra"}
+
+// errorLogRow 构造一行 error_logs 查询结果，timestamp 可传入无法扫描为时间的值
+func errorLogRow(timestamp driver.Value, message string) []driver.Value {
+	return []driver.Value{timestamp, "web", "s1", "t1", "u1", "/", "", "1.0.0", "web", "js", "TypeError", message, "error", "", `{"a":1}`}
+}
+
+func TestGetErrorLogsSkipsMalformedRows(t *testing.T) {
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := [][]driver.Value{
+		errorLogRow(ts, "first"),
+		errorLogRow("not a timestamp", "corrupt"),
+		errorLogRow(ts.Add(time.Second), "second"),
+	}
+
+	t.Run("skip enabled", func(t *testing.T) {
+		repo, _ := openFakeRepository(t, errorLogColumns, rows)
+		repo.SkipMalformedRows = true
+		ctx := WithSkippedRows(context.Background())
+
+		logs, err := repo.GetErrorLogs(ctx, []string{"web"}, ts, ts.Add(time.Hour), nil, nil, nil, models.ListOrder{})
+		if err != nil {
+			t.Fatalf("GetErrorLogs() error = %v", err)
+		}
+		if len(logs) != 2 || logs[0].Message != "first" || logs[1].Message != "second" {
+			t.Errorf("GetErrorLogs() = %v, want the two good rows", logs)
+		}
+		if got := SkippedRows(ctx); got != 1 {
+			t.Errorf("SkippedRows() = %d, want 1", got)
+		}
+	})
+
+	t.Run("skip disabled", func(t *testing.T) {
+		repo, _ := openFakeRepository(t, errorLogColumns, rows)
+		logs, err := repo.GetErrorLogs(context.Background(), []string{"web"}, ts, ts.Add(time.Hour), nil, nil, nil, models.ListOrder{})
+		if err == nil {
+			t.Errorf("GetErrorLogs() = %v, want scan error", logs)
+		}
+	})
+}
+
+func TestSkippedRowsWithoutCounter(t *testing.T) {
+	repo := &ClickHouseRepository{Logger: zap.NewNop(), SkipMalformedRows: true}
+	if !repo.skipRow(context.Background(), "error_logs", context.Canceled) {
+		t.Error("skipRow() = false with SkipMalformedRows enabled")
+	}
+	if got := SkippedRows(context.Background()); got != 0 {
+		t.Errorf("SkippedRows() without counter = %d, want 0", got)
+	}
+}
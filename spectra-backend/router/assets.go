@@ -0,0 +1,40 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 前端资源位置，相对于工作目录
+const (
+	staticDir       = "./static"
+	templatesGlob   = "templates/*"
+	homeTemplate    = "index.html"
+	staticURLPrefix = "/static"
+)
+
+// LoadAssets 加载静态文件和页面模板，资源缺失时只记录警告，不影响 API 启动
+// 模板未加载时 HomeRoutes 的首页返回 JSON，便于在没有前端资源的环境（如集成测试）中启动完整路由
+func LoadAssets(router *gin.Engine, logger *zap.Logger) {
+	if info, err := os.Stat(staticDir); err == nil && info.IsDir() {
+		router.Static(staticURLPrefix, staticDir)
+	} else {
+		logger.Warn("Static directory not found, skipping static files", zap.String("dir", staticDir))
+	}
+
+	// LoadHTMLGlob 在没有匹配的文件时会 panic，先检查再加载
+	matches, err := filepath.Glob(templatesGlob)
+	if err != nil || len(matches) == 0 {
+		logger.Warn("No templates found, homepage will serve JSON", zap.String("pattern", templatesGlob))
+		return
+	}
+	router.LoadHTMLGlob(templatesGlob)
+}
+
+// templatesLoaded 判断是否已加载页面模板
+func templatesLoaded(router *gin.Engine) bool {
+	return router.HTMLRender != nil
+}
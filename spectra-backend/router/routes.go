@@ -1,16 +1,76 @@
 package router
 
 import (
+	"context"
+	"net/http"
 	"spectra-backend/config"
+	"spectra-backend/eventbus"
 	"spectra-backend/handlers"
+	"spectra-backend/metrics"
+	"spectra-backend/middleware"
 	"spectra-backend/repository"
 	"spectra-backend/services"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
-func SetupRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger) {
+// deepHealthInterval 深度健康检查的最小调用间隔，每次检查都会写入探针行
+const deepHealthInterval = 10 * time.Second
+
+// warmupTimeout 启动时预热数据库连接的最长时间
+const warmupTimeout = 30 * time.Second
+
+// runtimeMetricsInterval 运行时指标（goroutine、连接池、订阅者）的采集间隔
+const runtimeMetricsInterval = 15 * time.Second
+
+// ingestPaths 数据上报接口相对于 /api（或 /api/<版本号>）的路径，新增上报接口时需同步添加
+var ingestPaths = map[string]struct{}{
+	"/error-logs":          {},
+	"/performance-metrics": {},
+	"/user-actions":        {},
+	"/custom-events":       {},
+	"/page-stays":          {},
+	"/events/compressed":   {},
+	"/ingest/stream":       {},
+}
+
+// healthPaths 健康检查和监控接口，不受全局并发上限限制，过载时仍能探活和抓取指标
+var healthPaths = map[string]struct{}{
+	"/ping":        {},
+	"/metrics":     {},
+	"/health/deep": {},
+}
+
+// IsHealthRequest 判断请求是否为健康检查或指标抓取
+func IsHealthRequest(path string, method string) bool {
+	_, ok := healthPaths[path]
+	return ok
+}
+
+// IsIngestRequest 判断请求是否为数据上报，上报接口与查询接口共用路径，仅以 POST 区分
+// 带版本号（/api/v1/...）和不带版本号（/api/...）的路径都能识别
+func IsIngestRequest(path string, method string) bool {
+	if method != http.MethodPost {
+		return false
+	}
+	rest, ok := strings.CutPrefix(path, "/api")
+	if !ok {
+		return false
+	}
+	if version, tail, found := strings.Cut(strings.TrimPrefix(rest, "/"), "/"); found && config.IsAPIVersion(version) {
+		rest = "/" + tail
+	}
+	_, ok = ingestPaths[rest]
+	return ok
+}
+
+// SetupRoutes 初始化依赖并注册路由
+// 返回的 drainStreams 在服务开始关闭时调用，通知实时推送连接断开并等待其退出
+// 返回的 shutdown 函数在服务关闭后调用，停止后台任务并等待进行中的写入完成
+func SetupRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger) (drainStreams func(context.Context) error, shutdown func()) {
 	// 首页和健康检查路由
 	HomeRoutes(router, logger)
 
@@ -19,43 +79,278 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, logger *zap.Logger) {
 	if err != nil {
 		logger.Fatal("Failed to initialize repository", zap.Error(err))
 	}
+	warmUpConnections(repo, cfg.DB.WarmupConns, logger)
+	checkSchema(repo, cfg.DB.SchemaCheck, logger)
+
+	// 初始化事件总线，新写入的事件会发布到总线供实时消费者订阅
+	bus := eventbus.NewBatching(time.Duration(cfg.EventBus.BatchWindow)*time.Millisecond, cfg.EventBus.BatchMaxSize)
 
 	// 初始化服务
-	logService := services.NewLogService(repo)
+	projectIDs, err := services.NewProjectIDPolicy(cfg.ProjectID)
+	if err != nil {
+		logger.Fatal("Invalid project_id configuration", zap.Error(err))
+	}
+	logService, err := services.NewLogService(repo, bus, cfg.Ingest, cfg.WebVitals, projectIDs)
+	if err != nil {
+		logger.Fatal("Failed to initialize log service", zap.Error(err))
+	}
+	dashboardService := services.NewDashboardService(repo, logger, cfg.Dashboard)
+	healthService := services.NewHealthService(repo)
+
+	// 写入排空开关，维护窗口前由管理接口切换
+	drain := middleware.NewDrainGate()
+
+	// 启动后台任务：看板摘要刷新、运行时指标采集
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	go dashboardService.Run(backgroundCtx)
+
+	// 定期采集运行时指标，提前发现 goroutine、连接和订阅泄漏
+	go metrics.RunRuntimeGauges(backgroundCtx, runtimeMetricsInterval, metrics.RuntimeSources{
+		DBStats:     repo.DB.Stats,
+		Subscribers: bus.SubscriberCount,
+	})
 
 	// 初始化处理器
-	logHandler := handlers.NewLogHandler(logService, logger)
-
-	// API 路由组
-	api := router.Group("/api")
-	{
-		// 错误日志相关路由
-		api.POST("/error-logs", logHandler.RecordErrorLog)
-		api.GET("/error-logs", logHandler.GetErrorLogs)
-
-		// 性能指标相关路由
-		api.POST("/performance-metrics", logHandler.RecordPerformanceMetric)
-		api.GET("/performance-metrics", logHandler.GetPerformanceMetrics)
-
-		// 用户行为相关路由
-		api.POST("/user-actions", logHandler.RecordUserAction)
-		api.GET("/user-actions", logHandler.GetUserActions)
-
-		// 自定义事件相关路由
-		api.POST("/custom-events", logHandler.RecordCustomEvent)
-		api.GET("/custom-events", logHandler.GetCustomEvents)
-
-		// 页面停留时长相关路由
-		api.POST("/page-stays", logHandler.RecordPageStay)
-		api.GET("/page-stays/average", logHandler.GetAveragePageStay)
+	logHandler := handlers.NewLogHandler(logService, logger, handlers.LogHandlerOptions{
+		StrictFields:     cfg.Ingest.StrictFields,
+		NoContent:        cfg.Ingest.NoContent,
+		SDKVersionHeader: cfg.Ingest.SDKHeaders.Version,
+		PlatformHeader:   cfg.Ingest.SDKHeaders.Platform,
+		RefreshHints:     services.NewRefreshHintService(repo, cfg.Dashboard.RefreshHint),
+		DefaultOrder:     cfg.Server.DefaultOrder,
+		ProjectAllowed:   middleware.ProjectAllowed(cfg.Server.AllowedProjects, projectIDs.Canonical),
+		ProjectPurge:     cfg.Auth.AllowProjectPurge,
+	})
+	dashboardHandler := handlers.NewDashboardHandler(dashboardService, logger)
+	healthHandler := handlers.NewHealthHandler(healthService, logger)
+	adminHandler := handlers.NewAdminHandler(drain, logger)
+
+	// 深度健康检查，会写入数据库，需要管理令牌并限制调用频率
+	router.GET("/health/deep",
+		middleware.AdminAuth(cfg.Auth.AdminToken),
+		middleware.RateLimit(deepHealthInterval, 1),
+		healthHandler.DeepCheck)
+
+	// API 路由注册在 /api/<版本号> 下，/api 作为当前版本的别名，兼容未带版本号的客户端
+	api := &apiRoutes{
+		cfg:              cfg,
+		logHandler:       logHandler,
+		dashboardHandler: dashboardHandler,
+		adminHandler:     adminHandler,
+		drain:            drain,
+		projectIDs:       projectIDs,
+		// 列表查询支持 ETag 条件请求，轮询时数据未变化返回 304
+		etag: middleware.ETag(),
+		// 聚合和关联查询限制并发，避免看板刷新时同时发起的大量聚合压垮 ClickHouse
+		// 两组路由共用同一个限制器
+		analytics: middleware.AnalyticsLimit(cfg.Analytics.MaxConcurrent,
+			time.Duration(cfg.Analytics.QueueTimeout)*time.Millisecond),
+		// 上报和聚合查询分别设置超时，放宽慢查询的超时不影响上报的响应时间
+		ingestTimeout:    middleware.GroupTimeout(logger, time.Duration(cfg.Server.GroupTimeouts.Ingest)*time.Millisecond),
+		analyticsTimeout: middleware.GroupTimeout(logger, time.Duration(cfg.Server.GroupTimeouts.Analytics)*time.Millisecond),
+		// 上报请求体按压缩上报的编码长度上限限制大小，在读入内存前拒绝过大的请求
+		ingestBodyLimit: middleware.MaxBodyBytes(services.MaxCompressedBodyBytes(cfg.Ingest)),
+	}
+	api.register(router.Group("/api/" + cfg.Server.APIVersion))
+	api.register(router.Group("/api"))
+
+	return bus.Shutdown, func() {
+		stopBackground()
+		// 先写出批量合并中剩余的事件，再关闭数据库连接
+		logService.Close()
+		bus.Close()
+		if err := repo.Close(); err != nil {
+			logger.Error("Failed to close repository", zap.Error(err))
+		}
+	}
+}
+
+// apiRoutes /api 下的路由及其依赖，同一组路由会注册到带版本号的路径和 /api 别名下
+type apiRoutes struct {
+	cfg              *config.Config
+	logHandler       *handlers.LogHandler
+	dashboardHandler *handlers.DashboardHandler
+	adminHandler     *handlers.AdminHandler
+	drain            *middleware.DrainGate
+	projectIDs       *services.ProjectIDPolicy
+
+	// 有状态的中间件只创建一次，各路由组共用
+	etag             gin.HandlerFunc
+	analytics        gin.HandlerFunc
+	ingestTimeout    gin.HandlerFunc
+	analyticsTimeout gin.HandlerFunc
+	ingestBodyLimit  gin.HandlerFunc
+}
+
+// register 在 group 下注册所有 API 路由
+func (r *apiRoutes) register(group *gin.RouterGroup) {
+	// 按 Accept 头或配置选择列表响应格式和 JSON 键名风格，并校验查询参数中的 project_id
+	api := group.Group("",
+		middleware.JSONCasing(r.cfg.Server.JSONCasing),
+		middleware.ResponseFormat(r.cfg.Server.ResponseEnvelope),
+		middleware.ProjectIDQuery(r.projectIDs.Normalize))
+
+	// 数据上报路由组，受排空开关、项目白名单和上报超时限制
+	// 请求体大小在白名单读取请求体之前限制，超限返回 413，不会整体读入内存
+	ingest := api.Group("", r.ingestTimeout, r.drain.Handler(), r.ingestBodyLimit, middleware.ProjectAllowlist(r.cfg.Server.AllowedProjects, r.projectIDs.Canonical))
+	ingest.POST("/error-logs", r.logHandler.RecordErrorLog)
+	ingest.POST("/performance-metrics", r.logHandler.RecordPerformanceMetric)
+	ingest.POST("/user-actions", r.logHandler.RecordUserAction)
+	ingest.POST("/custom-events", r.logHandler.RecordCustomEvent)
+	ingest.POST("/page-stays", r.logHandler.RecordPageStay)
+
+	// 压缩批量上报，请求体中的项目在解压后校验，与单条上报共用上报超时和请求体大小限制
+	api.POST("/events/compressed",
+		r.ingestTimeout,
+		r.drain.Handler(),
+		r.ingestBodyLimit,
+		middleware.ProjectAllowlistFunc(r.cfg.Server.AllowedProjects, r.projectIDs.Canonical, services.CompressedProjectIDs(r.cfg.Ingest)),
+		r.logHandler.RecordCompressedEvents)
+
+	// NDJSON 流式上报，请求体不整体读入内存，项目白名单由处理器逐行校验
+	// 不受上报超时限制：一次请求可持续发送大量事件，处理时长取决于客户端的发送速度，固定的截止时间会中断正常的长连接
+	api.POST("/ingest/stream", r.drain.Handler(), r.logHandler.IngestStream)
+
+	// 聚合查询路由组，受聚合查询超时和并发限制，超时包含排队等待时间
+	analytics := api.Group("", r.analyticsTimeout, r.analytics)
+
+	// 错误日志相关路由
+	api.GET("/error-logs", r.etag, r.logHandler.GetErrorLogs)
+	api.GET("/error-logs/trace/:trace_id", r.logHandler.GetErrorLogByTraceID)
+	api.POST("/error-logs/traces", r.logHandler.GetErrorLogsByTraceIDs)
+	analytics.GET("/error-logs/groups/:fingerprint/trend", r.logHandler.GetErrorGroupTrend)
+	analytics.GET("/error-logs/co-occurrence", r.logHandler.GetCoOccurringErrors)
+	analytics.GET("/error-logs/severity", r.logHandler.GetSeverityBreakdown)
+	analytics.GET("/error-logs/by-release", r.logHandler.GetErrorsByRelease)
+	analytics.GET("/error-logs/with-performance", r.logHandler.GetErrorLogsWithPerformance)
+	api.GET("/stack-traces/:hash", r.logHandler.GetStackTrace)
+
+	// 性能指标相关路由
+	api.GET("/performance-metrics", r.etag, r.logHandler.GetPerformanceMetrics)
+	analytics.GET("/performance-metrics/apdex", r.logHandler.GetApdex)
+	analytics.GET("/performance-metrics/web-vitals", r.logHandler.GetWebVitalsScorecard)
+	analytics.GET("/performance-metrics/slowest-urls", r.logHandler.GetSlowestURLs)
+	api.GET("/performance-metrics/trace/:trace_id", r.logHandler.GetPerformanceMetricByTraceID)
+
+	// 用户行为相关路由
+	api.GET("/user-actions", r.etag, r.logHandler.GetUserActions)
+	api.GET("/user-actions/trace/:trace_id", r.logHandler.GetUserActionByTraceID)
+	analytics.GET("/user-actions/status-classes", r.logHandler.GetStatusClassBreakdown)
+
+	// 自定义事件相关路由
+	api.GET("/custom-events", r.etag, r.logHandler.GetCustomEvents)
+
+	// 页面停留时长相关路由
+	analytics.GET("/page-stays/average", r.logHandler.GetAveragePageStay)
+
+	// 元数据路由
+	api.GET("/meta/metric-aliases", r.logHandler.GetMetricAliases)
+	api.GET("/meta/schema", r.logHandler.GetSchemaMetadata)
+
+	// 时间线相关路由
+	api.GET("/sessions/:session_id/timeline", r.logHandler.GetSessionTimeline)
+	api.GET("/traces/:trace_id/timeline", r.logHandler.GetTraceTimeline)
+
+	// 运维统计路由
+	analytics.GET("/analytics/ingestion-rate", r.logHandler.GetIngestionRate)
+	analytics.GET("/analytics/heatmap", r.logHandler.GetActivityHeatmap)
+	analytics.GET("/analytics/sessions/count", r.logHandler.GetSessionCount)
+	analytics.GET("/analytics/crash-free", r.logHandler.GetCrashFreeSessions)
+	analytics.GET("/analytics/sdk-versions", r.logHandler.GetSDKVersionBreakdown)
+	analytics.GET("/analytics/referrers", r.logHandler.GetReferrerBreakdown)
+
+	// 看板相关路由
+	analytics.GET("/dashboard/summary", r.dashboardHandler.GetSummary)
+	analytics.GET("/overview", r.logHandler.GetOverview)
+
+	// 项目相关路由
+	analytics.GET("/projects/:id/range", middleware.ProjectIDParam("id", r.projectIDs.Normalize), r.logHandler.GetDataRange)
+
+	// 管理路由（需要管理令牌）
+	admin := api.Group("", middleware.AdminAuth(r.cfg.Auth.AdminToken))
+	admin.POST("/import", r.drain.Handler(), r.logHandler.ImportEvents)
+	admin.DELETE("/users/:user_id", r.logHandler.DeleteUserData)
+	admin.DELETE("/sessions/:session_id", r.logHandler.DeleteSessionData)
+	admin.DELETE("/projects/:id", middleware.ProjectIDParam("id", r.projectIDs.Normalize), r.logHandler.DeleteProject)
+	admin.PUT("/admin/retention", r.logHandler.SetRetention)
+	admin.GET("/admin/tables", r.logHandler.GetTableStats)
+	admin.POST("/admin/drain", r.adminHandler.Drain)
+	admin.POST("/admin/resume", r.adminHandler.Resume)
+	admin.POST("/admin/refresh-summaries", r.dashboardHandler.RefreshSummaries)
+}
+
+// warmUpConnections 在开始监听前预热数据库连接池，失败只记录警告，不影响启动
+func warmUpConnections(repo *repository.ClickHouseRepository, n int, logger *zap.Logger) {
+	if n <= 0 {
+		return
 	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	warmed, err := repo.WarmUp(ctx, n)
+	if err != nil {
+		logger.Warn("Database connection warm-up incomplete",
+			zap.Int("warmed", warmed),
+			zap.Duration("took", time.Since(start)),
+			zap.Error(err))
+		return
+	}
+	logger.Info("Database connections warmed up",
+		zap.Int("warmed", warmed),
+		zap.Duration("took", time.Since(start)))
 }
 
+// checkSchema 启动时检查事件表的排序键和跳数索引，问题只记录警告，不影响启动
+// 检查方式不合法时拒绝启动
+func checkSchema(repo *repository.ClickHouseRepository, mode string, logger *zap.Logger) {
+	switch mode {
+	case repository.SchemaCheckOff:
+		return
+	case repository.SchemaCheckWarn, repository.SchemaCheckCreate:
+	default:
+		logger.Fatal("Invalid db.schema_check, expected warn, create or off", zap.String("schema_check", mode))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	defer cancel()
+
+	issues, err := repo.CheckSchema(ctx, mode == repository.SchemaCheckCreate)
+	for _, issue := range issues {
+		if issue.Fixed {
+			logger.Info("Added missing skip index, run SQL/migrations/007_skip_indexes.sql to build it for existing data",
+				zap.String("table", issue.Table),
+				zap.String("problem", issue.Problem))
+			continue
+		}
+		logger.Warn("Table schema differs from migrations, queries may scan more data than needed",
+			zap.String("table", issue.Table),
+			zap.String("problem", issue.Problem))
+	}
+	if err != nil {
+		logger.Warn("Table schema check incomplete", zap.Error(err))
+	}
+}
+
+// HomeRoutes 注册首页、指标和 ping 路由，需在 LoadAssets 之后调用
+// 未加载模板时首页返回 JSON 而不是渲染页面
 func HomeRoutes(router *gin.Engine, logger *zap.Logger) {
-	router.GET("/", func(c *gin.Context) {
-		logger.Info("Homepage accessed")
-		c.HTML(200, "index.html", nil)
-	})
+	if templatesLoaded(router) {
+		router.GET("/", func(c *gin.Context) {
+			logger.Info("Homepage accessed")
+			c.HTML(200, homeTemplate, nil)
+		})
+	} else {
+		router.GET("/", func(c *gin.Context) {
+			logger.Info("Homepage accessed")
+			c.JSON(200, gin.H{
+				"message": "Spectra backend is running",
+			})
+		})
+	}
+
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	router.GET("/ping", func(c *gin.Context) {
 		logger.Info("Ping API called")
@@ -0,0 +1,84 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"spectra-backend/config"
+	"spectra-backend/middleware"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIsIngestRequest(t *testing.T) {
+	tests := []struct {
+		path   string
+		method string
+		want   bool
+	}{
+		{"/api/error-logs", http.MethodPost, true},
+		{"/api/v1/error-logs", http.MethodPost, true},
+		{"/api/v2/page-stays", http.MethodPost, true},
+		{"/api/events/compressed", http.MethodPost, true},
+		{"/api/v1/ingest/stream", http.MethodPost, true},
+		{"/api/error-logs", http.MethodGet, false},
+		{"/api/error-logs/traces", http.MethodPost, false},
+		{"/api/import", http.MethodPost, false},
+		{"/api/latest/error-logs", http.MethodPost, false},
+		{"/error-logs", http.MethodPost, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIngestRequest(tt.path, tt.method); got != tt.want {
+			t.Errorf("IsIngestRequest(%q, %s) = %v, want %v", tt.path, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestIngestTimeoutRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 以返回特定状态码的中间件代替超时中间件，确认各路由是否挂载了上报超时
+	const ingestTimeoutStatus, analyticsTimeoutStatus = 298, 299
+	marker := func(status int) gin.HandlerFunc {
+		return func(c *gin.Context) { c.AbortWithStatus(status) }
+	}
+	// 排空开关处于关闭状态时返回 503，未挂载上报超时的写入路由在此中止，不会调用处理器
+	drain := middleware.NewDrainGate()
+	if err := drain.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() error = %v", err)
+	}
+
+	routes := &apiRoutes{
+		cfg:              &config.Config{Server: config.ServerConfig{JSONCasing: middleware.CasingSnake}},
+		drain:            drain,
+		etag:             func(c *gin.Context) { c.Next() },
+		analytics:        func(c *gin.Context) { c.Next() },
+		ingestTimeout:    marker(ingestTimeoutStatus),
+		analyticsTimeout: marker(analyticsTimeoutStatus),
+		ingestBodyLimit:  func(c *gin.Context) { c.Next() },
+	}
+	router := gin.New()
+	routes.register(router.Group("/api"))
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodPost, "/api/error-logs", ingestTimeoutStatus},
+		{http.MethodPost, "/api/page-stays", ingestTimeoutStatus},
+		{http.MethodPost, "/api/events/compressed", ingestTimeoutStatus},
+		// 流式上报的时长取决于客户端发送速度，不受上报超时限制
+		{http.MethodPost, "/api/ingest/stream", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/analytics/ingestion-rate", analyticsTimeoutStatus},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
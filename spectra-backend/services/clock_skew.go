@@ -0,0 +1,57 @@
+package services
+
+import (
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"time"
+)
+
+// extra 中记录时钟偏差的字段
+const (
+	clockSkewKey       = "_clock_skew_ms"    // 客户端时间减服务端接收时间（毫秒），正数表示客户端时钟偏快
+	clientTimestampKey = "_client_timestamp" // 被替换前的客户端时间
+)
+
+// checkClockSkew 比较客户端时间与服务端接收时间，偏差超过阈值时记录到 extra 和指标中
+// 开启修正时将事件时间替换为服务端接收时间，并保留原始客户端时间
+// 只用于实时上报，导入的历史数据时间本来就早于接收时间，不做检测
+func (s *logService) checkClockSkew(base *models.BaseLog, eventType string, received time.Time) {
+	threshold := time.Duration(s.ingest.ClockSkew.Threshold) * time.Second
+	if threshold <= 0 || base.Timestamp.IsZero() {
+		return
+	}
+
+	skew := base.Timestamp.Sub(received)
+	if skew.Abs() <= threshold {
+		return
+	}
+
+	fields := map[string]any{clockSkewKey: skew.Milliseconds()}
+	action := "flagged"
+	if s.ingest.ClockSkew.Correct {
+		fields[clientTimestampKey] = base.Timestamp
+		base.Timestamp = received
+		action = "corrected"
+	}
+	base.Extra = mergeExtra(base.Extra, fields)
+	metrics.ClockSkewedEvents.WithLabelValues(eventType, action).Inc()
+}
+
+// checkBatchClockSkew 对批次中的所有事件检测时钟偏差，接收时间取同一时刻
+func (s *logService) checkBatchClockSkew(batch *models.EventBatch, received time.Time) {
+	for _, log := range batch.ErrorLogs {
+		s.checkClockSkew(&log.BaseLog, models.EventTypeErrorLog, received)
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		s.checkClockSkew(&metric.BaseLog, models.EventTypePerformanceMetric, received)
+	}
+	for _, action := range batch.UserActions {
+		s.checkClockSkew(&action.BaseLog, models.EventTypeUserAction, received)
+	}
+	for _, event := range batch.CustomEvents {
+		s.checkClockSkew(&event.BaseLog, models.EventTypeCustomEvent, received)
+	}
+	for _, pageStay := range batch.PageStays {
+		s.checkClockSkew(&pageStay.BaseLog, models.EventTypePageStay, received)
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"spectra-backend/models"
+	"sync"
+	"time"
+)
+
+// schemaMetadataTTL 列元数据的缓存时间，表结构只在迁移时变化
+const schemaMetadataTTL = 5 * time.Minute
+
+// commonFilterColumns 所有列表接口都支持过滤的列：project_id、start_time / end_time、extra.<path>，以及 models.FilterColumns
+var commonFilterColumns = append([]string{"project_id", "timestamp", "extra"}, models.FilterColumns...)
+
+// eventFilterColumns 各事件类型的列表接口额外支持过滤的列
+var eventFilterColumns = map[string][]string{
+	models.EventTypeErrorLog:   {"severity", "type"},
+	models.EventTypeUserAction: {"status"},
+}
+
+// aggregatableColumns 分位数、Apdex、平均停留时长等聚合接口计算的列
+var aggregatableColumns = []string{"value"}
+
+// schemaMetadataCache 缓存列元数据，过期后下一次请求重新读取
+type schemaMetadataCache struct {
+	mu       sync.Mutex
+	metadata *models.SchemaMetadata
+	expires  time.Time
+}
+
+// GetSchemaMetadata 获取各事件表的列名、类型以及是否可过滤、可聚合，结果缓存 schemaMetadataTTL
+func (s *logService) GetSchemaMetadata(ctx context.Context) (*models.SchemaMetadata, error) {
+	s.schemaCache.mu.Lock()
+	defer s.schemaCache.mu.Unlock()
+
+	now := time.Now()
+	if s.schemaCache.metadata != nil && now.Before(s.schemaCache.expires) {
+		return s.schemaCache.metadata, nil
+	}
+
+	schemas, err := s.repo.GetEventTableColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range schemas {
+		filterable := append(slices.Clone(commonFilterColumns), eventFilterColumns[schemas[i].Type]...)
+		for j := range schemas[i].Columns {
+			column := &schemas[i].Columns[j]
+			column.Filterable = slices.Contains(filterable, column.Name)
+			column.Aggregatable = slices.Contains(aggregatableColumns, column.Name)
+		}
+	}
+
+	s.schemaCache.metadata = &models.SchemaMetadata{EventTypes: schemas, GeneratedAt: now}
+	s.schemaCache.expires = now.Add(schemaMetadataTTL)
+	return s.schemaCache.metadata, nil
+}
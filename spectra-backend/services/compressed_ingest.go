@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"time"
+)
+
+// BatchTooLargeError 批量上报超出事件数或字节数限制，处理器据此返回 413
+type BatchTooLargeError struct {
+	MaxItems int
+	MaxBytes int
+}
+
+func (e *BatchTooLargeError) Error() string {
+	return fmt.Sprintf("batch exceeds limits: at most %d items and %d decoded bytes per request", e.MaxItems, e.MaxBytes)
+}
+
+// batchLimits 批量上报的两项独立限制，取自 config.IngestConfig
+type batchLimits struct {
+	maxItems int
+	maxBytes int
+}
+
+// tooLarge 返回包含两项限制的错误
+func (l batchLimits) tooLarge() error {
+	return &BatchTooLargeError{MaxItems: l.maxItems, MaxBytes: l.maxBytes}
+}
+
+// maxEncodedBytes base64 载荷的长度上限，按解码上限推算，并为压缩后反而变大的数据预留余量
+func (l batchLimits) maxEncodedBytes() int {
+	return base64.StdEncoding.EncodedLen(l.maxBytes + l.maxBytes/8 + 1024)
+}
+
+// CompressedPayload 压缩上报的请求体
+// Data 为 gzip 压缩后再 base64 编码的 JSON，内容是单个 models.EventEnvelope 或其数组
+type CompressedPayload struct {
+	Data string `json:"data" binding:"required"`
+}
+
+// decodeCompressedEvents 解码 base64、解压 gzip 并解析其中的事件包装
+// 载荷格式错误时返回 ValidationError，超出事件数或解压后字节数限制时返回 BatchTooLargeError
+func decodeCompressedEvents(encoded string, limits batchLimits) ([]models.EventEnvelope, error) {
+	if len(encoded) > limits.maxEncodedBytes() {
+		return nil, limits.tooLarge()
+	}
+
+	compressed, err := decodeBase64(encoded)
+	if err != nil {
+		return nil, &ValidationError{Field: "data", Message: "must be valid base64"}
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, &ValidationError{Field: "data", Message: "must be gzip compressed"}
+	}
+	defer reader.Close()
+
+	// 只多读一个字节用于判断是否超限，防止压缩炸弹
+	inflated, err := io.ReadAll(io.LimitReader(reader, int64(limits.maxBytes)+1))
+	if err != nil {
+		return nil, &ValidationError{Field: "data", Message: "corrupt gzip stream"}
+	}
+	if len(inflated) > limits.maxBytes {
+		return nil, limits.tooLarge()
+	}
+
+	envelopes, err := parseEnvelopes(inflated)
+	if err != nil {
+		return nil, &ValidationError{Field: "data", Message: "must contain an event envelope or an array of envelopes"}
+	}
+	if len(envelopes) == 0 {
+		return nil, &ValidationError{Field: "data", Message: "contains no events"}
+	}
+	if len(envelopes) > limits.maxItems {
+		return nil, limits.tooLarge()
+	}
+	return envelopes, nil
+}
+
+// compressedBodyOverhead 压缩上报请求体中 base64 载荷以外部分（{"data":...} 包装和空白）的字节数余量
+const compressedBodyOverhead = 4096
+
+// MaxCompressedBodyBytes 压缩上报请求体的字节数上限，取 base64 载荷的长度上限加上 JSON 包装的余量
+// 超过该长度的请求体必然超出批量上报限制，路由据此在读入内存前拒绝，单条上报也不会超过该长度
+func MaxCompressedBodyBytes(ingest config.IngestConfig) int64 {
+	limits := batchLimits{maxItems: ingest.MaxBatchItems, maxBytes: ingest.MaxBatchBytes}
+	return int64(limits.maxEncodedBytes()) + compressedBodyOverhead
+}
+
+// CompressedProjectIDs 返回从压缩上报请求体中提取所有事件项目标识符的函数，供项目白名单校验使用
+// 使用与服务层相同的限制，超限的请求交给处理器返回 413
+func CompressedProjectIDs(ingest config.IngestConfig) func(body []byte) ([]string, error) {
+	limits := batchLimits{maxItems: ingest.MaxBatchItems, maxBytes: ingest.MaxBatchBytes}
+	return func(body []byte) ([]string, error) {
+		return compressedProjectIDs(body, limits)
+	}
+}
+
+// compressedProjectIDs 解码压缩载荷并提取每个事件的 project_id
+func compressedProjectIDs(body []byte, limits batchLimits) ([]string, error) {
+	var payload CompressedPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	envelopes, err := decodeCompressedEvents(payload.Data, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	projectIDs := make([]string, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		data, err := UpgradeSchema(envelope.Data)
+		if err != nil {
+			return nil, err
+		}
+		var event struct {
+			ProjectID string `json:"project_id"`
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			return nil, err
+		}
+		projectIDs = append(projectIDs, event.ProjectID)
+	}
+	return projectIDs, nil
+}
+
+// RecordCompressed 解码压缩载荷并写入其中的全部事件，返回各类型写入数量
+// 任一事件无法解析或校验失败时整批拒绝，错误字段标明事件下标，如 data[3].extra
+func (s *logService) RecordCompressed(ctx context.Context, encoded string) (map[string]int, error) {
+	received := time.Now()
+	envelopes, err := decodeCompressedEvents(encoded, s.batchLimits())
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &models.EventBatch{}
+	for i, envelope := range envelopes {
+		if err := s.decodeCompressedEvent(envelope, received, batch); err != nil {
+			field := fmt.Sprintf("data[%d]", i)
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				return nil, &ValidationError{Field: field + "." + validationErr.Field, Message: validationErr.Message}
+			}
+			return nil, &ValidationError{Field: field, Message: err.Error()}
+		}
+	}
+	if err := s.admitProjects(batchProjectIDs(batch)...); err != nil {
+		return nil, err
+	}
+	fillBatchUserAgent(batch, s.userAgentFields(ctx))
+	s.checkBatchClockSkew(batch, received)
+
+	if err := s.saveBatch(ctx, batch); err != nil {
+		return nil, err
+	}
+	return batch.Counts(), nil
+}
+
+// decodeCompressedEvent 解析并预处理压缩载荷中的单个事件，校验事件时间后加入批次
+func (s *logService) decodeCompressedEvent(envelope models.EventEnvelope, received time.Time, batch *models.EventBatch) error {
+	event, err := s.decodeEvent(envelope)
+	if err != nil {
+		return err
+	}
+	if err := s.checkEventAge(eventBase(event), envelope.Type, received); err != nil {
+		return err
+	}
+	return batch.Add(event)
+}
+
+// batchLimits 返回配置的批量上报限制
+func (s *logService) batchLimits() batchLimits {
+	return batchLimits{maxItems: s.ingest.MaxBatchItems, maxBytes: s.ingest.MaxBatchBytes}
+}
+
+// parseEnvelopes 解析单个事件包装或事件包装数组
+func parseEnvelopes(data []byte) ([]models.EventEnvelope, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var envelopes []models.EventEnvelope
+		if err := json.Unmarshal(data, &envelopes); err != nil {
+			return nil, err
+		}
+		return envelopes, nil
+	}
+
+	var envelope models.EventEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	return []models.EventEnvelope{envelope}, nil
+}
+
+// decodeBase64 解码标准或 URL 安全的 base64，允许省略填充
+func decodeBase64(encoded string) ([]byte, error) {
+	for _, encoding := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if decoded, err := encoding.DecodeString(encoded); err == nil {
+			return decoded, nil
+		}
+	}
+	return nil, errors.New("invalid base64")
+}
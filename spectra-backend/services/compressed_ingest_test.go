@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testBatchLimits 测试使用的批量上报限制，足够容纳各用例的载荷
+var testBatchLimits = batchLimits{maxItems: 10, maxBytes: 1 << 16}
+
+// gzipBase64 将数据 gzip 压缩后以 encoding 编码，模拟受限客户端的上报载荷
+func gzipBase64(t *testing.T, data string, encoding *base64.Encoding) string {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return encoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecodeCompressedEvents(t *testing.T) {
+	single := `{"type":"custom_event","data":{"project_id":"web","name":"signup"}}`
+	array := `[` + single + `,{"type":"error_log","data":{"project_id":"web","message":"boom"}}]`
+
+	tests := []struct {
+		name      string
+		encoded   string
+		wantTypes []string
+	}{
+		{"single envelope", gzipBase64(t, single, base64.StdEncoding), []string{"custom_event"}},
+		{"envelope array", gzipBase64(t, array, base64.StdEncoding), []string{"custom_event", "error_log"}},
+		{"unpadded base64", gzipBase64(t, single, base64.RawStdEncoding), []string{"custom_event"}},
+		{"url-safe base64", gzipBase64(t, array, base64.RawURLEncoding), []string{"custom_event", "error_log"}},
+		{"surrounding whitespace", gzipBase64(t, "\n  "+single+"\n", base64.StdEncoding), []string{"custom_event"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelopes, err := decodeCompressedEvents(tt.encoded, testBatchLimits)
+			if err != nil {
+				t.Fatalf("decodeCompressedEvents() error = %v", err)
+			}
+			if len(envelopes) != len(tt.wantTypes) {
+				t.Fatalf("got %d envelopes, want %d", len(envelopes), len(tt.wantTypes))
+			}
+			for i, envelope := range envelopes {
+				if envelope.Type != tt.wantTypes[i] {
+					t.Errorf("envelope %d type = %q, want %q", i, envelope.Type, tt.wantTypes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeCompressedEventsMalformed(t *testing.T) {
+	valid := gzipBase64(t, `{"type":"custom_event","data":{}}`, base64.StdEncoding)
+	raw, _ := base64.StdEncoding.DecodeString(valid)
+	truncated := base64.StdEncoding.EncodeToString(raw[:len(raw)-6])
+
+	tests := []struct {
+		name    string
+		encoded string
+		wantMsg string
+	}{
+		{"not base64", "!!not base64!!", "must be valid base64"},
+		{"not gzip", base64.StdEncoding.EncodeToString([]byte(`{"type":"custom_event"}`)), "must be gzip compressed"},
+		{"truncated gzip", truncated, "corrupt gzip stream"},
+		{"not json", gzipBase64(t, "hello", base64.StdEncoding), "must contain an event envelope or an array of envelopes"},
+		{"wrong json shape", gzipBase64(t, `"event"`, base64.StdEncoding), "must contain an event envelope or an array of envelopes"},
+		{"empty array", gzipBase64(t, `[]`, base64.StdEncoding), "contains no events"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCompressedEvents(tt.encoded, testBatchLimits)
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("error = %v, want ValidationError", err)
+			}
+			if validationErr.Field != "data" || validationErr.Message != tt.wantMsg {
+				t.Errorf("error = %v, want data: %s", err, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestRecordCompressed(t *testing.T) {
+	repo := &fakeRepository{}
+	service := newTestLogService(t, repo, config.IngestConfig{MaxBatchItems: 10, MaxBatchBytes: 1 << 16})
+
+	encoded := gzipBase64(t, `[
+		{"type":"custom_event","data":{"project_id":"web","name":"signup"}},
+		{"type":"error_log","data":{"schema_version":1,"projectId":"web","message":"boom"}},
+		{"type":"custom_event","data":{"project_id":"web","name":"login"}}
+	]`, base64.StdEncoding)
+
+	counts, err := service.RecordCompressed(context.Background(), encoded)
+	if err != nil {
+		t.Fatalf("RecordCompressed() error = %v", err)
+	}
+	if counts[models.EventTypeCustomEvent] != 2 || counts[models.EventTypeErrorLog] != 1 {
+		t.Errorf("counts = %v, want 2 custom events and 1 error log", counts)
+	}
+	if len(repo.batches) != 1 {
+		t.Fatalf("saved %d batches, want 1", len(repo.batches))
+	}
+	if got := repo.batches[0].ErrorLogs[0].ProjectID; got != "web" {
+		t.Errorf("legacy error log project_id = %q, want web", got)
+	}
+}
+
+func TestRecordCompressedRejectsInvalidEvent(t *testing.T) {
+	repo := &fakeRepository{}
+	service := newTestLogService(t, repo, config.IngestConfig{MaxBatchItems: 10, MaxBatchBytes: 1 << 16})
+
+	encoded := gzipBase64(t, `[
+		{"type":"custom_event","data":{"project_id":"web","name":"signup"}},
+		{"type":"custom_event","data":{"project_id":"web","sdk_version":"`+strings.Repeat("1", maxSDKFieldLength+1)+`"}}
+	]`, base64.StdEncoding)
+
+	_, err := service.RecordCompressed(context.Background(), encoded)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "data[1].sdk_version" {
+		t.Fatalf("error = %v, want ValidationError on data[1].sdk_version", err)
+	}
+	if len(repo.batches) != 0 {
+		t.Errorf("saved %d batches, want the whole batch rejected", len(repo.batches))
+	}
+}
+
+// customEventArray 生成包含 n 个自定义事件包装的 JSON 数组
+func customEventArray(n int) string {
+	envelopes := make([]string, n)
+	for i := range envelopes {
+		envelopes[i] = `{"type":"custom_event","data":{"project_id":"web","name":"e` + strconv.Itoa(i) + `"}}`
+	}
+	return "[" + strings.Join(envelopes, ",") + "]"
+}
+
+func TestDecodeCompressedEventsLimits(t *testing.T) {
+	threeItems := customEventArray(3)
+
+	tests := []struct {
+		name    string
+		data    string
+		limits  batchLimits
+		wantErr bool
+	}{
+		{"items at limit", threeItems, batchLimits{maxItems: 3, maxBytes: 1 << 16}, false},
+		{"items just over limit", customEventArray(4), batchLimits{maxItems: 3, maxBytes: 1 << 16}, true},
+		{"bytes at limit", threeItems, batchLimits{maxItems: 10, maxBytes: len(threeItems)}, false},
+		{"bytes just over limit", threeItems, batchLimits{maxItems: 10, maxBytes: len(threeItems) - 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCompressedEvents(gzipBase64(t, tt.data, base64.StdEncoding), tt.limits)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("decodeCompressedEvents() error = %v", err)
+				}
+				return
+			}
+			var tooLargeErr *BatchTooLargeError
+			if !errors.As(err, &tooLargeErr) {
+				t.Fatalf("error = %v, want BatchTooLargeError", err)
+			}
+			if tooLargeErr.MaxItems != tt.limits.maxItems || tooLargeErr.MaxBytes != tt.limits.maxBytes {
+				t.Errorf("error limits = %d items / %d bytes, want %d / %d",
+					tooLargeErr.MaxItems, tooLargeErr.MaxBytes, tt.limits.maxItems, tt.limits.maxBytes)
+			}
+		})
+	}
+}
+
+func TestDecodeCompressedEventsEncodedLengthLimit(t *testing.T) {
+	limits := batchLimits{maxItems: 10, maxBytes: 1024}
+	// 超过编码长度上限时不解码，直接按超限拒绝
+	encoded := strings.Repeat("A", limits.maxEncodedBytes()+1)
+	var tooLargeErr *BatchTooLargeError
+	if _, err := decodeCompressedEvents(encoded, limits); !errors.As(err, &tooLargeErr) {
+		t.Fatalf("error = %v, want BatchTooLargeError", err)
+	}
+}
+
+func TestMaxCompressedBodyBytes(t *testing.T) {
+	ingest := config.IngestConfig{MaxBatchItems: 10, MaxBatchBytes: 1024}
+	limits := batchLimits{maxItems: ingest.MaxBatchItems, maxBytes: ingest.MaxBatchBytes}
+	// 编码长度恰好达到上限的载荷仍由服务层处理，请求体上限不能先把它拒绝
+	body := `{"data":"` + strings.Repeat("A", limits.maxEncodedBytes()) + `"}`
+	if got := MaxCompressedBodyBytes(ingest); got < int64(len(body)) {
+		t.Errorf("MaxCompressedBodyBytes() = %d, want at least %d", got, len(body))
+	}
+}
+
+func TestBatchTooLargeErrorMessage(t *testing.T) {
+	err := &BatchTooLargeError{MaxItems: 500, MaxBytes: 1048576}
+	want := "batch exceeds limits: at most 500 items and 1048576 decoded bytes per request"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
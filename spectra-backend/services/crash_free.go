@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/models"
+	"time"
+)
+
+// defaultCrashSeverities 未指定 severity 时视为崩溃的错误严重程度，warning 和 info 不影响稳定性指标
+var defaultCrashSeverities = []string{models.SeverityFatal, models.SeverityError}
+
+// GetCrashFreeSessions 计算时间范围内没有发生错误的会话和用户占比
+// severities 为空时只统计 fatal 和 error；没有会话或用户时对应占比为 nil
+func (s *logService) GetCrashFreeSessions(ctx context.Context, projectIDs []string, severities []string, startTime, endTime time.Time, filters []models.ExtraFilter) (*models.CrashFreeStats, error) {
+	if len(severities) == 0 {
+		severities = defaultCrashSeverities
+	}
+
+	stats, err := s.repo.GetCrashFreeCounts(ctx, projectIDs, severities, startTime, endTime, filters)
+	if err != nil {
+		return nil, err
+	}
+	stats.CrashFreeSessions = crashFreeRatio(stats.Sessions, stats.CrashedSessions)
+	stats.CrashFreeUsers = crashFreeRatio(stats.Users, stats.CrashedUsers)
+	return stats, nil
+}
+
+// crashFreeRatio 返回未崩溃数占总数的比例，total 为 0 时返回 nil
+func crashFreeRatio(total, crashed uint64) *float64 {
+	if total == 0 {
+		return nil
+	}
+	ratio := float64(total-crashed) / float64(total)
+	return &ratio
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"spectra-backend/repository"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// dashboardWindows 看板支持的时间窗口
+var dashboardWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// DefaultDashboardWindow 未指定窗口时使用的默认值
+const DefaultDashboardWindow = "24h"
+
+// ErrUnknownWindow 请求了不支持的时间窗口
+var ErrUnknownWindow = errors.New("window must be one of 24h, 7d")
+
+// ErrRefreshInProgress 同一项目的摘要正在刷新
+var ErrRefreshInProgress = errors.New("summary refresh already in progress for this project")
+
+// DashboardService 看板摘要服务接口
+type DashboardService interface {
+	// GetSummary 优先返回缓存的摘要，未命中时实时计算
+	GetSummary(ctx context.Context, projectID string, window string) (*models.DashboardSummary, error)
+	// RefreshAll 重新计算所有活跃项目的摘要
+	RefreshAll(ctx context.Context) error
+	// RefreshProject 同步重新计算单个项目所有窗口的摘要，用于回填数据后强制刷新
+	RefreshProject(ctx context.Context, projectID string) (*models.SummaryRefresh, error)
+	// Run 按配置的间隔定期刷新，阻塞直到 ctx 取消
+	Run(ctx context.Context)
+}
+
+// dashboardService 看板摘要服务实现
+type dashboardService struct {
+	repo     repository.LogRepository
+	logger   *zap.Logger
+	interval time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]map[string]*models.DashboardSummary // project_id -> window -> 摘要
+
+	// refreshing 正在刷新的项目，同一项目同时只允许一次刷新
+	refreshMu  sync.Mutex
+	refreshing map[string]struct{}
+}
+
+// NewDashboardService 创建看板摘要服务实例
+func NewDashboardService(repo repository.LogRepository, logger *zap.Logger, cfg config.DashboardConfig) DashboardService {
+	return &dashboardService{
+		repo:       repo,
+		logger:     logger,
+		interval:   time.Duration(cfg.RefreshInterval) * time.Second,
+		cache:      make(map[string]map[string]*models.DashboardSummary),
+		refreshing: make(map[string]struct{}),
+	}
+}
+
+func (s *dashboardService) GetSummary(ctx context.Context, projectID string, window string) (*models.DashboardSummary, error) {
+	if _, ok := dashboardWindows[window]; !ok {
+		return nil, ErrUnknownWindow
+	}
+
+	if summary := s.cached(projectID, window); summary != nil {
+		return summary, nil
+	}
+
+	summary, err := s.compute(ctx, projectID, window)
+	if err != nil {
+		return nil, err
+	}
+	s.store(summary)
+	return summary, nil
+}
+
+func (s *dashboardService) RefreshAll(ctx context.Context) error {
+	var longest time.Duration
+	for _, duration := range dashboardWindows {
+		if duration > longest {
+			longest = duration
+		}
+	}
+
+	projectIDs, err := s.repo.GetActiveProjects(ctx, time.Now().Add(-longest))
+	if err != nil {
+		return err
+	}
+
+	for _, projectID := range projectIDs {
+		if _, err := s.RefreshProject(ctx, projectID); err != nil {
+			// 单个项目失败或正在手动刷新不影响其他项目
+			s.logger.Error("Failed to refresh dashboard summary",
+				zap.String("project_id", projectID),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *dashboardService) RefreshProject(ctx context.Context, projectID string) (*models.SummaryRefresh, error) {
+	if !s.lockRefresh(projectID) {
+		return nil, ErrRefreshInProgress
+	}
+	defer s.unlockRefresh(projectID)
+
+	start := time.Now()
+	refresh := &models.SummaryRefresh{ProjectID: projectID, StartedAt: start}
+	for window := range dashboardWindows {
+		summary, err := s.compute(ctx, projectID, window)
+		if err != nil {
+			return nil, fmt.Errorf("window %s: %w", window, err)
+		}
+		s.store(summary)
+		refresh.Windows = append(refresh.Windows, window)
+	}
+	sort.Strings(refresh.Windows)
+	refresh.DurationMs = time.Since(start).Milliseconds()
+	return refresh, nil
+}
+
+// lockRefresh 标记项目正在刷新，已在刷新时返回 false
+func (s *dashboardService) lockRefresh(projectID string) bool {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	if _, ok := s.refreshing[projectID]; ok {
+		return false
+	}
+	s.refreshing[projectID] = struct{}{}
+	return true
+}
+
+// unlockRefresh 清除项目的刷新标记
+func (s *dashboardService) unlockRefresh(projectID string) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	delete(s.refreshing, projectID)
+}
+
+func (s *dashboardService) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		s.logger.Info("Dashboard summary refresh disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		start := time.Now()
+		if err := s.RefreshAll(ctx); err != nil {
+			s.logger.Error("Failed to refresh dashboard summaries", zap.Error(err))
+		} else {
+			s.logger.Debug("Dashboard summaries refreshed", zap.Duration("took", time.Since(start)))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// compute 实时计算指定窗口的摘要
+func (s *dashboardService) compute(ctx context.Context, projectID string, window string) (*models.DashboardSummary, error) {
+	endTime := time.Now()
+	startTime := endTime.Add(-dashboardWindows[window])
+
+	summary, err := s.repo.GetDashboardSummary(ctx, projectID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	summary.Window = window
+	summary.ComputedAt = time.Now()
+	return summary, nil
+}
+
+// cached 返回缓存摘要的副本
+// 后台刷新关闭时不使用缓存；超过两个刷新周期未更新的缓存视为未命中
+func (s *dashboardService) cached(projectID string, window string) *models.DashboardSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary, ok := s.cache[projectID][window]
+	if !ok {
+		return nil
+	}
+	if s.interval <= 0 || time.Since(summary.ComputedAt) > 2*s.interval {
+		return nil
+	}
+
+	copied := *summary
+	copied.Cached = true
+	return &copied
+}
+
+// store 写入缓存
+func (s *dashboardService) store(summary *models.DashboardSummary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	windows, ok := s.cache[summary.ProjectID]
+	if !ok {
+		windows = make(map[string]*models.DashboardSummary)
+		s.cache[summary.ProjectID] = windows
+	}
+	windows[summary.Window] = summary
+}
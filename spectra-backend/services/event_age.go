@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"time"
+)
+
+// 事件时间超出范围的原因，用于 ingest_rejected_timestamps_total 指标
+const (
+	eventAgeTooOld   = "too_old"
+	eventAgeInFuture = "in_future"
+)
+
+// checkEventAge 校验事件时间与服务端接收时间的差距
+// 早于接收时间超过 ingest.event_age.max_age 或晚于接收时间超过 max_future 时返回 ValidationError 并记录指标
+// 在时钟偏差检测之前调用，避免超出范围的时间被修正为接收时间；只用于实时上报，未携带时间的事件不校验
+func (s *logService) checkEventAge(base *models.BaseLog, eventType string, received time.Time) error {
+	if base.Timestamp.IsZero() {
+		return nil
+	}
+
+	maxAge := time.Duration(s.ingest.EventAge.MaxAge) * time.Second
+	if maxAge > 0 && received.Sub(base.Timestamp) > maxAge {
+		metrics.RejectedEventTimestamps.WithLabelValues(eventType, eventAgeTooOld).Inc()
+		return &ValidationError{Field: "timestamp", Message: fmt.Sprintf("must not be more than %s in the past", maxAge)}
+	}
+	maxFuture := time.Duration(s.ingest.EventAge.MaxFuture) * time.Second
+	if maxFuture > 0 && base.Timestamp.Sub(received) > maxFuture {
+		metrics.RejectedEventTimestamps.WithLabelValues(eventType, eventAgeInFuture).Inc()
+		return &ValidationError{Field: "timestamp", Message: fmt.Sprintf("must not be more than %s in the future", maxFuture)}
+	}
+	return nil
+}
+
+// checkBatchEventAge 校验批次中所有事件的时间，接收时间取同一时刻，任一事件超出范围时返回错误
+func (s *logService) checkBatchEventAge(batch *models.EventBatch, received time.Time) error {
+	for _, log := range batch.ErrorLogs {
+		if err := s.checkEventAge(&log.BaseLog, models.EventTypeErrorLog, received); err != nil {
+			return err
+		}
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		if err := s.checkEventAge(&metric.BaseLog, models.EventTypePerformanceMetric, received); err != nil {
+			return err
+		}
+	}
+	for _, action := range batch.UserActions {
+		if err := s.checkEventAge(&action.BaseLog, models.EventTypeUserAction, received); err != nil {
+			return err
+		}
+	}
+	for _, event := range batch.CustomEvents {
+		if err := s.checkEventAge(&event.BaseLog, models.EventTypeCustomEvent, received); err != nil {
+			return err
+		}
+	}
+	for _, pageStay := range batch.PageStays {
+		if err := s.checkEventAge(&pageStay.BaseLog, models.EventTypePageStay, received); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"spectra-backend/config"
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCheckEventAge(t *testing.T) {
+	received := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	week, skew := 7*24*time.Hour, 5*time.Minute
+	tests := []struct {
+		name       string
+		timestamp  time.Time
+		wantReason string
+	}{
+		{"in range", received.Add(-time.Hour), ""},
+		{"exactly max age", received.Add(-week), ""},
+		{"past max age", received.Add(-week - time.Second), eventAgeTooOld},
+		{"within future skew", received.Add(skew), ""},
+		{"past future skew", received.Add(skew + time.Second), eventAgeInFuture},
+		{"no timestamp", time.Time{}, ""},
+	}
+
+	s := &logService{ingest: config.IngestConfig{EventAge: config.EventAgeConfig{MaxAge: int(week / time.Second), MaxFuture: int(skew / time.Second)}}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := metrics.RejectedEventTimestamps.WithLabelValues(models.EventTypeCustomEvent, tt.wantReason)
+			before := testutil.ToFloat64(counter)
+
+			err := s.checkEventAge(&models.BaseLog{Timestamp: tt.timestamp}, models.EventTypeCustomEvent, received)
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("checkEventAge() error = %v, want accepted", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != "timestamp" {
+				t.Errorf("checkEventAge() error = %v, want timestamp validation error", err)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("%s rejections increased by %v, want 1", tt.wantReason, got)
+			}
+		})
+	}
+}
+
+func TestCheckEventAgeDisabled(t *testing.T) {
+	s := &logService{}
+	received := time.Now()
+	for _, ts := range []time.Time{received.AddDate(-1, 0, 0), received.AddDate(1, 0, 0)} {
+		if err := s.checkEventAge(&models.BaseLog{Timestamp: ts}, models.EventTypeCustomEvent, received); err != nil {
+			t.Errorf("checkEventAge(%s) with no limits error = %v", ts, err)
+		}
+	}
+}
+
+func TestRecordRejectsStaleEvents(t *testing.T) {
+	ingest := config.IngestConfig{EventAge: config.EventAgeConfig{MaxAge: 3600, MaxFuture: 60}}
+	now := time.Now()
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		wantErr bool
+	}{
+		{"past", -2 * time.Hour, true},
+		{"future", 2 * time.Minute, true},
+		{"in range", -time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			service := newTestLogService(t, repo, ingest)
+
+			event := &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "web", Name: "signup", Timestamp: now.Add(tt.offset)}}
+			err := service.RecordCustomEvent(context.Background(), event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordCustomEvent() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr && len(repo.saved) != 0 {
+				t.Errorf("saved %d rejected events", len(repo.saved))
+			}
+
+			batch := &models.EventBatch{CustomEvents: []*models.CustomEvent{
+				{BaseLog: models.BaseLog{ProjectID: "web", Name: "ok", Timestamp: now}},
+				{BaseLog: models.BaseLog{ProjectID: "web", Name: "signup", Timestamp: now.Add(tt.offset)}},
+			}}
+			if err := service.RecordBatch(context.Background(), batch); (err != nil) != tt.wantErr {
+				t.Errorf("RecordBatch() error = %v, want error %v", err, tt.wantErr)
+			}
+			// 批次中任一事件超出范围时整个批次被拒绝
+			if tt.wantErr && len(repo.batches) != 0 {
+				t.Errorf("saved %d batches, want none", len(repo.batches))
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package services
+
+import "encoding/json"
+
+// mergeExtra 向 extra 对象中写入服务端字段，已有的同名字段会被覆盖
+// extra 为空时新建对象；extra 不是 JSON 对象时原样返回
+func mergeExtra(extra json.RawMessage, values map[string]any) json.RawMessage {
+	fields := map[string]json.RawMessage{}
+	if len(extra) > 0 {
+		if err := json.Unmarshal(extra, &fields); err != nil || fields == nil {
+			return extra
+		}
+	}
+
+	for key, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return extra
+		}
+		fields[key] = encoded
+	}
+
+	merged, err := json.Marshal(fields)
+	if err != nil {
+		return extra
+	}
+	return merged
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"spectra-backend/models"
+	"spectra-backend/repository"
+	"time"
+)
+
+// deepHealthTimeout 单次深度健康检查的总超时
+const deepHealthTimeout = 10 * time.Second
+
+// 深度健康检查结果状态
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthService 深度健康检查服务接口
+type HealthService interface {
+	// DeepCheck 依次执行 ping、写入探针、读回探针并校验内容，任一步骤失败即停止
+	DeepCheck(ctx context.Context) *models.DeepHealthReport
+}
+
+// healthService 深度健康检查服务实现
+type healthService struct {
+	repo repository.LogRepository
+}
+
+// NewHealthService 创建深度健康检查服务实例
+func NewHealthService(repo repository.LogRepository) HealthService {
+	return &healthService{repo: repo}
+}
+
+func (s *healthService) DeepCheck(ctx context.Context) *models.DeepHealthReport {
+	ctx, cancel := context.WithTimeout(ctx, deepHealthTimeout)
+	defer cancel()
+
+	started := time.Now()
+	report := &models.DeepHealthReport{Status: HealthStatusOK, CheckedAt: started.UTC()}
+
+	canary, err := newHealthCanary(started)
+	if err != nil {
+		report.Status = HealthStatusFail
+		report.Steps = append(report.Steps, models.HealthCheckStep{Name: "write", Error: err.Error()})
+		report.DurationMs = time.Since(started).Milliseconds()
+		return report
+	}
+
+	var stored *models.HealthCanary
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"ping", func() error { return s.repo.Ping(ctx) }},
+		{"write", func() error { return s.repo.SaveHealthCanary(ctx, canary) }},
+		{"read", func() error {
+			var err error
+			stored, err = s.repo.GetHealthCanary(ctx, canary.ID)
+			if err == nil && stored == nil {
+				err = errors.New("canary row not found after write")
+			}
+			return err
+		}},
+		{"verify", func() error { return verifyHealthCanary(canary, stored) }},
+	}
+
+	for _, step := range steps {
+		stepStarted := time.Now()
+		err := step.run()
+		result := models.HealthCheckStep{
+			Name:       step.name,
+			OK:         err == nil,
+			DurationMs: time.Since(stepStarted).Milliseconds(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			report.Status = HealthStatusFail
+		}
+		report.Steps = append(report.Steps, result)
+		if err != nil {
+			break
+		}
+	}
+
+	report.DurationMs = time.Since(started).Milliseconds()
+	return report
+}
+
+// newHealthCanary 生成随机 ID 的探针行
+// extra 包含嵌套对象、数组和非 ASCII 字符，用于发现 JSON 列的序列化问题
+// 只使用字符串和布尔值，避免 ClickHouse 将 64 位整数输出为带引号的字符串造成误报
+func newHealthCanary(now time.Time) (*models.HealthCanary, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate canary id: %w", err)
+	}
+	id := "canary-" + hex.EncodeToString(buf)
+
+	extra, err := json.Marshal(map[string]any{
+		"canary": id,
+		"nested": map[string]any{"text": "探针 ✓", "ok": true},
+		"tags":   []string{"write", "read"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode canary extra: %w", err)
+	}
+
+	return &models.HealthCanary{
+		// 写入路径保留毫秒精度，预先截断以便与读回的值比较
+		Timestamp: now.UTC().Truncate(time.Millisecond),
+		ID:        id,
+		Extra:     extra,
+	}, nil
+}
+
+// verifyHealthCanary 校验读回的探针与写入的一致，extra 按 JSON 语义比较
+func verifyHealthCanary(want, got *models.HealthCanary) error {
+	if !got.Timestamp.Equal(want.Timestamp) {
+		return fmt.Errorf("timestamp mismatch: wrote %s, read %s",
+			want.Timestamp.Format(time.RFC3339Nano), got.Timestamp.Format(time.RFC3339Nano))
+	}
+
+	var wantExtra, gotExtra any
+	if err := json.Unmarshal(want.Extra, &wantExtra); err != nil {
+		return fmt.Errorf("failed to decode written extra: %w", err)
+	}
+	if err := json.Unmarshal(got.Extra, &gotExtra); err != nil {
+		return fmt.Errorf("failed to decode read extra: %w", err)
+	}
+	if !reflect.DeepEqual(wantExtra, gotExtra) {
+		return fmt.Errorf("extra mismatch: wrote %s, read %s", want.Extra, got.Extra)
+	}
+	return nil
+}
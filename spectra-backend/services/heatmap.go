@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/models"
+	"time"
+)
+
+// GetActivityHeatmap 按星期和小时统计事件数，返回完整的 7×24 个单元格，没有数据的计数为 0
+// eventType 为空时统计所有事件表
+func (s *logService) GetActivityHeatmap(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, loc *time.Location) (*models.ActivityHeatmap, error) {
+	cells, err := s.repo.GetActivityHeatmap(ctx, projectIDs, eventType, startTime, endTime, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	heatmap := &models.ActivityHeatmap{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Type:       eventType,
+		Timezone:   loc.String(),
+		Cells:      make([]models.HeatmapCell, 0, 7*24),
+	}
+	for day := 1; day <= 7; day++ {
+		for hour := 0; hour < 24; hour++ {
+			heatmap.Cells = append(heatmap.Cells, models.HeatmapCell{DayOfWeek: day, Hour: hour})
+		}
+	}
+	for _, cell := range cells {
+		if cell.DayOfWeek < 1 || cell.DayOfWeek > 7 || cell.Hour < 0 || cell.Hour > 23 {
+			continue
+		}
+		heatmap.Cells[(cell.DayOfWeek-1)*24+cell.Hour].Count += cell.Count
+		heatmap.Total += cell.Count
+	}
+	return heatmap, nil
+}
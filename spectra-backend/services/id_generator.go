@@ -0,0 +1,85 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"spectra-backend/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 服务端生成 trace_id / session_id 的方案
+const (
+	IDSchemeUUID = "uuid" // 随机 UUIDv4
+	IDSchemeULID = "ulid" // ULID，前 48 位为毫秒时间戳，按字典序即按生成时间排序
+	IDSchemeNone = "none" // 不生成，保持为空
+)
+
+// generatedIDsKey 记录服务端生成字段名的 extra 键，下划线前缀避免与 SDK 字段冲突
+const generatedIDsKey = "_server_generated"
+
+// crockfordAlphabet ULID 使用的 Crockford Base32 字母表
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newIDGenerator 按方案创建 ID 生成函数，IDSchemeNone 时返回 nil
+func newIDGenerator(scheme string) (func() string, error) {
+	switch scheme {
+	case IDSchemeUUID:
+		return uuid.NewString, nil
+	case IDSchemeULID:
+		return newULID, nil
+	case IDSchemeNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid id scheme %q, must be one of %s, %s, %s", scheme, IDSchemeUUID, IDSchemeULID, IDSchemeNone)
+	}
+}
+
+// newULID 生成 26 位 ULID：48 位毫秒时间戳 + 80 位随机数
+func newULID() string {
+	var id [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("failed to read random bytes: %v", err))
+	}
+	return encodeULID(id)
+}
+
+// encodeULID 将 128 位 ID 编码为 Crockford Base32，最高位补 2 个 0 凑满 130 位
+func encodeULID(id [16]byte) string {
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+
+	var out [26]byte
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockfordAlphabet[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+// fillGeneratedIDs 为缺少 trace_id 或 session_id 的事件生成 ID，并在 extra 中记录由服务端生成的字段
+// 已有值的字段保持不变；extra 不是 JSON 对象时只生成 ID，不做记录
+func (s *logService) fillGeneratedIDs(base *models.BaseLog) {
+	if s.newID == nil {
+		return
+	}
+
+	var generated []string
+	if base.TraceID == "" {
+		base.TraceID = s.newID()
+		generated = append(generated, "trace_id")
+	}
+	if base.SessionID == "" {
+		base.SessionID = s.newID()
+		generated = append(generated, "session_id")
+	}
+	if len(generated) > 0 {
+		base.Extra = mergeExtra(base.Extra, map[string]any{generatedIDsKey: generated})
+	}
+}
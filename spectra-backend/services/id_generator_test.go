@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"spectra-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ulidPattern 26 位 Crockford Base32，首位不超过 7（时间戳只有 48 位）
+var ulidPattern = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+
+func TestFillGeneratedIDs(t *testing.T) {
+	tests := []struct {
+		name          string
+		base          models.BaseLog
+		wantGenerated []string
+	}{
+		{"both missing", models.BaseLog{}, []string{"trace_id", "session_id"}},
+		{"trace missing", models.BaseLog{SessionID: "s1"}, []string{"trace_id"}},
+		{"session missing", models.BaseLog{TraceID: "t1"}, []string{"session_id"}},
+		{"both present", models.BaseLog{TraceID: "t1", SessionID: "s1", Extra: json.RawMessage(`{"a":1}`)}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &logService{newID: func() string { return "generated" }}
+			base := tt.base
+			s.fillGeneratedIDs(&base)
+
+			if tt.base.TraceID != "" && base.TraceID != tt.base.TraceID {
+				t.Errorf("TraceID = %q, want client value %q kept", base.TraceID, tt.base.TraceID)
+			}
+			if tt.base.SessionID != "" && base.SessionID != tt.base.SessionID {
+				t.Errorf("SessionID = %q, want client value %q kept", base.SessionID, tt.base.SessionID)
+			}
+			if base.TraceID == "" || base.SessionID == "" {
+				t.Errorf("IDs not filled: trace_id=%q session_id=%q", base.TraceID, base.SessionID)
+			}
+
+			if tt.wantGenerated == nil {
+				if string(base.Extra) != string(tt.base.Extra) {
+					t.Errorf("Extra = %s, want unchanged %s", base.Extra, tt.base.Extra)
+				}
+				return
+			}
+			var extra map[string][]string
+			if err := json.Unmarshal(base.Extra, &extra); err != nil {
+				t.Fatalf("Extra = %s is not an object: %v", base.Extra, err)
+			}
+			if !reflect.DeepEqual(extra[generatedIDsKey], tt.wantGenerated) {
+				t.Errorf("%s = %v, want %v", generatedIDsKey, extra[generatedIDsKey], tt.wantGenerated)
+			}
+		})
+	}
+}
+
+func TestFillGeneratedIDsKeepsExtra(t *testing.T) {
+	s := &logService{newID: func() string { return "generated" }}
+	base := models.BaseLog{TraceID: "t1", Extra: json.RawMessage(`{"plan":"pro"}`)}
+	s.fillGeneratedIDs(&base)
+
+	var extra map[string]any
+	if err := json.Unmarshal(base.Extra, &extra); err != nil {
+		t.Fatalf("Extra = %s is not an object: %v", base.Extra, err)
+	}
+	if extra["plan"] != "pro" {
+		t.Errorf("existing extra field lost: %s", base.Extra)
+	}
+}
+
+func TestFillGeneratedIDsDisabled(t *testing.T) {
+	s := &logService{}
+	base := models.BaseLog{}
+	s.fillGeneratedIDs(&base)
+	if base.TraceID != "" || base.SessionID != "" || base.Extra != nil {
+		t.Errorf("scheme none changed the event: %+v", base)
+	}
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	newUUID, err := newIDGenerator(IDSchemeUUID)
+	if err != nil {
+		t.Fatalf("newIDGenerator(uuid) error = %v", err)
+	}
+	if id, err := uuid.Parse(newUUID()); err != nil || id.Version() != 4 {
+		t.Errorf("uuid scheme generated %v (err %v), want a UUIDv4", id, err)
+	}
+
+	newULIDFunc, err := newIDGenerator(IDSchemeULID)
+	if err != nil {
+		t.Fatalf("newIDGenerator(ulid) error = %v", err)
+	}
+	if id := newULIDFunc(); !ulidPattern.MatchString(id) {
+		t.Errorf("ulid scheme generated %q", id)
+	}
+
+	if generate, err := newIDGenerator(IDSchemeNone); err != nil || generate != nil {
+		t.Errorf("newIDGenerator(none) returned a generator (err %v), want nil", err)
+	}
+	if _, err := newIDGenerator("snowflake"); err == nil {
+		t.Error("newIDGenerator(snowflake) succeeded, want error")
+	}
+}
+
+func TestULIDSortsByTime(t *testing.T) {
+	first := newULID()
+	time.Sleep(2 * time.Millisecond)
+	second := newULID()
+	if first[:10] >= second[:10] {
+		t.Errorf("ULID timestamps not increasing: %s then %s", first, second)
+	}
+}
+
+func TestEncodeULID(t *testing.T) {
+	var zero [16]byte
+	if got := encodeULID(zero); got != "00000000000000000000000000" {
+		t.Errorf("encodeULID(zero) = %s", got)
+	}
+	var full [16]byte
+	for i := range full {
+		full[i] = 0xff
+	}
+	if got := encodeULID(full); got != "7ZZZZZZZZZZZZZZZZZZZZZZZZZ" {
+		t.Errorf("encodeULID(all ones) = %s", got)
+	}
+}
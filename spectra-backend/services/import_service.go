@@ -0,0 +1,182 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"spectra-backend/eventbus"
+	"spectra-backend/models"
+	"time"
+)
+
+const (
+	// importBatchSize 导入时每批写入的事件数
+	importBatchSize = 1000
+	// importMaxLineSize 单行 JSON 的最大字节数
+	importMaxLineSize = 1 << 20
+	// importMaxErrors 导入结果中最多保留的行错误数
+	importMaxErrors = 100
+)
+
+// RecordBatch 填充默认字段并校验后批量写入一批事件
+// 任一事件校验失败时整批拒绝
+func (s *logService) RecordBatch(ctx context.Context, batch *models.EventBatch) error {
+	received := time.Now()
+	for _, log := range batch.ErrorLogs {
+		if err := s.prepareErrorLog(log); err != nil {
+			return err
+		}
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		if err := s.preparePerformanceMetric(metric); err != nil {
+			return err
+		}
+	}
+	for _, action := range batch.UserActions {
+		if err := s.prepareUserAction(action); err != nil {
+			return err
+		}
+	}
+	for _, event := range batch.CustomEvents {
+		if err := s.prepareCustomEvent(event); err != nil {
+			return err
+		}
+	}
+	for _, pageStay := range batch.PageStays {
+		if err := s.preparePageStay(pageStay); err != nil {
+			return err
+		}
+	}
+	fillBatchUserAgent(batch, s.userAgentFields(ctx))
+	if err := s.checkBatchEventAge(batch, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(batchProjectIDs(batch)...); err != nil {
+		return err
+	}
+	s.checkBatchClockSkew(batch, received)
+	return s.saveBatch(ctx, batch)
+}
+
+// saveBatch 写入已经过预处理的批次，并在成功后发布事件
+func (s *logService) saveBatch(ctx context.Context, batch *models.EventBatch) error {
+	if err := s.repo.SaveBatch(ctx, batch); err != nil {
+		return err
+	}
+
+	for _, log := range batch.ErrorLogs {
+		s.publish(eventbus.TopicErrorLog, log.ProjectID, log)
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		s.publish(eventbus.TopicPerformanceMetric, metric.ProjectID, metric)
+	}
+	for _, action := range batch.UserActions {
+		s.publish(eventbus.TopicUserAction, action.ProjectID, action)
+	}
+	for _, event := range batch.CustomEvents {
+		s.publish(eventbus.TopicCustomEvent, event.ProjectID, event)
+	}
+	for _, pageStay := range batch.PageStays {
+		s.publish(eventbus.TopicPageStay, pageStay.ProjectID, pageStay)
+	}
+	return nil
+}
+
+// ImportEvents 流式读取 JSONL 数据并分批写入
+// 每行是一个 models.EventEnvelope，无法解析或校验失败的行记录到结果中并跳过；
+// 写库失败时立即返回，此前已提交的批次不会回滚
+func (s *logService) ImportEvents(ctx context.Context, reader io.Reader) (*models.ImportResult, error) {
+	result := &models.ImportResult{
+		Counts: make(map[string]int),
+		Errors: []models.ImportLineError{},
+	}
+
+	batch := &models.EventBatch{}
+	flush := func() error {
+		if batch.Len() == 0 {
+			return nil
+		}
+		if err := s.saveBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to import batch ending at line %d: %w", result.Lines, err)
+		}
+		for eventType, count := range batch.Counts() {
+			result.Counts[eventType] += count
+		}
+		result.Imported += batch.Len()
+		batch = &models.EventBatch{}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLineSize)
+	for scanner.Scan() {
+		result.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := s.decodeImportLine(line, batch); err != nil {
+			result.Failed++
+			if len(result.Errors) < importMaxErrors {
+				result.Errors = append(result.Errors, models.ImportLineError{Line: result.Lines, Error: err.Error()})
+			}
+			continue
+		}
+
+		if batch.Len() >= importBatchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read import stream at line %d: %w", result.Lines+1, err)
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// decodeImportLine 解析一行事件包装，预处理后加入批次
+func (s *logService) decodeImportLine(line []byte, batch *models.EventBatch) error {
+	var envelope models.EventEnvelope
+	if err := json.Unmarshal(line, &envelope); err != nil {
+		return err
+	}
+	return s.decodeEnvelope(envelope, batch)
+}
+
+// decodeEnvelope 按 schema_version 升级事件包装中的数据，解析并预处理，计入项目名额后加入批次
+func (s *logService) decodeEnvelope(envelope models.EventEnvelope, batch *models.EventBatch) error {
+	event, err := s.decodeEvent(envelope)
+	if err != nil {
+		return err
+	}
+	if err := s.admitProjects(eventBase(event).ProjectID); err != nil {
+		return err
+	}
+	return batch.Add(event)
+}
+
+// decodeEvent 按 schema_version 升级事件包装中的数据，解析并预处理，返回事件模型的指针
+func (s *logService) decodeEvent(envelope models.EventEnvelope) (any, error) {
+	data, err := UpgradeSchema(envelope.Data)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Data = data
+	event, err := envelope.Decode()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prepareEvent(event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
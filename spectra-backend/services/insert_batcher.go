@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"spectra-backend/config"
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"sync"
+	"time"
+)
+
+// batchFlushTimeout 单次刷新写入的最长时间，与请求的上下文无关
+const batchFlushTimeout = 30 * time.Second
+
+// 批次刷新的触发原因，用于 insert_batcher_flushes_total 指标
+const (
+	flushReasonInterval = "interval"
+	flushReasonSize     = "size"
+	flushReasonShutdown = "shutdown"
+)
+
+// insertBatcher 将单条上报的事件按表合并，定期或达到上限时通过 SaveBatch 一次写入
+// 每张表每次刷新只执行一次多行插入，避免逐条写入在 ClickHouse 中产生大量小 part
+// 调用方等待所在批次写入完成后才返回，写入失败时批次中的所有调用方都收到同一个错误
+type insertBatcher struct {
+	save     func(ctx context.Context, batch *models.EventBatch) error
+	interval time.Duration
+	maxSize  int
+
+	mu      sync.Mutex
+	pending *models.EventBatch
+	waiters []chan error
+	closed  bool
+
+	// full 待写入事件数达到上限时通知刷新协程，容量为 1，多次通知合并为一次
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newInsertBatcher 根据配置创建批量合并器并启动刷新协程，未启用时返回 nil
+func newInsertBatcher(save func(ctx context.Context, batch *models.EventBatch) error, cfg config.BatchingConfig) (*insertBatcher, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.FlushInterval <= 0 || cfg.MaxSize <= 0 {
+		return nil, errors.New("ingest.batching: flush_interval and max_size must be positive")
+	}
+
+	b := &insertBatcher{
+		save:     save,
+		interval: time.Duration(cfg.FlushInterval) * time.Millisecond,
+		maxSize:  cfg.MaxSize,
+		pending:  &models.EventBatch{},
+		full:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go b.run()
+	return b, nil
+}
+
+// add 将事件加入当前批次并等待批次写入完成
+// ctx 结束时立即返回 ctx 的错误，事件仍会随批次写入；合并器关闭后直接单独写入
+func (b *insertBatcher) add(ctx context.Context, event any) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		batch := &models.EventBatch{}
+		if err := batch.Add(event); err != nil {
+			return err
+		}
+		return b.save(ctx, batch)
+	}
+	if err := b.pending.Add(event); err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	result := make(chan error, 1)
+	b.waiters = append(b.waiters, result)
+	if b.pending.Len() >= b.maxSize {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+	b.mu.Unlock()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run 按间隔或在批次已满时刷新，关闭时写出剩余事件后退出
+func (b *insertBatcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			b.flush(flushReasonInterval)
+		case <-b.full:
+			b.flush(flushReasonSize)
+		case <-b.done:
+			b.flush(flushReasonShutdown)
+			return
+		}
+	}
+}
+
+// flush 取出当前批次写入，并把结果通知给批次中的所有调用方
+func (b *insertBatcher) flush(reason string) {
+	b.mu.Lock()
+	batch, waiters := b.pending, b.waiters
+	b.pending, b.waiters = &models.EventBatch{}, nil
+	b.mu.Unlock()
+
+	events := batch.Len()
+	if events == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), batchFlushTimeout)
+	err := b.save(ctx, batch)
+	cancel()
+	for _, waiter := range waiters {
+		waiter <- err
+	}
+
+	metrics.InsertBatcherFlushes.WithLabelValues(reason).Inc()
+	metrics.InsertBatcherEvents.Add(float64(events))
+	// 逐条写入时每个事件一次插入，合并后每张有数据的表一次插入
+	inserts := 0
+	for _, count := range batch.Counts() {
+		if count > 0 {
+			inserts++
+		}
+	}
+	metrics.InsertBatcherInsertsAvoided.Add(float64(events - inserts))
+}
+
+// close 停止接收新事件，写出剩余事件并等待刷新协程退出，可重复调用
+func (b *insertBatcher) close() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	close(b.done)
+	<-b.stopped
+}
@@ -2,6 +2,9 @@ package services
 
 import (
 	"context"
+	"io"
+	"spectra-backend/config"
+	"spectra-backend/eventbus"
 	"spectra-backend/models"
 	"spectra-backend/repository"
 	"time"
@@ -11,101 +14,445 @@ import (
 type LogService interface {
 	// ErrorLog 相关服务
 	RecordErrorLog(ctx context.Context, log *models.ErrorLog) error
-	GetErrorLogs(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.ErrorLog, error)
+	GetErrorLogs(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, severities []string, types []string, order models.ListOrder) ([]*models.ErrorLog, error)
+	GetErrorLogsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, severities []string, types []string, limit int) ([]*models.ErrorLog, error)
 	GetErrorLogByTraceID(ctx context.Context, traceID string) (*models.ErrorLog, error)
+	GetErrorLogsByTraceIDs(ctx context.Context, traceIDs []string) (map[string][]*models.ErrorLog, error)
+	GetErrorGroupTrend(ctx context.Context, projectIDs []string, fingerprint string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.ErrorGroupTrend, error)
+	GetCoOccurringErrors(ctx context.Context, projectIDs []string, errorName string, startTime, endTime time.Time) (*models.ErrorCoOccurrence, error)
+	GetErrorLogsWithPerformance(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]*models.ErrorWithPerformance, error)
+	GetSeverityBreakdown(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.SeverityBreakdown, error)
+	GetErrorsByRelease(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) (*models.ReleaseErrors, error)
+	GetStackTrace(ctx context.Context, hash string) (*models.StackTrace, error)
 
 	// PerformanceMetric 相关服务
 	RecordPerformanceMetric(ctx context.Context, metric *models.PerformanceMetric) error
-	GetPerformanceMetrics(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
-	GetPerformanceMetricsByType(ctx context.Context, projectID string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetrics(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetricsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.PerformanceMetric, error)
+	GetPerformanceMetricByTraceID(ctx context.Context, traceID string) (*models.PerformanceMetric, error)
+	GetPerformanceMetricsByType(ctx context.Context, projectIDs []string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error)
+	GetApdex(ctx context.Context, projectIDs []string, metricName string, threshold float64, minCount int, startTime, endTime time.Time) (*models.ApdexScore, error)
+	GetSlowestURLsByPercentile(ctx context.Context, projectIDs []string, metricName string, quantile float64, minCount int, startTime, endTime time.Time, limit int) ([]*models.URLPercentile, error)
+	GetWebVitalsScorecard(ctx context.Context, projectIDs []string, minCount int, startTime, endTime time.Time) (*models.WebVitalsScorecard, error)
+	MetricAliases() map[string]string
 
 	// UserAction 相关服务
 	RecordUserAction(ctx context.Context, action *models.UserAction) error
-	GetUserActions(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.UserAction, error)
-	GetUserActionsByType(ctx context.Context, projectID string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error)
+	GetUserActions(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, statusClasses []int, order models.ListOrder) ([]*models.UserAction, error)
+	GetUserActionsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, statusClasses []int, limit int) ([]*models.UserAction, error)
+	GetUserActionByTraceID(ctx context.Context, traceID string) (*models.UserAction, error)
+	GetStatusClassBreakdown(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.StatusClassBreakdown, error)
+	GetUserActionsByType(ctx context.Context, projectIDs []string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error)
 
 	// CustomEvent 相关服务
 	RecordCustomEvent(ctx context.Context, event *models.CustomEvent) error
-	GetCustomEvents(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
-	GetCustomEventsByName(ctx context.Context, projectID string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
+	GetCustomEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.CustomEvent, error)
+	GetCustomEventsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.CustomEvent, error)
+	GetCustomEventsByName(ctx context.Context, projectIDs []string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error)
 
 	// PageStay 相关服务
 	RecordPageStay(ctx context.Context, pageStay *models.PageStay) error
-	GetPageStays(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PageStay, error)
-	GetAveragePageStay(ctx context.Context, projectID string, startTime, endTime time.Time) (float64, error)
+	GetPageStays(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]*models.PageStay, error)
+	GetAveragePageStay(ctx context.Context, projectIDs []string, minCount int, startTime, endTime time.Time) (*models.PageStayAverage, error)
+
+	// 时间线相关服务
+	GetSessionTimeline(ctx context.Context, projectID string, sessionID string, allowPartial bool) (*models.Timeline, error)
+	GetTraceTimeline(ctx context.Context, projectID string, traceID string, allowPartial bool) (*models.Timeline, error)
+
+	// 项目相关服务
+	GetDataRange(ctx context.Context, projectID string) (*models.DataRange, error)
+
+	// 看板相关服务
+	GetOverview(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.Overview, error)
+
+	// 运维统计相关服务
+	GetIngestionRate(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.IngestionRate, error)
+	GetActivityHeatmap(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, loc *time.Location) (*models.ActivityHeatmap, error)
+	GetSessionCount(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, interval time.Duration, loc *time.Location) (*models.SessionCount, error)
+	GetCrashFreeSessions(ctx context.Context, projectIDs []string, severities []string, startTime, endTime time.Time, filters []models.ExtraFilter) (*models.CrashFreeStats, error)
+	GetSDKVersionBreakdown(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time) (*models.SDKVersionBreakdown, error)
+	GetEventsByReferrerDomain(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, limit int) (*models.ReferrerBreakdown, error)
+
+	// 批量写入与导入相关服务
+	RecordBatch(ctx context.Context, batch *models.EventBatch) error
+	ImportEvents(ctx context.Context, reader io.Reader) (*models.ImportResult, error)
+	RecordCompressed(ctx context.Context, encoded string) (map[string]int, error)
+	IngestStream(ctx context.Context, reader io.Reader, allowed func(projectID string) bool) (*models.StreamIngestResult, error)
+
+	// 数据删除相关服务
+	DeleteByUser(ctx context.Context, projectID string, userID string) (*models.DeletionSummary, error)
+	DeleteBySession(ctx context.Context, projectID string, sessionID string) (*models.DeletionSummary, error)
+	DeleteProject(ctx context.Context, projectID string, confirm string) (*models.DeletionSummary, error)
+
+	// 数据保留相关服务
+	SetRetention(ctx context.Context, days int) (*models.RetentionPolicy, error)
+
+	// 存储统计相关服务
+	GetStorageStats(ctx context.Context) (*models.StorageStats, error)
+
+	// 元数据相关服务
+	GetSchemaMetadata(ctx context.Context) (*models.SchemaMetadata, error)
+
+	// Close 停止单条上报的批量合并并写出剩余事件，未开启批量合并时不做任何操作
+	Close()
 }
 
 // logService 日志服务实现
 type logService struct {
-	repo repository.LogRepository
+	repo   repository.LogRepository
+	bus    *eventbus.Bus
+	ingest config.IngestConfig
+
+	// metricAliases 指标别名查找表，key 为小写别名
+	metricAliases map[string]string
+	// scrubber 敏感信息脱敏器，为 nil 时不脱敏
+	scrubber *scrubber
+	// newID 缺少 trace_id / session_id 时使用的 ID 生成函数，为 nil 时不生成
+	newID func() string
+	// userIDHash user_id 哈希函数，为 nil 时保存原始 user_id
+	userIDHash func(string) string
+	// botPatterns User-Agent 中出现即视为爬虫的片段（小写）
+	botPatterns []string
+	// webVitals Web Vitals 评分卡统计的指标及阈值，按名称排序
+	webVitals []models.WebVitalScore
+	// projectIDs project_id 格式规则，为 nil 时不校验
+	projectIDs *ProjectIDPolicy
+	// projectLimit 不同项目数限制器，为 nil 时不限制
+	projectLimit *projectLimiter
+	// batcher 单条上报的批量合并器，为 nil 时逐条写入
+	batcher *insertBatcher
+	// schemaCache 事件表列元数据缓存
+	schemaCache schemaMetadataCache
 }
 
 // NewLogService 创建日志服务实例
-func NewLogService(repo repository.LogRepository) LogService {
-	return &logService{
-		repo: repo,
+// bus 为 nil 时不发布事件；脱敏规则中的自定义正则无法编译、ID 方案不支持、开启 user_id 哈希但未配置 salt 或批量合并配置无效时返回错误
+// projectIDs 为 nil 时不校验 project_id 格式；开启 ingest.batching 时启动批量合并协程，需调用 Close 停止
+func NewLogService(repo repository.LogRepository, bus *eventbus.Bus, ingest config.IngestConfig, webVitals map[string]config.WebVitalThreshold, projectIDs *ProjectIDPolicy) (LogService, error) {
+	scrubber, err := newScrubber(ingest.Scrub)
+	if err != nil {
+		return nil, err
+	}
+	newID, err := newIDGenerator(ingest.IDScheme)
+	if err != nil {
+		return nil, err
+	}
+	userIDHash, err := newUserIDHasher(ingest.UserIDHash)
+	if err != nil {
+		return nil, err
+	}
+	batcher, err := newInsertBatcher(repo.SaveBatch, ingest.Batching)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &logService{
+		repo:          repo,
+		bus:           bus,
+		ingest:        ingest,
+		metricAliases: newMetricAliases(ingest.MetricAliases),
+		scrubber:      scrubber,
+		newID:         newID,
+		userIDHash:    userIDHash,
+		botPatterns:   newBotPatterns(ingest.UserAgent.BotPatterns),
+		projectIDs:    projectIDs,
+		projectLimit:  newProjectLimiter(ingest.ProjectLimit),
+		batcher:       batcher,
 	}
+	service.webVitals = service.newWebVitals(webVitals)
+	return service, nil
+}
+
+// Close 停止批量合并器，写出剩余事件后返回
+func (s *logService) Close() {
+	s.batcher.close()
+}
+
+// save 写入单条事件：开启批量合并时加入批次并等待批次写入完成，否则调用 direct 立即写入
+func (s *logService) save(ctx context.Context, event any, direct func(ctx context.Context) error) error {
+	if s.batcher == nil {
+		return direct(ctx)
+	}
+	return s.batcher.add(ctx, event)
+}
+
+// publish 在事件保存成功后发布到事件总线
+func (s *logService) publish(topic eventbus.Topic, projectID string, payload any) {
+	if s.bus == nil {
+		return
+	}
+	s.bus.Publish(eventbus.Event{Topic: topic, ProjectID: projectID, Payload: payload})
 }
 
 // 实现 ErrorLog 相关方法
 func (s *logService) RecordErrorLog(ctx context.Context, log *models.ErrorLog) error {
+	received := time.Now()
+	if err := s.prepareErrorLog(log); err != nil {
+		return err
+	}
+	fillUserAgent(&log.BaseLog, s.userAgentFields(ctx))
+	if err := s.checkEventAge(&log.BaseLog, models.EventTypeErrorLog, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(log.ProjectID); err != nil {
+		return err
+	}
+	s.checkClockSkew(&log.BaseLog, models.EventTypeErrorLog, received)
+	if err := s.save(ctx, log, func(ctx context.Context) error { return s.repo.SaveErrorLog(ctx, log) }); err != nil {
+		return err
+	}
+	s.publish(eventbus.TopicErrorLog, log.ProjectID, log)
+	return nil
+}
+
+// prepareErrorLog 填充错误日志的默认字段并校验
+func (s *logService) prepareErrorLog(log *models.ErrorLog) error {
+	if err := s.validateBase(&log.BaseLog, models.EventTypeErrorLog); err != nil {
+		return err
+	}
+	s.scrubBase(&log.BaseLog)
+	s.truncateURLs(&log.BaseLog, models.EventTypeErrorLog)
+	s.fillGeneratedIDs(&log.BaseLog)
+	s.hashUserID(&log.BaseLog)
+	log.Message = s.scrubber.scrubString(log.Message)
+	if err := normalizeSeverity(log); err != nil {
+		return err
+	}
+	extractStack(log)
 	if log.Timestamp.IsZero() {
 		log.Timestamp = time.Now()
 	}
 	if log.Type == "" {
 		log.Type = "error"
 	}
-	return s.repo.SaveErrorLog(ctx, log)
+	return nil
+}
+
+func (s *logService) GetErrorLogs(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, severities []string, types []string, order models.ListOrder) ([]*models.ErrorLog, error) {
+	return s.repo.GetErrorLogs(ctx, projectIDs, startTime, endTime, filters, severities, types, order)
 }
 
-func (s *logService) GetErrorLogs(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.ErrorLog, error) {
-	return s.repo.GetErrorLogs(ctx, projectID, startTime, endTime)
+func (s *logService) GetErrorLogsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, severities []string, types []string, limit int) ([]*models.ErrorLog, error) {
+	return s.repo.GetErrorLogsSince(ctx, projectIDs, since, filters, severities, types, limit)
 }
 
 func (s *logService) GetErrorLogByTraceID(ctx context.Context, traceID string) (*models.ErrorLog, error) {
 	return s.repo.GetErrorLogByTraceID(ctx, traceID)
 }
 
+// GetErrorLogsByTraceIDs 批量获取多个 traceID 的错误日志，按 traceID 分组
+// 每个请求的 traceID 在结果中都有对应的键，没有错误日志时为空列表
+func (s *logService) GetErrorLogsByTraceIDs(ctx context.Context, traceIDs []string) (map[string][]*models.ErrorLog, error) {
+	logs, err := s.repo.GetErrorLogsByTraceIDs(ctx, traceIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byTrace := make(map[string][]*models.ErrorLog, len(traceIDs))
+	for _, traceID := range traceIDs {
+		byTrace[traceID] = []*models.ErrorLog{}
+	}
+	for _, log := range logs {
+		byTrace[log.TraceID] = append(byTrace[log.TraceID], log)
+	}
+	return byTrace, nil
+}
+
+func (s *logService) GetErrorLogsWithPerformance(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) ([]*models.ErrorWithPerformance, error) {
+	return s.repo.GetErrorLogsWithPerformance(ctx, projectIDs, startTime, endTime, limit)
+}
+
+func (s *logService) GetCoOccurringErrors(ctx context.Context, projectIDs []string, errorName string, startTime, endTime time.Time) (*models.ErrorCoOccurrence, error) {
+	return s.repo.GetCoOccurringErrors(ctx, projectIDs, errorName, startTime, endTime)
+}
+
 // 实现 PerformanceMetric 相关方法
 func (s *logService) RecordPerformanceMetric(ctx context.Context, metric *models.PerformanceMetric) error {
+	received := time.Now()
+	if err := s.preparePerformanceMetric(metric); err != nil {
+		return err
+	}
+	fillUserAgent(&metric.BaseLog, s.userAgentFields(ctx))
+	if err := s.checkEventAge(&metric.BaseLog, models.EventTypePerformanceMetric, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(metric.ProjectID); err != nil {
+		return err
+	}
+	s.checkClockSkew(&metric.BaseLog, models.EventTypePerformanceMetric, received)
+	if err := s.save(ctx, metric, func(ctx context.Context) error { return s.repo.SavePerformanceMetric(ctx, metric) }); err != nil {
+		return err
+	}
+	s.publish(eventbus.TopicPerformanceMetric, metric.ProjectID, metric)
+	return nil
+}
+
+// preparePerformanceMetric 填充性能指标的默认字段并校验
+func (s *logService) preparePerformanceMetric(metric *models.PerformanceMetric) error {
+	if err := s.validateBase(&metric.BaseLog, models.EventTypePerformanceMetric); err != nil {
+		return err
+	}
+	s.scrubBase(&metric.BaseLog)
+	s.truncateURLs(&metric.BaseLog, models.EventTypePerformanceMetric)
+	s.fillGeneratedIDs(&metric.BaseLog)
+	s.hashUserID(&metric.BaseLog)
 	if metric.Timestamp.IsZero() {
 		metric.Timestamp = time.Now()
 	}
 	if metric.Type == "" {
 		metric.Type = "performance"
 	}
-	return s.repo.SavePerformanceMetric(ctx, metric)
+	metric.Name = s.canonicalMetricName(metric.Name)
+	return nil
+}
+
+func (s *logService) GetPerformanceMetrics(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.PerformanceMetric, error) {
+	return s.repo.GetPerformanceMetrics(ctx, projectIDs, startTime, endTime, filters, order)
+}
+
+func (s *logService) GetPerformanceMetricsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.PerformanceMetric, error) {
+	return s.repo.GetPerformanceMetricsSince(ctx, projectIDs, since, filters, limit)
+}
+
+func (s *logService) GetPerformanceMetricByTraceID(ctx context.Context, traceID string) (*models.PerformanceMetric, error) {
+	return s.repo.GetPerformanceMetricByTraceID(ctx, traceID)
+}
+
+func (s *logService) GetPerformanceMetricsByType(ctx context.Context, projectIDs []string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
+	return s.repo.GetPerformanceMetricsByType(ctx, projectIDs, s.canonicalMetricName(metricType), startTime, endTime)
+}
+
+// GetApdex 计算 Apdex 评分：(满意数 + 容忍数/2) / 总数，样本数少于 minCount 时标记为低置信度
+func (s *logService) GetApdex(ctx context.Context, projectIDs []string, metricName string, threshold float64, minCount int, startTime, endTime time.Time) (*models.ApdexScore, error) {
+	apdex, err := s.repo.GetApdex(ctx, projectIDs, s.canonicalMetricName(metricName), threshold, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if apdex.Total > 0 {
+		score := (float64(apdex.Satisfied) + float64(apdex.Tolerating)/2) / float64(apdex.Total)
+		apdex.Score = &score
+	}
+	apdex.LowConfidence = lowConfidence(apdex.Total, minCount)
+	return apdex, nil
 }
 
-func (s *logService) GetPerformanceMetrics(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
-	return s.repo.GetPerformanceMetrics(ctx, projectID, startTime, endTime)
+// lowConfidence 判断样本数是否少于 minCount，minCount 不大于 0 时不做判断
+func lowConfidence(count uint64, minCount int) bool {
+	return minCount > 0 && count < uint64(minCount)
 }
 
-func (s *logService) GetPerformanceMetricsByType(ctx context.Context, projectID string, metricType string, startTime, endTime time.Time) ([]*models.PerformanceMetric, error) {
-	return s.repo.GetPerformanceMetricsByType(ctx, projectID, metricType, startTime, endTime)
+// GetSlowestURLsByPercentile 获取指定性能指标分位值最高的页面，样本数少于 minCount 的页面不参与排序
+func (s *logService) GetSlowestURLsByPercentile(ctx context.Context, projectIDs []string, metricName string, quantile float64, minCount int, startTime, endTime time.Time, limit int) ([]*models.URLPercentile, error) {
+	return s.repo.GetSlowestURLsByPercentile(ctx, projectIDs, s.canonicalMetricName(metricName), quantile, minCount, startTime, endTime, limit)
 }
 
 // 实现 UserAction 相关方法
 func (s *logService) RecordUserAction(ctx context.Context, action *models.UserAction) error {
+	received := time.Now()
+	if err := s.prepareUserAction(action); err != nil {
+		return err
+	}
+	fillUserAgent(&action.BaseLog, s.userAgentFields(ctx))
+	if err := s.checkEventAge(&action.BaseLog, models.EventTypeUserAction, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(action.ProjectID); err != nil {
+		return err
+	}
+	s.checkClockSkew(&action.BaseLog, models.EventTypeUserAction, received)
+	if err := s.save(ctx, action, func(ctx context.Context) error { return s.repo.SaveUserAction(ctx, action) }); err != nil {
+		return err
+	}
+	s.publish(eventbus.TopicUserAction, action.ProjectID, action)
+	return nil
+}
+
+// prepareUserAction 填充用户行为的默认字段并校验
+func (s *logService) prepareUserAction(action *models.UserAction) error {
+	if err := s.validateBase(&action.BaseLog, models.EventTypeUserAction); err != nil {
+		return err
+	}
+	s.scrubBase(&action.BaseLog)
+	s.truncateURLs(&action.BaseLog, models.EventTypeUserAction)
+	s.fillGeneratedIDs(&action.BaseLog)
+	s.hashUserID(&action.BaseLog)
+	action.Message = s.scrubber.scrubString(action.Message)
 	if action.Timestamp.IsZero() {
 		action.Timestamp = time.Now()
 	}
 	if action.Type == "" {
 		action.Type = "user"
 	}
-	return s.repo.SaveUserAction(ctx, action)
+	return nil
+}
+
+func (s *logService) GetUserActions(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, statusClasses []int, order models.ListOrder) ([]*models.UserAction, error) {
+	return s.repo.GetUserActions(ctx, projectIDs, startTime, endTime, filters, statusClasses, order)
 }
 
-func (s *logService) GetUserActions(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.UserAction, error) {
-	return s.repo.GetUserActions(ctx, projectID, startTime, endTime)
+func (s *logService) GetUserActionsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, statusClasses []int, limit int) ([]*models.UserAction, error) {
+	return s.repo.GetUserActionsSince(ctx, projectIDs, since, filters, statusClasses, limit)
 }
 
-func (s *logService) GetUserActionsByType(ctx context.Context, projectID string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error) {
-	return s.repo.GetUserActionsByType(ctx, projectID, actionType, startTime, endTime)
+func (s *logService) GetUserActionByTraceID(ctx context.Context, traceID string) (*models.UserAction, error) {
+	return s.repo.GetUserActionByTraceID(ctx, traceID)
+}
+
+// GetStatusClassBreakdown 按 HTTP 状态码类别统计用户行为数，结果包含 1xx~5xx 全部类别，没有数据的计数为 0
+func (s *logService) GetStatusClassBreakdown(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.StatusClassBreakdown, error) {
+	counts, err := s.repo.GetStatusClassCounts(ctx, projectIDs, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	breakdown := &models.StatusClassBreakdown{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Classes:    make([]models.StatusClassCount, 0, len(models.StatusClasses)),
+	}
+	for _, class := range models.StatusClasses {
+		breakdown.Classes = append(breakdown.Classes, models.StatusClassCount{Class: models.StatusClassName(class), Count: counts[class]})
+		breakdown.Total += counts[class]
+	}
+	return breakdown, nil
+}
+
+func (s *logService) GetUserActionsByType(ctx context.Context, projectIDs []string, actionType string, startTime, endTime time.Time) ([]*models.UserAction, error) {
+	return s.repo.GetUserActionsByType(ctx, projectIDs, actionType, startTime, endTime)
 }
 
 // 实现 CustomEvent 相关方法
 func (s *logService) RecordCustomEvent(ctx context.Context, event *models.CustomEvent) error {
+	received := time.Now()
+	if err := s.prepareCustomEvent(event); err != nil {
+		return err
+	}
+	fillUserAgent(&event.BaseLog, s.userAgentFields(ctx))
+	if err := s.checkEventAge(&event.BaseLog, models.EventTypeCustomEvent, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(event.ProjectID); err != nil {
+		return err
+	}
+	s.checkClockSkew(&event.BaseLog, models.EventTypeCustomEvent, received)
+	if err := s.save(ctx, event, func(ctx context.Context) error { return s.repo.SaveCustomEvent(ctx, event) }); err != nil {
+		return err
+	}
+	s.publish(eventbus.TopicCustomEvent, event.ProjectID, event)
+	return nil
+}
+
+// prepareCustomEvent 填充自定义事件的默认字段并校验
+func (s *logService) prepareCustomEvent(event *models.CustomEvent) error {
+	if err := s.validateBase(&event.BaseLog, models.EventTypeCustomEvent); err != nil {
+		return err
+	}
+	s.scrubBase(&event.BaseLog)
+	s.truncateURLs(&event.BaseLog, models.EventTypeCustomEvent)
+	s.fillGeneratedIDs(&event.BaseLog)
+	s.hashUserID(&event.BaseLog)
+	event.Message = s.scrubber.scrubString(event.Message)
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
@@ -115,19 +462,51 @@ func (s *logService) RecordCustomEvent(ctx context.Context, event *models.Custom
 	if event.Message == "" {
 		event.Message = "custom_event"
 	}
-	return s.repo.SaveCustomEvent(ctx, event)
+	return nil
+}
+
+func (s *logService) GetCustomEvents(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, order models.ListOrder) ([]*models.CustomEvent, error) {
+	return s.repo.GetCustomEvents(ctx, projectIDs, startTime, endTime, filters, order)
 }
 
-func (s *logService) GetCustomEvents(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
-	return s.repo.GetCustomEvents(ctx, projectID, startTime, endTime)
+func (s *logService) GetCustomEventsSince(ctx context.Context, projectIDs []string, since models.EventCursor, filters []models.ExtraFilter, limit int) ([]*models.CustomEvent, error) {
+	return s.repo.GetCustomEventsSince(ctx, projectIDs, since, filters, limit)
 }
 
-func (s *logService) GetCustomEventsByName(ctx context.Context, projectID string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
-	return s.repo.GetCustomEventsByName(ctx, projectID, eventName, startTime, endTime)
+func (s *logService) GetCustomEventsByName(ctx context.Context, projectIDs []string, eventName string, startTime, endTime time.Time) ([]*models.CustomEvent, error) {
+	return s.repo.GetCustomEventsByName(ctx, projectIDs, eventName, startTime, endTime)
 }
 
 // 实现 PageStay 相关方法
 func (s *logService) RecordPageStay(ctx context.Context, pageStay *models.PageStay) error {
+	received := time.Now()
+	if err := s.preparePageStay(pageStay); err != nil {
+		return err
+	}
+	fillUserAgent(&pageStay.BaseLog, s.userAgentFields(ctx))
+	if err := s.checkEventAge(&pageStay.BaseLog, models.EventTypePageStay, received); err != nil {
+		return err
+	}
+	if err := s.admitProjects(pageStay.ProjectID); err != nil {
+		return err
+	}
+	s.checkClockSkew(&pageStay.BaseLog, models.EventTypePageStay, received)
+	if err := s.save(ctx, pageStay, func(ctx context.Context) error { return s.repo.SavePageStay(ctx, pageStay) }); err != nil {
+		return err
+	}
+	s.publish(eventbus.TopicPageStay, pageStay.ProjectID, pageStay)
+	return nil
+}
+
+// preparePageStay 填充页面停留的默认字段并校验
+func (s *logService) preparePageStay(pageStay *models.PageStay) error {
+	if err := s.validateBase(&pageStay.BaseLog, models.EventTypePageStay); err != nil {
+		return err
+	}
+	s.scrubBase(&pageStay.BaseLog)
+	s.truncateURLs(&pageStay.BaseLog, models.EventTypePageStay)
+	s.fillGeneratedIDs(&pageStay.BaseLog)
+	s.hashUserID(&pageStay.BaseLog)
 	if pageStay.Timestamp.IsZero() {
 		pageStay.Timestamp = time.Now()
 	}
@@ -137,13 +516,94 @@ func (s *logService) RecordPageStay(ctx context.Context, pageStay *models.PageSt
 	if pageStay.Name == "" {
 		pageStay.Name = "page_stay_time"
 	}
-	return s.repo.SavePageStay(ctx, pageStay)
+	return nil
+}
+
+func (s *logService) GetPageStays(ctx context.Context, projectIDs []string, startTime, endTime time.Time) ([]*models.PageStay, error) {
+	return s.repo.GetPageStays(ctx, projectIDs, startTime, endTime)
+}
+
+// GetAveragePageStay 计算平均页面停留时长，样本数少于 minCount 时标记为低置信度
+func (s *logService) GetAveragePageStay(ctx context.Context, projectIDs []string, minCount int, startTime, endTime time.Time) (*models.PageStayAverage, error) {
+	average, err := s.repo.GetAveragePageStay(ctx, projectIDs, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	average.LowConfidence = lowConfidence(average.Count, minCount)
+	return average, nil
+}
+
+// 实现时间线相关方法
+func (s *logService) GetSessionTimeline(ctx context.Context, projectID string, sessionID string, allowPartial bool) (*models.Timeline, error) {
+	return s.repo.GetTimeline(ctx, projectID, repository.TimelineFieldSession, sessionID, allowPartial)
+}
+
+func (s *logService) GetTraceTimeline(ctx context.Context, projectID string, traceID string, allowPartial bool) (*models.Timeline, error) {
+	return s.repo.GetTimeline(ctx, projectID, repository.TimelineFieldTrace, traceID, allowPartial)
+}
+
+// 实现项目相关方法
+func (s *logService) GetDataRange(ctx context.Context, projectID string) (*models.DataRange, error) {
+	return s.repo.GetDataRange(ctx, projectID)
+}
+
+func (s *logService) GetOverview(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.Overview, error) {
+	return s.repo.GetOverview(ctx, projectIDs, startTime, endTime)
+}
+
+// GetSDKVersionBreakdown 按 SDK 版本和平台统计事件数，eventType 为空时统计所有事件表
+func (s *logService) GetSDKVersionBreakdown(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time) (*models.SDKVersionBreakdown, error) {
+	versions, err := s.repo.GetSDKVersionBreakdown(ctx, projectIDs, eventType, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []models.SDKVersionCount{}
+	}
+	return &models.SDKVersionBreakdown{
+		ProjectIDs: projectIDs,
+		Type:       eventType,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Versions:   versions,
+	}, nil
 }
 
-func (s *logService) GetPageStays(ctx context.Context, projectID string, startTime, endTime time.Time) ([]*models.PageStay, error) {
-	return s.repo.GetPageStays(ctx, projectID, startTime, endTime)
+// GetEventsByReferrerDomain 按来源域名统计事件数、错误数和会话数，eventType 为空时统计所有事件表
+func (s *logService) GetEventsByReferrerDomain(ctx context.Context, projectIDs []string, eventType string, startTime, endTime time.Time, limit int) (*models.ReferrerBreakdown, error) {
+	referrers, err := s.repo.GetEventsByReferrerDomain(ctx, projectIDs, eventType, startTime, endTime, limit)
+	if err != nil {
+		return nil, err
+	}
+	if referrers == nil {
+		referrers = []models.ReferrerCount{}
+	}
+	return &models.ReferrerBreakdown{
+		ProjectIDs: projectIDs,
+		Type:       eventType,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Referrers:  referrers,
+	}, nil
 }
 
-func (s *logService) GetAveragePageStay(ctx context.Context, projectID string, startTime, endTime time.Time) (float64, error) {
-	return s.repo.GetAveragePageStay(ctx, projectID, startTime, endTime)
-}
\ No newline at end of file
+// 实现数据删除相关方法
+// DeleteByUser 开启 user_id 哈希时 userID 为原始值，按与写入相同的方式哈希后删除
+func (s *logService) DeleteByUser(ctx context.Context, projectID string, userID string) (*models.DeletionSummary, error) {
+	if s.userIDHash != nil {
+		userID = s.userIDHash(userID)
+	}
+	return s.repo.DeleteByUser(ctx, projectID, userID)
+}
+
+func (s *logService) DeleteBySession(ctx context.Context, projectID string, sessionID string) (*models.DeletionSummary, error) {
+	return s.repo.DeleteBySession(ctx, projectID, sessionID)
+}
+
+// ErrSystemTablesDenied 数据库用户没有读取 system.parts 的权限，无法统计存储占用
+var ErrSystemTablesDenied = repository.ErrSystemTablesDenied
+
+// GetStorageStats 获取各数据表的行数和磁盘占用
+func (s *logService) GetStorageStats(ctx context.Context) (*models.StorageStats, error) {
+	return s.repo.GetStorageStats(ctx)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"spectra-backend/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLogService 使用 fakeRepository 创建日志服务，未指定 ID 方案时不生成 ID
+func newTestLogService(t *testing.T, repo *fakeRepository, ingest config.IngestConfig) LogService {
+	t.Helper()
+	if ingest.IDScheme == "" {
+		ingest.IDScheme = "none"
+	}
+	service, err := NewLogService(repo, nil, ingest, nil, nil)
+	if err != nil {
+		t.Fatalf("NewLogService() error = %v", err)
+	}
+	t.Cleanup(service.Close)
+	return service
+}
+
+// fakeRepository 记录写入事件的仓储，未覆盖的方法调用时 panic
+type fakeRepository struct {
+	repository.LogRepository
+
+	mu      sync.Mutex
+	saved   []any
+	batches []*models.EventBatch
+
+	// 聚合查询返回的样本数
+	apdexTotal   uint64
+	averageCount uint64
+}
+
+func (r *fakeRepository) record(event any) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saved = append(r.saved, event)
+	return nil
+}
+
+func (r *fakeRepository) SaveErrorLog(_ context.Context, log *models.ErrorLog) error {
+	return r.record(log)
+}
+
+func (r *fakeRepository) SavePerformanceMetric(_ context.Context, metric *models.PerformanceMetric) error {
+	return r.record(metric)
+}
+
+func (r *fakeRepository) SaveUserAction(_ context.Context, action *models.UserAction) error {
+	return r.record(action)
+}
+
+func (r *fakeRepository) SaveCustomEvent(_ context.Context, event *models.CustomEvent) error {
+	return r.record(event)
+}
+
+func (r *fakeRepository) SavePageStay(_ context.Context, pageStay *models.PageStay) error {
+	return r.record(pageStay)
+}
+
+func (r *fakeRepository) SaveBatch(_ context.Context, batch *models.EventBatch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *fakeRepository) GetApdex(_ context.Context, _ []string, metricName string, threshold float64, _, _ time.Time) (*models.ApdexScore, error) {
+	return &models.ApdexScore{Name: metricName, Threshold: threshold, Satisfied: r.apdexTotal, Total: r.apdexTotal}, nil
+}
+
+func (r *fakeRepository) GetAveragePageStay(_ context.Context, _ []string, _, _ time.Time) (*models.PageStayAverage, error) {
+	return &models.PageStayAverage{AveragePageStay: 1000, Count: r.averageCount}, nil
+}
@@ -0,0 +1,32 @@
+package services
+
+import "strings"
+
+// newMetricAliases 构建别名查找表，别名不区分大小写
+func newMetricAliases(aliases map[string]string) map[string]string {
+	lookup := make(map[string]string, len(aliases))
+	for alias, canonical := range aliases {
+		lookup[strings.ToLower(strings.TrimSpace(alias))] = canonical
+	}
+	return lookup
+}
+
+// canonicalMetricName 将指标名称转换为标准名称，未配置别名时原样返回
+func (s *logService) canonicalMetricName(name string) string {
+	if len(s.metricAliases) == 0 {
+		return name
+	}
+	if canonical, ok := s.metricAliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return canonical
+	}
+	return name
+}
+
+// MetricAliases 返回当前生效的指标别名表（别名均为小写）
+func (s *logService) MetricAliases() map[string]string {
+	aliases := make(map[string]string, len(s.metricAliases))
+	for alias, canonical := range s.metricAliases {
+		aliases[alias] = canonical
+	}
+	return aliases
+}
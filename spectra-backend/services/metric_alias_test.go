@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"testing"
+)
+
+func TestCanonicalMetricName(t *testing.T) {
+	s := &logService{metricAliases: newMetricAliases(map[string]string{
+		"largest-contentful-paint":  "LCP",
+		" Largest_Contentful_Paint": "LCP",
+		"fid":                       "FID",
+	})}
+
+	tests := map[string]string{
+		"largest-contentful-paint": "LCP",
+		"LARGEST-CONTENTFUL-PAINT": "LCP",
+		"largest_contentful_paint": "LCP",
+		" fid ":                    "FID",
+		"LCP":                      "LCP",
+		"TTFB":                     "TTFB",
+		"":                         "",
+	}
+	for name, want := range tests {
+		if got := s.canonicalMetricName(name); got != want {
+			t.Errorf("canonicalMetricName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCanonicalMetricNameWithoutAliases(t *testing.T) {
+	s := &logService{metricAliases: newMetricAliases(nil)}
+	for _, name := range []string{"largest-contentful-paint", " LCP "} {
+		if got := s.canonicalMetricName(name); got != name {
+			t.Errorf("canonicalMetricName(%q) = %q, want unchanged", name, got)
+		}
+	}
+	if aliases := s.MetricAliases(); len(aliases) != 0 {
+		t.Errorf("MetricAliases() = %v, want empty", aliases)
+	}
+}
+
+func TestRecordPerformanceMetricCanonicalizesName(t *testing.T) {
+	repo := &fakeRepository{}
+	service := newTestLogService(t, repo, config.IngestConfig{
+		MetricAliases: map[string]string{"largest-contentful-paint": "LCP"},
+	})
+
+	metric := &models.PerformanceMetric{BaseLog: models.BaseLog{ProjectID: "web", Name: "Largest-Contentful-Paint"}, Value: 1200}
+	if err := service.RecordPerformanceMetric(context.Background(), metric); err != nil {
+		t.Fatalf("RecordPerformanceMetric() error = %v", err)
+	}
+	if len(repo.saved) != 1 {
+		t.Fatalf("saved %d events, want 1", len(repo.saved))
+	}
+	if got := repo.saved[0].(*models.PerformanceMetric).Name; got != "LCP" {
+		t.Errorf("saved metric name = %q, want LCP", got)
+	}
+
+	aliases := service.MetricAliases()
+	if len(aliases) != 1 || aliases["largest-contentful-paint"] != "LCP" {
+		t.Errorf("MetricAliases() = %v", aliases)
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/config"
+	"testing"
+	"time"
+)
+
+// minCountTests 样本数与 min_count 的边界情况
+var minCountTests = []struct {
+	name     string
+	count    uint64
+	minCount int
+	want     bool
+}{
+	{"below threshold", 19, 20, true},
+	{"at threshold", 20, 20, false},
+	{"above threshold", 21, 20, false},
+	{"no samples", 0, 1, true},
+	{"threshold disabled", 0, 0, false},
+}
+
+func TestLowConfidence(t *testing.T) {
+	for _, tt := range minCountTests {
+		if got := lowConfidence(tt.count, tt.minCount); got != tt.want {
+			t.Errorf("%s: lowConfidence(%d, %d) = %v, want %v", tt.name, tt.count, tt.minCount, got, tt.want)
+		}
+	}
+}
+
+func TestMinCountFlagsAggregates(t *testing.T) {
+	for _, tt := range minCountTests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{apdexTotal: tt.count, averageCount: tt.count}
+			service := newTestLogService(t, repo, config.IngestConfig{})
+			end := time.Now()
+
+			apdex, err := service.GetApdex(context.Background(), []string{"web"}, "lcp", 2500, tt.minCount, end.Add(-time.Hour), end)
+			if err != nil {
+				t.Fatalf("GetApdex() error = %v", err)
+			}
+			if apdex.LowConfidence != tt.want {
+				t.Errorf("apdex low_confidence = %v, want %v", apdex.LowConfidence, tt.want)
+			}
+
+			average, err := service.GetAveragePageStay(context.Background(), []string{"web"}, tt.minCount, end.Add(-time.Hour), end)
+			if err != nil {
+				t.Fatalf("GetAveragePageStay() error = %v", err)
+			}
+			if average.LowConfidence != tt.want {
+				t.Errorf("average low_confidence = %v, want %v", average.LowConfidence, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"spectra-backend/config"
+	"strings"
+)
+
+// ProjectIDPolicy project_id 的格式校验和规范化规则，上报和查询共用
+// 为 nil 时不做任何校验和转换
+type ProjectIDPolicy struct {
+	pattern   *regexp.Regexp
+	lowercase bool
+}
+
+// NewProjectIDPolicy 按配置创建 project_id 规则，pattern 为空时只做规范化
+func NewProjectIDPolicy(cfg config.ProjectIDConfig) (*ProjectIDPolicy, error) {
+	policy := &ProjectIDPolicy{lowercase: cfg.Lowercase}
+	if cfg.Pattern != "" {
+		pattern, err := regexp.Compile(cfg.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project_id pattern %q: %w", cfg.Pattern, err)
+		}
+		policy.pattern = pattern
+	}
+	return policy, nil
+}
+
+// Canonical 返回规范化后的 project_id，不做格式校验
+func (p *ProjectIDPolicy) Canonical(projectID string) string {
+	if p == nil || !p.lowercase {
+		return projectID
+	}
+	return strings.ToLower(projectID)
+}
+
+// Normalize 规范化 project_id 并校验格式，不符合时返回 ValidationError
+// 先规范化再校验，开启小写转换时模式只需考虑小写形式
+func (p *ProjectIDPolicy) Normalize(projectID string) (string, error) {
+	projectID = p.Canonical(projectID)
+	if p != nil && p.pattern != nil && !p.pattern.MatchString(projectID) {
+		return "", &ValidationError{
+			Field:   "project_id",
+			Message: fmt.Sprintf("must match pattern %s", p.pattern),
+		}
+	}
+	return projectID, nil
+}
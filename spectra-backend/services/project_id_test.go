@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"spectra-backend/config"
+	"strings"
+	"testing"
+)
+
+func TestProjectIDPolicyNormalize(t *testing.T) {
+	defaultPattern := `^[a-zA-Z0-9_-]{1,64}$`
+	tests := []struct {
+		name      string
+		cfg       config.ProjectIDConfig
+		projectID string
+		want      string
+		wantErr   bool
+	}{
+		{"letters digits dash underscore", config.ProjectIDConfig{Pattern: defaultPattern}, "Web_app-01", "Web_app-01", false},
+		{"64 characters", config.ProjectIDConfig{Pattern: defaultPattern}, strings.Repeat("a", 64), strings.Repeat("a", 64), false},
+		{"65 characters", config.ProjectIDConfig{Pattern: defaultPattern}, strings.Repeat("a", 65), "", true},
+		{"empty", config.ProjectIDConfig{Pattern: defaultPattern}, "", "", true},
+		{"space", config.ProjectIDConfig{Pattern: defaultPattern}, "web app", "", true},
+		{"slash", config.ProjectIDConfig{Pattern: defaultPattern}, "web/app", "", true},
+		{"unicode", config.ProjectIDConfig{Pattern: defaultPattern}, "项目", "", true},
+		{"lowercase", config.ProjectIDConfig{Pattern: defaultPattern, Lowercase: true}, "Web-App", "web-app", false},
+		// 先转小写再校验，模式只需考虑小写形式
+		{"lowercase before matching", config.ProjectIDConfig{Pattern: `^[a-z]+$`, Lowercase: true}, "WEB", "web", false},
+		{"no pattern", config.ProjectIDConfig{}, "web app/项目", "web app/项目", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewProjectIDPolicy(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewProjectIDPolicy() error = %v", err)
+			}
+			got, err := policy.Normalize(tt.projectID)
+			if tt.wantErr {
+				var validationErr *ValidationError
+				if !errors.As(err, &validationErr) || validationErr.Field != "project_id" {
+					t.Errorf("Normalize(%q) = %q, %v, want project_id validation error", tt.projectID, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Normalize(%q) = %q, %v, want %q", tt.projectID, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectIDPolicyNil(t *testing.T) {
+	var policy *ProjectIDPolicy
+	if got, err := policy.Normalize("Web App"); err != nil || got != "Web App" {
+		t.Errorf("nil policy Normalize() = %q, %v, want unchanged", got, err)
+	}
+}
+
+func TestNewProjectIDPolicyInvalidPattern(t *testing.T) {
+	if _, err := NewProjectIDPolicy(config.ProjectIDConfig{Pattern: "["}); err == nil {
+		t.Error("NewProjectIDPolicy() accepted an invalid pattern")
+	}
+}
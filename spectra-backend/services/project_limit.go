@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"spectra-backend/config"
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"sync"
+	"time"
+)
+
+// projectLimitSweepInterval 达到上限时清理过期项目的最小间隔，避免大量新项目请求时每次都遍历全部项目
+const projectLimitSweepInterval = time.Second
+
+// ProjectLimitError 近期接收的不同项目数已达到 ingest.project_limit.max_projects，处理器据此返回 429
+type ProjectLimitError struct {
+	MaxProjects int
+	Window      time.Duration
+}
+
+func (e *ProjectLimitError) Error() string {
+	return fmt.Sprintf("project limit reached: at most %d distinct projects per %s", e.MaxProjects, e.Window)
+}
+
+// projectLimiter 在内存中记录近期接收过数据的项目，不同项目数达到上限后拒绝新项目
+// 项目最后一次上报超过 window 后过期，不再占用名额；只统计当前实例，重启后清空
+type projectLimiter struct {
+	maxProjects int
+	window      time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // project_id -> 最后一次上报时间
+	lastSweep time.Time
+}
+
+// newProjectLimiter 根据配置创建项目数限制器，max_projects 不大于 0 时返回 nil 表示不限制
+func newProjectLimiter(cfg config.ProjectLimitConfig) *projectLimiter {
+	if cfg.MaxProjects <= 0 {
+		return nil
+	}
+	window := time.Duration(cfg.Window) * time.Second
+	if window <= 0 {
+		window = 24 * time.Hour
+	}
+	return &projectLimiter{
+		maxProjects: cfg.MaxProjects,
+		window:      window,
+		seen:        make(map[string]time.Time),
+	}
+}
+
+// admit 一次性接收一组项目，projectIDs 可以包含重复项：新项目加入后不超过上限时记录全部项目的上报时间，
+// 否则一个都不记录并返回 ProjectLimitError，避免被拒绝的批次中的新项目占用名额
+// 已记录的项目不受限制；限制器为 nil 时总是放行
+func (l *projectLimiter) admit(projectIDs []string, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	unseen := l.unseen(projectIDs)
+	if unseen > 0 && len(l.seen)+unseen > l.maxProjects && now.Sub(l.lastSweep) >= projectLimitSweepInterval {
+		l.sweep(now)
+		unseen = l.unseen(projectIDs)
+	}
+	if unseen > 0 && len(l.seen)+unseen > l.maxProjects {
+		metrics.RejectedProjects.Inc()
+		return &ProjectLimitError{MaxProjects: l.maxProjects, Window: l.window}
+	}
+
+	for _, projectID := range projectIDs {
+		l.seen[projectID] = now
+	}
+	return nil
+}
+
+// unseen 返回 projectIDs 中未记录的不同项目数，调用方须持有锁
+// 已过期但尚未清理的项目仍视为已记录，与清理前的状态保持一致
+func (l *projectLimiter) unseen(projectIDs []string) int {
+	counted := make(map[string]struct{})
+	for _, projectID := range projectIDs {
+		if _, ok := l.seen[projectID]; ok {
+			continue
+		}
+		counted[projectID] = struct{}{}
+	}
+	return len(counted)
+}
+
+// admitProjects 在事件通过全部校验后为其项目计入名额，达到上限时返回 ProjectLimitError
+// 须在白名单、字段、extra 和事件时间校验之后调用，被拒绝的事件不占用名额
+func (s *logService) admitProjects(projectIDs ...string) error {
+	return s.projectLimit.admit(projectIDs, time.Now())
+}
+
+// batchProjectIDs 返回批次中所有事件的 project_id，可能包含重复项
+func batchProjectIDs(batch *models.EventBatch) []string {
+	projectIDs := make([]string, 0, batch.Len())
+	for _, log := range batch.ErrorLogs {
+		projectIDs = append(projectIDs, log.ProjectID)
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		projectIDs = append(projectIDs, metric.ProjectID)
+	}
+	for _, action := range batch.UserActions {
+		projectIDs = append(projectIDs, action.ProjectID)
+	}
+	for _, event := range batch.CustomEvents {
+		projectIDs = append(projectIDs, event.ProjectID)
+	}
+	for _, pageStay := range batch.PageStays {
+		projectIDs = append(projectIDs, pageStay.ProjectID)
+	}
+	return projectIDs
+}
+
+// sweep 删除超过 window 未上报的项目，调用方须持有锁
+func (l *projectLimiter) sweep(now time.Time) {
+	for projectID, last := range l.seen {
+		if now.Sub(last) > l.window {
+			delete(l.seen, projectID)
+		}
+	}
+	l.lastSweep = now
+}
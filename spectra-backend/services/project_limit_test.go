@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProjectLimiterAdmit(t *testing.T) {
+	limiter := newProjectLimiter(config.ProjectLimitConfig{MaxProjects: 2, Window: 60})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, projectID := range []string{"a", "b", "a"} {
+		if err := limiter.admit([]string{projectID}, now); err != nil {
+			t.Fatalf("admit(%s) error = %v", projectID, err)
+		}
+	}
+	var limitErr *ProjectLimitError
+	if err := limiter.admit([]string{"c"}, now); !errors.As(err, &limitErr) || limitErr.MaxProjects != 2 {
+		t.Fatalf("admit(c) at the limit error = %v, want ProjectLimitError", err)
+	}
+
+	// b 持续上报，a 超过窗口后过期，释放名额
+	if err := limiter.admit([]string{"b"}, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("admit(b) error = %v", err)
+	}
+	if err := limiter.admit([]string{"c"}, now.Add(61*time.Second)); err != nil {
+		t.Errorf("admit(c) after a expired error = %v", err)
+	}
+}
+
+func TestProjectLimiterAdmitBatch(t *testing.T) {
+	limiter := newProjectLimiter(config.ProjectLimitConfig{MaxProjects: 3, Window: 60})
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := limiter.admit([]string{"a", "a", "b"}, now); err != nil {
+		t.Fatalf("admit(a, a, b) error = %v", err)
+	}
+
+	// 只剩一个名额时包含两个新项目的批次整体拒绝，其中的项目都不计入
+	if err := limiter.admit([]string{"a", "c", "d"}, now); err == nil {
+		t.Fatal("admit(a, c, d) succeeded with one slot left")
+	}
+	if err := limiter.admit([]string{"c", "c"}, now); err != nil {
+		t.Errorf("admit(c) after rejected batch error = %v, want the slot to be free", err)
+	}
+}
+
+func TestProjectLimiterDisabled(t *testing.T) {
+	if limiter := newProjectLimiter(config.ProjectLimitConfig{}); limiter != nil {
+		t.Fatalf("newProjectLimiter() with max_projects 0 = %+v, want nil", limiter)
+	}
+	var limiter *projectLimiter
+	if err := limiter.admit([]string{"a"}, time.Now()); err != nil {
+		t.Errorf("nil limiter admit() error = %v", err)
+	}
+}
+
+func TestRejectedEventsDoNotConsumeProjectSlots(t *testing.T) {
+	ingest := config.IngestConfig{
+		ProjectLimit: config.ProjectLimitConfig{MaxProjects: 1, Window: 3600},
+		EventAge:     config.EventAgeConfig{MaxAge: 3600},
+	}
+	rejected := []struct {
+		name  string
+		event *models.CustomEvent
+	}{
+		{"sdk field too long", &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "long-sdk", SDKVersion: strings.Repeat("1", maxSDKFieldLength+1)}}},
+		{"invalid extra", &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "bad-extra", Extra: []byte(`{"a":`)}}},
+		{"too old", &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "stale", Timestamp: time.Now().Add(-2 * time.Hour)}}},
+	}
+
+	repo := &fakeRepository{}
+	service := newTestLogService(t, repo, ingest)
+	for _, tt := range rejected {
+		var validationErr *ValidationError
+		if err := service.RecordCustomEvent(context.Background(), tt.event); !errors.As(err, &validationErr) {
+			t.Fatalf("%s: RecordCustomEvent() error = %v, want ValidationError", tt.name, err)
+		}
+	}
+
+	// 被拒绝的批次同样不占用名额
+	batch := &models.EventBatch{CustomEvents: []*models.CustomEvent{
+		{BaseLog: models.BaseLog{ProjectID: "batch", Name: "ok"}},
+		{BaseLog: models.BaseLog{ProjectID: "batch", Extra: []byte(`not json`)}},
+	}}
+	if err := service.RecordBatch(context.Background(), batch); err == nil {
+		t.Fatal("RecordBatch() with an invalid event succeeded")
+	}
+
+	if err := service.RecordCustomEvent(context.Background(), &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "web"}}); err != nil {
+		t.Fatalf("RecordCustomEvent() for the first valid project error = %v", err)
+	}
+	var limitErr *ProjectLimitError
+	if err := service.RecordCustomEvent(context.Background(), &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "ios"}}); !errors.As(err, &limitErr) {
+		t.Errorf("RecordCustomEvent() for a second project error = %v, want ProjectLimitError", err)
+	}
+}
+
+func TestStreamLineDisallowedProjectDoesNotConsumeSlot(t *testing.T) {
+	service := newTestLogService(t, &fakeRepository{}, config.IngestConfig{
+		ProjectLimit: config.ProjectLimitConfig{MaxProjects: 1, Window: 3600},
+	}).(*logService)
+	allowed := func(projectID string) bool { return projectID != "blocked" }
+	batch := &models.EventBatch{}
+
+	line := `{"type":"custom_event","data":{"project_id":"blocked","name":"signup"}}`
+	if _, err := service.decodeStreamLine([]byte(line), time.Now(), allowed, batch); !errors.Is(err, errProjectNotAllowed) {
+		t.Fatalf("decodeStreamLine(blocked) error = %v, want errProjectNotAllowed", err)
+	}
+	line = `{"type":"custom_event","data":{"project_id":"ios","name":"signup","platform":"` + strings.Repeat("x", maxSDKFieldLength+1) + `"}}`
+	if _, err := service.decodeStreamLine([]byte(line), time.Now(), allowed, batch); err == nil {
+		t.Fatal("decodeStreamLine() with an over-long platform succeeded")
+	}
+
+	line = `{"type":"custom_event","data":{"project_id":"web","name":"signup"}}`
+	if _, err := service.decodeStreamLine([]byte(line), time.Now(), allowed, batch); err != nil {
+		t.Fatalf("decodeStreamLine(web) error = %v", err)
+	}
+	if batch.Len() != 1 {
+		t.Errorf("batch has %d events, want 1", batch.Len())
+	}
+}
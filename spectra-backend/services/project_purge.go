@@ -0,0 +1,19 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/models"
+)
+
+// ProjectPurgeRequest 删除整个项目的请求体，confirm 必须与要删除的项目ID一致，防止误删
+type ProjectPurgeRequest struct {
+	Confirm string `json:"confirm" binding:"required"`
+}
+
+// DeleteProject 删除项目在所有事件表中的全部数据，confirm 与项目ID不一致时返回 ValidationError
+func (s *logService) DeleteProject(ctx context.Context, projectID string, confirm string) (*models.DeletionSummary, error) {
+	if confirm != projectID {
+		return nil, &ValidationError{Field: "confirm", Message: "must match the project ID"}
+	}
+	return s.repo.DeleteProject(ctx, projectID)
+}
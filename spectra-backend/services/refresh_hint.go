@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"spectra-backend/config"
+	"spectra-backend/repository"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// refreshHintWindow 计算写入速率时统计的最近时间范围
+	refreshHintWindow = 5 * time.Minute
+	// refreshHintTTL 同一组项目的刷新建议缓存时间，避免每次轮询都额外查询一次
+	refreshHintTTL = 30 * time.Second
+	// refreshHintTargetEvents 建议的刷新间隔使每次轮询平均能拿到的新事件数
+	refreshHintTargetEvents = 10
+	// maxRefreshHintEntries 缓存的项目组合数上限，超出时清理过期项
+	maxRefreshHintEntries = 1024
+)
+
+// RefreshHintService 根据最近的写入速率给出看板轮询间隔建议
+type RefreshHintService interface {
+	// Suggest 返回建议的刷新间隔，流量越大间隔越短，结果限制在配置的上下限之间
+	Suggest(ctx context.Context, projectIDs []string) (time.Duration, error)
+}
+
+// refreshHint 缓存的刷新建议
+type refreshHint struct {
+	interval time.Duration
+	expires  time.Time
+}
+
+// refreshHintService 刷新建议服务实现
+type refreshHintService struct {
+	repo     repository.LogRepository
+	min, max time.Duration
+
+	mu    sync.Mutex
+	cache map[string]refreshHint // 排序后以逗号连接的项目列表 -> 刷新建议
+}
+
+// NewRefreshHintService 创建刷新建议服务实例，未开启时返回 nil
+func NewRefreshHintService(repo repository.LogRepository, cfg config.RefreshHintConfig) RefreshHintService {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &refreshHintService{
+		repo:  repo,
+		min:   time.Duration(cfg.Min) * time.Second,
+		max:   time.Duration(max(cfg.Max, cfg.Min)) * time.Second,
+		cache: make(map[string]refreshHint),
+	}
+}
+
+func (s *refreshHintService) Suggest(ctx context.Context, projectIDs []string) (time.Duration, error) {
+	sorted := slices.Clone(projectIDs)
+	slices.Sort(sorted)
+	key := strings.Join(sorted, ",")
+
+	now := time.Now()
+	s.mu.Lock()
+	hint, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok && now.Before(hint.expires) {
+		return hint.interval, nil
+	}
+
+	count, err := s.repo.CountEvents(ctx, projectIDs, now.Add(-refreshHintWindow), now)
+	if err != nil {
+		return 0, err
+	}
+	interval := s.intervalFor(count)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= maxRefreshHintEntries {
+		for k, v := range s.cache {
+			if !now.Before(v.expires) {
+				delete(s.cache, k)
+			}
+		}
+		if len(s.cache) >= maxRefreshHintEntries {
+			clear(s.cache)
+		}
+	}
+	s.cache[key] = refreshHint{interval: interval, expires: now.Add(refreshHintTTL)}
+	return interval, nil
+}
+
+// intervalFor 按最近窗口内的事件数计算刷新间隔：平均每次轮询能拿到 refreshHintTargetEvents 条新事件
+// 没有写入时返回上限
+func (s *refreshHintService) intervalFor(count uint64) time.Duration {
+	if count == 0 {
+		return s.max
+	}
+	interval := refreshHintWindow * refreshHintTargetEvents / time.Duration(count)
+	return min(max(interval.Round(time.Second), s.min), s.max)
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/models"
+	"time"
+)
+
+const (
+	// elevatedErrorRateFactor 版本错误率超过整体错误率的该倍数时标记为错误率偏高
+	elevatedErrorRateFactor = 2
+	// elevatedMinErrors 标记错误率偏高所需的最少错误数，避免样本很少的版本因个别错误被标记
+	elevatedMinErrors = 10
+)
+
+// GetErrorsByRelease 按版本（sdk_version）统计错误数和错误率，标记错误率明显高于整体的版本
+// 整体错误率按返回的版本合计计算
+func (s *logService) GetErrorsByRelease(ctx context.Context, projectIDs []string, startTime, endTime time.Time, limit int) (*models.ReleaseErrors, error) {
+	releases, err := s.repo.GetErrorsByRelease(ctx, projectIDs, startTime, endTime, limit)
+	if err != nil {
+		return nil, err
+	}
+	if releases == nil {
+		releases = []models.ReleaseErrorCount{}
+	}
+
+	var events, errors uint64
+	for i := range releases {
+		events += releases[i].Events
+		errors += releases[i].Errors
+		if releases[i].Events > 0 {
+			releases[i].ErrorRate = float64(releases[i].Errors) / float64(releases[i].Events)
+		}
+	}
+	var overall float64
+	if events > 0 {
+		overall = float64(errors) / float64(events)
+	}
+	for i := range releases {
+		releases[i].Elevated = overall > 0 &&
+			releases[i].Errors >= elevatedMinErrors &&
+			releases[i].ErrorRate > overall*elevatedErrorRateFactor
+	}
+
+	return &models.ReleaseErrors{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		ErrorRate:  overall,
+		Releases:   releases,
+	}, nil
+}
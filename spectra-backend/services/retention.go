@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"spectra-backend/models"
+)
+
+// maxRetentionDays 允许设置的最长保留天数
+const maxRetentionDays = 3650
+
+// RetentionRequest 修改数据保留策略的请求体，days 为 0 表示不自动删除
+type RetentionRequest struct {
+	Days *int `json:"days" binding:"required,min=0,max=3650"`
+}
+
+// SetRetention 修改所有事件表的保留天数
+func (s *logService) SetRetention(ctx context.Context, days int) (*models.RetentionPolicy, error) {
+	if days < 0 || days > maxRetentionDays {
+		return nil, &ValidationError{Field: "days", Message: fmt.Sprintf("must be between 0 and %d", maxRetentionDays)}
+	}
+	return s.repo.SetRetention(ctx, days)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// CurrentSchemaVersion 当前上报数据的结构版本
+// 未携带 schema_version 的请求按当前版本处理
+const CurrentSchemaVersion = 2
+
+// schemaUpgrades 旧版本到下一版本的转换函数，key 为转换前的版本
+var schemaUpgrades = map[int]func(payload map[string]any) error{
+	1: upgradeSchemaV1,
+}
+
+// schemaV1Renames v1 SDK 使用驼峰字段名，extra 字段名为 data
+var schemaV1Renames = map[string]string{
+	"projectId": "project_id",
+	"sessionId": "session_id",
+	"traceId":   "trace_id",
+	"userId":    "user_id",
+	"data":      "extra",
+}
+
+// UpgradeSchema 将旧版本 SDK 上报的数据转换为当前结构
+// 根据 schema_version 逐级升级，高于当前版本或非法的版本号返回 ValidationError
+func UpgradeSchema(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var payload map[string]any
+	if err := decoder.Decode(&payload); err != nil || payload == nil {
+		// 非对象请求体交给后续绑定返回错误
+		return body, nil
+	}
+
+	raw, ok := payload["schema_version"]
+	if !ok {
+		return body, nil
+	}
+	version, err := parseSchemaVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+	if version == CurrentSchemaVersion {
+		return body, nil
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		if err := schemaUpgrades[version](payload); err != nil {
+			return nil, err
+		}
+	}
+	delete(payload, "schema_version")
+
+	upgraded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode upgraded payload: %w", err)
+	}
+	return upgraded, nil
+}
+
+// parseSchemaVersion 解析 schema_version，兼容数字和字符串形式
+func parseSchemaVersion(raw any) (int, error) {
+	var text string
+	switch v := raw.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	}
+
+	version, err := strconv.Atoi(text)
+	if err != nil || version < 1 {
+		return 0, &ValidationError{Field: "schema_version", Message: "must be a positive integer"}
+	}
+	if version > CurrentSchemaVersion {
+		return 0, &ValidationError{
+			Field:   "schema_version",
+			Message: fmt.Sprintf("version %d is not supported, this server supports up to %d", version, CurrentSchemaVersion),
+		}
+	}
+	return version, nil
+}
+
+// upgradeSchemaV1 v1 -> v2：驼峰字段改为下划线，time（毫秒时间戳）改为 RFC3339 格式的 timestamp
+func upgradeSchemaV1(payload map[string]any) error {
+	for oldKey, newKey := range schemaV1Renames {
+		value, ok := payload[oldKey]
+		if !ok {
+			continue
+		}
+		delete(payload, oldKey)
+		if _, exists := payload[newKey]; !exists {
+			payload[newKey] = value
+		}
+	}
+
+	raw, ok := payload["time"]
+	if !ok {
+		return nil
+	}
+	delete(payload, "time")
+	number, ok := raw.(json.Number)
+	if !ok {
+		return &ValidationError{Field: "time", Message: "must be a unix timestamp in milliseconds"}
+	}
+	millis, err := number.Int64()
+	if err != nil {
+		return &ValidationError{Field: "time", Message: "must be a unix timestamp in milliseconds"}
+	}
+	if _, exists := payload["timestamp"]; !exists {
+		payload["timestamp"] = time.UnixMilli(millis).UTC().Format(time.RFC3339Nano)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestUpgradeSchemaV1(t *testing.T) {
+	body := []byte(`{"schema_version":1,"projectId":"web","sessionId":"s1","traceId":"t1","userId":"u1",` +
+		`"data":{"plan":"pro"},"time":1700000000123,"message":"boom"}`)
+
+	upgraded, err := UpgradeSchema(body)
+	if err != nil {
+		t.Fatalf("UpgradeSchema() error = %v", err)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(upgraded, &payload); err != nil {
+		t.Fatalf("upgraded payload is not JSON: %v", err)
+	}
+	want := map[string]any{
+		"project_id": "web",
+		"session_id": "s1",
+		"trace_id":   "t1",
+		"user_id":    "u1",
+		"extra":      map[string]any{"plan": "pro"},
+		"timestamp":  "2023-11-14T22:13:20.123Z",
+		"message":    "boom",
+	}
+	if len(payload) != len(want) {
+		t.Errorf("upgraded payload = %v, want keys %v", payload, want)
+	}
+	for key, value := range want {
+		got, _ := json.Marshal(payload[key])
+		expected, _ := json.Marshal(value)
+		if string(got) != string(expected) {
+			t.Errorf("%s = %s, want %s", key, got, expected)
+		}
+	}
+}
+
+func TestUpgradeSchemaKeepsCurrentFields(t *testing.T) {
+	// 同时带有旧字段和新字段时以新字段为准
+	body := []byte(`{"schema_version":"1","projectId":"old","project_id":"new","time":1,"timestamp":"2024-01-01T00:00:00Z"}`)
+
+	upgraded, err := UpgradeSchema(body)
+	if err != nil {
+		t.Fatalf("UpgradeSchema() error = %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(upgraded, &payload); err != nil {
+		t.Fatalf("upgraded payload is not JSON: %v", err)
+	}
+	if payload["project_id"] != "new" || payload["timestamp"] != "2024-01-01T00:00:00Z" {
+		t.Errorf("upgraded payload = %v, want current fields kept", payload)
+	}
+	if _, ok := payload["projectId"]; ok {
+		t.Errorf("legacy key projectId should be removed, got %v", payload)
+	}
+}
+
+func TestUpgradeSchemaPassThrough(t *testing.T) {
+	for _, body := range []string{
+		`{"project_id":"web"}`,
+		`{"schema_version":2,"project_id":"web"}`,
+		`[1,2]`,
+		`{`,
+	} {
+		upgraded, err := UpgradeSchema([]byte(body))
+		if err != nil {
+			t.Errorf("UpgradeSchema(%s) error = %v", body, err)
+			continue
+		}
+		if string(upgraded) != body {
+			t.Errorf("UpgradeSchema(%s) = %s, want body unchanged", body, upgraded)
+		}
+	}
+}
+
+func TestUpgradeSchemaRejectsVersion(t *testing.T) {
+	for _, body := range []string{
+		`{"schema_version":3}`,
+		`{"schema_version":0}`,
+		`{"schema_version":"v1"}`,
+		`{"schema_version":1.5}`,
+		`{"schema_version":1,"time":"yesterday"}`,
+	} {
+		_, err := UpgradeSchema([]byte(body))
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("UpgradeSchema(%s) error = %v, want ValidationError", body, err)
+		}
+	}
+}
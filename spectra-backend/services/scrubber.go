@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"spectra-backend/config"
+	"spectra-backend/models"
+)
+
+// scrubMask 敏感信息被替换后的占位文本
+const scrubMask = "[REDACTED]"
+
+// scrubRule 单条脱敏规则
+type scrubRule struct {
+	pattern *regexp.Regexp
+	// replacement 替换模板，支持 $1 等分组引用
+	replacement string
+	// validate 非空时只替换校验通过的匹配，用于降低误判
+	validate func(match string) bool
+}
+
+// defaultScrubRules 内置脱敏规则：邮箱、Bearer 令牌、URL 中的令牌参数、银行卡号
+var defaultScrubRules = []scrubRule{
+	{
+		pattern:     regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+		replacement: scrubMask,
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		replacement: "${1}" + scrubMask,
+	},
+	{
+		pattern:     regexp.MustCompile(`(?i)([?&](?:access_token|id_token|token|api_key|apikey|password|secret)=)[^&#\s]+`),
+		replacement: "${1}" + scrubMask,
+	},
+	{
+		// 13~19 位数字，允许空格或连字符分隔，并通过 Luhn 校验
+		pattern:     regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		replacement: scrubMask,
+		validate:    luhnValid,
+	},
+}
+
+// scrubber 基于正则的敏感信息脱敏器
+type scrubber struct {
+	rules []scrubRule
+}
+
+// newScrubber 根据配置创建脱敏器，未启用时返回 nil
+func newScrubber(cfg config.ScrubConfig) (*scrubber, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	rules := append([]scrubRule{}, defaultScrubRules...)
+	for _, expr := range cfg.Patterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub pattern %q: %w", expr, err)
+		}
+		rules = append(rules, scrubRule{pattern: pattern, replacement: scrubMask})
+	}
+	return &scrubber{rules: rules}, nil
+}
+
+// scrubString 依次应用所有规则
+func (s *scrubber) scrubString(value string) string {
+	if s == nil || value == "" {
+		return value
+	}
+	for _, rule := range s.rules {
+		if rule.validate == nil {
+			value = rule.pattern.ReplaceAllString(value, rule.replacement)
+			continue
+		}
+		value = rule.pattern.ReplaceAllStringFunc(value, func(match string) string {
+			if rule.validate(match) {
+				return rule.replacement
+			}
+			return match
+		})
+	}
+	return value
+}
+
+// scrubJSON 脱敏 JSON 中所有字符串值，键名保持不变
+// 无法解析的内容原样返回，由校验逻辑处理
+func (s *scrubber) scrubJSON(raw json.RawMessage) json.RawMessage {
+	if s == nil || len(bytes.TrimSpace(raw)) == 0 {
+		return raw
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return raw
+	}
+
+	scrubbed, err := json.Marshal(s.scrubValue(value))
+	if err != nil {
+		return raw
+	}
+	return scrubbed
+}
+
+// scrubValue 递归脱敏对象和数组中的字符串
+func (s *scrubber) scrubValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return s.scrubString(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = s.scrubValue(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = s.scrubValue(item)
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+// scrubBase 脱敏所有事件共有的 URL、Referrer 和 Extra 字段
+func (s *logService) scrubBase(base *models.BaseLog) {
+	base.URL = s.scrubber.scrubString(base.URL)
+	base.Referrer = s.scrubber.scrubString(base.Referrer)
+	base.Extra = s.scrubber.scrubJSON(base.Extra)
+}
+
+// luhnValid Luhn 校验，忽略空格和连字符
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/models"
+	"strings"
+	"time"
+)
+
+// normalizeSeverity 将严重程度统一为小写，未上报时默认为 error，不在枚举中时返回校验错误
+func normalizeSeverity(log *models.ErrorLog) error {
+	severity := strings.ToLower(strings.TrimSpace(log.Severity))
+	if severity == "" {
+		severity = models.SeverityError
+	}
+	if !models.IsErrorSeverity(severity) {
+		return &ValidationError{Field: "severity", Message: "must be one of " + strings.Join(models.ErrorSeverities, ", ")}
+	}
+	log.Severity = severity
+	return nil
+}
+
+// GetSeverityBreakdown 按严重程度统计错误数，结果按严重程度从高到低排列，没有数据的计数为 0
+func (s *logService) GetSeverityBreakdown(ctx context.Context, projectIDs []string, startTime, endTime time.Time) (*models.SeverityBreakdown, error) {
+	counts, err := s.repo.GetSeverityCounts(ctx, projectIDs, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	bySeverity := make(map[string]uint64, len(counts))
+	for _, count := range counts {
+		bySeverity[count.Severity] += count.Count
+	}
+
+	breakdown := &models.SeverityBreakdown{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Severities: make([]models.SeverityCount, 0, len(models.ErrorSeverities)),
+	}
+	for _, severity := range models.ErrorSeverities {
+		count := bySeverity[severity]
+		breakdown.Severities = append(breakdown.Severities, models.SeverityCount{Severity: severity, Count: count})
+		breakdown.Total += count
+	}
+	return breakdown, nil
+}
@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+	"spectra-backend/repository"
+)
+
+// WithSkippedRows 返回携带跳过行计数器的上下文，列表查询跳过无法解析的行时累加计数
+func WithSkippedRows(ctx context.Context) context.Context {
+	return repository.WithSkippedRows(ctx)
+}
+
+// SkippedRows 返回上下文中累计跳过的行数，上下文未携带计数器时返回 0
+func SkippedRows(ctx context.Context) int64 {
+	return repository.SkippedRows(ctx)
+}
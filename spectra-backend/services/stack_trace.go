@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"spectra-backend/models"
+)
+
+// stackExtraKey SDK 在 extra 中上报堆栈使用的键
+const stackExtraKey = "stack"
+
+// stackHashPattern 堆栈哈希格式，64 位小写十六进制
+var stackHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// ErrInvalidStackHash 堆栈哈希格式不正确
+var ErrInvalidStackHash = errors.New("stack hash must be 64 lowercase hex characters")
+
+// extractStack 将 extra.stack 移出 extra，记录其 SHA-256 哈希，完整堆栈由仓库层按哈希去重保存
+// extra 不是 JSON 对象或 stack 不是非空字符串时保持不变
+func extractStack(log *models.ErrorLog) {
+	if len(log.Extra) == 0 {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(log.Extra, &fields); err != nil || fields == nil {
+		return
+	}
+	var stack string
+	if err := json.Unmarshal(fields[stackExtraKey], &stack); err != nil || stack == "" {
+		return
+	}
+
+	delete(fields, stackExtraKey)
+	extra, err := json.Marshal(fields)
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(stack))
+	log.Stack = stack
+	log.StackHash = hex.EncodeToString(sum[:])
+	log.Extra = extra
+}
+
+// GetStackTrace 根据哈希获取完整堆栈，不存在时返回 nil, nil
+func (s *logService) GetStackTrace(ctx context.Context, hash string) (*models.StackTrace, error) {
+	if !stackHashPattern.MatchString(hash) {
+		return nil, ErrInvalidStackHash
+	}
+	return s.repo.GetStackTrace(ctx, hash)
+}
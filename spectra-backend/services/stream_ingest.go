@@ -0,0 +1,117 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"spectra-backend/models"
+	"time"
+)
+
+// streamFlushInterval 流式上报中距上次提交超过该时间后，收到下一行时提交当前批次，避免低速流长时间不落库
+const streamFlushInterval = time.Second
+
+// errProjectNotAllowed 流式上报中事件的项目不在白名单中
+var errProjectNotAllowed = errors.New("project_id is not allowed")
+
+// IngestStream 流式读取混合类型的 NDJSON 上报数据，按 type 分组后分批写入，不将整个请求体读入内存
+// 每行是一个 models.EventEnvelope；无法解析、校验失败或项目不在白名单中的行计入对应类型的错误数并跳过
+// 每攒够 ingest.max_batch_items 条，或距上次提交超过 streamFlushInterval 时提交一批；
+// 写库失败时立即返回，此前已提交的批次不会回滚。allowed 为 nil 时不限制项目
+func (s *logService) IngestStream(ctx context.Context, reader io.Reader, allowed func(projectID string) bool) (*models.StreamIngestResult, error) {
+	result := &models.StreamIngestResult{
+		Counts:      make(map[string]int),
+		ErrorCounts: make(map[string]int),
+		Errors:      []models.ImportLineError{},
+	}
+	batchSize := s.ingest.MaxBatchItems
+	if batchSize <= 0 {
+		batchSize = importBatchSize
+	}
+
+	userAgent := s.userAgentFields(ctx)
+	batch := &models.EventBatch{}
+	lastFlush := time.Now()
+	flush := func() error {
+		lastFlush = time.Now()
+		if batch.Len() == 0 {
+			return nil
+		}
+		fillBatchUserAgent(batch, userAgent)
+		if err := s.saveBatch(ctx, batch); err != nil {
+			return fmt.Errorf("failed to ingest batch ending at line %d: %w", result.Lines, err)
+		}
+		for eventType, count := range batch.Counts() {
+			result.Counts[eventType] += count
+		}
+		result.Ingested += batch.Len()
+		batch = &models.EventBatch{}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLineSize)
+	for scanner.Scan() {
+		result.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		if eventType, err := s.decodeStreamLine(line, time.Now(), allowed, batch); err != nil {
+			result.Failed++
+			result.ErrorCounts[eventType]++
+			if len(result.Errors) < importMaxErrors {
+				result.Errors = append(result.Errors, models.ImportLineError{Line: result.Lines, Error: err.Error()})
+			}
+		}
+
+		if batch.Len() >= batchSize || time.Since(lastFlush) >= streamFlushInterval {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read ingest stream at line %d: %w", result.Lines+1, err)
+	}
+
+	if err := flush(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// decodeStreamLine 解析一行事件包装，预处理、校验项目和事件时间，计入项目名额并检测时钟偏差后加入批次
+// 返回该行的事件类型，类型无法确定时为 models.StreamUnknownType
+func (s *logService) decodeStreamLine(line []byte, received time.Time, allowed func(projectID string) bool, batch *models.EventBatch) (string, error) {
+	var envelope models.EventEnvelope
+	if err := json.Unmarshal(line, &envelope); err != nil {
+		return models.StreamUnknownType, err
+	}
+	eventType := envelope.Type
+	if !models.IsEventType(eventType) {
+		eventType = models.StreamUnknownType
+	}
+
+	event, err := s.decodeEvent(envelope)
+	if err != nil {
+		return eventType, err
+	}
+	base := eventBase(event)
+	if allowed != nil && !allowed(base.ProjectID) {
+		return eventType, errProjectNotAllowed
+	}
+	if err := s.checkEventAge(base, eventType, received); err != nil {
+		return eventType, err
+	}
+	if err := s.admitProjects(base.ProjectID); err != nil {
+		return eventType, err
+	}
+	s.checkClockSkew(base, eventType, received)
+	return eventType, batch.Add(event)
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"spectra-backend/models"
+	"time"
+)
+
+const (
+	// minTrendInterval 趋势时间桶的最小粒度
+	minTrendInterval = time.Minute
+	// maxTrendBuckets 单次趋势查询最多返回的时间桶数
+	maxTrendBuckets = 1000
+)
+
+// autoTrendIntervals 未指定时间桶大小时按从小到大选择，取第一个不超过 maxTrendBuckets 的值
+var autoTrendIntervals = []time.Duration{
+	time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour,
+}
+
+// ErrInvalidInterval 趋势时间桶大小不合法
+var ErrInvalidInterval = fmt.Errorf("interval must be a whole number of seconds, at least %s and at most %d buckets", minTrendInterval, maxTrendBuckets)
+
+// resolveTrendInterval 校验时间桶大小，为 0 时自动选择
+func resolveTrendInterval(startTime, endTime time.Time, interval time.Duration, loc *time.Location) (time.Duration, error) {
+	span := endTime.Sub(startTime)
+	if interval == 0 {
+		for _, candidate := range autoTrendIntervals {
+			if span/candidate < maxTrendBuckets {
+				return candidate, nil
+			}
+		}
+		return 0, ErrInvalidInterval
+	}
+
+	if interval < minTrendInterval || interval%time.Second != 0 || span/interval >= maxTrendBuckets {
+		return 0, ErrInvalidInterval
+	}
+	return interval, nil
+}
+
+// fillTrendBuckets 生成 [start, end] 内的全部时间桶，与仓储层的对齐方式一致
+// 时间桶按 loc 时区的本地时钟读数以 Unix 纪元对齐，天级时间桶从当地零点开始
+func fillTrendBuckets(buckets []models.TrendBucket, startTime, endTime time.Time, interval time.Duration, loc *time.Location) []models.TrendBucket {
+	counts := make(map[int64]uint64, len(buckets))
+	for _, bucket := range buckets {
+		counts[wallClock(bucket.Time, loc)] = bucket.Count
+	}
+
+	step := int64(interval / time.Second)
+	start, end := wallClock(startTime, loc), wallClock(endTime, loc)
+	first := start - start%step
+	filled := make([]models.TrendBucket, 0, (end-first)/step+1)
+	for t := first; t <= end; t += step {
+		wall := time.Unix(t, 0).UTC()
+		filled = append(filled, models.TrendBucket{
+			Time:  time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, loc),
+			Count: counts[t],
+		})
+	}
+	return filled
+}
+
+// wallClock 返回 t 在 loc 时区的本地时钟读数，按 UTC 解释后的 Unix 秒数
+func wallClock(t time.Time, loc *time.Location) int64 {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), 0, time.UTC).Unix()
+}
+
+// fingerprintPattern 错误分组指纹格式，32 位小写十六进制
+var fingerprintPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ErrInvalidFingerprint 错误分组指纹格式不正确
+var ErrInvalidFingerprint = errors.New("fingerprint must be 32 lowercase hex characters")
+
+// GetErrorGroupTrend 获取错误分组的出现趋势，没有数据的时间桶补 0
+// interval 为 0 时自动选择；时间范围内没有该分组时返回 nil, nil
+func (s *logService) GetErrorGroupTrend(ctx context.Context, projectIDs []string, fingerprint string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.ErrorGroupTrend, error) {
+	if !fingerprintPattern.MatchString(fingerprint) {
+		return nil, ErrInvalidFingerprint
+	}
+	interval, err := resolveTrendInterval(startTime, endTime, interval, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	trend, err := s.repo.GetErrorGroupTrend(ctx, projectIDs, fingerprint, startTime, endTime, interval, loc)
+	if err != nil || trend == nil {
+		return trend, err
+	}
+
+	trend.StartTime = startTime
+	trend.EndTime = endTime
+	trend.Interval = interval.String()
+	trend.Timezone = loc.String()
+	trend.Buckets = fillTrendBuckets(trend.Buckets, startTime, endTime, interval, loc)
+	return trend, nil
+}
+
+// GetIngestionRate 获取所有事件表合计的写入量趋势，没有数据的时间桶补 0
+// interval 为 0 时自动选择
+func (s *logService) GetIngestionRate(ctx context.Context, projectIDs []string, startTime, endTime time.Time, interval time.Duration, loc *time.Location) (*models.IngestionRate, error) {
+	interval, err := resolveTrendInterval(startTime, endTime, interval, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets, err := s.repo.GetIngestionRate(ctx, projectIDs, startTime, endTime, interval, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	rate := &models.IngestionRate{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Interval:   interval.String(),
+		Timezone:   loc.String(),
+		Buckets:    fillTrendBuckets(buckets, startTime, endTime, interval, loc),
+	}
+	for _, bucket := range buckets {
+		rate.Total += bucket.Count
+	}
+	return rate, nil
+}
+
+// GetSessionCount 获取时间范围内的不同会话数
+// interval 为 0 时只返回总数，否则同时返回按时间桶统计的活跃会话数，没有数据的时间桶补 0
+func (s *logService) GetSessionCount(ctx context.Context, projectIDs []string, startTime, endTime time.Time, filters []models.ExtraFilter, interval time.Duration, loc *time.Location) (*models.SessionCount, error) {
+	if interval != 0 {
+		var err error
+		if interval, err = resolveTrendInterval(startTime, endTime, interval, loc); err != nil {
+			return nil, err
+		}
+	}
+
+	total, err := s.repo.GetSessionCount(ctx, projectIDs, startTime, endTime, filters)
+	if err != nil {
+		return nil, err
+	}
+	count := &models.SessionCount{
+		ProjectIDs: projectIDs,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		Total:      total,
+	}
+	if interval == 0 {
+		return count, nil
+	}
+
+	buckets, err := s.repo.GetSessionCountBuckets(ctx, projectIDs, startTime, endTime, interval, loc, filters)
+	if err != nil {
+		return nil, err
+	}
+	count.Interval = interval.String()
+	count.Timezone = loc.String()
+	count.Buckets = fillTrendBuckets(buckets, startTime, endTime, interval, loc)
+	return count, nil
+}
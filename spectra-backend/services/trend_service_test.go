@@ -0,0 +1,104 @@
+package services
+
+import (
+	"spectra-backend/models"
+	"testing"
+	"time"
+)
+
+// mustLoadLocation 加载 IANA 时区，失败时终止测试
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load time zone %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestFillTrendBucketsDailyInTimeZone(t *testing.T) {
+	// 2024-01-01T17:00Z 在上海是 1 月 2 日凌晨 1 点，属于当地 1 月 2 日的桶；在纽约是 1 月 1 日中午，属于当地 1 月 1 日的桶
+	for zone, eventDay := range map[string]int{"Asia/Shanghai": 2, "America/New_York": 1} {
+		t.Run(zone, func(t *testing.T) {
+			loc := mustLoadLocation(t, zone)
+			start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			end := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+			event := time.Date(2024, 1, 1, 17, 0, 0, 0, time.UTC).In(loc)
+			eventBucket := time.Date(2024, 1, eventDay, 0, 0, 0, 0, loc)
+			if event.Day() != eventDay {
+				t.Fatalf("event falls on local day %d, want %d", event.Day(), eventDay)
+			}
+
+			filled := fillTrendBuckets([]models.TrendBucket{{Time: eventBucket, Count: 3}}, start, end, 24*time.Hour, loc)
+
+			localStart := start.In(loc)
+			var want []time.Time
+			for day := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc); !day.After(end); day = day.AddDate(0, 0, 1) {
+				want = append(want, day)
+			}
+			if len(filled) != len(want) {
+				t.Fatalf("got %d buckets %v, want %d", len(filled), filled, len(want))
+			}
+			for i, bucket := range filled {
+				if !bucket.Time.Equal(want[i]) {
+					t.Errorf("bucket %d starts at %s, want local midnight %s", i, bucket.Time, want[i])
+				}
+				if h, m, _ := bucket.Time.In(loc).Clock(); h != 0 || m != 0 {
+					t.Errorf("bucket %d starts at %s, not at local midnight", i, bucket.Time.In(loc))
+				}
+				wantCount := uint64(0)
+				if bucket.Time.Equal(eventBucket) {
+					wantCount = 3
+				}
+				if bucket.Count != wantCount {
+					t.Errorf("bucket %s count = %d, want %d", bucket.Time, bucket.Count, wantCount)
+				}
+			}
+		})
+	}
+}
+
+func TestFillTrendBucketsDayBoundaryDiffersFromUTC(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+
+	// 同一段 UTC 时间在 UTC 下只有一天，在上海跨越当地零点，分为两天
+	if got := fillTrendBuckets(nil, start, end, 24*time.Hour, time.UTC); len(got) != 1 {
+		t.Errorf("UTC buckets = %v, want 1", got)
+	}
+	got := fillTrendBuckets(nil, start, end, 24*time.Hour, shanghai)
+	if len(got) != 2 {
+		t.Fatalf("Asia/Shanghai buckets = %v, want 2", got)
+	}
+	if want := time.Date(2024, 1, 2, 0, 0, 0, 0, shanghai); !got[1].Time.Equal(want) {
+		t.Errorf("second bucket = %s, want %s", got[1].Time, want)
+	}
+}
+
+func TestResolveTrendInterval(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		span     time.Duration
+		interval time.Duration
+		want     time.Duration
+		wantErr  bool
+	}{
+		{"auto picks minutes for an hour", time.Hour, 0, time.Minute, false},
+		{"auto picks hours for a month", 30 * 24 * time.Hour, 0, time.Hour, false},
+		{"explicit interval", 24 * time.Hour, 15 * time.Minute, 15 * time.Minute, false},
+		{"below minimum", time.Hour, 30 * time.Second, 0, true},
+		{"fractional seconds", time.Hour, 90*time.Second + time.Millisecond, 0, true},
+		{"too many buckets", 1000 * time.Minute, time.Minute, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTrendInterval(start, start.Add(tt.span), tt.interval, time.UTC)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("resolveTrendInterval() = %s, %v, want %s (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+	"strings"
+	"unicode/utf8"
+)
+
+// truncatedKey 记录被截断字段原始长度的 extra 键，如 {"url": 5123}
+const truncatedKey = "_truncated"
+
+// truncateURLs 将超过 ingest.max_url_length 的 URL 和 Referrer 截断，并在 extra 中记录原始长度
+func (s *logService) truncateURLs(base *models.BaseLog, eventType string) {
+	limit := s.ingest.MaxURLLength
+	if limit <= 0 {
+		return
+	}
+
+	truncated := map[string]int{}
+	if len(base.URL) > limit {
+		truncated["url"] = len(base.URL)
+		base.URL = truncateURL(base.URL, limit)
+		metrics.TruncatedURLs.WithLabelValues(eventType, "url").Inc()
+	}
+	if len(base.Referrer) > limit {
+		truncated["referrer"] = len(base.Referrer)
+		base.Referrer = truncateURL(base.Referrer, limit)
+		metrics.TruncatedURLs.WithLabelValues(eventType, "referrer").Inc()
+	}
+	if len(truncated) > 0 {
+		base.Extra = mergeExtra(base.Extra, map[string]any{truncatedKey: truncated})
+	}
+}
+
+// truncateURL 将 URL 缩短到不超过 limit 字节
+// 先去掉查询参数和片段，保留 scheme、host 和 path，使同一页面的 URL 在统计时仍能归为一组；仍然超长时按字节截断
+func truncateURL(raw string, limit int) string {
+	if len(raw) <= limit {
+		return raw
+	}
+	// 按原始字符串处理，不重新编码 URL
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		raw = raw[:i]
+	}
+	if len(raw) <= limit {
+		return raw
+	}
+
+	// 不在多字节字符或 %XX 转义序列中间截断
+	end := limit
+	for end > 0 && !utf8.RuneStart(raw[end]) {
+		end--
+	}
+	if i := strings.LastIndexByte(raw[:end], '%'); i >= 0 && end-i < 3 {
+		end = i
+	}
+	return raw[:end]
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"spectra-backend/models"
+	"strings"
+)
+
+// extra 中记录 User-Agent 解析结果的字段
+const (
+	userAgentBrowserKey    = "browser"
+	userAgentOSKey         = "os"
+	userAgentDeviceTypeKey = models.ExtraDeviceTypeKey
+)
+
+// User-Agent 无法识别或来自爬虫、脚本时使用的取值
+const (
+	userAgentUnknown = "unknown"
+	userAgentBot     = models.DeviceTypeBot
+)
+
+// 设备类型
+const (
+	deviceTypeDesktop = "desktop"
+	deviceTypeMobile  = "mobile"
+	deviceTypeTablet  = "tablet"
+)
+
+// userAgentKey 上下文中保存请求 User-Agent 的键
+type userAgentKey struct{}
+
+// WithUserAgent 返回携带请求 User-Agent 的上下文，开启 ingest.user_agent 时上报的事件据此补充浏览器、系统和设备类型
+// 上下文未携带 User-Agent 时（如服务内部写入）不做补充；携带空值时各字段记为 unknown
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey{}, userAgent)
+}
+
+// userAgentInfo User-Agent 解析结果
+type userAgentInfo struct {
+	Browser    string
+	OS         string
+	DeviceType string
+}
+
+// userAgentBrowsers 浏览器识别规则，按顺序匹配第一个出现的片段
+// 基于 Chromium 的浏览器同时带有 Chrome/ 和 Safari/，Chrome 又带有 Safari/，因此派生浏览器须排在前面
+var userAgentBrowsers = []struct {
+	marker string
+	name   string
+}{
+	{"edg/", "Edge"},
+	{"edga/", "Edge"},
+	{"edgios/", "Edge"},
+	{"edge/", "Edge"},
+	{"opr/", "Opera"},
+	{"opera", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"ucbrowser/", "UC Browser"},
+	{"micromessenger/", "WeChat"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"chromium/", "Chrome"},
+	{"msie ", "Internet Explorer"},
+	{"trident/", "Internet Explorer"},
+	{"safari/", "Safari"},
+}
+
+// userAgentSystems 操作系统识别规则，按顺序匹配第一个出现的片段
+// iOS 和 Android 的 User-Agent 中分别带有 Mac OS X 和 Linux，因此须排在前面
+var userAgentSystems = []struct {
+	marker string
+	name   string
+}{
+	{"windows phone", "Windows Phone"},
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iOS"},
+	{"ipod", "iOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// parseUserAgent 从 User-Agent 中识别浏览器、操作系统和设备类型
+// 包含 botPatterns（小写）中任一片段的视为爬虫或脚本，三个字段均记为 bot；无法识别的字段记为 unknown
+func parseUserAgent(userAgent string, botPatterns []string) userAgentInfo {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return userAgentInfo{Browser: userAgentUnknown, OS: userAgentUnknown, DeviceType: userAgentUnknown}
+	}
+	for _, pattern := range botPatterns {
+		if strings.Contains(ua, pattern) {
+			return userAgentInfo{Browser: userAgentBot, OS: userAgentBot, DeviceType: userAgentBot}
+		}
+	}
+
+	info := userAgentInfo{Browser: userAgentUnknown, OS: userAgentUnknown, DeviceType: userAgentUnknown}
+	for _, browser := range userAgentBrowsers {
+		if strings.Contains(ua, browser.marker) {
+			info.Browser = browser.name
+			break
+		}
+	}
+	for _, system := range userAgentSystems {
+		if strings.Contains(ua, system.marker) {
+			info.OS = system.name
+			break
+		}
+	}
+	info.DeviceType = deviceType(ua, info.OS)
+	return info
+}
+
+// deviceType 根据 User-Agent（小写）和识别出的操作系统判断设备类型
+// Android 平板的 User-Agent 不带 Mobile，以此与手机区分
+func deviceType(ua, os string) string {
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return deviceTypeTablet
+	case os == "Android" && !strings.Contains(ua, "mobile"):
+		return deviceTypeTablet
+	case strings.Contains(ua, "mobi") || os == "iOS" || os == "Android" || os == "Windows Phone":
+		return deviceTypeMobile
+	case os == userAgentUnknown:
+		return userAgentUnknown
+	default:
+		return deviceTypeDesktop
+	}
+}
+
+// newBotPatterns 将配置的爬虫特征片段转为小写并去掉空值
+func newBotPatterns(patterns []string) []string {
+	result := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+// userAgentFields 解析上下文中的 User-Agent，返回需写入 extra 的字段
+// 未开启 ingest.user_agent 或上下文未携带 User-Agent 时返回 nil；同一请求的事件共用解析结果
+func (s *logService) userAgentFields(ctx context.Context) map[string]any {
+	if !s.ingest.UserAgent.Enabled {
+		return nil
+	}
+	userAgent, ok := ctx.Value(userAgentKey{}).(string)
+	if !ok {
+		return nil
+	}
+
+	info := parseUserAgent(userAgent, s.botPatterns)
+	return map[string]any{
+		userAgentBrowserKey:    info.Browser,
+		userAgentOSKey:         info.OS,
+		userAgentDeviceTypeKey: info.DeviceType,
+	}
+}
+
+// fillUserAgent 将 User-Agent 解析结果写入事件 extra，客户端已上报的同名字段优先，fields 为 nil 时不做处理
+func fillUserAgent(base *models.BaseLog, fields map[string]any) {
+	if fields == nil {
+		return
+	}
+
+	existing := map[string]json.RawMessage{}
+	if len(base.Extra) > 0 {
+		if err := json.Unmarshal(base.Extra, &existing); err != nil {
+			return
+		}
+	}
+	missing := make(map[string]any, len(fields))
+	for key, value := range fields {
+		if _, ok := existing[key]; !ok {
+			missing[key] = value
+		}
+	}
+	if len(missing) > 0 {
+		base.Extra = mergeExtra(base.Extra, missing)
+	}
+}
+
+// fillBatchUserAgent 将同一请求的 User-Agent 解析结果写入批次中的所有事件
+func fillBatchUserAgent(batch *models.EventBatch, fields map[string]any) {
+	if fields == nil {
+		return
+	}
+	for _, log := range batch.ErrorLogs {
+		fillUserAgent(&log.BaseLog, fields)
+	}
+	for _, metric := range batch.PerformanceMetrics {
+		fillUserAgent(&metric.BaseLog, fields)
+	}
+	for _, action := range batch.UserActions {
+		fillUserAgent(&action.BaseLog, fields)
+	}
+	for _, event := range batch.CustomEvents {
+		fillUserAgent(&event.BaseLog, fields)
+	}
+	for _, pageStay := range batch.PageStays {
+		fillUserAgent(&pageStay.BaseLog, fields)
+	}
+}
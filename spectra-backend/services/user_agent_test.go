@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"testing"
+)
+
+// testBotPatterns 与 ingest.user_agent.bot_patterns 的默认值相同
+var testBotPatterns = newBotPatterns([]string{
+	"bot", "crawler", "spider", "slurp", "headless", "lighthouse",
+	"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "postmanruntime",
+})
+
+func TestParseUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		ua   string
+		want userAgentInfo
+	}{
+		{"chrome on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			userAgentInfo{"Chrome", "Windows", deviceTypeDesktop}},
+		{"edge on windows",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.2210.91",
+			userAgentInfo{"Edge", "Windows", deviceTypeDesktop}},
+		{"safari on macos",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+			userAgentInfo{"Safari", "macOS", deviceTypeDesktop}},
+		{"firefox on linux",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			userAgentInfo{"Firefox", "Linux", deviceTypeDesktop}},
+		{"safari on iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			userAgentInfo{"Safari", "iOS", deviceTypeMobile}},
+		{"chrome on iphone",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			userAgentInfo{"Chrome", "iOS", deviceTypeMobile}},
+		{"safari on ipad",
+			"Mozilla/5.0 (iPad; CPU OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Mobile/15E148 Safari/604.1",
+			userAgentInfo{"Safari", "iOS", deviceTypeTablet}},
+		{"chrome on android phone",
+			"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Mobile Safari/537.36",
+			userAgentInfo{"Chrome", "Android", deviceTypeMobile}},
+		{"android tablet",
+			"Mozilla/5.0 (Linux; Android 13; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.144 Safari/537.36",
+			userAgentInfo{"Chrome", "Android", deviceTypeTablet}},
+		{"samsung internet",
+			"Mozilla/5.0 (Linux; Android 14; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			userAgentInfo{"Samsung Internet", "Android", deviceTypeMobile}},
+		{"wechat",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 MicroMessenger/8.0.44",
+			userAgentInfo{"WeChat", "iOS", deviceTypeMobile}},
+		{"internet explorer",
+			"Mozilla/5.0 (Windows NT 10.0; Trident/7.0; rv:11.0) like Gecko",
+			userAgentInfo{"Internet Explorer", "Windows", deviceTypeDesktop}},
+		{"googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			userAgentInfo{userAgentBot, userAgentBot, userAgentBot}},
+		{"headless chrome",
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/120.0.0.0 Safari/537.36",
+			userAgentInfo{userAgentBot, userAgentBot, userAgentBot}},
+		{"curl", "curl/8.4.0", userAgentInfo{userAgentBot, userAgentBot, userAgentBot}},
+		{"empty", "", userAgentInfo{userAgentUnknown, userAgentUnknown, userAgentUnknown}},
+		{"unrecognized", "MyApp/1.0", userAgentInfo{userAgentUnknown, userAgentUnknown, userAgentUnknown}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseUserAgent(tt.ua, testBotPatterns); got != tt.want {
+				t.Errorf("parseUserAgent(%q) = %+v, want %+v", tt.ua, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBotPatterns(t *testing.T) {
+	got := newBotPatterns([]string{" Bot ", "", "CURL/"})
+	if len(got) != 2 || got[0] != "bot" || got[1] != "curl/" {
+		t.Errorf("newBotPatterns() = %q, want [bot curl/]", got)
+	}
+	if info := parseUserAgent("curl/8.4.0", nil); info.Browser == userAgentBot {
+		t.Error("parseUserAgent() flagged a bot with no patterns configured")
+	}
+}
+
+func TestRecordFillsUserAgent(t *testing.T) {
+	const firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	tests := []struct {
+		name    string
+		enabled bool
+		ctx     context.Context
+		extra   string
+		want    map[string]string
+	}{
+		{"enabled", true, WithUserAgent(context.Background(), firefox), "",
+			map[string]string{"browser": "Firefox", "os": "Linux", "device_type": "desktop"}},
+		{"client value wins", true, WithUserAgent(context.Background(), firefox), `{"browser":"MyApp"}`,
+			map[string]string{"browser": "MyApp", "os": "Linux", "device_type": "desktop"}},
+		{"disabled", false, WithUserAgent(context.Background(), firefox), "", nil},
+		{"no user agent in context", true, context.Background(), "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeRepository{}
+			service := newTestLogService(t, repo, config.IngestConfig{UserAgent: config.UserAgentConfig{Enabled: tt.enabled}})
+			event := &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "web", Name: "signup"}}
+			if tt.extra != "" {
+				event.Extra = json.RawMessage(tt.extra)
+			}
+			if err := service.RecordCustomEvent(tt.ctx, event); err != nil {
+				t.Fatalf("RecordCustomEvent() error = %v", err)
+			}
+
+			extra := map[string]string{}
+			if len(event.Extra) > 0 {
+				if err := json.Unmarshal(event.Extra, &extra); err != nil {
+					t.Fatalf("extra %s is not a string object: %v", event.Extra, err)
+				}
+			}
+			for _, key := range []string{userAgentBrowserKey, userAgentOSKey, userAgentDeviceTypeKey} {
+				if extra[key] != tt.want[key] {
+					t.Errorf("extra[%s] = %q, want %q (extra %s)", key, extra[key], tt.want[key], event.Extra)
+				}
+			}
+		})
+	}
+}
+
+func TestRecordTagsKnownBot(t *testing.T) {
+	const googlebot = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	repo := &fakeRepository{}
+	service := newTestLogService(t, repo, config.IngestConfig{UserAgent: config.UserAgentConfig{Enabled: true, BotPatterns: []string{"Googlebot"}}})
+	event := &models.CustomEvent{BaseLog: models.BaseLog{ProjectID: "web", Name: "page_view"}}
+	if err := service.RecordCustomEvent(WithUserAgent(context.Background(), googlebot), event); err != nil {
+		t.Fatalf("RecordCustomEvent() error = %v", err)
+	}
+
+	// exclude_bots 依赖该字段过滤爬虫流量
+	var extra map[string]string
+	if err := json.Unmarshal(event.Extra, &extra); err != nil {
+		t.Fatalf("extra %s is not a string object: %v", event.Extra, err)
+	}
+	if extra[models.ExtraDeviceTypeKey] != models.DeviceTypeBot {
+		t.Errorf("extra[%s] = %q, want %q", models.ExtraDeviceTypeKey, extra[models.ExtraDeviceTypeKey], models.DeviceTypeBot)
+	}
+}
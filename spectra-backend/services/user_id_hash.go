@@ -0,0 +1,36 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"spectra-backend/config"
+	"spectra-backend/models"
+)
+
+// newUserIDHasher 根据配置创建 user_id 哈希函数，未启用时返回 nil；启用但未配置 salt 时返回错误
+// 使用以 salt 为密钥的 HMAC-SHA256，输出 64 位十六进制字符串；哈希是单向的，无法还原原始 user_id
+func newUserIDHasher(cfg config.UserIDHashConfig) (func(string) string, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Salt == "" {
+		return nil, errors.New("ingest.user_id_hash: salt is required when hashing is enabled")
+	}
+
+	key := []byte(cfg.Salt)
+	return func(userID string) string {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(userID))
+		return hex.EncodeToString(mac.Sum(nil))
+	}, nil
+}
+
+// hashUserID 开启 user_id 哈希时将事件的 user_id 替换为哈希值，空值保持为空，不计入独立用户
+func (s *logService) hashUserID(base *models.BaseLog) {
+	if s.userIDHash == nil || base.UserID == "" {
+		return
+	}
+	base.UserID = s.userIDHash(base.UserID)
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"spectra-backend/metrics"
+	"spectra-backend/models"
+)
+
+// ValidationError 上报事件校验失败，处理器据此返回 422
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// maxSDKFieldLength sdk_version 和 platform 的最大字节数
+const maxSDKFieldLength = 64
+
+// validateBase 校验所有事件共有的字段，project_id 按规则规范化后保存
+// 项目数限制不在此处计入，由调用方在全部校验通过后调用 admitProjects
+func (s *logService) validateBase(base *models.BaseLog, eventType string) error {
+	projectID, err := s.projectIDs.Normalize(base.ProjectID)
+	if err != nil {
+		return err
+	}
+	base.ProjectID = projectID
+	if len(base.SDKVersion) > maxSDKFieldLength {
+		return &ValidationError{Field: "sdk_version", Message: fmt.Sprintf("must be at most %d bytes", maxSDKFieldLength)}
+	}
+	if len(base.Platform) > maxSDKFieldLength {
+		return &ValidationError{Field: "platform", Message: fmt.Sprintf("must be at most %d bytes", maxSDKFieldLength)}
+	}
+	return s.validateExtra(base.Extra, eventType)
+}
+
+// validateExtra 校验 Extra 字段的大小和 JSON 合法性，并记录大小分布
+func (s *logService) validateExtra(extra json.RawMessage, eventType string) error {
+	trimmed := bytes.TrimSpace(extra)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return nil
+	}
+
+	metrics.ExtraSizeBytes.WithLabelValues(eventType).Observe(float64(len(trimmed)))
+
+	if s.ingest.MaxExtraBytes > 0 && len(trimmed) > s.ingest.MaxExtraBytes {
+		return &ValidationError{
+			Field:   "extra",
+			Message: fmt.Sprintf("payload is %d bytes, exceeds limit of %d bytes", len(trimmed), s.ingest.MaxExtraBytes),
+		}
+	}
+	if !json.Valid(trimmed) {
+		return &ValidationError{Field: "extra", Message: "must be valid JSON"}
+	}
+	return nil
+}
+
+// prepareEvent 按事件类型填充默认字段并校验，event 必须是事件模型的指针
+func (s *logService) prepareEvent(event any) error {
+	switch e := event.(type) {
+	case *models.ErrorLog:
+		return s.prepareErrorLog(e)
+	case *models.PerformanceMetric:
+		return s.preparePerformanceMetric(e)
+	case *models.UserAction:
+		return s.prepareUserAction(e)
+	case *models.CustomEvent:
+		return s.prepareCustomEvent(e)
+	case *models.PageStay:
+		return s.preparePageStay(e)
+	default:
+		return fmt.Errorf("unsupported event %T", event)
+	}
+}
+
+// eventBase 返回事件模型中的公共字段，event 不是事件模型的指针时返回 nil
+func eventBase(event any) *models.BaseLog {
+	switch e := event.(type) {
+	case *models.ErrorLog:
+		return &e.BaseLog
+	case *models.PerformanceMetric:
+		return &e.BaseLog
+	case *models.UserAction:
+		return &e.BaseLog
+	case *models.CustomEvent:
+		return &e.BaseLog
+	case *models.PageStay:
+		return &e.BaseLog
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"spectra-backend/config"
+	"spectra-backend/models"
+	"strings"
+	"time"
+)
+
+// newWebVitals 根据配置生成评分卡统计的指标列表
+// 配置中的名称统一转为大写并按指标别名转换为标准名称，与保存性能指标时的名称一致
+func (s *logService) newWebVitals(thresholds map[string]config.WebVitalThreshold) []models.WebVitalScore {
+	vitals := make([]models.WebVitalScore, 0, len(thresholds))
+	for name, threshold := range thresholds {
+		vitals = append(vitals, models.WebVitalScore{
+			Name:          s.canonicalMetricName(strings.ToUpper(name)),
+			GoodThreshold: threshold.Good,
+			PoorThreshold: threshold.Poor,
+		})
+	}
+	sort.Slice(vitals, func(i, j int) bool {
+		return vitals[i].Name < vitals[j].Name
+	})
+	return vitals
+}
+
+// GetWebVitalsScorecard 统计核心 Web Vitals 的良好/需改进/差占比，并按 p75 给出评级，样本数少于 minCount 的指标标记为低置信度
+func (s *logService) GetWebVitalsScorecard(ctx context.Context, projectIDs []string, minCount int, startTime, endTime time.Time) (*models.WebVitalsScorecard, error) {
+	scores, err := s.repo.GetWebVitals(ctx, projectIDs, s.webVitals, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range scores {
+		score := &scores[i]
+		score.LowConfidence = lowConfidence(score.Total, minCount)
+		if score.Total == 0 {
+			continue
+		}
+		total := float64(score.Total)
+		good := float64(score.Good) / total
+		needsImprovement := float64(score.NeedsImprovement) / total
+		poor := float64(score.Poor) / total
+		score.GoodRatio = &good
+		score.NeedsImprovementRatio = &needsImprovement
+		score.PoorRatio = &poor
+		if score.P75 != nil {
+			rating := webVitalRating(*score.P75, score.GoodThreshold, score.PoorThreshold)
+			score.Rating = &rating
+		}
+	}
+
+	return &models.WebVitalsScorecard{
+		StartTime: startTime,
+		EndTime:   endTime,
+		Metrics:   scores,
+	}, nil
+}
+
+// webVitalRating 按阈值评级，与分档统计的边界一致
+func webVitalRating(value, good, poor float64) string {
+	switch {
+	case value <= good:
+		return models.WebVitalRatingGood
+	case value <= poor:
+		return models.WebVitalRatingNeedsImprovement
+	default:
+		return models.WebVitalRatingPoor
+	}
+}